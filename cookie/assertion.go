@@ -0,0 +1,135 @@
+package cookie
+
+import (
+	"crypto/ed25519"
+	"slices"
+	"time"
+
+	"aidanwoods.dev/go-paseto"
+	"github.com/go-playground/errors/v5"
+)
+
+const (
+	// SessionAssertionPurpose is the purpose of the assertions issued for a session by the session handlers
+	SessionAssertionPurpose = "ccc-session-assertion"
+
+	// AssertionSessionID is the key of the session ID in a session assertion. The username is its subject.
+	AssertionSessionID Key = "sessionID"
+	// AssertionAuthMethod is the key of the authentication method of the session in a session assertion
+	AssertionAuthMethod Key = "authMethod"
+	// AssertionImpersonator is the key of the impersonator of the session in a session assertion, if any
+	AssertionImpersonator Key = "impersonator"
+)
+
+// Signer signs assertions using PASETO v4.public with an Ed25519 key. Unlike cookies, assertions are not
+// encrypted: they can be read by anyone, and verified by other services with only the public key.
+type Signer struct {
+	keyID     string
+	secretKey paseto.V4AsymmetricSecretKey
+	publicKey ed25519.PublicKey
+}
+
+// NewSigner returns a new Signer. keyID, which can be empty, is written in the footer of the assertions
+// so that a Verifier with several keys uses the matching public key.
+func NewSigner(keyID string, privateKey ed25519.PrivateKey) (*Signer, error) {
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return nil, errors.Newf("invalid Ed25519 private key size %d", len(privateKey))
+	}
+
+	secretKey, err := paseto.NewV4AsymmetricSecretKeyFromEd25519(privateKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "paseto.NewV4AsymmetricSecretKeyFromEd25519()")
+	}
+
+	publicKey, ok := privateKey.Public().(ed25519.PublicKey)
+	if !ok {
+		return nil, errors.New("failed to derive the Ed25519 public key")
+	}
+
+	return &Signer{
+		keyID:     keyID,
+		secretKey: secretKey,
+		publicKey: publicKey,
+	}, nil
+}
+
+// PublicKey returns the key that verifies the assertions signed by s
+func (s *Signer) PublicKey() VerificationKey {
+	return VerificationKey{ID: s.keyID, PublicKey: s.publicKey}
+}
+
+// Sign signs the values as an assertion for purpose that expires at expiration, and returns the assertion.
+// The assertion is issued and valid from now, and can only be verified for the same purpose.
+func (s *Signer) Sign(purpose string, expiration time.Time, values *Values) string {
+	now := time.Now()
+	values.token.SetIssuedAt(now)
+	values.token.SetNotBefore(now)
+	values.token.SetExpiration(expiration)
+	values.setKeyID(s.keyID)
+	values.retiredKey = false
+
+	return values.token.V4Sign(s.secretKey, []byte(purpose))
+}
+
+// VerificationKey is an Ed25519 public key with the ID of its Signer
+type VerificationKey struct {
+	// ID of the key, as given to NewSigner
+	ID        string
+	PublicKey ed25519.PublicKey
+}
+
+// Verifier verifies the assertions signed by Signer
+type Verifier struct {
+	keys []verificationKey
+}
+
+// verificationKey is a public key with its optional ID
+type verificationKey struct {
+	id  string
+	key paseto.V4AsymmetricPublicKey
+}
+
+// NewVerifier returns a new Verifier for assertions signed with any of the keys
+func NewVerifier(keys ...VerificationKey) (*Verifier, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("at least one verification key is required")
+	}
+
+	verificationKeys := make([]verificationKey, 0, len(keys))
+	for i, key := range keys {
+		if len(key.PublicKey) != ed25519.PublicKeySize {
+			return nil, errors.Newf("invalid Ed25519 public key size %d: key %d", len(key.PublicKey), i)
+		}
+		publicKey, err := paseto.NewV4AsymmetricPublicKeyFromEd25519(key.PublicKey)
+		if err != nil {
+			return nil, errors.Wrapf(err, "paseto.NewV4AsymmetricPublicKeyFromEd25519(): key %d", i)
+		}
+		verificationKeys = append(verificationKeys, verificationKey{id: key.ID, key: publicKey})
+	}
+
+	return &Verifier{keys: verificationKeys}, nil
+}
+
+// Verify verifies an assertion signed for purpose and returns its values. The key with the ID in the
+// footer of the assertion is tried first. An assertion is not valid before its not-before time or after
+// its expiration.
+func (v *Verifier) Verify(purpose, assertion string) (*Values, error) {
+	parser := paseto.NewParser()
+	parser.AddRule(notBefore)
+
+	keys := slices.Clone(v.keys)
+	sortKeyIDFirst(keys, func(k verificationKey) string { return k.id }, footerKeyID(parser, paseto.V4Public, assertion))
+
+	var firstErr error
+	for _, key := range keys {
+		token, err := parser.ParseV4Public(key.key, assertion, []byte(purpose))
+		if err == nil {
+			return &Values{token: *token}, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return nil, errors.Wrap(firstErr, "paseto.ParseV4Public()")
+}
@@ -0,0 +1,102 @@
+package cookie
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	"time"
+)
+
+func newTestSigner(t *testing.T, keyID string) *Signer {
+	t.Helper()
+
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("ed25519.GenerateKey() error = %v", err)
+	}
+	signer, err := NewSigner(keyID, privateKey)
+	if err != nil {
+		t.Fatalf("NewSigner() error = %v", err)
+	}
+
+	return signer
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	t.Parallel()
+
+	signer := newTestSigner(t, "2026")
+	oldSigner := newTestSigner(t, "2025")
+	otherSigner := newTestSigner(t, "")
+
+	verifier, err := NewVerifier(signer.PublicKey(), oldSigner.PublicKey())
+	if err != nil {
+		t.Fatalf("NewVerifier() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		signer     *Signer
+		purpose    string
+		expiration time.Time
+		wantKeyID  string
+		wantErr    bool
+	}{
+		{
+			name:       "signed with the current key",
+			signer:     signer,
+			purpose:    SessionAssertionPurpose,
+			expiration: time.Now().Add(time.Minute),
+			wantKeyID:  "2026",
+		},
+		{
+			name:       "signed with an older key",
+			signer:     oldSigner,
+			purpose:    SessionAssertionPurpose,
+			expiration: time.Now().Add(time.Minute),
+			wantKeyID:  "2025",
+		},
+		{
+			name:       "signed with an unknown key",
+			signer:     otherSigner,
+			purpose:    SessionAssertionPurpose,
+			expiration: time.Now().Add(time.Minute),
+			wantErr:    true,
+		},
+		{
+			name:       "signed for another purpose",
+			signer:     signer,
+			purpose:    "other",
+			expiration: time.Now().Add(time.Minute),
+			wantErr:    true,
+		},
+		{
+			name:       "expired",
+			signer:     signer,
+			purpose:    SessionAssertionPurpose,
+			expiration: time.Now().Add(-time.Minute),
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assertion := tt.signer.Sign(tt.purpose, tt.expiration, NewValues().SetSubject("test_user"))
+
+			got, err := verifier.Verify(SessionAssertionPurpose, assertion)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Verifier.Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.KeyID() != tt.wantKeyID {
+				t.Errorf("Values.KeyID() = %v, want %v", got.KeyID(), tt.wantKeyID)
+			}
+			if sub, err := got.GetSubject(); err != nil || sub != "test_user" {
+				t.Errorf("Values.GetSubject() = %v, %v, want test_user", sub, err)
+			}
+		})
+	}
+}
@@ -2,7 +2,9 @@
 package cookie
 
 import (
+	"encoding/json"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
@@ -13,20 +15,65 @@ import (
 
 // Client implements reading and writing encrypted cookies
 type Client struct {
-	pasetoKey paseto.V4SymmetricKey
+	pasetoKey   identifiedKey
+	retiredKeys []identifiedKey
+	attributes  Attributes
+}
+
+// identifiedKey is a symmetric key with its optional ID
+type identifiedKey struct {
+	id  string
+	key paseto.V4SymmetricKey
+}
+
+// IdentifiedKey is a key with an ID. The ID of the key that encrypts a cookie is written in the
+// footer of the cookie, so that it is decrypted with the same key.
+type IdentifiedKey struct {
+	// ID of the key. It is not encrypted, so it must not reveal the key.
+	ID string
+	// KeyBase64 is a base64-encoded string of at least 32 random bytes
+	KeyBase64 string
 }
 
 // New returns a new Client
 // keyBase64 must be a base64-encoded string of at least 32 random bytes,
 // used to derived the symmetric key.
-func New(keyBase64 string) (*Client, error) {
-	pasetoKey, err := createPasetoKey(keyBase64)
+// retiredKeysBase64 are keys previously used as keyBase64. Cookies are always encrypted with keyBase64,
+// but cookies encrypted with a retired key can still be read, so that the key can be rotated without
+// invalidating existing cookies.
+func New(keyBase64 string, retiredKeysBase64 ...string) (*Client, error) {
+	retiredKeys := make([]IdentifiedKey, 0, len(retiredKeysBase64))
+	for _, retiredKeyBase64 := range retiredKeysBase64 {
+		retiredKeys = append(retiredKeys, IdentifiedKey{KeyBase64: retiredKeyBase64})
+	}
+
+	return NewWithKeyIDs(IdentifiedKey{KeyBase64: keyBase64}, retiredKeys...)
+}
+
+// NewWithKeyIDs returns a new Client like New, with keys that have IDs. The ID of key is written in the
+// footer of the cookies, and a cookie is decrypted with the key that has the ID in its footer first.
+func NewWithKeyIDs(key IdentifiedKey, retiredKeys ...IdentifiedKey) (*Client, error) {
+	pasetoKey, err := createPasetoKey(key.KeyBase64)
 	if err != nil {
 		return nil, errors.Wrap(err, "createPasetoKey()")
 	}
 
+	retiredPasetoKeys := make([]identifiedKey, 0, len(retiredKeys))
+	for i, retiredKey := range retiredKeys {
+		if retiredKey.KeyBase64 == "" {
+			return nil, errors.Newf("retired key %d is empty", i)
+		}
+		retiredPasetoKey, err := createPasetoKey(retiredKey.KeyBase64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "createPasetoKey(): retired key %d", i)
+		}
+		retiredPasetoKeys = append(retiredPasetoKeys, identifiedKey{id: retiredKey.ID, key: retiredPasetoKey})
+	}
+
 	client := &Client{
-		pasetoKey: pasetoKey,
+		pasetoKey:   identifiedKey{id: key.ID, key: pasetoKey},
+		retiredKeys: retiredPasetoKeys,
+		attributes:  Attributes{SameSite: http.SameSiteDefaultMode},
 	}
 
 	return client, nil
@@ -48,7 +95,7 @@ func (c *Client) Read(r *http.Request, cookieName string) (values *Values, found
 		if strings.Contains(err.Error(), "this token has expired") {
 			return cval, false, nil
 		}
-		logger.FromReq(r).Warnf("Invalid cookie or encryption key was rotated: %v", err)
+		logger.FromReq(r).Warnf("Invalid cookie or cookie encrypted with an unknown key: %v", err)
 
 		return cval, false, nil
 	}
@@ -56,62 +103,156 @@ func (c *Client) Read(r *http.Request, cookieName string) (values *Values, found
 	return cval, true, nil
 }
 
+// Attributes are the attributes of a cookie written by Client, other than its name, value and expiration.
+type Attributes struct {
+	// Path of the cookie. An empty Path is written as "/".
+	Path     string
+	Domain   string
+	HttpOnly bool
+	SameSite http.SameSite
+	// Partitioned stores the cookie separately for each top-level site (CHIPS).
+	// It is only written on Secure cookies, as browsers reject it otherwise.
+	Partitioned bool
+}
+
+// cookie returns an http.Cookie with the attributes
+func (a Attributes) cookie(cookieName, value string, expires time.Time) *http.Cookie {
+	path := a.Path
+	if path == "" {
+		path = "/"
+	}
+
+	return &http.Cookie{
+		Name:        cookieName,
+		Expires:     expires,
+		Value:       value,
+		Path:        path,
+		Domain:      a.Domain,
+		Secure:      SecureCookie(),
+		HttpOnly:    a.HttpOnly,
+		SameSite:    a.SameSite,
+		Partitioned: a.Partitioned && SecureCookie(),
+	}
+}
+
 // WriteSessionCookie writes a session cookie to the response
 func (c *Client) WriteSessionCookie(w http.ResponseWriter, cookieName, domain string, httpOnly bool, sameSite http.SameSite, values *Values) {
-	http.SetCookie(w, &http.Cookie{
-		Name:     cookieName,
-		Expires:  time.Time{},
-		Value:    c.Encrypt(cookieName, time.Now().AddDate(10, 0, 0), values),
-		Path:     "/",
-		Domain:   domain,
-		Secure:   SecureCookie(),
-		HttpOnly: httpOnly,
-		SameSite: sameSite,
-	})
+	c.WriteSessionCookieWithAttributes(w, cookieName, Attributes{Domain: domain, HttpOnly: httpOnly, SameSite: sameSite}, values)
+}
+
+// WriteSessionCookieWithAttributes writes a session cookie with the given attributes to the response
+func (c *Client) WriteSessionCookieWithAttributes(w http.ResponseWriter, cookieName string, attrs Attributes, values *Values) {
+	http.SetCookie(w, attrs.cookie(cookieName, c.Encrypt(cookieName, time.Now().AddDate(10, 0, 0), values), time.Time{}))
 }
 
 // WritePersistentCookie writes a persistent cookie to the response
 func (c *Client) WritePersistentCookie(w http.ResponseWriter, cookieName, domain string, httpOnly bool, sameSite http.SameSite, expiration time.Duration, values *Values) {
+	c.WritePersistentCookieWithAttributes(w, cookieName, Attributes{Domain: domain, HttpOnly: httpOnly, SameSite: sameSite}, expiration, values)
+}
+
+// WritePersistentCookieWithAttributes writes a persistent cookie with the given attributes to the response
+func (c *Client) WritePersistentCookieWithAttributes(w http.ResponseWriter, cookieName string, attrs Attributes, expiration time.Duration, values *Values) {
 	expirationTime := time.Now().Add(expiration)
-	http.SetCookie(w, &http.Cookie{
-		Name:     cookieName,
-		Expires:  expirationTime,
-		Value:    c.Encrypt(cookieName, expirationTime, values),
-		Path:     "/",
-		Domain:   domain,
-		Secure:   SecureCookie(),
-		HttpOnly: httpOnly,
-		SameSite: sameSite,
-	})
+	http.SetCookie(w, attrs.cookie(cookieName, c.Encrypt(cookieName, expirationTime, values), expirationTime))
+}
+
+// SetAttributes sets the attributes of the cookies written by the client, which Delete deletes cookies with.
+// It should be called before the client is used. (default: the Path "/" and no Domain)
+func (c *Client) SetAttributes(attrs Attributes) {
+	c.attributes = attrs
 }
 
-// Delete deletes a cookie from the response
+// Delete deletes a cookie written with the attributes set with SetAttributes from the response
 func (c *Client) Delete(w http.ResponseWriter, cookieName string) {
-	http.SetCookie(w, &http.Cookie{
-		Name:     cookieName,
-		Expires:  time.Unix(0, 0),
-		Value:    "",
-		Path:     "/",
-		Domain:   "",
-		Secure:   SecureCookie(),
-		HttpOnly: false,
-		SameSite: http.SameSiteDefaultMode,
-	})
+	c.DeleteWithAttributes(w, cookieName, c.attributes)
+}
+
+// DeleteWithAttributes deletes a cookie written with the given attributes from the response.
+// Browsers only delete a cookie when the Path and Domain match the ones it was written with.
+func (c *Client) DeleteWithAttributes(w http.ResponseWriter, cookieName string, attrs Attributes) {
+	http.SetCookie(w, attrs.cookie(cookieName, "", time.Unix(0, 0)))
 }
 
-// Encrypt encrypts a cookie and returns the value
+// Encrypt encrypts a cookie with the primary key and returns the value.
+// The ID of the primary key, if any, is written in the footer of the cookie.
 func (c *Client) Encrypt(cookieName string, expiration time.Time, values *Values) string {
 	values.token.SetExpiration(expiration)
+	values.setKeyID(c.pasetoKey.id)
+	values.retiredKey = false
 
-	return values.token.V4Encrypt(c.pasetoKey, []byte(cookieName))
+	return values.token.V4Encrypt(c.pasetoKey.key, []byte(cookieName))
 }
 
-// Decrypt decrypts a cookie and returns the values
+// Decrypt decrypts a cookie with the primary key, or else with the first retired key that
+// can decrypt it, and returns the values. The key with the ID in the footer of the cookie
+// is tried first. Values.RetiredKey reports if a retired key was used.
+// A cookie is not valid before its not-before time, when it has one. When a key authenticates
+// the cookie, but it is not valid, such as when it has expired, the paseto.RuleError is returned.
 func (c *Client) Decrypt(cookieName, cookieValue string) (*Values, error) {
-	token, err := paseto.NewParser().ParseV4Local(c.pasetoKey, cookieValue, []byte(cookieName))
-	if err != nil {
-		return nil, errors.Wrap(err, "paseto.ParseV4Local()")
+	parser := paseto.NewParser()
+	parser.AddRule(notBefore)
+
+	keys := make([]identifiedKey, 0, len(c.retiredKeys)+1)
+	keys = append(keys, c.pasetoKey)
+	keys = append(keys, c.retiredKeys...)
+	sortKeyIDFirst(keys, func(k identifiedKey) string { return k.id }, footerKeyID(parser, paseto.V4Local, cookieValue))
+
+	var firstErr error
+	for _, key := range keys {
+		token, err := parser.ParseV4Local(key.key, cookieValue, []byte(cookieName))
+		if err == nil {
+			return &Values{token: *token, retiredKey: key != c.pasetoKey}, nil
+		}
+		// The cookie was encrypted with this key, so the other keys can not do better
+		if errors.Is(err, paseto.RuleError{}) {
+			return nil, errors.Wrap(err, "paseto.ParseV4Local()")
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return nil, errors.Wrap(firstErr, "paseto.ParseV4Local()")
+}
+
+// footerKeyID returns the key ID in the footer of the token, or an empty string if it has none
+func footerKeyID(parser paseto.Parser, protocol paseto.Protocol, token string) string {
+	f, err := parser.UnsafeParseFooter(protocol, token)
+	if err != nil || len(f) == 0 {
+		return ""
+	}
+
+	var decoded footer
+	if err := json.Unmarshal(f, &decoded); err != nil {
+		return ""
+	}
+
+	return decoded.KeyID
+}
+
+// sortKeyIDFirst moves the keys with keyID before the other keys
+func sortKeyIDFirst[K any](keys []K, id func(K) string, keyID string) {
+	if keyID == "" {
+		return
+	}
+
+	slices.SortStableFunc(keys, func(a, b K) int {
+		switch {
+		case id(a) == keyID && id(b) != keyID:
+			return -1
+		case id(a) != keyID && id(b) == keyID:
+			return 1
+		default:
+			return 0
+		}
+	})
+}
+
+// notBefore is a paseto.Rule that rejects a token before its not-before time, when it has one
+func notBefore(token paseto.Token) error {
+	if _, ok := token.Claims()["nbf"]; !ok {
+		return nil
 	}
 
-	return &Values{token: *token}, nil
+	return paseto.NotBeforeNbf()(token)
 }
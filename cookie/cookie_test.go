@@ -0,0 +1,120 @@
+package cookie
+
+import (
+	"testing"
+	"time"
+
+	"aidanwoods.dev/go-paseto"
+	"github.com/go-playground/errors/v5"
+)
+
+func TestClient_Decrypt(t *testing.T) {
+	t.Parallel()
+
+	const (
+		oldKey   = "Rsgb6WsDvBsMQ5IJr2WJjVLCPO+o9WW6SdVktdaaq9O0WFA0Hc/EmJeOwCGV6LIqG8ue3iSZ/lycpv8ZNKvWjWU42hZnlO15vYANZG89R1ncjmu4KStldFuP/r0RFhZa"
+		otherKey = "/7g/JKoRb43cvFI+q2iwA3Ru9ZTXI4enxfxiDpfZg0mw/jX4HjjaIFxyAnz0KeyNoPfSgLAHuQlPqBkCa/NEMw=="
+		newKey   = "b2q0GfY0c3mRqL3pQv6xN1z8wK5tH7uJ9aS2dE4fG6hI8jK0lM2nO4pQ6rS8tU0vW2xY4zA6bC8dE0fG2hI4jK6l"
+	)
+
+	oldClient, err := NewWithKeyIDs(IdentifiedKey{ID: "2025", KeyBase64: oldKey})
+	if err != nil {
+		t.Fatalf("NewWithKeyIDs() error = %v", err)
+	}
+	oldClientWithoutID, err := New(oldKey)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	otherClient, err := New(otherKey)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	newClient, err := NewWithKeyIDs(IdentifiedKey{ID: "2026", KeyBase64: newKey}, IdentifiedKey{ID: "2025", KeyBase64: oldKey})
+	if err != nil {
+		t.Fatalf("NewWithKeyIDs() error = %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		client         *Client
+		values         *Values
+		expiration     time.Duration
+		wantKeyID      string
+		wantRetiredKey bool
+		wantErr        bool
+		wantRuleErr    bool
+	}{
+		{
+			name:      "primary key",
+			client:    newClient,
+			values:    NewValues(),
+			wantKeyID: "2026",
+		},
+		{
+			name:           "retired key with its ID",
+			client:         oldClient,
+			values:         NewValues(),
+			wantKeyID:      "2025",
+			wantRetiredKey: true,
+		},
+		{
+			name:           "retired key without an ID",
+			client:         oldClientWithoutID,
+			values:         NewValues(),
+			wantRetiredKey: true,
+		},
+		{
+			name:        "not before is in the future",
+			client:      newClient,
+			values:      NewValues().SetNotBefore(time.Now().Add(time.Hour)),
+			wantErr:     true,
+			wantRuleErr: true,
+		},
+		{
+			name:        "expired with a retired key without an ID",
+			client:      oldClientWithoutID,
+			values:      NewValues(),
+			expiration:  -time.Hour,
+			wantErr:     true,
+			wantRuleErr: true,
+		},
+		{
+			name:    "unknown key",
+			client:  otherClient,
+			values:  NewValues(),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			expiration := tt.expiration
+			if expiration == 0 {
+				expiration = time.Hour
+			}
+			encrypted := tt.client.Encrypt("cookie", time.Now().Add(expiration), tt.values.SetString("name", "value"))
+
+			got, err := newClient.Decrypt("cookie", encrypted)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Client.Decrypt() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if errors.Is(err, paseto.RuleError{}) != tt.wantRuleErr {
+					t.Errorf("Client.Decrypt() error = %v, wantRuleErr %v", err, tt.wantRuleErr)
+				}
+
+				return
+			}
+			if got.KeyID() != tt.wantKeyID {
+				t.Errorf("Values.KeyID() = %v, want %v", got.KeyID(), tt.wantKeyID)
+			}
+			if got.RetiredKey() != tt.wantRetiredKey {
+				t.Errorf("Values.RetiredKey() = %v, want %v", got.RetiredKey(), tt.wantRetiredKey)
+			}
+			if value, err := got.GetString("name"); err != nil || value != "value" {
+				t.Errorf("Values.GetString() = %v, %v, want value", value, err)
+			}
+		})
+	}
+}
@@ -1,6 +1,9 @@
 package cookie
 
 import (
+	"encoding/json"
+	"slices"
+	"strings"
 	"time"
 
 	"aidanwoods.dev/go-paseto"
@@ -21,6 +24,9 @@ type (
 // Values holds data to be stored in the cookie
 type Values struct {
 	token paseto.Token
+
+	// retiredKey is set when the values were decrypted with a retired key
+	retiredKey bool
 }
 
 // NewValues returns a new empty token
@@ -28,6 +34,43 @@ func NewValues() *Values {
 	return &Values{token: paseto.NewToken()}
 }
 
+// footer is the unencrypted, authenticated footer of the cookie
+type footer struct {
+	// KeyID identifies the key that encrypted the cookie
+	KeyID string `json:"kid,omitempty"`
+}
+
+// KeyID returns the ID of the key that encrypted the cookie, read from its footer.
+// It is empty when the key has no ID.
+func (v *Values) KeyID() string {
+	var f footer
+	if err := json.Unmarshal(v.token.Footer(), &f); err != nil {
+		return ""
+	}
+
+	return f.KeyID
+}
+
+// setKeyID writes keyID in the footer, or removes the footer when keyID is empty
+func (v *Values) setKeyID(keyID string) {
+	v.token.SetFooter(nil)
+	if keyID == "" {
+		return
+	}
+
+	f, err := json.Marshal(footer{KeyID: keyID})
+	if err != nil {
+		panic(errors.Wrap(err, "json.Marshal()"))
+	}
+	v.token.SetFooter(f)
+}
+
+// RetiredKey reports if the values were read from a cookie encrypted with a retired key.
+// The cookie should be written again so that it is encrypted with the primary key.
+func (v *Values) RetiredKey() bool {
+	return v.retiredKey
+}
+
 // Get gets the given key and writes the value into output (which should be a a pointer), if present by parsing the JSON using encoding/json.
 func (v *Values) Get(key Key, output any) (err error) {
 	if err := v.token.Get(keyPrefix+string(key), output); err != nil {
@@ -37,6 +80,17 @@ func (v *Values) Get(key Key, output any) (err error) {
 	return nil
 }
 
+// GetAs returns the value for a given key decoded as T using encoding/json, or error if this is not possible
+// (cannot be decoded as T, or value does not exist)
+func GetAs[T any](v *Values, key Key) (T, error) {
+	var value T
+	if err := v.Get(key, &value); err != nil {
+		return value, err
+	}
+
+	return value, nil
+}
+
 // GetString returns the value for a given key as a string, or error if this is not possible (cannot be a string, or value does not exist)
 func (v *Values) GetString(key Key) (string, error) {
 	value, err := v.token.GetString(keyPrefix + string(key))
@@ -57,6 +111,51 @@ func (v *Values) GetTime(key Key) (time.Time, error) {
 	return t, nil
 }
 
+// GetInt returns the value for a given key as an int, or error if this is not possible (cannot be an int, or value does not exist)
+func (v *Values) GetInt(key Key) (int, error) {
+	return GetAs[int](v, key)
+}
+
+// GetBool returns the value for a given key as a bool, or error if this is not possible (cannot be a bool, or value does not exist)
+func (v *Values) GetBool(key Key) (bool, error) {
+	return GetAs[bool](v, key)
+}
+
+// Keys returns the keys set in the values, sorted. Standard PASETO claims are not included.
+func (v *Values) Keys() []Key {
+	var keys []Key
+	for claim := range v.token.Claims() {
+		if key, ok := strings.CutPrefix(claim, keyPrefix); ok {
+			keys = append(keys, Key(key))
+		}
+	}
+	slices.Sort(keys)
+
+	return keys
+}
+
+// Delete removes the given key. If, for some reason, the remaining values cannot be serialized as JSON Delete will panic.
+func (v *Values) Delete(key Key) *Values {
+	var claims map[string]json.RawMessage
+	if err := json.Unmarshal(v.token.ClaimsJSON(), &claims); err != nil {
+		panic(errors.Wrap(err, "json.Unmarshal()"))
+	}
+	delete(claims, keyPrefix+string(key))
+
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		panic(errors.Wrap(err, "json.Marshal()"))
+	}
+
+	token, err := paseto.NewTokenFromClaimsJSON(claimsJSON, v.token.Footer())
+	if err != nil {
+		panic(errors.Wrap(err, "paseto.NewTokenFromClaimsJSON()"))
+	}
+	v.token = *token
+
+	return v
+}
+
 // Set sets the key with the specified value. Note that this value needs to be serialisable to JSON using encoding/json. Set will check this and return an error if it is not serialisable.
 func (v *Values) Set(key Key, value any) error {
 	if err := v.token.Set(keyPrefix+string(key), value); err != nil {
@@ -79,3 +178,117 @@ func (v *Values) SetTime(key Key, value time.Time) *Values {
 
 	return v
 }
+
+// SetInt sets the given key with value.
+func (v *Values) SetInt(key Key, value int) *Values {
+	if err := v.Set(key, value); err != nil {
+		panic(err)
+	}
+
+	return v
+}
+
+// SetBool sets the given key with value.
+func (v *Values) SetBool(key Key, value bool) *Values {
+	if err := v.Set(key, value); err != nil {
+		panic(err)
+	}
+
+	return v
+}
+
+// GetIssuedAt returns the issued-at time (standard "iat" claim), or error if it is not set
+func (v *Values) GetIssuedAt() (time.Time, error) {
+	t, err := v.token.GetIssuedAt()
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "token.GetIssuedAt()")
+	}
+
+	return t, nil
+}
+
+// SetIssuedAt sets the issued-at time (standard "iat" claim).
+func (v *Values) SetIssuedAt(value time.Time) *Values {
+	v.token.SetIssuedAt(value)
+
+	return v
+}
+
+// GetNotBefore returns the not-before time (standard "nbf" claim), or error if it is not set
+func (v *Values) GetNotBefore() (time.Time, error) {
+	t, err := v.token.GetNotBefore()
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "token.GetNotBefore()")
+	}
+
+	return t, nil
+}
+
+// SetNotBefore sets the not-before time (standard "nbf" claim). The cookie can not be read before this time.
+func (v *Values) SetNotBefore(value time.Time) *Values {
+	v.token.SetNotBefore(value)
+
+	return v
+}
+
+// GetExpiration returns the expiration time (standard "exp" claim), set when the cookie is written,
+// or error if it is not set
+func (v *Values) GetExpiration() (time.Time, error) {
+	t, err := v.token.GetExpiration()
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "token.GetExpiration()")
+	}
+
+	return t, nil
+}
+
+// GetAudience returns the audience (standard "aud" claim), or error if it is not set
+func (v *Values) GetAudience() (string, error) {
+	aud, err := v.token.GetAudience()
+	if err != nil {
+		return "", errors.Wrap(err, "token.GetAudience()")
+	}
+
+	return aud, nil
+}
+
+// SetAudience sets the audience (standard "aud" claim).
+func (v *Values) SetAudience(value string) *Values {
+	v.token.SetAudience(value)
+
+	return v
+}
+
+// GetSubject returns the subject (standard "sub" claim), or error if it is not set
+func (v *Values) GetSubject() (string, error) {
+	sub, err := v.token.GetSubject()
+	if err != nil {
+		return "", errors.Wrap(err, "token.GetSubject()")
+	}
+
+	return sub, nil
+}
+
+// SetSubject sets the subject (standard "sub" claim).
+func (v *Values) SetSubject(value string) *Values {
+	v.token.SetSubject(value)
+
+	return v
+}
+
+// GetIssuer returns the issuer (standard "iss" claim), or error if it is not set
+func (v *Values) GetIssuer() (string, error) {
+	iss, err := v.token.GetIssuer()
+	if err != nil {
+		return "", errors.Wrap(err, "token.GetIssuer()")
+	}
+
+	return iss, nil
+}
+
+// SetIssuer sets the issuer (standard "iss" claim).
+func (v *Values) SetIssuer(value string) *Values {
+	v.token.SetIssuer(value)
+
+	return v
+}
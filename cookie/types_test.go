@@ -0,0 +1,55 @@
+package cookie
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestValues_typed(t *testing.T) {
+	t.Parallel()
+
+	type point struct {
+		X int `json:"x"`
+		Y int `json:"y"`
+	}
+
+	v := NewValues().
+		SetString("name", "value").
+		SetInt("count", 3).
+		SetBool("enabled", true).
+		SetSubject("test_user")
+	if err := v.Set("point", point{X: 1, Y: 2}); err != nil {
+		t.Fatalf("Values.Set() error = %v", err)
+	}
+
+	if got, err := v.GetInt("count"); err != nil || got != 3 {
+		t.Errorf("Values.GetInt() = %v, %v, want 3", got, err)
+	}
+	if got, err := v.GetBool("enabled"); err != nil || !got {
+		t.Errorf("Values.GetBool() = %v, %v, want true", got, err)
+	}
+	if got, err := GetAs[point](v, "point"); err != nil || got != (point{X: 1, Y: 2}) {
+		t.Errorf("GetAs() = %v, %v, want %v", got, err, point{X: 1, Y: 2})
+	}
+	if _, err := v.GetInt("name"); err == nil {
+		t.Errorf("Values.GetInt() of a string error = nil, want error")
+	}
+	if got, err := v.GetSubject(); err != nil || got != "test_user" {
+		t.Errorf("Values.GetSubject() = %v, %v, want test_user", got, err)
+	}
+
+	if got, want := v.Keys(), []Key{"count", "enabled", "name", "point"}; !slices.Equal(got, want) {
+		t.Errorf("Values.Keys() = %v, want %v", got, want)
+	}
+
+	v.Delete("name")
+	if _, err := v.GetString("name"); err == nil {
+		t.Errorf("Values.GetString() of a deleted key error = nil, want error")
+	}
+	if got, err := v.GetInt("count"); err != nil || got != 3 {
+		t.Errorf("Values.GetInt() after Delete() = %v, %v, want 3", got, err)
+	}
+	if got, err := v.GetSubject(); err != nil || got != "test_user" {
+		t.Errorf("Values.GetSubject() after Delete() = %v, %v, want test_user", got, err)
+	}
+}
@@ -0,0 +1,38 @@
+package basesession
+
+import (
+	"context"
+	"time"
+
+	"github.com/cccteam/ccc/tracer"
+	"github.com/cccteam/session/cookie"
+	"github.com/cccteam/session/sessioninfo"
+	"github.com/go-playground/errors/v5"
+)
+
+// SessionAssertion returns an assertion of the session in ctx, signed by AssertionSigner, that expires after lifetime.
+// Its subject is the username and it holds the session ID, the authentication method and the impersonator, if any.
+// It is verified with cookie.Verifier for cookie.SessionAssertionPurpose.
+// ValidateSession handler must be called before calling SessionAssertion
+func (s *BaseSession) SessionAssertion(ctx context.Context, lifetime time.Duration) (string, error) {
+	_, span := tracer.Start(ctx)
+	defer span.End()
+
+	if s.AssertionSigner == nil {
+		return "", errors.New("no assertion signer configured")
+	}
+	if lifetime <= 0 {
+		return "", errors.Newf("invalid assertion lifetime %s", lifetime)
+	}
+
+	sessInfo := sessioninfo.FromCtx(ctx)
+	values := cookie.NewValues().
+		SetSubject(sessInfo.Username).
+		SetString(cookie.AssertionSessionID, sessInfo.ID.String()).
+		SetString(cookie.AssertionAuthMethod, string(sessInfo.AuthMethod))
+	if sessInfo.Impersonator != nil {
+		values.SetString(cookie.AssertionImpersonator, sessInfo.Impersonator.Username)
+	}
+
+	return s.AssertionSigner.Sign(cookie.SessionAssertionPurpose, time.Now().Add(lifetime), values), nil
+}
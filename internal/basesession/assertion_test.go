@@ -0,0 +1,93 @@
+package basesession
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/cccteam/ccc"
+	"github.com/cccteam/session/cookie"
+	"github.com/cccteam/session/sessioninfo"
+)
+
+func TestBaseSession_SessionAssertion(t *testing.T) {
+	t.Parallel()
+
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("ed25519.GenerateKey() error = %v", err)
+	}
+	signer, err := cookie.NewSigner("", privateKey)
+	if err != nil {
+		t.Fatalf("cookie.NewSigner() error = %v", err)
+	}
+	verifier, err := cookie.NewVerifier(signer.PublicKey())
+	if err != nil {
+		t.Fatalf("cookie.NewVerifier() error = %v", err)
+	}
+
+	sessionID := ccc.Must(ccc.NewUUID())
+
+	tests := []struct {
+		name             string
+		signer           *cookie.Signer
+		impersonator     *sessioninfo.Impersonator
+		wantImpersonator string
+		wantErr          bool
+	}{
+		{
+			name:   "session",
+			signer: signer,
+		},
+		{
+			name:             "impersonated session",
+			signer:           signer,
+			impersonator:     &sessioninfo.Impersonator{Username: "admin"},
+			wantImpersonator: "admin",
+		},
+		{
+			name:    "no signer",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := &BaseSession{AssertionSigner: tt.signer}
+			ctx := context.WithValue(context.Background(), sessioninfo.CtxSessionInfo, &sessioninfo.SessionInfo{
+				ID:           sessionID,
+				Username:     "test_user",
+				AuthMethod:   sessioninfo.AuthMethodPreauth,
+				Impersonator: tt.impersonator,
+			})
+
+			assertion, err := s.SessionAssertion(ctx, time.Minute)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BaseSession.SessionAssertion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			values, err := verifier.Verify(cookie.SessionAssertionPurpose, assertion)
+			if err != nil {
+				t.Fatalf("cookie.Verifier.Verify() error = %v", err)
+			}
+			if got, _ := values.GetSubject(); got != "test_user" {
+				t.Errorf("subject = %v, want %v", got, "test_user")
+			}
+			if got, _ := values.GetString(cookie.AssertionSessionID); got != sessionID.String() {
+				t.Errorf("session ID = %v, want %v", got, sessionID)
+			}
+			if got, _ := values.GetString(cookie.AssertionAuthMethod); got != string(sessioninfo.AuthMethodPreauth) {
+				t.Errorf("auth method = %v, want %v", got, sessioninfo.AuthMethodPreauth)
+			}
+			if got, _ := values.GetString(cookie.AssertionImpersonator); got != tt.wantImpersonator {
+				t.Errorf("impersonator = %v, want %v", got, tt.wantImpersonator)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ package basesession
 import (
 	"context"
 	"net/http"
+	"net/netip"
 	"strconv"
 	"time"
 
@@ -11,7 +12,9 @@ import (
 	"github.com/cccteam/ccc/tracer"
 	"github.com/cccteam/httpio"
 	"github.com/cccteam/logger"
+	"github.com/cccteam/session/cookie"
 	internalcookie "github.com/cccteam/session/internal/cookie"
+	"github.com/cccteam/session/internal/cookiesession"
 	"github.com/cccteam/session/sessioninfo"
 	"github.com/cccteam/session/sessionstorage"
 	"github.com/go-playground/errors/v5"
@@ -20,12 +23,54 @@ import (
 // LogHandler defines the handler signature required for handling logs.
 type LogHandler func(handler func(w http.ResponseWriter, r *http.Request) error) http.HandlerFunc
 
+// DefaultActivityThrottle is the minimum time between two activity updates of a session
+// when BaseSession.ActivityThrottle is not set.
+const DefaultActivityThrottle = 5 * time.Second
+
 // BaseSession implements the shared features for all session implementations
 type BaseSession struct {
 	SessionTimeout time.Duration
-	Handle         LogHandler
-	Storage        sessionstorage.BaseStore
-	CookieHandler  internalcookie.Handler
+	// MaxSessionLifetime is the maximum time since creation that a session is valid for, regardless of activity.
+	// Zero disables the limit.
+	MaxSessionLifetime time.Duration
+	Handle             LogHandler
+	Storage            sessionstorage.BaseStore
+	CookieHandler      internalcookie.Handler
+	// ActivityThrottle is the minimum time between two activity updates of a session. (default: DefaultActivityThrottle)
+	ActivityThrottle time.Duration
+	// ActivityWriter buffers activity updates when set, instead of writing them during the request.
+	ActivityWriter *sessionstorage.ActivityWriter
+	// RotationInterval is how long a session ID is used before ValidateSession rotates it. Zero disables rotation.
+	RotationInterval time.Duration
+	// RememberMeTimeout is how long a remember-me session stays valid without activity.
+	RememberMeTimeout time.Duration
+	// TrustedProxies are the proxies whose X-Forwarded-For header is used to find the client IP of a request.
+	TrustedProxies []netip.Prefix
+	// MaxSessionsPerUser is the maximum number of active sessions of a user. Zero disables the limit.
+	MaxSessionsPerUser int
+	// SessionLimitPolicy decides what happens when a user with MaxSessionsPerUser active sessions logs in.
+	SessionLimitPolicy sessionstorage.SessionLimitPolicy
+	// Hooks are the callbacks called on the transitions of a session.
+	Hooks Hooks
+	// ImpersonationAuthorizer decides who may impersonate whom. Impersonation is refused when it is nil.
+	ImpersonationAuthorizer ImpersonationAuthorizer
+	// AssertionSigner signs the assertions issued by SessionAssertion. They can not be issued when it is nil.
+	AssertionSigner *cookie.Signer
+}
+
+// ApplySessionLimit sets the limit on active sessions per user on Storage. Sessions count as active
+// until they time out or exceed the MaxSessionLifetime. It must be called once the options are applied.
+func (s *BaseSession) ApplySessionLimit() {
+	if s.MaxSessionsPerUser <= 0 {
+		return
+	}
+
+	s.Storage.SetSessionLimit(sessionstorage.SessionLimit{
+		MaxSessions: s.MaxSessionsPerUser,
+		Policy:      s.SessionLimitPolicy,
+		IdleTimeout: s.SessionTimeout,
+		MaxAge:      s.MaxSessionLifetime,
+	})
 }
 
 // StartSession initializes a session by restoring it from a cookie, or if
@@ -41,6 +86,15 @@ func (s *BaseSession) StartSession(next http.Handler) http.Handler {
 			return httpio.NewEncoder(w).ClientMessage(ctx, err)
 		}
 
+		// Write the session of a stateless store to the Auth Cookie when it changed
+		if session, ok := cookiesession.FromCtx(ctx); ok {
+			sw := s.newCookieSessionWriter(w, r, session)
+			next.ServeHTTP(sw, r.WithContext(ctx))
+			sw.writeSession()
+
+			return nil
+		}
+
 		next.ServeHTTP(w, r.WithContext(ctx))
 
 		return nil
@@ -64,6 +118,15 @@ func (s *BaseSession) StartSessionAPI(ctx context.Context, w http.ResponseWriter
 		}
 	}
 
+	// Read the session of a stateless store from the Auth Cookie
+	if s.stateless() {
+		if foundAuthCookie && validSessionID {
+			ctx = s.loadCookieSession(ctx, cval)
+		} else {
+			ctx = s.loadCookieSession(ctx, nil)
+		}
+	}
+
 	if !foundAuthCookie || !validSessionID {
 		var err error
 		sessionID, err = ccc.NewUUID()
@@ -77,11 +140,17 @@ func (s *BaseSession) StartSessionAPI(ctx context.Context, w http.ResponseWriter
 		// Upgrade cookie to SameSite=Strict
 		// CallbackOIDC() sets it to None to allow OAuth flow to work
 		s.CookieHandler.WriteAuthCookie(w, true, cval)
+	} else if cval.RetiredKey() {
+		// Encrypt the cookie with the current key
+		s.CookieHandler.WriteAuthCookie(w, true, cval)
 	}
 
 	// Store sessionID in context
 	ctx = context.WithValue(ctx, sessioninfo.CTXSessionID, sessionID)
 
+	// Store the client in context for a session created during the request
+	ctx = s.ClientContext(ctx, r)
+
 	// Add session ID to logging context
 	l := logger.FromCtx(ctx).AddRequestAttribute("session ID", sessionID).
 		WithAttributes().AddAttribute("session ID", sessionID).Logger()
@@ -104,13 +173,30 @@ func (s *BaseSession) ValidateSession(next http.Handler) http.Handler {
 			return httpio.NewEncoder(w).ClientMessage(ctx, err)
 		}
 
+		ctx, err = s.RotateSessionIfDue(ctx, w, r)
+		if err != nil {
+			return httpio.NewEncoder(w).ClientMessage(ctx, err)
+		}
+
 		next.ServeHTTP(w, r.WithContext(ctx))
 
+		s.FlushSessionData(ctx)
+
 		return nil
 	})
 }
 
-// ValidateSessionAPI checks the session cookie and if it is valid, stores the session data into the context
+// FlushSessionData writes the changes to the session data in ctx. The response has already been
+// written when it is called, so a failure is logged rather than returned.
+func (s *BaseSession) FlushSessionData(ctx context.Context) {
+	if err := sessioninfo.Data(ctx).Flush(ctx); err != nil {
+		logger.FromCtx(ctx).Error(errors.Wrap(err, "sessioninfo.SessionData.Flush()"))
+	}
+}
+
+// ValidateSessionAPI checks the session cookie and if it is valid, stores the session info and
+// its sessioninfo.SessionData into the context. Changes to the session data are only written by
+// sessioninfo.SessionData.Flush, which the ValidateSession middleware calls when the request ends.
 func (s *BaseSession) ValidateSessionAPI(ctx context.Context) (context.Context, error) {
 	ctx, span := tracer.Start(ctx)
 	defer span.End()
@@ -121,36 +207,293 @@ func (s *BaseSession) ValidateSessionAPI(ctx context.Context) (context.Context,
 		return ctx, httpio.NewUnauthorizedMessageWithError(err, "invalid session")
 	}
 
+	// Include activity that is buffered but not written yet
+	sessInfo.UpdatedAt = s.lastActivity(sessInfo)
+
 	// Check for expiration
-	if sessInfo.Expired || time.Since(sessInfo.UpdatedAt) > s.SessionTimeout {
+	if sessInfo.Expired {
+		return ctx, httpio.NewUnauthorizedMessage("session expired")
+	}
+
+	if err := s.checkIdle(ctx, sessInfo); err != nil {
+		return ctx, err
+	}
+
+	// Check for the maximum lifetime, which applies regardless of activity
+	if s.MaxSessionLifetime > 0 && time.Since(sessInfo.CreatedAt) > s.MaxSessionLifetime {
+		if err := s.expireSession(ctx, sessInfo); err != nil {
+			return ctx, err
+		}
+
 		return ctx, httpio.NewUnauthorizedMessage("session expired")
 	}
 
 	// Update last activity (rate limit updates)
-	if time.Since(sessInfo.UpdatedAt) > time.Second*5 {
-		if err := s.Storage.UpdateSessionActivity(ctx, sessInfo.ID); err != nil {
-			return ctx, errors.Wrap(err, "sessionstorage.BaseStore.UpdateSessionActivity()")
+	lastActivity := sessInfo.UpdatedAt
+	if time.Since(sessInfo.UpdatedAt) > s.activityThrottle() {
+		if err := s.updateSessionActivity(ctx, sessInfo.ID); err != nil {
+			return ctx, err
 		}
+		lastActivity = time.Now()
 	}
 
 	// Store session info in context
 	ctx = context.WithValue(ctx, sessioninfo.CtxSessionInfo, sessInfo)
+	ctx = context.WithValue(ctx, sessioninfo.CtxSessionExpiry, s.expiry(sessInfo, lastActivity))
+	ctx = context.WithValue(ctx, sessioninfo.CtxSessionData, sessioninfo.NewSessionData(s.Storage, sessInfo.ID))
+	s.flushBeforeCookieSession(ctx)
 
 	// Add user to logging context
 	l := logger.FromCtx(ctx).
 		AddRequestAttribute("username", sessInfo.Username).
 		WithAttributes().AddAttribute("username", sessInfo.Username).Logger()
 
+	// Record the impersonator of an impersonated session, including on the sessions it creates
+	if sessInfo.Impersonator != nil {
+		ctx = context.WithValue(ctx, sessioninfo.CtxImpersonator, sessInfo.Impersonator)
+		l = l.AddRequestAttribute("impersonator", sessInfo.Impersonator.Username).
+			WithAttributes().AddAttribute("impersonator", sessInfo.Impersonator.Username).Logger()
+	}
+	ctx = logger.NewCtx(ctx, l)
+
+	s.RunHook(ctx, s.Hooks.Validated, sessInfo)
+
+	return ctx, nil
+}
+
+// lastActivity returns the activity time of the session, including activity that is buffered but not written yet.
+func (s *BaseSession) lastActivity(sessInfo *sessioninfo.SessionInfo) time.Time {
+	if s.ActivityWriter != nil {
+		if updatedAt, ok := s.ActivityWriter.LastActivity(sessInfo.ID); ok && updatedAt.After(sessInfo.UpdatedAt) {
+			return updatedAt
+		}
+	}
+
+	return sessInfo.UpdatedAt
+}
+
+// checkIdle returns an Unauthorized error when the session has been idle for longer than its idle timeout.
+func (s *BaseSession) checkIdle(ctx context.Context, sessInfo *sessioninfo.SessionInfo) error {
+	if time.Since(sessInfo.UpdatedAt) <= s.idleTimeout(sessInfo) {
+		return nil
+	}
+
+	// An idle session is only marked as expired for the Expired hook, so that it is called once
+	if s.Hooks.Expired != nil {
+		if err := s.expireSession(ctx, sessInfo); err != nil {
+			return err
+		}
+	}
+
+	return httpio.NewUnauthorizedMessage("session expired")
+}
+
+// expireSession marks the session as expired and calls the Expired hook.
+func (s *BaseSession) expireSession(ctx context.Context, sessInfo *sessioninfo.SessionInfo) error {
+	if err := s.Storage.DestroySession(ctx, sessInfo.ID); err != nil {
+		return errors.Wrap(err, "sessionstorage.BaseStore.DestroySession()")
+	}
+
+	s.RunHook(ctx, s.Hooks.Expired, sessInfo)
+
+	return nil
+}
+
+// expiry returns the times at which the session expires, given its last activity.
+func (s *BaseSession) expiry(sessInfo *sessioninfo.SessionInfo, lastActivity time.Time) *sessioninfo.Expiry {
+	expiry := &sessioninfo.Expiry{IdleExpiresAt: lastActivity.Add(s.idleTimeout(sessInfo))}
+	if s.MaxSessionLifetime > 0 {
+		expiry.AbsoluteExpiresAt = sessInfo.CreatedAt.Add(s.MaxSessionLifetime)
+	}
+
+	return expiry
+}
+
+// idleTimeout returns how long the session stays valid without activity. Sessions with their own
+// idle timeout, such as remember-me sessions, use it when it is longer than SessionTimeout.
+func (s *BaseSession) idleTimeout(sessInfo *sessioninfo.SessionInfo) time.Duration {
+	return max(s.SessionTimeout, sessInfo.IdleTimeout)
+}
+
+func (s *BaseSession) activityThrottle() time.Duration {
+	if s.ActivityThrottle > 0 {
+		return s.ActivityThrottle
+	}
+
+	return DefaultActivityThrottle
+}
+
+// updateSessionActivity records the session activity with the ActivityWriter when one
+// is set, or writes it to storage.
+func (s *BaseSession) updateSessionActivity(ctx context.Context, sessionID ccc.UUID) error {
+	if s.ActivityWriter != nil {
+		if err := s.ActivityWriter.Record(ctx, sessionID); err != nil {
+			return errors.Wrap(err, "sessionstorage.ActivityWriter.Record()")
+		}
+
+		return nil
+	}
+
+	if err := s.Storage.UpdateSessionActivity(ctx, sessionID); err != nil {
+		return errors.Wrap(err, "sessionstorage.BaseStore.UpdateSessionActivity()")
+	}
+
+	return nil
+}
+
+// NewSession creates a session for username in storage and writes new Auth and XSRF Token cookies for it.
+// The session records the client in ctx and the method the user authenticated with. A rememberMe session
+// stays valid for RememberMeTimeout without activity, and its Auth Cookie persists across browser restarts.
+func (s *BaseSession) NewSession(ctx context.Context, w http.ResponseWriter, storage sessionstorage.PreauthStore, username string, method sessioninfo.AuthMethod, rememberMe bool) (ccc.UUID, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	ctx = WithAuthMethod(ctx, method)
+
+	var idleTimeout time.Duration
+	var sessionID ccc.UUID
+	var err error
+	if rememberMe {
+		idleTimeout = s.RememberMeTimeout
+		sessionID, err = storage.NewPersistentSession(ctx, username, idleTimeout)
+		if err != nil {
+			return ccc.NilUUID, errors.Wrap(err, "sessionstorage.PreauthStore.NewPersistentSession()")
+		}
+	} else {
+		sessionID, err = storage.NewSession(ctx, username)
+		if err != nil {
+			return ccc.NilUUID, errors.Wrap(err, "sessionstorage.PreauthStore.NewSession()")
+		}
+	}
+
+	// Write new Auth Cookie
+	s.newAuthCookie(w, sessionID, idleTimeout)
+
+	// Write new XSRF Token Cookie to match the new SessionID
+	s.CookieHandler.CreateXSRFTokenCookie(w, sessionID)
+
+	s.SessionCreated(ctx, sessionID)
+
+	return sessionID, nil
+}
+
+// newAuthCookie writes a new Auth Cookie for sessionID. The cookie persists for idleTimeout
+// when the session has its own idle timeout, and ends with the browser session otherwise.
+func (s *BaseSession) newAuthCookie(w http.ResponseWriter, sessionID ccc.UUID, idleTimeout time.Duration) {
+	if idleTimeout > 0 {
+		s.CookieHandler.NewPersistentAuthCookie(w, true, sessionID, idleTimeout)
+
+		return
+	}
+
+	s.CookieHandler.NewAuthCookie(w, true, sessionID)
+}
+
+// RotateSessionAPI moves the session in the context to a new session ID, keeping its data, and
+// writes new Auth and XSRF Token cookies for it. The returned context holds the new session ID.
+// Requests that are still using the old session ID fail to validate after the rotation.
+func (s *BaseSession) RotateSessionAPI(ctx context.Context, w http.ResponseWriter) (context.Context, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	oldSessionID := sessioninfo.IDFromCtx(ctx)
+	sessionID, err := s.Storage.RotateSession(ctx, oldSessionID)
+	if err != nil {
+		return ctx, errors.Wrap(err, "sessionstorage.BaseStore.RotateSession()")
+	}
+
+	// Keep a remember-me session persistent. The session info is only in the
+	// context when ValidateSessionAPI was called first.
+	sessInfo, ok := ctx.Value(sessioninfo.CtxSessionInfo).(*sessioninfo.SessionInfo)
+	if !ok {
+		if sessInfo, err = s.Storage.Session(ctx, sessionID); err != nil {
+			return ctx, errors.Wrap(err, "sessionstorage.BaseStore.Session()")
+		}
+	}
+
+	// Write new Auth Cookie
+	s.newAuthCookie(w, sessionID, sessInfo.IdleTimeout)
+
+	// Write new XSRF Token Cookie to match the new SessionID
+	s.CookieHandler.CreateXSRFTokenCookie(w, sessionID)
+
+	// Store the new sessionID in context
+	ctx = context.WithValue(ctx, sessioninfo.CTXSessionID, sessionID)
+	if ok {
+		rotated := *sessInfo
+		rotated.ID = sessionID
+		ctx = context.WithValue(ctx, sessioninfo.CtxSessionInfo, &rotated)
+	}
+	if data, ok := ctx.Value(sessioninfo.CtxSessionData).(*sessioninfo.SessionData); ok {
+		data.SetSessionID(sessionID)
+	}
+
+	// Log the association between the old and new sessionID
+	l := logger.FromCtx(ctx).AddRequestAttribute("rotated session ID", oldSessionID).
+		WithAttributes().AddAttribute("session ID", sessionID).Logger()
+
 	return logger.NewCtx(ctx, l), nil
 }
 
-// Authenticated is the handler reports if the session is authenticated
-func (s *BaseSession) Authenticated() http.HandlerFunc {
-	type response struct {
-		Authenticated bool   `json:"authenticated"`
-		Username      string `json:"username"`
+// RotateSessionIfDue rotates the session when its ID was issued more than RotationInterval ago.
+// Only requests with a safe method are rotated, so that the XSRF Token sent with an unsafe request
+// always matches its session. ValidateSessionAPI must be called before RotateSessionIfDue.
+func (s *BaseSession) RotateSessionIfDue(ctx context.Context, w http.ResponseWriter, r *http.Request) (context.Context, error) {
+	if s.RotationInterval <= 0 || !internalcookie.SafeMethods.Contain(r.Method) {
+		return ctx, nil
+	}
+
+	cval, found, err := s.CookieHandler.ReadAuthCookie(r)
+	if err != nil {
+		return ctx, errors.Wrap(err, "cookie.CookieHandler.ReadAuthCookie()")
+	}
+	if !found {
+		return ctx, nil
+	}
+
+	// Cookies issued before rotation was enabled have no issue time and are rotated right away
+	if issuedAt, err := cval.GetTime(internalcookie.IssuedAt); err == nil && time.Since(issuedAt) < s.RotationInterval {
+		return ctx, nil
+	}
+
+	rotatedCtx, err := s.RotateSessionAPI(ctx, w)
+	if err != nil {
+		// A concurrent request with the same cookie has already rotated the session
+		if httpio.HasNotFound(err) {
+			return ctx, nil
+		}
+
+		return ctx, err
+	}
+
+	return rotatedCtx, nil
+}
+
+// AuthenticatedResponse is the response of the Authenticated handlers
+type AuthenticatedResponse struct {
+	Authenticated bool   `json:"authenticated"`
+	Username      string `json:"username"`
+	// Impersonated is set when the session was started by Impersonator to act as Username.
+	Impersonated bool   `json:"impersonated,omitempty"`
+	Impersonator string `json:"impersonator,omitempty"`
+}
+
+// NewAuthenticatedResponse returns the response of the Authenticated handlers for an authenticated session
+func NewAuthenticatedResponse(sessInfo *sessioninfo.SessionInfo) AuthenticatedResponse {
+	res := AuthenticatedResponse{
+		Authenticated: true,
+		Username:      sessInfo.Username,
 	}
+	if sessInfo.Impersonator != nil {
+		res.Impersonated = true
+		res.Impersonator = sessInfo.Impersonator.Username
+	}
+
+	return res
+}
 
+// Authenticated is the handler reports if the session is authenticated
+func (s *BaseSession) Authenticated() http.HandlerFunc {
 	return s.Handle(func(w http.ResponseWriter, r *http.Request) error {
 		ctx, span := tracer.Start(r.Context())
 		defer span.End()
@@ -158,21 +501,13 @@ func (s *BaseSession) Authenticated() http.HandlerFunc {
 		ctx, err := s.ValidateSessionAPI(ctx)
 		if err != nil {
 			if httpio.HasUnauthorized(err) {
-				return httpio.NewEncoder(w).Ok(response{})
+				return httpio.NewEncoder(w).Ok(AuthenticatedResponse{})
 			}
 
 			return httpio.NewEncoder(w).ClientMessage(ctx, err)
 		}
 
-		sessInfo := sessioninfo.FromCtx(ctx)
-
-		// set response values
-		res := response{
-			Authenticated: true,
-			Username:      sessInfo.Username,
-		}
-
-		return httpio.NewEncoder(w).Ok(res)
+		return httpio.NewEncoder(w).Ok(NewAuthenticatedResponse(sessioninfo.FromCtx(ctx)))
 	})
 }
 
@@ -182,8 +517,7 @@ func (s *BaseSession) Logout() http.HandlerFunc {
 		ctx, span := tracer.Start(r.Context())
 		defer span.End()
 
-		// Destroy session in database
-		if err := s.Storage.DestroySession(ctx, sessioninfo.IDFromCtx(ctx)); err != nil {
+		if err := s.LogoutAPI(ctx); err != nil {
 			return httpio.NewEncoder(w).ClientMessage(ctx, err)
 		}
 
@@ -191,6 +525,20 @@ func (s *BaseSession) Logout() http.HandlerFunc {
 	})
 }
 
+// LogoutAPI destroys the current session and calls the LoggedOut hook
+func (s *BaseSession) LogoutAPI(ctx context.Context) error {
+	sessionID := sessioninfo.IDFromCtx(ctx)
+
+	// Destroy session in database
+	if err := s.Storage.DestroySession(ctx, sessionID); err != nil {
+		return errors.Wrap(err, "sessionstorage.BaseStore.DestroySession()")
+	}
+
+	s.runHookByID(ctx, s.Hooks.LoggedOut, sessionID)
+
+	return nil
+}
+
 // SetXSRFToken sets the XSRF Token
 func (s *BaseSession) SetXSRFToken(next http.Handler) http.Handler {
 	return s.Handle(func(w http.ResponseWriter, r *http.Request) error {
@@ -234,3 +582,21 @@ func (s *BaseSession) ValidateXSRFToken(next http.Handler) http.Handler {
 		return nil
 	})
 }
+
+// UserSessions returns a page of the sessions for username that match filter, newest first.
+// When filter.IdleTimeout is zero, SessionTimeout is used so that timed out sessions are reported as expired.
+func (s *BaseSession) UserSessions(ctx context.Context, username string, filter sessionstorage.SessionFilter, page sessionstorage.Page) (*sessionstorage.SessionPage, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	if filter.IdleTimeout == 0 {
+		filter.IdleTimeout = s.SessionTimeout
+	}
+
+	sessions, err := s.Storage.UserSessions(ctx, username, filter, page)
+	if err != nil {
+		return nil, errors.Wrap(err, "sessionstorage.BaseStore.UserSessions()")
+	}
+
+	return sessions, nil
+}
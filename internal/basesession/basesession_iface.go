@@ -1,6 +1,9 @@
 package basesession
 
-import http "net/http"
+import (
+	http "net/http"
+	"time"
+)
 
 // Handlers defines the interface for session handlers used by all session implementations
 type Handlers interface {
@@ -10,4 +13,8 @@ type Handlers interface {
 	ValidateSession(next http.Handler) http.Handler
 	SetXSRFToken(next http.Handler) http.Handler
 	ValidateXSRFToken(next http.Handler) http.Handler
+	Sessions() http.HandlerFunc
+	RevokeSession() http.HandlerFunc
+	RevokeOtherSessions() http.HandlerFunc
+	RequireRecentAuth(maxAge time.Duration) func(next http.Handler) http.Handler
 }
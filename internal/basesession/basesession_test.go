@@ -17,6 +17,7 @@ import (
 	internalcookie "github.com/cccteam/session/internal/cookie"
 	"github.com/cccteam/session/mock/mock_cookie"
 	"github.com/cccteam/session/sessioninfo"
+	"github.com/cccteam/session/sessionstorage"
 	"github.com/cccteam/session/sessionstorage/mock/mock_sessionstorage"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/errors/v5"
@@ -319,6 +320,435 @@ func TestBaseSessionValidateSession(t *testing.T) {
 	}
 }
 
+func TestBaseSession_ValidateSession_sessionData(t *testing.T) {
+	t.Parallel()
+
+	sessionID := ccc.Must(ccc.UUIDFromString("de6e1a12-2d4d-4c4d-aaf1-d82cb9a9eff5"))
+
+	tests := []struct {
+		name        string
+		handler     func(t *testing.T, r *http.Request)
+		updateErr   error
+		wantWrite   bool
+		wantDeleted []string
+	}{
+		{
+			name: "writes the changes when the request ends",
+			handler: func(t *testing.T, r *http.Request) {
+				data := sessioninfo.DataFromRequest(r)
+				var step int
+				if found, err := data.Get(r.Context(), "step", &step); err != nil || !found || step != 1 {
+					t.Errorf("SessionData.Get() = %v, %v, %v, want 1, true, nil", step, found, err)
+				}
+				if err := data.Set("theme", "dark"); err != nil {
+					t.Errorf("SessionData.Set() error = %v", err)
+				}
+				data.Delete("step")
+			},
+			wantWrite:   true,
+			wantDeleted: []string{"step"},
+		},
+		{
+			name:    "skips the write without changes",
+			handler: func(_ *testing.T, _ *http.Request) {},
+		},
+		{
+			name: "a failed write does not change the response",
+			handler: func(t *testing.T, r *http.Request) {
+				if err := sessioninfo.DataFromRequest(r).Set("theme", "dark"); err != nil {
+					t.Errorf("SessionData.Set() error = %v", err)
+				}
+			},
+			updateErr: errors.New("write failed"),
+			wantWrite: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			storage := mock_sessionstorage.NewMockBaseStore(gomock.NewController(t))
+			storage.EXPECT().Session(gomock.Any(), sessionID).Return(&sessioninfo.SessionInfo{ID: sessionID, Username: "specialUser", UpdatedAt: time.Now()}, nil)
+			storage.EXPECT().SessionData(gomock.Any(), sessionID).Return(map[string]json.RawMessage{"step": json.RawMessage(`1`)}, nil).AnyTimes()
+			if tt.wantWrite {
+				storage.EXPECT().UpdateSessionData(gomock.Any(), sessionID, map[string]json.RawMessage{"theme": json.RawMessage(`"dark"`)}, tt.wantDeleted).Return(tt.updateErr)
+			}
+
+			a := &BaseSession{
+				SessionTimeout: time.Minute,
+				Handle: func(handler func(w http.ResponseWriter, r *http.Request) error) http.HandlerFunc {
+					return func(w http.ResponseWriter, r *http.Request) {
+						_ = handler(w, r)
+					}
+				},
+				Storage: storage,
+			}
+			w := httptest.NewRecorder()
+			a.ValidateSession(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					tt.handler(t, r)
+					w.WriteHeader(http.StatusNoContent)
+				}),
+			).ServeHTTP(w, mockRequestWithSession(context.Background(), t, http.MethodGet, "", sessionID.String()))
+			if w.Code != http.StatusNoContent {
+				t.Errorf("BaseSession.ValidateSession() status = %v, want %v", w.Code, http.StatusNoContent)
+			}
+		})
+	}
+}
+
+func TestBaseSession_ValidateSessionAPI_activity(t *testing.T) {
+	t.Parallel()
+
+	sessionID := ccc.Must(ccc.UUIDFromString("de6e1a12-2d4d-4c4d-aaf1-d82cb9a9eff5"))
+
+	tests := []struct {
+		name             string
+		activityThrottle time.Duration
+		withWriter       bool
+		recordActivity   bool
+		updatedAgo       time.Duration
+		wantUpdates      int
+		wantErr          bool
+	}{
+		{
+			name:        "default throttle updates activity older than 5s",
+			updatedAgo:  10 * time.Second,
+			wantUpdates: 1,
+		},
+		{
+			name:             "throttle window skips the update",
+			activityThrottle: time.Minute,
+			updatedAgo:       10 * time.Second,
+		},
+		{
+			name:        "writer buffers the update until it is closed",
+			withWriter:  true,
+			updatedAgo:  10 * time.Second,
+			wantUpdates: 1,
+		},
+		{
+			name:           "buffered activity keeps the session alive",
+			withWriter:     true,
+			recordActivity: true,
+			updatedAgo:     2 * time.Minute,
+			wantUpdates:    1,
+		},
+		{
+			name:       "stored activity older than the timeout expires the session",
+			withWriter: true,
+			updatedAgo: 2 * time.Minute,
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			storage := mock_sessionstorage.NewMockBaseStore(ctrl)
+			storage.EXPECT().Session(gomock.Any(), sessionID).
+				Return(&sessioninfo.SessionInfo{ID: sessionID, Username: "specialUser", UpdatedAt: time.Now().Add(-tt.updatedAgo)}, nil)
+			storage.EXPECT().UpdateSessionActivity(gomock.Any(), sessionID).Return(nil).Times(tt.wantUpdates)
+
+			a := &BaseSession{
+				SessionTimeout:   time.Minute,
+				Storage:          storage,
+				ActivityThrottle: tt.activityThrottle,
+			}
+			if tt.withWriter {
+				a.ActivityWriter = sessionstorage.NewActivityWriter(storage, sessionstorage.ActivityWriterOptions{FlushInterval: time.Hour})
+				t.Cleanup(func() { _ = a.ActivityWriter.Close(context.Background()) })
+			}
+			if tt.recordActivity {
+				if err := a.ActivityWriter.Record(context.Background(), sessionID); err != nil {
+					t.Fatalf("ActivityWriter.Record() error = %v", err)
+				}
+			}
+
+			ctx := context.WithValue(context.Background(), sessioninfo.CTXSessionID, sessionID)
+			if _, err := a.ValidateSessionAPI(ctx); (err != nil) != tt.wantErr {
+				t.Errorf("BaseSession.ValidateSessionAPI() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBaseSession_ValidateSessionAPI_maxSessionLifetime(t *testing.T) {
+	t.Parallel()
+
+	sessionID := ccc.Must(ccc.UUIDFromString("de6e1a12-2d4d-4c4d-aaf1-d82cb9a9eff5"))
+
+	tests := []struct {
+		name               string
+		maxSessionLifetime time.Duration
+		createdAgo         time.Duration
+		destroyErr         error
+		wantDestroy        bool
+		wantAbsolute       time.Duration
+		wantErr            bool
+	}{
+		{
+			name:       "disabled by default",
+			createdAgo: 48 * time.Hour,
+		},
+		{
+			name:               "session within the lifetime",
+			maxSessionLifetime: 12 * time.Hour,
+			createdAgo:         11 * time.Hour,
+			wantAbsolute:       time.Hour,
+		},
+		{
+			name:               "session past the lifetime is destroyed",
+			maxSessionLifetime: 12 * time.Hour,
+			createdAgo:         13 * time.Hour,
+			wantDestroy:        true,
+			wantErr:            true,
+		},
+		{
+			name:               "destroy error",
+			maxSessionLifetime: 12 * time.Hour,
+			createdAgo:         13 * time.Hour,
+			destroyErr:         errors.New("destroy failed"),
+			wantDestroy:        true,
+			wantErr:            true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			storage := mock_sessionstorage.NewMockBaseStore(ctrl)
+			storage.EXPECT().Session(gomock.Any(), sessionID).Return(&sessioninfo.SessionInfo{
+				ID:        sessionID,
+				Username:  "specialUser",
+				CreatedAt: time.Now().Add(-tt.createdAgo),
+				UpdatedAt: time.Now(),
+			}, nil)
+			if tt.wantDestroy {
+				storage.EXPECT().DestroySession(gomock.Any(), sessionID).Return(tt.destroyErr)
+			}
+
+			a := &BaseSession{
+				SessionTimeout:     time.Minute,
+				MaxSessionLifetime: tt.maxSessionLifetime,
+				Storage:            storage,
+			}
+
+			ctx := context.WithValue(context.Background(), sessioninfo.CTXSessionID, sessionID)
+			ctx, err := a.ValidateSessionAPI(ctx)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BaseSession.ValidateSessionAPI() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if tt.destroyErr == nil && !httpio.HasUnauthorized(err) {
+					t.Errorf("BaseSession.ValidateSessionAPI() error = %v, want unauthorized", err)
+				}
+
+				return
+			}
+
+			expiry := sessioninfo.ExpiryFromCtx(ctx)
+			if idle := expiry.IdleRemaining(); idle <= 0 || idle > time.Minute {
+				t.Errorf("Expiry.IdleRemaining() = %v, want within (0, 1m]", idle)
+			}
+			absolute, ok := expiry.AbsoluteRemaining()
+			if ok != (tt.maxSessionLifetime > 0) {
+				t.Fatalf("Expiry.AbsoluteRemaining() ok = %v, want %v", ok, tt.maxSessionLifetime > 0)
+			}
+			if ok && (absolute > tt.wantAbsolute || absolute < tt.wantAbsolute-time.Second) {
+				t.Errorf("Expiry.AbsoluteRemaining() = %v, want %v", absolute, tt.wantAbsolute)
+			}
+		})
+	}
+}
+
+func TestBaseSession_ValidateSessionAPI_persistentSession(t *testing.T) {
+	t.Parallel()
+
+	sessionID := ccc.Must(ccc.UUIDFromString("de6e1a12-2d4d-4c4d-aaf1-d82cb9a9eff5"))
+
+	tests := []struct {
+		name        string
+		idleTimeout time.Duration
+		wantErr     bool
+	}{
+		{
+			name:    "idle session is expired",
+			wantErr: true,
+		},
+		{
+			name:        "persistent session within its own idle timeout",
+			idleTimeout: 24 * time.Hour,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			storage := mock_sessionstorage.NewMockBaseStore(ctrl)
+			storage.EXPECT().Session(gomock.Any(), sessionID).Return(&sessioninfo.SessionInfo{
+				ID:          sessionID,
+				Username:    "specialUser",
+				CreatedAt:   time.Now().Add(-2 * time.Hour),
+				UpdatedAt:   time.Now().Add(-time.Hour),
+				IdleTimeout: tt.idleTimeout,
+			}, nil)
+			storage.EXPECT().UpdateSessionActivity(gomock.Any(), sessionID).Return(nil).AnyTimes()
+
+			a := &BaseSession{
+				SessionTimeout: time.Minute,
+				Storage:        storage,
+			}
+
+			ctx := context.WithValue(context.Background(), sessioninfo.CTXSessionID, sessionID)
+			ctx, err := a.ValidateSessionAPI(ctx)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BaseSession.ValidateSessionAPI() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if idle := sessioninfo.ExpiryFromCtx(ctx).IdleRemaining(); idle <= time.Minute || idle > tt.idleTimeout {
+				t.Errorf("Expiry.IdleRemaining() = %v, want within (1m, %v]", idle, tt.idleTimeout)
+			}
+		})
+	}
+}
+
+func TestBaseSession_RotateSessionIfDue(t *testing.T) {
+	t.Parallel()
+
+	sessionID := ccc.Must(ccc.UUIDFromString("de6e1a12-2d4d-4c4d-aaf1-d82cb9a9eff5"))
+	rotatedID := ccc.Must(ccc.UUIDFromString("38bd570b-1280-421b-888e-a63f0ca35be7"))
+
+	tests := []struct {
+		name             string
+		rotationInterval time.Duration
+		method           string
+		cookie           *cookie.Values
+		idleTimeout      time.Duration
+		rotateErr        error
+		wantRotate       bool
+		wantID           ccc.UUID
+		wantErr          bool
+	}{
+		{
+			name:   "rotation disabled",
+			method: http.MethodGet,
+			cookie: cookie.NewValues().SetTime(internalcookie.IssuedAt, time.Now().Add(-time.Hour)),
+			wantID: sessionID,
+		},
+		{
+			name:             "unsafe method is not rotated",
+			rotationInterval: time.Minute,
+			method:           http.MethodPost,
+			cookie:           cookie.NewValues().SetTime(internalcookie.IssuedAt, time.Now().Add(-time.Hour)),
+			wantID:           sessionID,
+		},
+		{
+			name:             "session ID issued within the interval",
+			rotationInterval: time.Minute,
+			method:           http.MethodGet,
+			cookie:           cookie.NewValues().SetTime(internalcookie.IssuedAt, time.Now()),
+			wantID:           sessionID,
+		},
+		{
+			name:             "session ID issued before the interval is rotated",
+			rotationInterval: time.Minute,
+			method:           http.MethodGet,
+			cookie:           cookie.NewValues().SetTime(internalcookie.IssuedAt, time.Now().Add(-time.Hour)),
+			wantRotate:       true,
+			wantID:           rotatedID,
+		},
+		{
+			name:             "cookie without issue time is rotated",
+			rotationInterval: time.Minute,
+			method:           http.MethodGet,
+			cookie:           cookie.NewValues(),
+			wantRotate:       true,
+			wantID:           rotatedID,
+		},
+		{
+			name:             "persistent session keeps a persistent cookie",
+			rotationInterval: time.Minute,
+			method:           http.MethodGet,
+			cookie:           cookie.NewValues(),
+			idleTimeout:      time.Hour,
+			wantRotate:       true,
+			wantID:           rotatedID,
+		},
+		{
+			name:             "session already rotated by a concurrent request",
+			rotationInterval: time.Minute,
+			method:           http.MethodGet,
+			cookie:           cookie.NewValues(),
+			rotateErr:        httpio.NewNotFoundMessage("session not found"),
+			wantRotate:       true,
+			wantID:           sessionID,
+		},
+		{
+			name:             "rotation error",
+			rotationInterval: time.Minute,
+			method:           http.MethodGet,
+			cookie:           cookie.NewValues(),
+			rotateErr:        errors.New("rotate failed"),
+			wantRotate:       true,
+			wantErr:          true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			storage := mock_sessionstorage.NewMockBaseStore(ctrl)
+			cookieHandler := mock_cookie.NewMockHandler(ctrl)
+			cookieHandler.EXPECT().ReadAuthCookie(gomock.Any()).Return(tt.cookie, true, nil).AnyTimes()
+			if tt.wantRotate {
+				storage.EXPECT().RotateSession(gomock.Any(), sessionID).Return(rotatedID, tt.rotateErr)
+				switch {
+				case tt.rotateErr != nil:
+				case tt.idleTimeout > 0:
+					cookieHandler.EXPECT().NewPersistentAuthCookie(gomock.Any(), true, rotatedID, tt.idleTimeout).Return(cookie.NewValues())
+					cookieHandler.EXPECT().CreateXSRFTokenCookie(gomock.Any(), rotatedID)
+				default:
+					cookieHandler.EXPECT().NewAuthCookie(gomock.Any(), true, rotatedID).Return(cookie.NewValues())
+					cookieHandler.EXPECT().CreateXSRFTokenCookie(gomock.Any(), rotatedID)
+				}
+			}
+
+			a := &BaseSession{
+				RotationInterval: tt.rotationInterval,
+				Storage:          storage,
+				CookieHandler:    cookieHandler,
+			}
+
+			ctx := context.WithValue(context.Background(), sessioninfo.CTXSessionID, sessionID)
+			ctx = context.WithValue(ctx, sessioninfo.CtxSessionInfo, &sessioninfo.SessionInfo{ID: sessionID, Username: "specialUser", IdleTimeout: tt.idleTimeout})
+			r := httptest.NewRequestWithContext(ctx, tt.method, "/", http.NoBody)
+
+			ctx, err := a.RotateSessionIfDue(ctx, httptest.NewRecorder(), r)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BaseSession.RotateSessionIfDue() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if got := sessioninfo.IDFromCtx(ctx); got != tt.wantID {
+				t.Errorf("sessioninfo.IDFromCtx() = %v, want %v", got, tt.wantID)
+			}
+			if got := sessioninfo.FromCtx(ctx); got.ID != tt.wantID || got.Username != "specialUser" {
+				t.Errorf("sessioninfo.FromCtx() = %+v, want ID %v and Username specialUser", got, tt.wantID)
+			}
+		})
+	}
+}
+
 func TestBaseSessionCheckSession(t *testing.T) {
 	t.Parallel()
 
@@ -825,6 +1255,53 @@ func TestBaseSessionValidateXSRFToken(t *testing.T) {
 	}
 }
 
+func TestBaseSession_ApplySessionLimit(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name               string
+		maxSessionsPerUser int
+		policy             sessionstorage.SessionLimitPolicy
+		want               *sessionstorage.SessionLimit
+	}{
+		{
+			name: "disabled by default",
+		},
+		{
+			name:               "evict oldest session",
+			maxSessionsPerUser: 3,
+			policy:             sessionstorage.EvictOldestSession,
+			want:               &sessionstorage.SessionLimit{MaxSessions: 3, Policy: sessionstorage.EvictOldestSession, IdleTimeout: time.Minute, MaxAge: time.Hour},
+		},
+		{
+			name:               "reject new session",
+			maxSessionsPerUser: 1,
+			policy:             sessionstorage.RejectNewSession,
+			want:               &sessionstorage.SessionLimit{MaxSessions: 1, Policy: sessionstorage.RejectNewSession, IdleTimeout: time.Minute, MaxAge: time.Hour},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			storage := mock_sessionstorage.NewMockBaseStore(ctrl)
+			if tt.want != nil {
+				storage.EXPECT().SetSessionLimit(*tt.want)
+			}
+
+			a := &BaseSession{
+				SessionTimeout:     time.Minute,
+				MaxSessionLifetime: time.Hour,
+				Storage:            storage,
+				MaxSessionsPerUser: tt.maxSessionsPerUser,
+				SessionLimitPolicy: tt.policy,
+			}
+			a.ApplySessionLimit()
+		})
+	}
+}
+
 func createHTTPRequestWithSessionID(method, urlPath string) *http.Request {
 	ctx := context.Background()
 	ctx = context.WithValue(ctx, sessioninfo.CTXSessionID, ccc.Must(ccc.NewUUID()))
@@ -0,0 +1,70 @@
+package basesession
+
+import (
+	"context"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/cccteam/session/sessioninfo"
+)
+
+// ClientContext stores the client that sent r in ctx, so that new sessions record it.
+func (s *BaseSession) ClientContext(ctx context.Context, r *http.Request) context.Context {
+	return context.WithValue(ctx, sessioninfo.CtxClient, &sessioninfo.Client{
+		IP:        s.clientIP(r),
+		UserAgent: r.UserAgent(),
+	})
+}
+
+// WithAuthMethod stores method on the client in ctx, so that new sessions record how the user authenticated.
+func WithAuthMethod(ctx context.Context, method sessioninfo.AuthMethod) context.Context {
+	client := *sessioninfo.ClientFromCtx(ctx)
+	client.AuthMethod = method
+
+	return context.WithValue(ctx, sessioninfo.CtxClient, &client)
+}
+
+// clientIP returns the address of the client that sent r. When the request came through trusted
+// proxies, it is the last address in the X-Forwarded-For header that is not a trusted proxy.
+func (s *BaseSession) clientIP(r *http.Request) string {
+	addr, ok := parseAddr(r.RemoteAddr)
+	if !ok {
+		return r.RemoteAddr
+	}
+
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0 && s.trustedProxy(addr); i-- {
+		// Addresses before an invalid entry can not be trusted
+		hop, ok := parseAddr(strings.TrimSpace(forwarded[i]))
+		if !ok {
+			break
+		}
+		addr = hop
+	}
+
+	return addr.String()
+}
+
+// trustedProxy reports whether addr belongs to one of the TrustedProxies.
+func (s *BaseSession) trustedProxy(addr netip.Addr) bool {
+	for _, prefix := range s.TrustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseAddr parses an IP address with an optional port.
+func parseAddr(s string) (netip.Addr, bool) {
+	if addrPort, err := netip.ParseAddrPort(s); err == nil {
+		return addrPort.Addr().Unmap(), true
+	}
+	if addr, err := netip.ParseAddr(s); err == nil {
+		return addr.Unmap(), true
+	}
+
+	return netip.Addr{}, false
+}
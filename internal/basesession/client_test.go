@@ -0,0 +1,97 @@
+package basesession
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/cccteam/session/sessioninfo"
+)
+
+func TestBaseSession_ClientContext(t *testing.T) {
+	t.Parallel()
+
+	trustedProxies := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("2001:db8::/32")}
+
+	tests := []struct {
+		name           string
+		trustedProxies []netip.Prefix
+		remoteAddr     string
+		forwardedFor   []string
+		want           string
+	}{
+		{
+			name:       "direct connection",
+			remoteAddr: "203.0.113.7:52100",
+			want:       "203.0.113.7",
+		},
+		{
+			name:         "forwarded header from an untrusted client is ignored",
+			remoteAddr:   "203.0.113.7:52100",
+			forwardedFor: []string{"198.51.100.1"},
+			want:         "203.0.113.7",
+		},
+		{
+			name:           "forwarded header from a trusted proxy",
+			trustedProxies: trustedProxies,
+			remoteAddr:     "10.0.0.2:52100",
+			forwardedFor:   []string{"198.51.100.1"},
+			want:           "198.51.100.1",
+		},
+		{
+			name:           "spoofed addresses before the first untrusted address are ignored",
+			trustedProxies: trustedProxies,
+			remoteAddr:     "10.0.0.2:52100",
+			forwardedFor:   []string{"192.0.2.1, 198.51.100.1", "10.0.0.3"},
+			want:           "198.51.100.1",
+		},
+		{
+			name:           "IPv6 trusted proxy",
+			trustedProxies: trustedProxies,
+			remoteAddr:     "[2001:db8::1]:52100",
+			forwardedFor:   []string{"198.51.100.1"},
+			want:           "198.51.100.1",
+		},
+		{
+			name:           "invalid forwarded address stops at the last valid hop",
+			trustedProxies: trustedProxies,
+			remoteAddr:     "10.0.0.2:52100",
+			forwardedFor:   []string{"198.51.100.1, unknown"},
+			want:           "10.0.0.2",
+		},
+		{
+			name:           "trusted proxy without a forwarded header",
+			trustedProxies: trustedProxies,
+			remoteAddr:     "10.0.0.2:52100",
+			want:           "10.0.0.2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			r.RemoteAddr = tt.remoteAddr
+			r.Header.Set("User-Agent", "test-agent")
+			for _, v := range tt.forwardedFor {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+
+			s := &BaseSession{TrustedProxies: tt.trustedProxies}
+			ctx := WithAuthMethod(s.ClientContext(context.Background(), r), sessioninfo.AuthMethodPassword)
+
+			got := sessioninfo.ClientFromCtx(ctx)
+			if got.IP != tt.want {
+				t.Errorf("sessioninfo.ClientFromCtx().IP = %q, want %q", got.IP, tt.want)
+			}
+			if got.UserAgent != "test-agent" {
+				t.Errorf("sessioninfo.ClientFromCtx().UserAgent = %q, want %q", got.UserAgent, "test-agent")
+			}
+			if got.AuthMethod != sessioninfo.AuthMethodPassword {
+				t.Errorf("sessioninfo.ClientFromCtx().AuthMethod = %q, want %q", got.AuthMethod, sessioninfo.AuthMethodPassword)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package basesession
+
+import (
+	"context"
+
+	"github.com/cccteam/ccc"
+	"github.com/cccteam/logger"
+	"github.com/cccteam/session/sessioninfo"
+	"github.com/cccteam/session/sessionstorage"
+	"github.com/go-playground/errors/v5"
+)
+
+// SessionHook is called on a transition of a session with the request context and the session information.
+type SessionHook func(ctx context.Context, sessInfo *sessioninfo.SessionInfo)
+
+// Hooks are the callbacks called on the transitions of a session. A nil hook is skipped.
+// Hooks run synchronously during the request, after the transition has been stored.
+type Hooks struct {
+	// Created is called when a login creates a session.
+	Created SessionHook
+	// Validated is called when ValidateSessionAPI finds the session of a request valid.
+	Validated SessionHook
+	// Expired is called when a request uses a session that has been idle for longer than its
+	// timeout or is older than MaxSessionLifetime. The session is marked as expired, so it is
+	// called once per session.
+	Expired SessionHook
+	// LoggedOut is called when a session is destroyed by Logout, or when an active session is revoked by its user.
+	LoggedOut SessionHook
+	// UserSessionsDestroyed is called for each active session destroyed by DestroyAllUserSessions.
+	UserSessionsDestroyed SessionHook
+	// FrontChannelLogout is called for each active session destroyed by an OIDC front-channel logout.
+	FrontChannelLogout SessionHook
+}
+
+// RunHook calls hook for each of sessions. It is a no-op when hook is nil.
+func (s *BaseSession) RunHook(ctx context.Context, hook SessionHook, sessions ...*sessioninfo.SessionInfo) {
+	if hook == nil {
+		return
+	}
+
+	for _, sessInfo := range sessions {
+		hook(ctx, sessInfo)
+	}
+}
+
+// SessionCreated calls the Created hook for the new session sessionID.
+func (s *BaseSession) SessionCreated(ctx context.Context, sessionID ccc.UUID) {
+	s.runHookByID(ctx, s.Hooks.Created, sessionID)
+}
+
+// runHookByID calls hook for the session sessionID, using the session information in ctx when it
+// is for sessionID and reading it from storage otherwise. The transition has already happened when
+// it is called, so a failure to read the session is logged rather than returned.
+func (s *BaseSession) runHookByID(ctx context.Context, hook SessionHook, sessionID ccc.UUID) {
+	if hook == nil {
+		return
+	}
+
+	sessInfo, ok := ctx.Value(sessioninfo.CtxSessionInfo).(*sessioninfo.SessionInfo)
+	if !ok || sessInfo.ID != sessionID {
+		var err error
+		if sessInfo, err = s.Storage.Session(ctx, sessionID); err != nil {
+			logger.FromCtx(ctx).Error(errors.Wrap(err, "sessionstorage.BaseStore.Session()"))
+
+			return
+		}
+	}
+
+	hook(ctx, sessInfo)
+}
+
+// ActiveUserSessions returns every session of username that is still valid, newest first.
+func (s *BaseSession) ActiveUserSessions(ctx context.Context, username string) ([]*sessioninfo.SessionInfo, error) {
+	var sessions []*sessioninfo.SessionInfo
+	var page sessionstorage.Page
+	for {
+		p, err := s.activeSessions(ctx, username, page)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, p.Sessions...)
+
+		if p.NextCursor == "" {
+			return sessions, nil
+		}
+		page.Cursor = p.NextCursor
+	}
+}
+
+// DestroyAllUserSessions destroys all sessions of username in storage and calls the UserSessionsDestroyed
+// hook for each session that was active. The sessions are only listed when the hook is set.
+func (s *BaseSession) DestroyAllUserSessions(ctx context.Context, storage sessionstorage.PreauthStore, username string) error {
+	var sessions []*sessioninfo.SessionInfo
+	if s.Hooks.UserSessionsDestroyed != nil {
+		var err error
+		if sessions, err = s.ActiveUserSessions(ctx, username); err != nil {
+			return errors.Wrap(err, "BaseSession.ActiveUserSessions()")
+		}
+	}
+
+	if err := storage.DestroyAllUserSessions(ctx, username); err != nil {
+		return errors.Wrap(err, "sessionstorage.PreauthStore.DestroyAllUserSessions()")
+	}
+
+	s.RunHook(ctx, s.Hooks.UserSessionsDestroyed, sessions...)
+
+	return nil
+}
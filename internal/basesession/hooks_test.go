@@ -0,0 +1,271 @@
+package basesession
+
+import (
+	"context"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cccteam/ccc"
+	"github.com/cccteam/httpio"
+	"github.com/cccteam/session/mock/mock_cookie"
+	"github.com/cccteam/session/sessioninfo"
+	"github.com/cccteam/session/sessionstorage"
+	"go.uber.org/mock/gomock"
+)
+
+// hookCalls records the sessions a hook was called for
+type hookCalls struct {
+	mu  sync.Mutex
+	ids []ccc.UUID
+}
+
+func (h *hookCalls) hook(_ context.Context, sessInfo *sessioninfo.SessionInfo) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.ids = append(h.ids, sessInfo.ID)
+}
+
+func (h *hookCalls) sorted() []ccc.UUID {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return sortedIDs(h.ids...)
+}
+
+func sortedIDs(ids ...ccc.UUID) []ccc.UUID {
+	return slices.SortedFunc(slices.Values(ids), func(a, b ccc.UUID) int {
+		return slices.Compare(a.UUID[:], b.UUID[:])
+	})
+}
+
+func TestBaseSession_Hooks_validateSession(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name               string
+		sessionTimeout     time.Duration
+		maxSessionLifetime time.Duration
+		destroyed          bool
+		wantValidated      bool
+		wantExpired        bool
+	}{
+		{
+			name:           "valid session",
+			sessionTimeout: time.Hour,
+			wantValidated:  true,
+		},
+		{
+			name:           "idle session",
+			sessionTimeout: time.Nanosecond,
+			wantExpired:    true,
+		},
+		{
+			name:               "session past the maximum lifetime",
+			sessionTimeout:     time.Hour,
+			maxSessionLifetime: time.Nanosecond,
+			wantExpired:        true,
+		},
+		{
+			name:           "destroyed session",
+			sessionTimeout: time.Hour,
+			destroyed:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			store := sessionstorage.NewMemoryPreauth()
+			id, err := store.NewSession(ctx, "specialUser")
+			if err != nil {
+				t.Fatalf("PreauthStore.NewSession() error = %v", err)
+			}
+			if tt.destroyed {
+				if err := store.DestroySession(ctx, id); err != nil {
+					t.Fatalf("PreauthStore.DestroySession() error = %v", err)
+				}
+			}
+			time.Sleep(time.Millisecond)
+
+			validated, expired := &hookCalls{}, &hookCalls{}
+			a := &BaseSession{
+				SessionTimeout:     tt.sessionTimeout,
+				MaxSessionLifetime: tt.maxSessionLifetime,
+				Storage:            store,
+				Hooks:              Hooks{Validated: validated.hook, Expired: expired.hook},
+			}
+
+			// The second request shows that an expired session is reported once
+			ctx = context.WithValue(ctx, sessioninfo.CTXSessionID, id)
+			for range 2 {
+				if _, err := a.ValidateSessionAPI(ctx); (err != nil) == tt.wantValidated {
+					t.Fatalf("BaseSession.ValidateSessionAPI() error = %v, want error %v", err, !tt.wantValidated)
+				}
+			}
+
+			var wantValidated, wantExpired []ccc.UUID
+			if tt.wantValidated {
+				wantValidated = []ccc.UUID{id, id}
+			}
+			if tt.wantExpired {
+				wantExpired = []ccc.UUID{id}
+			}
+			if got := validated.sorted(); !slices.Equal(got, wantValidated) {
+				t.Errorf("Validated hook called for %v, want %v", got, wantValidated)
+			}
+			if got := expired.sorted(); !slices.Equal(got, wantExpired) {
+				t.Errorf("Expired hook called for %v, want %v", got, wantExpired)
+			}
+		})
+	}
+}
+
+func TestBaseSession_Hooks_idleSessionWithoutHook(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := sessionstorage.NewMemoryPreauth()
+	id, err := store.NewSession(ctx, "specialUser")
+	if err != nil {
+		t.Fatalf("PreauthStore.NewSession() error = %v", err)
+	}
+	time.Sleep(time.Millisecond)
+
+	a := &BaseSession{SessionTimeout: time.Nanosecond, Storage: store}
+	if _, err := a.ValidateSessionAPI(context.WithValue(ctx, sessioninfo.CTXSessionID, id)); !httpio.HasUnauthorized(err) {
+		t.Fatalf("BaseSession.ValidateSessionAPI() error = %v, want Unauthorized", err)
+	}
+
+	sessInfo, err := store.Session(ctx, id)
+	if err != nil {
+		t.Fatalf("PreauthStore.Session() error = %v", err)
+	}
+	if sessInfo.Expired {
+		t.Error("Session().Expired = true, want an idle session to be left unchanged without an Expired hook")
+	}
+}
+
+func TestBaseSession_Hooks_created(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := sessionstorage.NewMemoryPreauth()
+	cookieHandler := mock_cookie.NewMockHandler(gomock.NewController(t))
+	cookieHandler.EXPECT().NewAuthCookie(gomock.Any(), true, gomock.Any()).AnyTimes()
+	cookieHandler.EXPECT().CreateXSRFTokenCookie(gomock.Any(), gomock.Any()).AnyTimes()
+
+	created := &hookCalls{}
+	var username string
+	a := &BaseSession{
+		SessionTimeout: time.Hour,
+		Storage:        store,
+		CookieHandler:  cookieHandler,
+		Hooks: Hooks{Created: func(ctx context.Context, sessInfo *sessioninfo.SessionInfo) {
+			username = sessInfo.Username
+			created.hook(ctx, sessInfo)
+		}},
+	}
+
+	id, err := a.NewSession(ctx, httptest.NewRecorder(), store, "specialUser", sessioninfo.AuthMethodPreauth, false)
+	if err != nil {
+		t.Fatalf("BaseSession.NewSession() error = %v", err)
+	}
+
+	if got := created.sorted(); !slices.Equal(got, []ccc.UUID{id}) {
+		t.Errorf("Created hook called for %v, want %v", got, []ccc.UUID{id})
+	}
+	if username != "specialUser" {
+		t.Errorf("Created hook SessionInfo.Username = %q, want %q", username, "specialUser")
+	}
+}
+
+func TestBaseSession_Hooks_destroyed(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		hooks   func(h *hookCalls) Hooks
+		destroy func(ctx context.Context, a *BaseSession, store sessionstorage.PreauthStore) error
+		// wantCurrent and wantOther report whether the hook is called for the current and other session of the user
+		wantCurrent bool
+		wantOther   bool
+	}{
+		{
+			name: "Logout",
+			destroy: func(ctx context.Context, a *BaseSession, _ sessionstorage.PreauthStore) error {
+				return a.LogoutAPI(ctx)
+			},
+			wantCurrent: true,
+			hooks:       func(h *hookCalls) Hooks { return Hooks{LoggedOut: h.hook} },
+		},
+		{
+			name: "RevokeOtherSessions",
+			destroy: func(ctx context.Context, a *BaseSession, _ sessionstorage.PreauthStore) error {
+				return a.RevokeOtherSessionsAPI(ctx)
+			},
+			wantOther: true,
+			hooks:     func(h *hookCalls) Hooks { return Hooks{LoggedOut: h.hook} },
+		},
+		{
+			name: "DestroyAllUserSessions",
+			destroy: func(ctx context.Context, a *BaseSession, store sessionstorage.PreauthStore) error {
+				return a.DestroyAllUserSessions(ctx, store, "specialUser")
+			},
+			wantCurrent: true,
+			wantOther:   true,
+			hooks:       func(h *hookCalls) Hooks { return Hooks{UserSessionsDestroyed: h.hook} },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			store := sessionstorage.NewMemoryPreauth()
+			var ids []ccc.UUID
+			for _, username := range []string{"specialUser", "specialUser", "specialUser", "otherUser"} {
+				id, err := store.NewSession(ctx, username)
+				if err != nil {
+					t.Fatalf("PreauthStore.NewSession() error = %v", err)
+				}
+				ids = append(ids, id)
+			}
+			current, other, destroyed := ids[0], ids[1], ids[2]
+			if err := store.DestroySession(ctx, destroyed); err != nil {
+				t.Fatalf("PreauthStore.DestroySession() error = %v", err)
+			}
+
+			calls := &hookCalls{}
+			a := &BaseSession{
+				SessionTimeout: time.Hour,
+				Storage:        store,
+				Hooks:          tt.hooks(calls),
+			}
+
+			ctx = context.WithValue(ctx, sessioninfo.CTXSessionID, current)
+			ctx, err := a.ValidateSessionAPI(ctx)
+			if err != nil {
+				t.Fatalf("BaseSession.ValidateSessionAPI() error = %v", err)
+			}
+			if err := tt.destroy(ctx, a, store); err != nil {
+				t.Fatalf("destroy() error = %v", err)
+			}
+
+			var want []ccc.UUID
+			if tt.wantCurrent {
+				want = append(want, current)
+			}
+			if tt.wantOther {
+				want = append(want, other)
+			}
+			if got := calls.sorted(); !slices.Equal(got, sortedIDs(want...)) {
+				t.Errorf("hook called for %v, want %v", got, sortedIDs(want...))
+			}
+		})
+	}
+}
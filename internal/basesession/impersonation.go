@@ -0,0 +1,131 @@
+package basesession
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/cccteam/ccc"
+	"github.com/cccteam/ccc/tracer"
+	"github.com/cccteam/httpio"
+	"github.com/cccteam/logger"
+	"github.com/cccteam/session/sessioninfo"
+	"github.com/cccteam/session/sessionstorage"
+	"github.com/go-playground/errors/v5"
+)
+
+// ImpersonationAuthorizer decides whether the user impersonator may impersonate the user target.
+// It returns an error to refuse the impersonation.
+type ImpersonationAuthorizer func(ctx context.Context, impersonator, target string) error
+
+// ImpersonateAPI starts a session as username for the user of the session in ctx and writes new Auth and
+// XSRF Token cookies for it. The new session records that user and their session as its impersonator, and
+// the session of the impersonator is kept so that EndImpersonationAPI can return to it. It returns a Forbidden
+// error when ImpersonationAuthorizer is not set or refuses the impersonation. Once it is authorized, checkTarget,
+// when not nil, validates username and returns the username of the new session, so that nothing about the
+// user is revealed to a caller that is not allowed to impersonate them.
+// ValidateSessionAPI must be called before ImpersonateAPI.
+func (s *BaseSession) ImpersonateAPI(
+	ctx context.Context, w http.ResponseWriter, storage sessionstorage.PreauthStore, username string,
+	checkTarget func(ctx context.Context, username string) (string, error),
+) (ccc.UUID, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	current := sessioninfo.FromCtx(ctx)
+	if current.Impersonator != nil {
+		return ccc.NilUUID, httpio.NewBadRequestMessage("session is already impersonating a user")
+	}
+	if s.ImpersonationAuthorizer == nil {
+		return ccc.NilUUID, httpio.NewForbiddenMessage("impersonation is not enabled")
+	}
+	if err := s.ImpersonationAuthorizer(ctx, current.Username, username); err != nil {
+		return ccc.NilUUID, httpio.NewForbiddenMessageWithError(err, "not allowed to impersonate user")
+	}
+	if checkTarget != nil {
+		var err error
+		if username, err = checkTarget(ctx, username); err != nil {
+			return ccc.NilUUID, err
+		}
+	}
+	// Compared after checkTarget, so that a variant of their own username can not get past it
+	if username == current.Username {
+		return ccc.NilUUID, httpio.NewBadRequestMessage("users can not impersonate themselves")
+	}
+
+	ctx = context.WithValue(ctx, sessioninfo.CtxImpersonator, &sessioninfo.Impersonator{
+		Username:  current.Username,
+		SessionID: current.ID,
+	})
+
+	sessionID, err := s.NewSession(ctx, w, storage, username, sessioninfo.AuthMethodImpersonation, false)
+	if err != nil {
+		return ccc.NilUUID, errors.Wrap(err, "BaseSession.NewSession()")
+	}
+
+	logger.FromCtx(ctx).Infof("user %s started impersonating user %s in session %s", current.Username, username, sessionID)
+
+	return sessionID, nil
+}
+
+// EndImpersonationAPI destroys the impersonated session in ctx and writes Auth and XSRF Token cookies for the
+// session of the impersonator. It returns an Unauthorized error when the session of the impersonator is no
+// longer valid, including when it has been idle for longer than its idle timeout, as ValidateSessionAPI does.
+// The returned context holds the session of the impersonator.
+// ValidateSessionAPI must be called before EndImpersonationAPI.
+func (s *BaseSession) EndImpersonationAPI(ctx context.Context, w http.ResponseWriter) (context.Context, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	current := sessioninfo.FromCtx(ctx)
+	impersonator := current.Impersonator
+	if impersonator == nil {
+		return ctx, httpio.NewBadRequestMessage("session is not impersonating a user")
+	}
+
+	if err := s.Storage.DestroySession(ctx, current.ID); err != nil {
+		return ctx, errors.Wrap(err, "sessionstorage.BaseStore.DestroySession()")
+	}
+
+	s.RunHook(ctx, s.Hooks.LoggedOut, current)
+
+	logger.FromCtx(ctx).Infof("user %s stopped impersonating user %s in session %s", impersonator.Username, current.Username, current.ID)
+
+	sessInfo, err := s.Storage.Session(ctx, impersonator.SessionID)
+	if err != nil {
+		return ctx, httpio.NewUnauthorizedMessageWithError(err, "invalid session")
+	}
+	if sessInfo.Expired || sessInfo.Username != impersonator.Username {
+		return ctx, httpio.NewUnauthorizedMessage("session expired")
+	}
+	sessInfo.UpdatedAt = s.lastActivity(sessInfo)
+	if err := s.checkIdle(ctx, sessInfo); err != nil {
+		return ctx, err
+	}
+	if s.MaxSessionLifetime > 0 && time.Since(sessInfo.CreatedAt) > s.MaxSessionLifetime {
+		return ctx, httpio.NewUnauthorizedMessage("session expired")
+	}
+
+	if err := s.updateSessionActivity(ctx, sessInfo.ID); err != nil {
+		return ctx, err
+	}
+
+	// Write new Auth Cookie
+	s.newAuthCookie(w, sessInfo.ID, sessInfo.IdleTimeout)
+
+	// Write new XSRF Token Cookie to match the SessionID
+	s.CookieHandler.CreateXSRFTokenCookie(w, sessInfo.ID)
+
+	// Store the session of the impersonator in context
+	ctx = context.WithValue(ctx, sessioninfo.CTXSessionID, sessInfo.ID)
+	ctx = context.WithValue(ctx, sessioninfo.CtxSessionInfo, sessInfo)
+	ctx = context.WithValue(ctx, sessioninfo.CtxSessionExpiry, s.expiry(sessInfo, time.Now()))
+	ctx = context.WithValue(ctx, sessioninfo.CtxSessionData, sessioninfo.NewSessionData(s.Storage, sessInfo.ID))
+	ctx = context.WithValue(ctx, sessioninfo.CtxImpersonator, nil)
+
+	l := logger.FromCtx(ctx).
+		AddRequestAttribute("username", sessInfo.Username).
+		WithAttributes().AddAttribute("username", sessInfo.Username).Logger()
+
+	return logger.NewCtx(ctx, l), nil
+}
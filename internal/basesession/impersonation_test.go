@@ -0,0 +1,287 @@
+package basesession
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cccteam/ccc"
+	"github.com/cccteam/httpio"
+	"github.com/cccteam/session/mock/mock_cookie"
+	"github.com/cccteam/session/sessioninfo"
+	"github.com/cccteam/session/sessionstorage"
+	"github.com/cccteam/session/sessionstorage/mock/mock_sessionstorage"
+	"github.com/go-playground/errors/v5"
+	"go.uber.org/mock/gomock"
+)
+
+func TestBaseSession_Impersonation(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := sessionstorage.NewMemoryPreauth()
+	adminID, err := store.NewSession(ctx, "admin")
+	if err != nil {
+		t.Fatalf("PreauthStore.NewSession() error = %v", err)
+	}
+
+	var impersonatedID ccc.UUID
+	cookieHandler := mock_cookie.NewMockHandler(gomock.NewController(t))
+	gomock.InOrder(
+		cookieHandler.EXPECT().NewAuthCookie(gomock.Any(), true, gomock.Any()).Do(func(_ any, _ bool, id ccc.UUID) { impersonatedID = id }),
+		cookieHandler.EXPECT().CreateXSRFTokenCookie(gomock.Any(), gomock.Any()),
+		cookieHandler.EXPECT().NewAuthCookie(gomock.Any(), true, adminID),
+		cookieHandler.EXPECT().CreateXSRFTokenCookie(gomock.Any(), adminID),
+	)
+
+	a := &BaseSession{
+		SessionTimeout:          time.Hour,
+		Storage:                 store,
+		CookieHandler:           cookieHandler,
+		ImpersonationAuthorizer: func(context.Context, string, string) error { return nil },
+	}
+
+	adminCtx, err := a.ValidateSessionAPI(context.WithValue(ctx, sessioninfo.CTXSessionID, adminID))
+	if err != nil {
+		t.Fatalf("BaseSession.ValidateSessionAPI() error = %v", err)
+	}
+	if _, ok := sessioninfo.ImpersonatorFromCtx(adminCtx); ok {
+		t.Error("sessioninfo.ImpersonatorFromCtx() ok = true, want false for a session that is not impersonated")
+	}
+	if _, err := a.ImpersonateAPI(adminCtx, httptest.NewRecorder(), store, "admin", nil); !httpio.HasBadRequest(err) {
+		t.Errorf("BaseSession.ImpersonateAPI() error = %v, want BadRequest when impersonating yourself", err)
+	}
+	if _, err := a.EndImpersonationAPI(adminCtx, httptest.NewRecorder()); !httpio.HasBadRequest(err) {
+		t.Errorf("BaseSession.EndImpersonationAPI() error = %v, want BadRequest for a session that is not impersonated", err)
+	}
+
+	id, err := a.ImpersonateAPI(adminCtx, httptest.NewRecorder(), store, "specialUser", nil)
+	if err != nil {
+		t.Fatalf("BaseSession.ImpersonateAPI() error = %v", err)
+	}
+	if id != impersonatedID {
+		t.Errorf("BaseSession.ImpersonateAPI() = %v, want the session of the Auth Cookie %v", id, impersonatedID)
+	}
+
+	ctx, err = a.ValidateSessionAPI(context.WithValue(ctx, sessioninfo.CTXSessionID, id))
+	if err != nil {
+		t.Fatalf("BaseSession.ValidateSessionAPI() error = %v", err)
+	}
+	if got := sessioninfo.FromCtx(ctx); got.Username != "specialUser" || got.AuthMethod != sessioninfo.AuthMethodImpersonation {
+		t.Errorf("SessionInfo Username = %q and AuthMethod = %q, want %q and %q", got.Username, got.AuthMethod, "specialUser", sessioninfo.AuthMethodImpersonation)
+	}
+	impersonator, ok := sessioninfo.ImpersonatorFromCtx(ctx)
+	if !ok {
+		t.Fatal("sessioninfo.ImpersonatorFromCtx() ok = false, want true")
+	}
+	if impersonator.Username != "admin" || impersonator.SessionID != adminID {
+		t.Errorf("sessioninfo.ImpersonatorFromCtx() = %+v, want admin with session %v", impersonator, adminID)
+	}
+	if got := NewAuthenticatedResponse(sessioninfo.FromCtx(ctx)); !got.Impersonated || got.Impersonator != "admin" {
+		t.Errorf("NewAuthenticatedResponse() = %+v, want impersonated by admin", got)
+	}
+	if _, err := a.ImpersonateAPI(ctx, httptest.NewRecorder(), store, "otherUser", nil); !httpio.HasBadRequest(err) {
+		t.Errorf("BaseSession.ImpersonateAPI() error = %v, want BadRequest when already impersonating", err)
+	}
+
+	ctx, err = a.EndImpersonationAPI(ctx, httptest.NewRecorder())
+	if err != nil {
+		t.Fatalf("BaseSession.EndImpersonationAPI() error = %v", err)
+	}
+	if got := sessioninfo.IDFromCtx(ctx); got != adminID {
+		t.Errorf("sessioninfo.IDFromCtx() = %v, want %v", got, adminID)
+	}
+	if _, ok := sessioninfo.ImpersonatorFromCtx(ctx); ok {
+		t.Error("sessioninfo.ImpersonatorFromCtx() ok = true after EndImpersonationAPI(), want false")
+	}
+	if sessInfo, err := store.Session(ctx, id); err != nil || !sessInfo.Expired {
+		t.Errorf("impersonated session = %+v, %v, want expired", sessInfo, err)
+	}
+}
+
+func TestBaseSession_EndImpersonationAPI_impersonatorLoggedOut(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := sessionstorage.NewMemoryPreauth()
+	adminID, err := store.NewSession(ctx, "admin")
+	if err != nil {
+		t.Fatalf("PreauthStore.NewSession() error = %v", err)
+	}
+	impersonatorCtx := context.WithValue(ctx, sessioninfo.CtxImpersonator, &sessioninfo.Impersonator{Username: "admin", SessionID: adminID})
+	id, err := store.NewSession(impersonatorCtx, "specialUser")
+	if err != nil {
+		t.Fatalf("PreauthStore.NewSession() error = %v", err)
+	}
+	if err := store.DestroySession(ctx, adminID); err != nil {
+		t.Fatalf("PreauthStore.DestroySession() error = %v", err)
+	}
+
+	a := &BaseSession{SessionTimeout: time.Hour, Storage: store}
+	ctx, err = a.ValidateSessionAPI(context.WithValue(ctx, sessioninfo.CTXSessionID, id))
+	if err != nil {
+		t.Fatalf("BaseSession.ValidateSessionAPI() error = %v", err)
+	}
+
+	if _, err := a.EndImpersonationAPI(ctx, httptest.NewRecorder()); !httpio.HasUnauthorized(err) {
+		t.Errorf("BaseSession.EndImpersonationAPI() error = %v, want Unauthorized", err)
+	}
+	if sessInfo, err := store.Session(ctx, id); err != nil || !sessInfo.Expired {
+		t.Errorf("impersonated session = %+v, %v, want expired", sessInfo, err)
+	}
+}
+
+func TestBaseSession_EndImpersonationAPI_impersonatorIdle(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		expiredHook bool
+	}{
+		{
+			name: "idle session of the impersonator",
+		},
+		{
+			name:        "idle session of the impersonator with an Expired hook",
+			expiredHook: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			adminID := ccc.Must(ccc.NewUUID())
+			current := &sessioninfo.SessionInfo{
+				ID:           ccc.Must(ccc.NewUUID()),
+				Username:     "specialUser",
+				Impersonator: &sessioninfo.Impersonator{Username: "admin", SessionID: adminID},
+			}
+			admin := &sessioninfo.SessionInfo{ID: adminID, Username: "admin", CreatedAt: time.Now().Add(-3 * time.Hour), UpdatedAt: time.Now().Add(-2 * time.Hour)}
+
+			// The session of the impersonator is not touched, so that it is not revived
+			storage := mock_sessionstorage.NewMockBaseStore(gomock.NewController(t))
+			storage.EXPECT().DestroySession(gomock.Any(), current.ID).Return(nil)
+			storage.EXPECT().Session(gomock.Any(), adminID).Return(admin, nil)
+
+			calls := &hookCalls{}
+			a := &BaseSession{SessionTimeout: time.Hour, Storage: storage}
+			if tt.expiredHook {
+				a.Hooks.Expired = calls.hook
+				storage.EXPECT().DestroySession(gomock.Any(), adminID).Return(nil)
+			}
+
+			ctx := context.WithValue(context.Background(), sessioninfo.CtxSessionInfo, current)
+			if _, err := a.EndImpersonationAPI(ctx, httptest.NewRecorder()); !httpio.HasUnauthorized(err) {
+				t.Errorf("BaseSession.EndImpersonationAPI() error = %v, want Unauthorized", err)
+			}
+			if tt.expiredHook && len(calls.sorted()) != 1 {
+				t.Errorf("Expired hook called for %v, want %v", calls.sorted(), adminID)
+			}
+		})
+	}
+}
+
+func TestBaseSession_ImpersonateAPI_authorization(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		authorizer    ImpersonationAuthorizer
+		wantForbidden bool
+	}{
+		{
+			name:          "no authorizer",
+			wantForbidden: true,
+		},
+		{
+			name:          "authorizer refuses",
+			authorizer:    func(context.Context, string, string) error { return errors.New("not an admin") },
+			wantForbidden: true,
+		},
+		{
+			name: "authorizer allows",
+			authorizer: func(_ context.Context, impersonator, target string) error {
+				if impersonator != "admin" || target != "specialUser" {
+					return errors.Newf("unexpected impersonation of %s by %s", target, impersonator)
+				}
+
+				return nil
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			store := sessionstorage.NewMemoryPreauth()
+			adminID, err := store.NewSession(ctx, "admin")
+			if err != nil {
+				t.Fatalf("PreauthStore.NewSession() error = %v", err)
+			}
+
+			cookieHandler := mock_cookie.NewMockHandler(gomock.NewController(t))
+			if !tt.wantForbidden {
+				cookieHandler.EXPECT().NewAuthCookie(gomock.Any(), true, gomock.Any())
+				cookieHandler.EXPECT().CreateXSRFTokenCookie(gomock.Any(), gomock.Any())
+			}
+
+			a := &BaseSession{SessionTimeout: time.Hour, Storage: store, CookieHandler: cookieHandler, ImpersonationAuthorizer: tt.authorizer}
+			adminCtx, err := a.ValidateSessionAPI(context.WithValue(ctx, sessioninfo.CTXSessionID, adminID))
+			if err != nil {
+				t.Fatalf("BaseSession.ValidateSessionAPI() error = %v", err)
+			}
+
+			var checkedTarget bool
+			checkTarget := func(_ context.Context, username string) (string, error) {
+				checkedTarget = true
+
+				return username, nil
+			}
+
+			_, err = a.ImpersonateAPI(adminCtx, httptest.NewRecorder(), store, "specialUser", checkTarget)
+			if got := httpio.HasForbidden(err); got != tt.wantForbidden {
+				t.Errorf("BaseSession.ImpersonateAPI() error = %v, want Forbidden %v", err, tt.wantForbidden)
+			}
+			if !tt.wantForbidden && err != nil {
+				t.Errorf("BaseSession.ImpersonateAPI() error = %v", err)
+			}
+			if checkedTarget == tt.wantForbidden {
+				t.Errorf("checkTarget called = %v, want %v", checkedTarget, !tt.wantForbidden)
+			}
+		})
+	}
+}
+
+func TestBaseSession_ImpersonateAPI_canonicalSelf(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := sessionstorage.NewMemoryPreauth()
+	adminID, err := store.NewSession(ctx, "admin")
+	if err != nil {
+		t.Fatalf("PreauthStore.NewSession() error = %v", err)
+	}
+
+	a := &BaseSession{
+		SessionTimeout:          time.Hour,
+		Storage:                 store,
+		CookieHandler:           mock_cookie.NewMockHandler(gomock.NewController(t)),
+		ImpersonationAuthorizer: func(context.Context, string, string) error { return nil },
+	}
+	adminCtx, err := a.ValidateSessionAPI(context.WithValue(ctx, sessioninfo.CTXSessionID, adminID))
+	if err != nil {
+		t.Fatalf("BaseSession.ValidateSessionAPI() error = %v", err)
+	}
+
+	// checkTarget resolves usernames case insensitively, as a user store might
+	checkTarget := func(_ context.Context, username string) (string, error) {
+		return strings.ToLower(username), nil
+	}
+
+	if _, err := a.ImpersonateAPI(adminCtx, httptest.NewRecorder(), store, "ADMIN", checkTarget); !httpio.HasBadRequest(err) {
+		t.Errorf("BaseSession.ImpersonateAPI() error = %v, want BadRequest when impersonating a variant of your own username", err)
+	}
+}
@@ -0,0 +1,51 @@
+package basesession
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/cccteam/ccc/tracer"
+	"github.com/cccteam/httpio"
+	"github.com/cccteam/session/sessioninfo"
+)
+
+// ReauthenticationRequired is the message of the Unauthorized error returned when the session is valid
+// but its user last authenticated too long ago, so that clients can prompt for re-authentication
+// rather than a new login.
+const ReauthenticationRequired = "reauthentication required"
+
+// RequireRecentAuth returns a middleware that rejects the request with a ReauthenticationRequired Unauthorized
+// error when the user of the session last authenticated more than maxAge ago, and impersonated sessions with a
+// Forbidden error. ValidateSession handler must be called before calling RequireRecentAuth
+func (s *BaseSession) RequireRecentAuth(maxAge time.Duration) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return s.Handle(func(w http.ResponseWriter, r *http.Request) error {
+			ctx, span := tracer.Start(r.Context())
+			defer span.End()
+
+			if err := RequireRecentAuthAPI(ctx, maxAge); err != nil {
+				return httpio.NewEncoder(w).ClientMessage(ctx, err)
+			}
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+
+			return nil
+		})
+	}
+}
+
+// RequireRecentAuthAPI returns a ReauthenticationRequired Unauthorized error when the user of the session
+// in ctx last authenticated more than maxAge ago. Impersonated sessions are always rejected with a Forbidden
+// error, as the user never authenticated in them and the impersonator can not re-authenticate as the user.
+func RequireRecentAuthAPI(ctx context.Context, maxAge time.Duration) error {
+	sessInfo := sessioninfo.FromCtx(ctx)
+	if sessInfo.Impersonator != nil {
+		return httpio.NewForbiddenMessage("impersonated sessions can not re-authenticate")
+	}
+	if time.Since(sessInfo.LastAuthenticatedAt) > maxAge {
+		return httpio.NewUnauthorizedMessage(ReauthenticationRequired)
+	}
+
+	return nil
+}
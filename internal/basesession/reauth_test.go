@@ -0,0 +1,84 @@
+package basesession
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cccteam/session/sessioninfo"
+)
+
+func TestBaseSession_RequireRecentAuth(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name                string
+		lastAuthenticatedAt time.Time
+		impersonator        *sessioninfo.Impersonator
+		maxAge              time.Duration
+		wantStatus          int
+		wantNext            bool
+	}{
+		{
+			name:                "recent authentication",
+			lastAuthenticatedAt: time.Now().Add(-time.Minute),
+			maxAge:              5 * time.Minute,
+			wantStatus:          http.StatusOK,
+			wantNext:            true,
+		},
+		{
+			name:                "authentication too old",
+			lastAuthenticatedAt: time.Now().Add(-10 * time.Minute),
+			maxAge:              5 * time.Minute,
+			wantStatus:          http.StatusUnauthorized,
+		},
+		{
+			name:                "impersonated session",
+			lastAuthenticatedAt: time.Now(),
+			impersonator:        &sessioninfo.Impersonator{Username: "admin"},
+			maxAge:              5 * time.Minute,
+			wantStatus:          http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			a := &BaseSession{
+				Handle: func(handler func(w http.ResponseWriter, r *http.Request) error) http.HandlerFunc {
+					return func(w http.ResponseWriter, r *http.Request) {
+						if err := handler(w, r); err != nil {
+							_ = err
+						}
+					}
+				},
+			}
+
+			ctx := context.WithValue(context.Background(), sessioninfo.CtxSessionInfo, &sessioninfo.SessionInfo{
+				Username:            "specialUser",
+				LastAuthenticatedAt: tt.lastAuthenticatedAt,
+				Impersonator:        tt.impersonator,
+			})
+			r := httptest.NewRequestWithContext(ctx, http.MethodPost, "/user/password", http.NoBody)
+			w := httptest.NewRecorder()
+
+			var gotNext bool
+			a.RequireRecentAuth(tt.maxAge)(
+				http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) { gotNext = true }),
+			).ServeHTTP(w, r)
+
+			if got := w.Code; got != tt.wantStatus {
+				t.Errorf("BaseSession.RequireRecentAuth() status = %v, want %v", got, tt.wantStatus)
+			}
+			if gotNext != tt.wantNext {
+				t.Errorf("BaseSession.RequireRecentAuth() called next = %v, want %v", gotNext, tt.wantNext)
+			}
+			if tt.wantStatus == http.StatusUnauthorized && !strings.Contains(w.Body.String(), ReauthenticationRequired) {
+				t.Errorf("BaseSession.RequireRecentAuth() body = %q, want message %q", w.Body.String(), ReauthenticationRequired)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package basesession
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/cccteam/ccc"
+	"github.com/cccteam/ccc/tracer"
+	"github.com/cccteam/httpio"
+	"github.com/cccteam/session/sessioninfo"
+	"github.com/cccteam/session/sessionstorage"
+	"github.com/go-playground/errors/v5"
+)
+
+// RouterSessionID is the router path parameter that holds the ID of the session to revoke
+const RouterSessionID = "sessionID"
+
+// sessionResponse describes one of the user's sessions
+type sessionResponse struct {
+	ID           ccc.UUID               `json:"id"`
+	Current      bool                   `json:"current"`
+	CreatedAt    time.Time              `json:"createdAt"`
+	LastActiveAt time.Time              `json:"lastActiveAt"`
+	ClientIP     string                 `json:"clientIp,omitempty"`
+	UserAgent    string                 `json:"userAgent,omitempty"`
+	AuthMethod   sessioninfo.AuthMethod `json:"authMethod,omitempty"`
+	// Impersonator is the user that started the session to act as the current user.
+	Impersonator string `json:"impersonator,omitempty"`
+}
+
+// Sessions is the handler that lists the active sessions of the current user, newest first.
+// The "cursor" query parameter requests the page after the one that returned it as nextCursor.
+// ValidateSession handler must be called before calling Sessions
+func (s *BaseSession) Sessions() http.HandlerFunc {
+	type response struct {
+		Sessions   []sessionResponse `json:"sessions"`
+		NextCursor string            `json:"nextCursor,omitempty"`
+	}
+
+	return s.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		ctx, span := tracer.Start(r.Context())
+		defer span.End()
+
+		current := sessioninfo.FromCtx(ctx)
+		page, err := s.activeSessions(ctx, current.Username, sessionstorage.Page{Cursor: r.URL.Query().Get("cursor")})
+		if err != nil {
+			return httpio.NewEncoder(w).ClientMessage(ctx, err)
+		}
+
+		res := response{
+			Sessions:   make([]sessionResponse, 0, len(page.Sessions)),
+			NextCursor: page.NextCursor,
+		}
+		for _, si := range page.Sessions {
+			var impersonator string
+			if si.Impersonator != nil {
+				impersonator = si.Impersonator.Username
+			}
+			res.Sessions = append(res.Sessions, sessionResponse{
+				ID:           si.ID,
+				Current:      si.ID == current.ID,
+				CreatedAt:    si.CreatedAt,
+				LastActiveAt: si.UpdatedAt,
+				ClientIP:     si.ClientIP,
+				UserAgent:    si.UserAgent,
+				AuthMethod:   si.AuthMethod,
+				Impersonator: impersonator,
+			})
+		}
+
+		return httpio.NewEncoder(w).Ok(res)
+	})
+}
+
+// RevokeSession is the handler that destroys the session given by the RouterSessionID path parameter,
+// which must belong to the current user. ValidateSession handler must be called before calling RevokeSession
+func (s *BaseSession) RevokeSession() http.HandlerFunc {
+	return s.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		ctx, span := tracer.Start(r.Context())
+		defer span.End()
+
+		sessionID := httpio.Param[ccc.UUID](r, RouterSessionID)
+		if err := s.RevokeSessionAPI(ctx, sessionID); err != nil {
+			return httpio.NewEncoder(w).ClientMessage(ctx, err)
+		}
+
+		return httpio.NewEncoder(w).Ok(nil)
+	})
+}
+
+// RevokeSessionAPI destroys the session sessionID of the user of the session in ctx, and calls the
+// LoggedOut hook when the session was active. It returns a NotFound error when the session belongs
+// to another user, so that session IDs can not be probed.
+func (s *BaseSession) RevokeSessionAPI(ctx context.Context, sessionID ccc.UUID) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	sessInfo, err := s.Storage.Session(ctx, sessionID)
+	if err != nil {
+		return errors.Wrap(err, "sessionstorage.BaseStore.Session()")
+	}
+	if sessInfo.Username != sessioninfo.FromCtx(ctx).Username {
+		return httpio.NewNotFoundMessagef("session %q not found", sessionID)
+	}
+
+	active := s.active(sessInfo)
+	if err := s.Storage.DestroySession(ctx, sessionID); err != nil {
+		return errors.Wrap(err, "sessionstorage.BaseStore.DestroySession()")
+	}
+
+	// A session that already expired was not logged out by the revoke
+	if active {
+		s.RunHook(ctx, s.Hooks.LoggedOut, sessInfo)
+	}
+
+	return nil
+}
+
+// RevokeOtherSessions is the handler that destroys every active session of the current user except the
+// current session. ValidateSession handler must be called before calling RevokeOtherSessions
+func (s *BaseSession) RevokeOtherSessions() http.HandlerFunc {
+	return s.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		ctx, span := tracer.Start(r.Context())
+		defer span.End()
+
+		if err := s.RevokeOtherSessionsAPI(ctx); err != nil {
+			return httpio.NewEncoder(w).ClientMessage(ctx, err)
+		}
+
+		return httpio.NewEncoder(w).Ok(nil)
+	})
+}
+
+// RevokeOtherSessionsAPI destroys every active session of the user of the session in ctx, except that session
+func (s *BaseSession) RevokeOtherSessionsAPI(ctx context.Context) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	current := sessioninfo.FromCtx(ctx)
+
+	var page sessionstorage.Page
+	for {
+		sessions, err := s.activeSessions(ctx, current.Username, page)
+		if err != nil {
+			return err
+		}
+
+		for _, si := range sessions.Sessions {
+			if si.ID == current.ID {
+				continue
+			}
+			if err := s.Storage.DestroySession(ctx, si.ID); err != nil {
+				return errors.Wrap(err, "sessionstorage.BaseStore.DestroySession()")
+			}
+			s.RunHook(ctx, s.Hooks.LoggedOut, si)
+		}
+
+		if sessions.NextCursor == "" {
+			return nil
+		}
+		page.Cursor = sessions.NextCursor
+	}
+}
+
+// active reports whether the session is not expired, idle or past the maximum lifetime,
+// including activity that is buffered but not written yet.
+func (s *BaseSession) active(sessInfo *sessioninfo.SessionInfo) bool {
+	if sessInfo.Expired {
+		return false
+	}
+
+	if time.Since(s.lastActivity(sessInfo)) > s.idleTimeout(sessInfo) {
+		return false
+	}
+
+	return s.MaxSessionLifetime <= 0 || time.Since(sessInfo.CreatedAt) <= s.MaxSessionLifetime
+}
+
+// activeSessions returns a page of the sessions of username that are still valid, newest first.
+func (s *BaseSession) activeSessions(ctx context.Context, username string, page sessionstorage.Page) (*sessionstorage.SessionPage, error) {
+	sessions, err := s.UserSessions(ctx, username, sessionstorage.SessionFilter{State: sessionstorage.SessionStateActive}, page)
+	if err != nil {
+		return nil, errors.Wrap(err, "BaseSession.UserSessions()")
+	}
+
+	if s.MaxSessionLifetime > 0 {
+		// Sessions past the maximum lifetime are only marked as expired when they are next used
+		createdBefore := time.Now().Add(-s.MaxSessionLifetime)
+		active := sessions.Sessions[:0]
+		for _, si := range sessions.Sessions {
+			if !si.CreatedAt.Before(createdBefore) {
+				active = append(active, si)
+			}
+		}
+		sessions.Sessions = active
+	}
+
+	return sessions, nil
+}
@@ -0,0 +1,265 @@
+package basesession
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/cccteam/ccc"
+	"github.com/cccteam/httpio"
+	"github.com/cccteam/session/sessioninfo"
+	"github.com/cccteam/session/sessionstorage"
+	"github.com/cccteam/session/sessionstorage/mock/mock_sessionstorage"
+	"github.com/go-playground/errors/v5"
+	"go.uber.org/mock/gomock"
+)
+
+func TestBaseSession_Sessions(t *testing.T) {
+	t.Parallel()
+
+	current := &sessioninfo.SessionInfo{ID: ccc.Must(ccc.NewUUID()), Username: "specialUser", CreatedAt: time.Now().Add(-time.Hour)}
+	other := &sessioninfo.SessionInfo{
+		ID:         ccc.Must(ccc.NewUUID()),
+		Username:   "specialUser",
+		CreatedAt:  time.Now().Add(-2 * time.Hour),
+		ClientIP:   "203.0.113.7",
+		UserAgent:  "test-agent",
+		AuthMethod: sessioninfo.AuthMethodPassword,
+	}
+	aged := &sessioninfo.SessionInfo{ID: ccc.Must(ccc.NewUUID()), Username: "specialUser", CreatedAt: time.Now().Add(-48 * time.Hour)}
+
+	tests := []struct {
+		name               string
+		maxSessionLifetime time.Duration
+		cursor             string
+		storeErr           error
+		wantIDs            []ccc.UUID
+		wantNextCursor     string
+		wantStatus         int
+	}{
+		{
+			name:           "lists active sessions",
+			cursor:         "page2",
+			wantIDs:        []ccc.UUID{current.ID, other.ID, aged.ID},
+			wantNextCursor: "page3",
+			wantStatus:     http.StatusOK,
+		},
+		{
+			name:               "skips sessions past the maximum lifetime",
+			maxSessionLifetime: 24 * time.Hour,
+			wantIDs:            []ccc.UUID{current.ID, other.ID},
+			wantNextCursor:     "page3",
+			wantStatus:         http.StatusOK,
+		},
+		{
+			name:       "store error",
+			storeErr:   errors.New("list failed"),
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			storage := mock_sessionstorage.NewMockBaseStore(ctrl)
+			filter := sessionstorage.SessionFilter{State: sessionstorage.SessionStateActive, IdleTimeout: time.Minute}
+			if tt.storeErr != nil {
+				storage.EXPECT().UserSessions(gomock.Any(), "specialUser", filter, sessionstorage.Page{Cursor: tt.cursor}).Return(nil, tt.storeErr)
+			} else {
+				storage.EXPECT().UserSessions(gomock.Any(), "specialUser", filter, sessionstorage.Page{Cursor: tt.cursor}).Return(&sessionstorage.SessionPage{
+					Sessions:   []*sessioninfo.SessionInfo{current, other, aged},
+					NextCursor: "page3",
+				}, nil)
+			}
+
+			a := &BaseSession{
+				SessionTimeout:     time.Minute,
+				MaxSessionLifetime: tt.maxSessionLifetime,
+				Storage:            storage,
+				Handle: func(handler func(w http.ResponseWriter, r *http.Request) error) http.HandlerFunc {
+					return func(w http.ResponseWriter, r *http.Request) {
+						_ = handler(w, r)
+					}
+				},
+			}
+
+			target := "/sessions"
+			if tt.cursor != "" {
+				target += "?cursor=" + tt.cursor
+			}
+			r := httptest.NewRequest(http.MethodGet, target, http.NoBody)
+			r = r.WithContext(context.WithValue(r.Context(), sessioninfo.CtxSessionInfo, current))
+			w := httptest.NewRecorder()
+			a.Sessions().ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("BaseSession.Sessions() status = %v, want %v", w.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var got struct {
+				Sessions []struct {
+					ID         ccc.UUID `json:"id"`
+					Current    bool     `json:"current"`
+					ClientIP   string   `json:"clientIp"`
+					UserAgent  string   `json:"userAgent"`
+					AuthMethod string   `json:"authMethod"`
+				} `json:"sessions"`
+				NextCursor string `json:"nextCursor"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("json.Unmarshal() error = %v", err)
+			}
+
+			var ids []ccc.UUID
+			for _, s := range got.Sessions {
+				ids = append(ids, s.ID)
+				if s.Current != (s.ID == current.ID) {
+					t.Errorf("session %v current = %v, want %v", s.ID, s.Current, s.ID == current.ID)
+				}
+				if s.ID == other.ID && (s.ClientIP != other.ClientIP || s.UserAgent != other.UserAgent || s.AuthMethod != string(other.AuthMethod)) {
+					t.Errorf("session %v client = (%q, %q, %q), want (%q, %q, %q)", s.ID, s.ClientIP, s.UserAgent, s.AuthMethod, other.ClientIP, other.UserAgent, other.AuthMethod)
+				}
+			}
+			if !slices.Equal(ids, tt.wantIDs) {
+				t.Errorf("BaseSession.Sessions() ids = %v, want %v", ids, tt.wantIDs)
+			}
+			if got.NextCursor != tt.wantNextCursor {
+				t.Errorf("BaseSession.Sessions() nextCursor = %q, want %q", got.NextCursor, tt.wantNextCursor)
+			}
+		})
+	}
+}
+
+func TestBaseSession_RevokeSessionAPI(t *testing.T) {
+	t.Parallel()
+
+	sessionID := ccc.Must(ccc.NewUUID())
+
+	tests := []struct {
+		name         string
+		owner        string
+		expired      bool
+		idle         bool
+		sessionErr   error
+		destroyErr   error
+		wantDestroy  bool
+		wantHook     bool
+		wantErr      bool
+		wantNotFound bool
+	}{
+		{
+			name:        "revokes a session of the user",
+			owner:       "specialUser",
+			wantDestroy: true,
+			wantHook:    true,
+		},
+		{
+			name:        "revokes an expired session without the LoggedOut hook",
+			owner:       "specialUser",
+			expired:     true,
+			wantDestroy: true,
+		},
+		{
+			name:        "revokes an idle session without the LoggedOut hook",
+			owner:       "specialUser",
+			idle:        true,
+			wantDestroy: true,
+		},
+		{
+			name:         "session of another user",
+			owner:        "otherUser",
+			wantErr:      true,
+			wantNotFound: true,
+		},
+		{
+			name:         "session not found",
+			sessionErr:   httpio.NewNotFoundMessage("session not found"),
+			wantErr:      true,
+			wantNotFound: true,
+		},
+		{
+			name:        "destroy error",
+			owner:       "specialUser",
+			destroyErr:  errors.New("destroy failed"),
+			wantDestroy: true,
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			storage := mock_sessionstorage.NewMockBaseStore(ctrl)
+			if tt.sessionErr != nil {
+				storage.EXPECT().Session(gomock.Any(), sessionID).Return(nil, tt.sessionErr)
+			} else {
+				updatedAt := time.Now()
+				if tt.idle {
+					updatedAt = updatedAt.Add(-2 * time.Hour)
+				}
+				storage.EXPECT().Session(gomock.Any(), sessionID).Return(&sessioninfo.SessionInfo{ID: sessionID, Username: tt.owner, UpdatedAt: updatedAt, Expired: tt.expired}, nil)
+			}
+			if tt.wantDestroy {
+				storage.EXPECT().DestroySession(gomock.Any(), sessionID).Return(tt.destroyErr)
+			}
+
+			calls := &hookCalls{}
+			a := &BaseSession{Storage: storage, SessionTimeout: time.Hour, Hooks: Hooks{LoggedOut: calls.hook}}
+			ctx := context.WithValue(context.Background(), sessioninfo.CtxSessionInfo, &sessioninfo.SessionInfo{ID: ccc.Must(ccc.NewUUID()), Username: "specialUser"})
+
+			err := a.RevokeSessionAPI(ctx, sessionID)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BaseSession.RevokeSessionAPI() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantNotFound && !httpio.HasNotFound(err) {
+				t.Errorf("BaseSession.RevokeSessionAPI() error = %v, want not found", err)
+			}
+			var want []ccc.UUID
+			if tt.wantHook {
+				want = []ccc.UUID{sessionID}
+			}
+			if got := calls.sorted(); !slices.Equal(got, want) {
+				t.Errorf("LoggedOut hook called for %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestBaseSession_RevokeOtherSessionsAPI(t *testing.T) {
+	t.Parallel()
+
+	current := &sessioninfo.SessionInfo{ID: ccc.Must(ccc.NewUUID()), Username: "specialUser", CreatedAt: time.Now()}
+	first := &sessioninfo.SessionInfo{ID: ccc.Must(ccc.NewUUID()), Username: "specialUser", CreatedAt: time.Now()}
+	second := &sessioninfo.SessionInfo{ID: ccc.Must(ccc.NewUUID()), Username: "specialUser", CreatedAt: time.Now()}
+
+	ctrl := gomock.NewController(t)
+	storage := mock_sessionstorage.NewMockBaseStore(ctrl)
+	filter := sessionstorage.SessionFilter{State: sessionstorage.SessionStateActive, IdleTimeout: time.Minute}
+	gomock.InOrder(
+		storage.EXPECT().UserSessions(gomock.Any(), "specialUser", filter, sessionstorage.Page{}).Return(&sessionstorage.SessionPage{
+			Sessions:   []*sessioninfo.SessionInfo{first, current},
+			NextCursor: "page2",
+		}, nil),
+		storage.EXPECT().DestroySession(gomock.Any(), first.ID).Return(nil),
+		storage.EXPECT().UserSessions(gomock.Any(), "specialUser", filter, sessionstorage.Page{Cursor: "page2"}).Return(&sessionstorage.SessionPage{
+			Sessions: []*sessioninfo.SessionInfo{second},
+		}, nil),
+		storage.EXPECT().DestroySession(gomock.Any(), second.ID).Return(nil),
+	)
+
+	a := &BaseSession{SessionTimeout: time.Minute, Storage: storage}
+	ctx := context.WithValue(context.Background(), sessioninfo.CtxSessionInfo, current)
+
+	if err := a.RevokeOtherSessionsAPI(ctx); err != nil {
+		t.Fatalf("BaseSession.RevokeOtherSessionsAPI() error = %v", err)
+	}
+}
@@ -0,0 +1,155 @@
+package basesession
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cccteam/ccc"
+	"github.com/cccteam/logger"
+	"github.com/cccteam/session/cookie"
+	internalcookie "github.com/cccteam/session/internal/cookie"
+	"github.com/cccteam/session/internal/cookiesession"
+	"github.com/cccteam/session/sessionstorage"
+	"github.com/go-playground/errors/v5"
+)
+
+// stateless reports whether Storage keeps sessions in the Auth Cookie instead of a database
+func (s *BaseSession) stateless() bool {
+	store, ok := s.Storage.(sessionstorage.StatelessStore)
+
+	return ok && store.Stateless()
+}
+
+// loadCookieSession stores the session kept in the Auth Cookie in ctx for a stateless store.
+// cval is nil when the request has no valid Auth Cookie.
+func (s *BaseSession) loadCookieSession(ctx context.Context, cval *cookie.Values) context.Context {
+	var encoded string
+	if cval != nil {
+		encoded, _ = cval.GetString(internalcookie.Session)
+	}
+
+	ctx, _, err := cookiesession.NewContext(ctx, encoded)
+	if err != nil {
+		logger.FromCtx(ctx).Warnf("invalid session in Auth Cookie: %v", err)
+	}
+
+	return ctx
+}
+
+// flushBeforeCookieSession writes the changes to the session data in ctx before the session of a
+// stateless store is written to the Auth Cookie, as the response has been written when the
+// ValidateSession handler flushes them.
+func (s *BaseSession) flushBeforeCookieSession(ctx context.Context) {
+	if session, ok := cookiesession.FromCtx(ctx); ok {
+		session.BeforeEncode(func() { s.FlushSessionData(ctx) })
+	}
+}
+
+// cookieSessionWriter writes the session of a stateless store to the Auth Cookie before the response
+// header is written, when the session changed during the request.
+type cookieSessionWriter struct {
+	http.ResponseWriter
+	s       *BaseSession
+	r       *http.Request
+	session *cookiesession.Session
+	written bool
+}
+
+func (s *BaseSession) newCookieSessionWriter(w http.ResponseWriter, r *http.Request, session *cookiesession.Session) *cookieSessionWriter {
+	return &cookieSessionWriter{
+		ResponseWriter: w,
+		s:              s,
+		r:              r,
+		session:        session,
+	}
+}
+
+// WriteHeader writes the session to the Auth Cookie before the header
+func (w *cookieSessionWriter) WriteHeader(statusCode int) {
+	w.writeSession()
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write writes the session to the Auth Cookie before the header
+func (w *cookieSessionWriter) Write(b []byte) (int, error) {
+	w.writeSession()
+
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush writes the session to the Auth Cookie before the header
+func (w *cookieSessionWriter) Flush() {
+	w.writeSession()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying http.ResponseWriter for http.ResponseController
+func (w *cookieSessionWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// writeSession writes the session to the Auth Cookie, replacing an Auth Cookie already written in the response.
+// Once the header is written the session can no longer change the cookie.
+func (w *cookieSessionWriter) writeSession() {
+	if w.written {
+		return
+	}
+	w.written = true
+
+	sessionID, encoded, changed, err := w.session.Encode()
+	if err != nil {
+		logger.FromReq(w.r).Error(errors.Wrap(err, "cookiesession.Session.Encode()"))
+
+		return
+	}
+	if !changed {
+		return
+	}
+
+	cval := w.authCookie(sessionID)
+	cval.SetString(internalcookie.Session, encoded)
+	sameSiteStrict, _ := cval.GetString(internalcookie.SameSiteStrict)
+
+	w.s.CookieHandler.WriteAuthCookie(w.ResponseWriter, sameSiteStrict != strconv.FormatBool(false), cval)
+}
+
+// authCookie removes the Auth Cookie written in the response and returns its values, or else the values
+// of the Auth Cookie of the request, when they are for sessionID. It returns new values otherwise.
+func (w *cookieSessionWriter) authCookie(sessionID ccc.UUID) *cookie.Values {
+	name := w.s.CookieHandler.AuthCookieName()
+	header := w.Header()
+
+	var written *http.Cookie
+	var setCookies []string
+	for _, setCookie := range header.Values("Set-Cookie") {
+		if c, err := http.ParseSetCookie(setCookie); err == nil && c.Name == name {
+			written = c
+
+			continue
+		}
+		setCookies = append(setCookies, setCookie)
+	}
+	header.Del("Set-Cookie")
+	for _, setCookie := range setCookies {
+		header.Add("Set-Cookie", setCookie)
+	}
+
+	r := w.r
+	if written != nil {
+		c := &http.Cookie{Name: written.Name, Value: written.Value}
+		r = (&http.Request{Header: http.Header{"Cookie": []string{c.String()}}}).WithContext(w.r.Context())
+	}
+	if cval, found, err := w.s.CookieHandler.ReadAuthCookie(r); err == nil && found {
+		if id, _ := cval.GetString(internalcookie.SessionID); id == sessionID.String() {
+			return cval
+		}
+	}
+
+	return cookie.NewValues().
+		SetString(internalcookie.SessionID, sessionID.String()).
+		SetTime(internalcookie.IssuedAt, time.Now())
+}
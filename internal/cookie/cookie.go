@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/cccteam/ccc"
 	"github.com/cccteam/logger"
@@ -23,30 +24,56 @@ type Client struct {
 
 // NewCookieClient returns a new CookieClient
 func NewCookieClient(masterKeyBase64 string, opts ...Option) (*Client, error) {
-	c, err := cookie.New(masterKeyBase64)
+	options := &cookieOptions{
+		CookieName:         AuthCookieName,
+		XSRFCookieName:     XSRFCookieName,
+		XSRFHeaderName:     XSRFHeaderName,
+		OIDCCookieName:     OIDCCookieName,
+		OIDCCookieLifetime: OIDCCookieExpiration,
+		SameSite:           http.SameSiteStrictMode,
+		OIDCSameSite:       http.SameSiteDefaultMode,
+	}
+
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	if err := options.applyPrefix(); err != nil {
+		return nil, err
+	}
+
+	c, err := cookie.New(masterKeyBase64, options.RetiredKeys...)
 	if err != nil {
 		return nil, errors.Wrap(err, "cookie.New()")
 	}
 
 	client := &Client{
-		cookie: c,
-		cookieOptions: &cookieOptions{
-			CookieName:     AuthCookieName,
-			XSRFCookieName: XSRFCookieName,
-			XSRFHeaderName: XSRFHeaderName,
-		},
-	}
-
-	for _, opt := range opts {
-		opt(client.cookieOptions)
+		cookie:        c,
+		cookieOptions: options,
 	}
+	c.SetAttributes(client.attributes(true, options.SameSite))
 
 	return client, nil
 }
 
 // NewAuthCookie writes a new Auth Cookie for given sessionID
 func (c *Client) NewAuthCookie(w http.ResponseWriter, sameSiteStrict bool, sessionID ccc.UUID) *cookie.Values {
-	cval := cookie.NewValues().SetString(SessionID, sessionID.String())
+	cval := cookie.NewValues().
+		SetString(SessionID, sessionID.String()).
+		SetTime(IssuedAt, time.Now())
+
+	c.WriteAuthCookie(w, sameSiteStrict, cval)
+
+	return cval
+}
+
+// NewPersistentAuthCookie writes a new Auth Cookie for given sessionID that outlives the browser session.
+// The cookie expires lifetime after it was last written.
+func (c *Client) NewPersistentAuthCookie(w http.ResponseWriter, sameSiteStrict bool, sessionID ccc.UUID, lifetime time.Duration) *cookie.Values {
+	cval := cookie.NewValues().
+		SetString(SessionID, sessionID.String()).
+		SetTime(IssuedAt, time.Now()).
+		SetString(Lifetime, lifetime.String())
 
 	c.WriteAuthCookie(w, sameSiteStrict, cval)
 
@@ -65,14 +92,27 @@ func (c *Client) ReadAuthCookie(r *http.Request) (values *cookie.Values, found b
 
 // WriteAuthCookie writes the Auth cookie to the response
 func (c *Client) WriteAuthCookie(w http.ResponseWriter, sameSiteStrict bool, values *cookie.Values) {
-	sameSite := http.SameSiteStrictMode
+	sameSite := c.SameSite
 	if !sameSiteStrict {
 		sameSite = http.SameSiteNoneMode
 	}
 
 	values.SetString(SameSiteStrict, strconv.FormatBool(sameSiteStrict))
 
-	c.cookie.WriteSessionCookie(w, c.CookieName, c.Domain, true, sameSite, values)
+	if lifetime, err := values.GetString(Lifetime); err == nil {
+		if d, err := time.ParseDuration(lifetime); err == nil && d > 0 {
+			c.cookie.WritePersistentCookieWithAttributes(w, c.CookieName, c.attributes(true, sameSite), d, values)
+
+			return
+		}
+	}
+
+	c.cookie.WriteSessionCookieWithAttributes(w, c.CookieName, c.attributes(true, sameSite), values)
+}
+
+// AuthCookieName returns the name of the Auth cookie
+func (c *Client) AuthCookieName() string {
+	return c.CookieName
 }
 
 // RefreshXSRFTokenCookie updates the cookie when it is close to expiration, or sets it if it does not exist.
@@ -84,7 +124,8 @@ func (c *Client) RefreshXSRFTokenCookie(w http.ResponseWriter, r *http.Request,
 
 	if found {
 		cSessionID, err := cval.GetString(SessionID)
-		if err == nil {
+		// A cookie encrypted with a retired key is written again with the current key
+		if err == nil && !cval.RetiredKey() {
 			if sessionID.String() == cSessionID {
 				return false, nil
 			}
@@ -100,7 +141,7 @@ func (c *Client) RefreshXSRFTokenCookie(w http.ResponseWriter, r *http.Request,
 func (c *Client) CreateXSRFTokenCookie(w http.ResponseWriter, sessionID ccc.UUID) {
 	cval := cookie.NewValues().SetString(SessionID, sessionID.String())
 
-	c.cookie.WriteSessionCookie(w, c.XSRFCookieName, c.Domain, false, http.SameSiteStrictMode, cval)
+	c.cookie.WriteSessionCookieWithAttributes(w, c.XSRFCookieName, c.attributes(false, c.SameSite), cval)
 }
 
 // HasValidXSRFToken checks if the XSRF token is valid
@@ -135,7 +176,7 @@ func (c *Client) readXSRFHeader(r *http.Request) (values *cookie.Values, found b
 		if strings.Contains(err.Error(), "this token has expired") {
 			return nil, false
 		}
-		logger.FromReq(r).Warnf("Invalid cookie or encryption key was rotated: %v", err)
+		logger.FromReq(r).Warnf("Invalid cookie or cookie encrypted with an unknown key: %v", err)
 
 		return nil, false
 	}
@@ -145,12 +186,12 @@ func (c *Client) readXSRFHeader(r *http.Request) (values *cookie.Values, found b
 
 // WriteOidcCookie writes the OIDC cookie to the response
 func (c *Client) WriteOidcCookie(w http.ResponseWriter, values *cookie.Values) {
-	c.cookie.WritePersistentCookie(w, OIDCCookieName, c.Domain, false, http.SameSiteDefaultMode, OIDCCookieExpiration, values)
+	c.cookie.WritePersistentCookieWithAttributes(w, c.OIDCCookieName, c.attributes(false, c.OIDCSameSite), c.OIDCCookieLifetime, values)
 }
 
 // ReadOidcCookie reads the OIDC cookie from the request
 func (c *Client) ReadOidcCookie(r *http.Request) (values *cookie.Values, found bool, err error) {
-	cval, found, err := c.cookie.Read(r, OIDCCookieName)
+	cval, found, err := c.cookie.Read(r, c.OIDCCookieName)
 	if err != nil {
 		return nil, found, errors.Wrap(err, "cookie.Client.Read()")
 	}
@@ -160,7 +201,18 @@ func (c *Client) ReadOidcCookie(r *http.Request) (values *cookie.Values, found b
 
 // DeleteOidcCookie deletes the OIDC cookie from the response
 func (c *Client) DeleteOidcCookie(w http.ResponseWriter) {
-	c.cookie.Delete(w, OIDCCookieName)
+	c.cookie.DeleteWithAttributes(w, c.OIDCCookieName, c.attributes(false, c.OIDCSameSite))
+}
+
+// attributes returns the attributes of a cookie written with the cookie policy
+func (c *Client) attributes(httpOnly bool, sameSite http.SameSite) cookie.Attributes {
+	return cookie.Attributes{
+		Path:        c.Path,
+		Domain:      c.Domain,
+		HttpOnly:    httpOnly,
+		SameSite:    sameSite,
+		Partitioned: c.Partitioned,
+	}
 }
 
 // Cookie returns the underlying cookie.Client
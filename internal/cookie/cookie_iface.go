@@ -2,6 +2,7 @@ package cookie
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/cccteam/ccc"
 	"github.com/cccteam/session/cookie"
@@ -12,10 +13,12 @@ var _ Handler = &Client{}
 // Handler Interface included for testability
 type Handler interface {
 	NewAuthCookie(w http.ResponseWriter, sameSiteStrict bool, sessionID ccc.UUID) *cookie.Values
+	NewPersistentAuthCookie(w http.ResponseWriter, sameSiteStrict bool, sessionID ccc.UUID, lifetime time.Duration) *cookie.Values
 	ReadAuthCookie(r *http.Request) (values *cookie.Values, found bool, err error)
 	WriteAuthCookie(w http.ResponseWriter, sameSiteStrict bool, values *cookie.Values)
 	RefreshXSRFTokenCookie(w http.ResponseWriter, r *http.Request, sessionID ccc.UUID) (set bool, err error)
 	CreateXSRFTokenCookie(w http.ResponseWriter, sessionID ccc.UUID)
 	HasValidXSRFToken(r *http.Request) (bool, error)
+	AuthCookieName() string
 	Cookie() *cookie.Client
 }
@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"aidanwoods.dev/go-paseto"
 	"github.com/cccteam/ccc"
@@ -112,6 +113,9 @@ func Test_newAuthCookie(t *testing.T) {
 				if _, err := got.GetString(SessionID); err != nil {
 					t.Errorf("got[cookie.SCSessionID] not set. expected it set")
 				}
+				if _, err := got.GetTime(IssuedAt); err != nil {
+					t.Errorf("got[IssuedAt] not set. expected it set")
+				}
 			}
 
 			cookieVal := w.Header().Get("Set-Cookie")
@@ -124,6 +128,34 @@ func Test_newAuthCookie(t *testing.T) {
 	}
 }
 
+func Test_newPersistentAuthCookie(t *testing.T) {
+	t.Parallel()
+
+	a, err := NewCookieClient(cookieKey)
+	if err != nil {
+		t.Fatalf("NewCookieClient() error = %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	got := a.NewPersistentAuthCookie(w, true, ccc.UUID{}, time.Hour)
+	if got == nil {
+		t.Fatalf("NewPersistentAuthCookie() = nil, want values")
+	}
+	if lifetime, err := got.GetString(Lifetime); err != nil || lifetime != time.Hour.String() {
+		t.Errorf("got[Lifetime] = %q, %v, want %q", lifetime, err, time.Hour.String())
+	}
+	if cookieVal := w.Header().Get("Set-Cookie"); !strings.Contains(cookieVal, "; Expires=") {
+		t.Errorf("Set-Cookie = %q, want persistent cookie with Expires", cookieVal)
+	}
+
+	// Rewriting the cookie keeps it persistent
+	w = httptest.NewRecorder()
+	a.WriteAuthCookie(w, true, got)
+	if cookieVal := w.Header().Get("Set-Cookie"); !strings.Contains(cookieVal, "; Expires=") {
+		t.Errorf("WriteAuthCookie() Set-Cookie = %q, want persistent cookie with Expires", cookieVal)
+	}
+}
+
 func Test_readAuthCookie(t *testing.T) {
 	t.Parallel()
 
@@ -187,6 +219,99 @@ func Test_readAuthCookie(t *testing.T) {
 	}
 }
 
+func Test_readAuthCookie_retiredKeys(t *testing.T) {
+	t.Parallel()
+
+	const newCookieKey = "2ZAaCMljTx8OQHM8MUKn2qmJY/+9aNvaKc9HAlK75mG4ny7mwH//nfyUPHQKgMejsh8fln8d9/nSa0RhZJ9kdg=="
+
+	tests := []struct {
+		name           string
+		cookieKey      string
+		retiredKeys    []string
+		wantFound      bool
+		wantRetiredKey bool
+		wantErr        bool
+	}{
+		{
+			name:      "primary key",
+			cookieKey: cookieKey,
+			wantFound: true,
+		},
+		{
+			name:           "retired key",
+			cookieKey:      newCookieKey,
+			retiredKeys:    []string{"Jpk9/2TbLBWd/7TnZH5Me9DSyvQMr8N4BAULsOBMiAzQvWpUxL5aYeeEtqUtiFQP", cookieKey},
+			wantFound:      true,
+			wantRetiredKey: true,
+		},
+		{
+			name:      "unknown key",
+			cookieKey: newCookieKey,
+		},
+		{
+			name:        "empty retired key",
+			cookieKey:   newCookieKey,
+			retiredKeys: []string{""},
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			oldClient, err := NewCookieClient(cookieKey)
+			if err != nil {
+				t.Fatalf("NewCookieClient() error = %v", err)
+			}
+			sessionID := ccc.Must(ccc.NewUUID())
+			w := httptest.NewRecorder()
+			oldClient.NewAuthCookie(w, true, sessionID)
+			oldClient.CreateXSRFTokenCookie(w, sessionID)
+			r := &http.Request{Header: http.Header{"Cookie": w.Header().Values("Set-Cookie")}}
+
+			c, err := NewCookieClient(tt.cookieKey, WithRetiredKeys(tt.retiredKeys...))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewCookieClient() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			cval, found, err := c.ReadAuthCookie(r)
+			if err != nil {
+				t.Fatalf("ReadAuthCookie() error = %v", err)
+			}
+			if found != tt.wantFound {
+				t.Fatalf("ReadAuthCookie() found = %v, want %v", found, tt.wantFound)
+			}
+			if !found {
+				return
+			}
+			if got := cval.RetiredKey(); got != tt.wantRetiredKey {
+				t.Errorf("Values.RetiredKey() = %v, want %v", got, tt.wantRetiredKey)
+			}
+			if got, _ := cval.GetString(SessionID); got != sessionID.String() {
+				t.Errorf("Values.GetString() = %v, want %v", got, sessionID)
+			}
+
+			// A cookie encrypted with a retired key is encrypted with the primary key when written again
+			w = httptest.NewRecorder()
+			c.WriteAuthCookie(w, true, cval)
+			if set, err := c.RefreshXSRFTokenCookie(w, r, sessionID); err != nil || set != tt.wantRetiredKey {
+				t.Errorf("RefreshXSRFTokenCookie() = %v, %v, want %v, nil", set, err, tt.wantRetiredKey)
+			}
+			r = &http.Request{Header: http.Header{"Cookie": w.Header().Values("Set-Cookie")}}
+			primaryClient, err := NewCookieClient(tt.cookieKey)
+			if err != nil {
+				t.Fatalf("NewCookieClient() error = %v", err)
+			}
+			if _, found, err := primaryClient.ReadAuthCookie(r); err != nil || !found {
+				t.Errorf("ReadAuthCookie() = %v, %v after write, want true, nil with the primary key only", found, err)
+			}
+		})
+	}
+}
+
 func Test_writeAuthCookie(t *testing.T) {
 	t.Parallel()
 	tests := []struct {
@@ -553,3 +678,134 @@ func Test_validate_TokenHeader_to_TokenCookie(t *testing.T) {
 		})
 	}
 }
+
+func Test_cookiePolicy(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name             string
+		opts             []Option
+		want             []string
+		notWant          []string
+		wantOIDCLifetime time.Duration
+		wantErr          bool
+	}{
+		{
+			name: "default",
+			want: []string{
+				"auth=", "XSRF-TOKEN=", "OIDC=", "Path=/;", "SameSite=Strict",
+			},
+			notWant:          []string{"Domain=", "Partitioned"},
+			wantOIDCLifetime: OIDCCookieExpiration,
+		},
+		{
+			name: "domain, path and same site",
+			opts: []Option{WithCookieDomain("example.com"), WithCookiePath("/app"), WithCookieSameSite(http.SameSiteLaxMode)},
+			want: []string{
+				"auth=", "XSRF-TOKEN=", "OIDC=", "Domain=example.com", "Path=/app", "SameSite=Lax",
+			},
+			notWant: []string{"SameSite=Strict", "Path=/;"},
+		},
+		{
+			name: "host prefix, partitioned and OIDC cookie",
+			opts: []Option{
+				WithCookiePrefix(HostPrefix), WithCookiePartitioned(true),
+				WithOIDCCookieName("login"), WithOIDCCookieSameSite(http.SameSiteNoneMode), WithOIDCCookieLifetime(time.Minute),
+			},
+			want: []string{
+				"__Host-auth=", "__Host-XSRF-TOKEN=", "__Host-login=", "Partitioned", "SameSite=None",
+			},
+			notWant:          []string{" auth=", "OIDC="},
+			wantOIDCLifetime: time.Minute,
+		},
+		{
+			name: "secure prefix with domain",
+			opts: []Option{WithCookiePrefix(SecurePrefix), WithCookieDomain("example.com")},
+			want: []string{"__Secure-auth=", "__Secure-XSRF-TOKEN=", "__Secure-OIDC=", "Domain=example.com"},
+		},
+		{
+			name:    "host prefix with domain",
+			opts:    []Option{WithCookiePrefix(HostPrefix), WithCookieDomain("example.com")},
+			wantErr: true,
+		},
+		{
+			name:    "host prefix with path",
+			opts:    []Option{WithCookiePrefix(HostPrefix), WithCookiePath("/app")},
+			wantErr: true,
+		},
+		{
+			name:    "invalid prefix",
+			opts:    []Option{WithCookiePrefix("__Other-")},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c, err := NewCookieClient(cookieKey, tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewCookieClient() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			w := httptest.NewRecorder()
+			c.NewAuthCookie(w, true, ccc.Must(ccc.NewUUID()))
+			c.CreateXSRFTokenCookie(w, ccc.Must(ccc.NewUUID()))
+			c.WriteOidcCookie(w, cookie.NewValues())
+			c.DeleteOidcCookie(w)
+			c.Cookie().Delete(w, c.AuthCookieName())
+
+			setCookies := w.Header().Values("Set-Cookie")
+			if len(setCookies) != 5 {
+				t.Fatalf("Set-Cookie headers = %d, want 5", len(setCookies))
+			}
+			if tt.wantOIDCLifetime > 0 {
+				oidcCookie, err := http.ParseSetCookie(setCookies[2])
+				if err != nil {
+					t.Fatalf("http.ParseSetCookie() error = %v", err)
+				}
+				if got := time.Until(oidcCookie.Expires); got > tt.wantOIDCLifetime || got < tt.wantOIDCLifetime-time.Minute {
+					t.Errorf("OIDC cookie expires in %v, want %v", got, tt.wantOIDCLifetime)
+				}
+			}
+			// Every attribute of the OIDC cookie is repeated when it is deleted, so that browsers delete it
+			if !cmp.Equal(cookieAttributes(setCookies[2]), cookieAttributes(setCookies[3])) {
+				t.Errorf("deleted OIDC cookie %q does not match the written cookie %q", setCookies[3], setCookies[2])
+			}
+			if !cmp.Equal(cookieAttributes(setCookies[0]), cookieAttributes(setCookies[4])) {
+				t.Errorf("deleted Auth cookie %q does not match the written cookie %q", setCookies[4], setCookies[0])
+			}
+
+			all := " " + strings.Join(setCookies, "\n ")
+			for _, want := range tt.want {
+				if !strings.Contains(all, want) {
+					t.Errorf("Set-Cookie headers do not contain %q:\n%s", want, all)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(all, notWant) {
+					t.Errorf("Set-Cookie headers contain %q:\n%s", notWant, all)
+				}
+			}
+		})
+	}
+}
+
+// cookieAttributes returns the name and attributes of a Set-Cookie header that do not depend on its value or expiration
+func cookieAttributes(setCookie string) []string {
+	var attrs []string
+	for i, attr := range strings.Split(setCookie, "; ") {
+		if i == 0 {
+			attr, _, _ = strings.Cut(attr, "=")
+		}
+		if strings.HasPrefix(attr, "Expires=") || strings.HasPrefix(attr, "Max-Age=") {
+			continue
+		}
+		attrs = append(attrs, attr)
+	}
+
+	return attrs
+}
@@ -1,10 +1,56 @@
 package cookie
 
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cccteam/session/cookie"
+	"github.com/go-playground/errors/v5"
+)
+
 type cookieOptions struct {
-	CookieName     string
-	XSRFCookieName string
-	XSRFHeaderName string
-	Domain         string
+	CookieName         string
+	XSRFCookieName     string
+	XSRFHeaderName     string
+	OIDCCookieName     string
+	OIDCCookieLifetime time.Duration
+	Domain             string
+	Path               string
+	Prefix             Prefix
+	Partitioned        bool
+	SameSite           http.SameSite
+	OIDCSameSite       http.SameSite
+	RetiredKeys        []string
+}
+
+// applyPrefix validates the cookie policy for the prefix and adds it to the cookie names.
+// Browsers only accept prefixed cookies that are Secure, so the prefix is not added when
+// cookies are not Secure (insecurecookie build tag).
+func (c *cookieOptions) applyPrefix() error {
+	switch c.Prefix {
+	case "":
+		return nil
+	case HostPrefix:
+		if c.Domain != "" || (c.Path != "" && c.Path != "/") {
+			return errors.Newf("cookie prefix %s requires an empty domain and the path /", c.Prefix)
+		}
+	case SecurePrefix:
+	default:
+		return errors.Newf("invalid cookie prefix %q", c.Prefix)
+	}
+
+	if !cookie.SecureCookie() {
+		return nil
+	}
+
+	for _, name := range []*string{&c.CookieName, &c.XSRFCookieName, &c.OIDCCookieName} {
+		if !strings.HasPrefix(*name, string(c.Prefix)) {
+			*name = string(c.Prefix) + *name
+		}
+	}
+
+	return nil
 }
 
 // Option defines a function signature for setting cookie client options.
@@ -37,3 +83,60 @@ func WithXSRFHeaderName(name string) Option {
 		c.XSRFHeaderName = name
 	})
 }
+
+// WithRetiredKeys sets the keys previously used to encrypt cookies. Cookies encrypted with
+// a retired key are still accepted and are encrypted with the current key when written again.
+func WithRetiredKeys(keysBase64 ...string) Option {
+	return Option(func(c *cookieOptions) {
+		c.RetiredKeys = keysBase64
+	})
+}
+
+// WithCookiePath sets the path of the cookies. (default: /)
+func WithCookiePath(path string) Option {
+	return Option(func(c *cookieOptions) {
+		c.Path = path
+	})
+}
+
+// WithCookiePrefix sets the prefix added to the names of the cookies.
+func WithCookiePrefix(prefix Prefix) Option {
+	return Option(func(c *cookieOptions) {
+		c.Prefix = prefix
+	})
+}
+
+// WithCookiePartitioned sets the Partitioned attribute of the cookies.
+func WithCookiePartitioned(partitioned bool) Option {
+	return Option(func(c *cookieOptions) {
+		c.Partitioned = partitioned
+	})
+}
+
+// WithCookieSameSite sets the SameSite attribute of the session and XSRF cookies.
+func WithCookieSameSite(sameSite http.SameSite) Option {
+	return Option(func(c *cookieOptions) {
+		c.SameSite = sameSite
+	})
+}
+
+// WithOIDCCookieName sets the cookie name for the OIDC cookie.
+func WithOIDCCookieName(name string) Option {
+	return Option(func(c *cookieOptions) {
+		c.OIDCCookieName = name
+	})
+}
+
+// WithOIDCCookieLifetime sets how long the OIDC cookie is valid for.
+func WithOIDCCookieLifetime(d time.Duration) Option {
+	return Option(func(c *cookieOptions) {
+		c.OIDCCookieLifetime = d
+	})
+}
+
+// WithOIDCCookieSameSite sets the SameSite attribute of the OIDC cookie.
+func WithOIDCCookieSameSite(sameSite http.SameSite) Option {
+	return Option(func(c *cookieOptions) {
+		c.OIDCSameSite = sameSite
+	})
+}
@@ -15,6 +15,15 @@ const (
 	// SameSiteStrict is the key used to store the sameSiteStrict cookie setting
 	SameSiteStrict cookie.Key = "sameSiteStrict"
 
+	// IssuedAt is the key used to store when the SessionID in the Auth Cookie was issued
+	IssuedAt cookie.Key = "issuedAt"
+
+	// Session is the key used to store the session of a stateless session store
+	Session cookie.Key = "session"
+
+	// Lifetime is the key used to store how long a persistent Auth Cookie lives after it is written
+	Lifetime cookie.Key = "lifetime"
+
 	// OIDCState is the key used to store the state
 	OIDCState cookie.Key = "state"
 
@@ -42,6 +51,17 @@ const (
 	OIDCCookieExpiration = 10 * time.Minute
 )
 
+// Prefix is a cookie name prefix that browsers enforce requirements for
+type Prefix string
+
+const (
+	// HostPrefix requires the cookie to be Secure, without a Domain and with the Path "/"
+	HostPrefix Prefix = "__Host-"
+
+	// SecurePrefix requires the cookie to be Secure
+	SecurePrefix Prefix = "__Secure-"
+)
+
 // SafeMethods are Idempotent methods as defined by RFC7231 section 4.2.2.
 var SafeMethods = methods([]string{"GET", "HEAD", "OPTIONS", "TRACE"})
 
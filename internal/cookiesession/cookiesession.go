@@ -0,0 +1,121 @@
+// Package cookiesession holds the session of a request for the stateless session store, which keeps
+// sessions in the Auth Cookie instead of a database.
+package cookiesession
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"sync"
+
+	"github.com/cccteam/ccc"
+	"github.com/cccteam/session/internal/dbtype"
+	"github.com/go-playground/errors/v5"
+)
+
+type ctxKey struct{}
+
+// State is the session and its data kept in the Auth Cookie
+type State struct {
+	Session *dbtype.Session            `json:"session"`
+	Data    map[string]json.RawMessage `json:"data,omitempty"`
+}
+
+// Session is the session of a request. It is read from the Auth Cookie when the request starts
+// and written back to it before the response when it changed.
+type Session struct {
+	mu           sync.Mutex
+	state        State
+	changed      bool
+	beforeEncode func()
+}
+
+// NewContext returns a context holding the session encoded in the Auth Cookie. An empty encoded
+// value starts a request without a session. The returned context holds an empty session when
+// encoded can not be decoded.
+func NewContext(ctx context.Context, encoded string) (context.Context, *Session, error) {
+	s := &Session{}
+	ctx = context.WithValue(ctx, ctxKey{}, s)
+
+	if encoded == "" {
+		return ctx, s, nil
+	}
+
+	var state State
+	if err := json.Unmarshal([]byte(encoded), &state); err != nil {
+		return ctx, s, errors.Wrap(err, "json.Unmarshal()")
+	}
+	s.state = state
+
+	return ctx, s, nil
+}
+
+// FromCtx returns the session of the request stored in ctx by NewContext
+func FromCtx(ctx context.Context) (*Session, bool) {
+	s, ok := ctx.Value(ctxKey{}).(*Session)
+
+	return s, ok
+}
+
+// View calls view with a copy of the state of the session. State.Session is nil when the request has no session.
+func (s *Session) View(view func(state State) error) error {
+	s.mu.Lock()
+	state := State{Data: maps.Clone(s.state.Data)}
+	if s.state.Session != nil {
+		session := *s.state.Session
+		state.Session = &session
+	}
+	s.mu.Unlock()
+
+	return view(state)
+}
+
+// Update calls update with the state of the session, which it can change. The session is written
+// to the Auth Cookie when update reports a change.
+func (s *Session) Update(update func(state *State) (changed bool, err error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changed, err := update(&s.state)
+	if changed {
+		s.changed = true
+	}
+
+	return err
+}
+
+// BeforeEncode sets a function called by Encode before the session is encoded, to apply changes
+// that are pending until the end of the request, e.g. to the session data.
+func (s *Session) BeforeEncode(f func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.beforeEncode = f
+}
+
+// Encode returns the ID of the session and the session encoded for the Auth Cookie when it changed during the request.
+func (s *Session) Encode() (sessionID ccc.UUID, encoded string, changed bool, err error) {
+	s.mu.Lock()
+	beforeEncode := s.beforeEncode
+	s.beforeEncode = nil
+	s.mu.Unlock()
+
+	if beforeEncode != nil {
+		beforeEncode()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.changed || s.state.Session == nil {
+		return ccc.NilUUID, "", false, nil
+	}
+
+	b, err := json.Marshal(s.state)
+	if err != nil {
+		return ccc.NilUUID, "", false, errors.Wrap(err, "json.Marshal()")
+	}
+	s.changed = false
+
+	return s.state.Session.ID, string(b), true, nil
+}
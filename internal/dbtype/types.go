@@ -2,10 +2,14 @@
 package dbtype
 
 import (
+	"encoding/json"
+	"strings"
 	"time"
 
 	"github.com/cccteam/ccc"
 	"github.com/cccteam/ccc/securehash"
+	"github.com/cccteam/httpio"
+	"github.com/cccteam/session/sessioninfo"
 )
 
 // Session defines the structure for storing session data in the database.
@@ -15,6 +19,64 @@ type Session struct {
 	CreatedAt time.Time `spanner:"CreatedAt" db:"CreatedAt"`
 	UpdatedAt time.Time `spanner:"UpdatedAt" db:"UpdatedAt"`
 	Expired   bool      `spanner:"Expired"   db:"Expired"`
+	// IdleTimeoutSeconds overrides the configured session timeout when it is not zero.
+	IdleTimeoutSeconds int64 `spanner:"IdleTimeoutSeconds" db:"IdleTimeoutSeconds"`
+	// ClientIP, UserAgent and AuthMethod record where the session was created from and how the user authenticated.
+	ClientIP   string `spanner:"ClientIp"   db:"ClientIp"`
+	UserAgent  string `spanner:"UserAgent"  db:"UserAgent"`
+	AuthMethod string `spanner:"AuthMethod" db:"AuthMethod"`
+	// ImpersonatorUsername and ImpersonatorSessionID record the user, and their session, that started
+	// an impersonated session. They are empty for other sessions.
+	ImpersonatorUsername  string `spanner:"ImpersonatorUsername"  db:"ImpersonatorUsername"`
+	ImpersonatorSessionID string `spanner:"ImpersonatorSessionId" db:"ImpersonatorSessionId"`
+	// LastAuthenticatedAt is when the user last authenticated in the session. It is before CreatedAt
+	// for sessions created before it was recorded, whose login time is used instead, and for
+	// impersonated sessions, in which the user never authenticated.
+	LastAuthenticatedAt time.Time `spanner:"LastAuthenticatedAt" db:"LastAuthenticatedAt"`
+}
+
+// IdleTimeout returns the idle timeout of the session, or zero when it uses the configured session timeout.
+func (s *Session) IdleTimeout() time.Duration {
+	return time.Duration(s.IdleTimeoutSeconds) * time.Second
+}
+
+// Idle reports whether the session was last updated before idleBefore and, when it has its
+// own idle timeout, that timeout has also passed at now.
+func (s *Session) Idle(idleBefore, now time.Time) bool {
+	if idleBefore.IsZero() || !s.UpdatedAt.Before(idleBefore) {
+		return false
+	}
+
+	return s.IdleTimeoutSeconds == 0 || s.UpdatedAt.Add(s.IdleTimeout()).Before(now)
+}
+
+// SessionInfo converts the session to a sessioninfo.SessionInfo.
+func (s *Session) SessionInfo() *sessioninfo.SessionInfo {
+	var impersonator *sessioninfo.Impersonator
+	if s.ImpersonatorUsername != "" {
+		// An unparsable ID leaves the nil UUID, whose session can not be resumed
+		sessionID, _ := ccc.UUIDFromString(s.ImpersonatorSessionID)
+		impersonator = &sessioninfo.Impersonator{Username: s.ImpersonatorUsername, SessionID: sessionID}
+	}
+
+	lastAuthenticatedAt := s.LastAuthenticatedAt
+	if lastAuthenticatedAt.Before(s.CreatedAt) && s.ImpersonatorUsername == "" {
+		lastAuthenticatedAt = s.CreatedAt
+	}
+
+	return &sessioninfo.SessionInfo{
+		ID:                  s.ID,
+		Username:            s.Username,
+		CreatedAt:           s.CreatedAt,
+		UpdatedAt:           s.UpdatedAt,
+		Expired:             s.Expired,
+		IdleTimeout:         s.IdleTimeout(),
+		ClientIP:            s.ClientIP,
+		UserAgent:           s.UserAgent,
+		AuthMethod:          sessioninfo.AuthMethod(s.AuthMethod),
+		Impersonator:        impersonator,
+		LastAuthenticatedAt: lastAuthenticatedAt,
+	}
 }
 
 // InsertSession defines the structure for inserting new session data into the database.
@@ -23,6 +85,54 @@ type InsertSession struct {
 	CreatedAt time.Time `spanner:"CreatedAt"`
 	UpdatedAt time.Time `spanner:"UpdatedAt"`
 	Expired   bool      `spanner:"Expired"`
+	// IdleTimeoutSeconds overrides the configured session timeout when it is not zero.
+	IdleTimeoutSeconds int64 `spanner:"IdleTimeoutSeconds"`
+	// ClientIP, UserAgent and AuthMethod record where the session was created from and how the user authenticated.
+	ClientIP   string `spanner:"ClientIp"`
+	UserAgent  string `spanner:"UserAgent"`
+	AuthMethod string `spanner:"AuthMethod"`
+	// ImpersonatorUsername and ImpersonatorSessionID record the user, and their session, that started
+	// an impersonated session. They are empty for other sessions.
+	ImpersonatorUsername  string `spanner:"ImpersonatorUsername"`
+	ImpersonatorSessionID string `spanner:"ImpersonatorSessionId"`
+	// LastAuthenticatedAt is when the user last authenticated in the session.
+	LastAuthenticatedAt time.Time `spanner:"LastAuthenticatedAt"`
+	// Limit, when set, limits the number of active sessions of the user, including the new session.
+	Limit *InsertLimit `spanner:"-"`
+}
+
+// InsertLimit limits the number of active sessions of a user when a session is inserted. Sessions
+// that are expired, are idle (see Session.Idle) given IdleBefore and Now, or were created before
+// CreatedBefore are not active. A zero time disables the corresponding check.
+type InsertLimit struct {
+	MaxSessions int
+	// Reject fails the insert when the user already has MaxSessions active sessions,
+	// instead of expiring the oldest of them.
+	Reject        bool
+	IdleBefore    time.Time
+	CreatedBefore time.Time
+	Now           time.Time
+}
+
+// Active reports whether session counts towards the limit.
+func (l *InsertLimit) Active(session *Session) bool {
+	return !session.Expired &&
+		!session.Idle(l.IdleBefore, l.Now) &&
+		(l.CreatedBefore.IsZero() || !session.CreatedAt.Before(l.CreatedBefore))
+}
+
+// Evict returns the sessions to expire so that a new session fits in the limit, given the active
+// sessions of the user oldest first. It returns an error when the limit rejects the new session.
+func (l *InsertLimit) Evict(active []ccc.UUID) ([]ccc.UUID, error) {
+	excess := len(active) - l.MaxSessions + 1
+	if excess <= 0 {
+		return nil, nil
+	}
+	if l.Reject {
+		return nil, httpio.NewConflictMessagef("the maximum of %d active sessions has been reached", l.MaxSessions)
+	}
+
+	return active[:excess], nil
 }
 
 // InsertOIDCSession defines the structure for inserting new OIDC session data into the database.
@@ -31,6 +141,23 @@ type InsertOIDCSession struct {
 	InsertSession
 }
 
+// SessionActivity is the time of the last request made with a session.
+type SessionActivity struct {
+	ID        ccc.UUID
+	UpdatedAt time.Time
+}
+
+// SessionDataTableSuffix is appended to the session table name to name the table holding the session data.
+const SessionDataTableSuffix = "Data"
+
+// SessionDataUpdate is a change to the data stored with a session.
+// Keys in Set are written with their JSON value and keys in Deleted are removed.
+type SessionDataUpdate struct {
+	Set       map[string]json.RawMessage
+	Deleted   []string
+	UpdatedAt time.Time
+}
+
 // SessionUser is a person authorized to access the application
 type SessionUser struct {
 	ID           ccc.UUID         `spanner:"Id"           db:"Id"`
@@ -45,3 +172,144 @@ type InsertSessionUser struct {
 	PasswordHash *securehash.Hash `spanner:"PasswordHash" db:"PasswordHash"`
 	Disabled     bool             `spanner:"Disabled"     db:"Disabled"`
 }
+
+// PurgeSessions defines the criteria for deleting session rows from the database.
+// A row is deleted when it is expired, is idle (see Session.Idle) given IdleBefore and Now,
+// or was created before CreatedBefore. A zero time disables the corresponding check.
+type PurgeSessions struct {
+	IdleBefore    time.Time
+	CreatedBefore time.Time
+	Now           time.Time
+	// Limit is the maximum number of rows deleted by a single call.
+	Limit int
+}
+
+// UserSessions defines the criteria for listing a user's sessions from the database.
+// Sessions are returned newest first, ordered by CreatedAt and then Id.
+type UserSessions struct {
+	Username string
+	// Active includes sessions that are not expired and, when IdleBefore is set, are not idle (see Session.Idle).
+	Active bool
+	// Expired includes sessions that are expired or, when IdleBefore is set, are idle given IdleBefore and Now.
+	Expired    bool
+	IdleBefore time.Time
+	Now        time.Time
+	// After continues a listing with the sessions that sort after it. Nil starts with the newest session.
+	After *SessionCursor
+	// Limit is the maximum number of rows returned by a single call.
+	Limit int
+}
+
+// SessionCursor identifies the position of a session in a UserSessions listing.
+type SessionCursor struct {
+	CreatedAt time.Time
+	ID        ccc.UUID
+}
+
+// Matches reports whether session belongs in the listing described by q. It is used by
+// drivers that filter sessions in process rather than in a query.
+func (q *UserSessions) Matches(session *Session) bool {
+	if session.Username != q.Username {
+		return false
+	}
+
+	expired := session.Expired || session.Idle(q.IdleBefore, q.Now)
+	if (expired && !q.Expired) || (!expired && !q.Active) {
+		return false
+	}
+
+	return q.After == nil || CompareSessions(session, &Session{ID: q.After.ID, CreatedAt: q.After.CreatedAt}) > 0
+}
+
+// CompareSessions orders sessions the way UserSessions lists them, newest first.
+// It returns a negative number when a sorts before b.
+func CompareSessions(a, b *Session) int {
+	if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+		return c
+	}
+
+	return strings.Compare(b.ID.String(), a.ID.String())
+}
+
+// SessionState selects sessions by state in UserSessions.
+type SessionState int
+
+const (
+	// SessionStateAll selects active and expired sessions.
+	SessionStateAll SessionState = iota
+	// SessionStateActive selects sessions that can still be used.
+	SessionStateActive
+	// SessionStateExpired selects sessions that were destroyed or timed out.
+	SessionStateExpired
+)
+
+// SessionFilter selects the sessions returned by UserSessions.
+type SessionFilter struct {
+	State SessionState
+	// IdleTimeout treats sessions without activity for longer than this duration as expired.
+	// Sessions with a longer idle timeout of their own are expired once it has passed.
+	// Zero relies on the Expired flag alone.
+	IdleTimeout time.Duration
+}
+
+// SessionLimitPolicy decides what happens when a user who already has the maximum number of
+// active sessions logs in.
+type SessionLimitPolicy int
+
+const (
+	// EvictOldestSession expires the oldest active sessions of the user to make room for the new session.
+	EvictOldestSession SessionLimitPolicy = iota
+	// RejectNewSession fails the new session with a Conflict error and keeps the existing sessions.
+	RejectNewSession
+)
+
+// SessionLimit limits the number of active sessions per user.
+type SessionLimit struct {
+	// MaxSessions is the maximum number of active sessions of a user. Zero disables the limit.
+	MaxSessions int
+	// Policy decides what happens when the limit is reached.
+	Policy SessionLimitPolicy
+	// IdleTimeout treats sessions without activity for longer than this duration as inactive.
+	// Sessions with a longer idle timeout of their own are inactive once it has passed.
+	// Zero relies on the Expired flag alone.
+	IdleTimeout time.Duration
+	// MaxAge treats sessions created longer ago than this duration as inactive. Zero disables the check.
+	MaxAge time.Duration
+}
+
+// InsertLimit returns the limit for a session inserted at now, or nil when there is no limit.
+func (l SessionLimit) InsertLimit(now time.Time) *InsertLimit {
+	if l.MaxSessions <= 0 {
+		return nil
+	}
+
+	limit := &InsertLimit{
+		MaxSessions: l.MaxSessions,
+		Reject:      l.Policy == RejectNewSession,
+		Now:         now,
+	}
+	if l.IdleTimeout > 0 {
+		limit.IdleBefore = now.Add(-l.IdleTimeout)
+	}
+	if l.MaxAge > 0 {
+		limit.CreatedBefore = now.Add(-l.MaxAge)
+	}
+
+	return limit
+}
+
+// Page requests one page of a listing.
+type Page struct {
+	// Cursor is the NextCursor of the previous page, or empty for the first page.
+	Cursor string
+	// Size is the maximum number of items returned. Defaults to sessionstorage.DefaultPageSize and is
+	// capped at sessionstorage.MaxPageSize.
+	Size int
+}
+
+// SessionPage is one page of sessions, newest first.
+type SessionPage struct {
+	Sessions []*sessioninfo.SessionInfo
+	// NextCursor requests the following page. It is empty on the last page.
+	NextCursor string
+}
@@ -0,0 +1,155 @@
+// Package lru implements a size bounded least recently used cache with expiring entries.
+package lru
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Cache is a least recently used cache that holds at most size entries, each for at most ttl.
+// It is safe for concurrent use.
+type Cache[K comparable, V any] struct {
+	mu    sync.Mutex
+	size  int
+	ttl   time.Duration
+	ll    *list.List
+	items map[K]*list.Element
+	now   func() time.Time
+}
+
+type entry[K comparable, V any] struct {
+	key     K
+	value   V
+	expires time.Time
+}
+
+// New creates a Cache that holds at most size entries, each for at most ttl.
+func New[K comparable, V any](size int, ttl time.Duration) *Cache[K, V] {
+	return &Cache[K, V]{
+		size:  size,
+		ttl:   ttl,
+		ll:    list.New(),
+		items: make(map[K]*list.Element),
+		now:   time.Now,
+	}
+}
+
+// Get returns the value stored for key and marks it as recently used.
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		var zero V
+
+		return zero, false
+	}
+
+	e := entryOf[K, V](el)
+	if !c.now().Before(e.expires) {
+		c.removeElement(el)
+
+		var zero V
+
+		return zero, false
+	}
+	c.ll.MoveToFront(el)
+
+	return e.value, true
+}
+
+// Add stores value for key, evicting the least recently used entry when the cache is full.
+func (c *Cache[K, V]) Add(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires := c.now().Add(c.ttl)
+	if el, ok := c.items[key]; ok {
+		e := entryOf[K, V](el)
+		e.value = value
+		e.expires = expires
+		c.ll.MoveToFront(el)
+
+		return
+	}
+
+	c.items[key] = c.ll.PushFront(&entry[K, V]{key: key, value: value, expires: expires})
+	for c.ll.Len() > c.size {
+		c.removeElement(c.ll.Back())
+	}
+}
+
+// Update replaces the value stored for key with fn(value) without extending its lifetime.
+// It reports whether key was present.
+func (c *Cache[K, V]) Update(key K, fn func(V) V) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return false
+	}
+
+	e := entryOf[K, V](el)
+	e.value = fn(e.value)
+
+	return true
+}
+
+// Remove deletes the entry for key.
+func (c *Cache[K, V]) Remove(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.removeElement(el)
+	}
+}
+
+// RemoveFunc deletes every entry for which fn returns true and returns the number deleted.
+func (c *Cache[K, V]) RemoveFunc(fn func(key K, value V) bool) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var n int
+	for el := c.ll.Front(); el != nil; {
+		next := el.Next()
+		if e := entryOf[K, V](el); fn(e.key, e.value) {
+			c.removeElement(el)
+			n++
+		}
+		el = next
+	}
+
+	return n
+}
+
+// Purge deletes all entries.
+func (c *Cache[K, V]) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ll.Init()
+	clear(c.items)
+}
+
+// Len returns the number of entries in the cache, including expired entries that have not been evicted yet.
+func (c *Cache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.ll.Len()
+}
+
+func (c *Cache[K, V]) removeElement(el *list.Element) {
+	c.ll.Remove(el)
+	delete(c.items, entryOf[K, V](el).key)
+}
+
+func entryOf[K comparable, V any](el *list.Element) *entry[K, V] {
+	e, _ := el.Value.(*entry[K, V])
+
+	return e
+}
@@ -0,0 +1,80 @@
+package lru
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCache_Eviction(t *testing.T) {
+	t.Parallel()
+
+	c := New[string, int](2, time.Minute)
+	c.Add("a", 1)
+	c.Add("b", 2)
+	if _, ok := c.Get("a"); !ok {
+		t.Fatalf("Get(a) ok = false, want true")
+	}
+	c.Add("c", 3)
+
+	if _, ok := c.Get("b"); ok {
+		t.Errorf("Get(b) ok = true, want least recently used entry evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := c.Get(key); !ok {
+			t.Errorf("Get(%s) ok = false, want true", key)
+		}
+	}
+	if got := c.Len(); got != 2 {
+		t.Errorf("Len() = %d, want 2", got)
+	}
+}
+
+func TestCache_Expiry(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	c := New[string, int](10, time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.Add("a", 1)
+	now = now.Add(30 * time.Second)
+	if !c.Update("a", func(v int) int { return v + 1 }) {
+		t.Fatalf("Update(a) = false, want true")
+	}
+	if got, ok := c.Get("a"); !ok || got != 2 {
+		t.Errorf("Get(a) = %d, %v, want 2, true", got, ok)
+	}
+
+	now = now.Add(30 * time.Second)
+	if _, ok := c.Get("a"); ok {
+		t.Errorf("Get(a) ok = true, want entry expired")
+	}
+	if got := c.Len(); got != 0 {
+		t.Errorf("Len() = %d, want 0", got)
+	}
+}
+
+func TestCache_Remove(t *testing.T) {
+	t.Parallel()
+
+	c := New[string, int](10, time.Minute)
+	for i, key := range []string{"a", "b", "c", "d"} {
+		c.Add(key, i)
+	}
+
+	c.Remove("a")
+	if n := c.RemoveFunc(func(_ string, v int) bool { return v%2 == 1 }); n != 2 {
+		t.Errorf("RemoveFunc() = %d, want 2", n)
+	}
+	if _, ok := c.Get("c"); !ok {
+		t.Errorf("Get(c) ok = false, want true")
+	}
+	if got := c.Len(); got != 1 {
+		t.Errorf("Len() = %d, want 1", got)
+	}
+
+	c.Purge()
+	if got := c.Len(); got != 0 {
+		t.Errorf("Len() after Purge() = %d, want 0", got)
+	}
+}
@@ -12,6 +12,7 @@ package mock_basesession
 import (
 	http "net/http"
 	reflect "reflect"
+	time "time"
 
 	gomock "go.uber.org/mock/gomock"
 )
@@ -68,6 +69,62 @@ func (mr *MockHandlersMockRecorder) Logout() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Logout", reflect.TypeOf((*MockHandlers)(nil).Logout))
 }
 
+// RequireRecentAuth mocks base method.
+func (m *MockHandlers) RequireRecentAuth(maxAge time.Duration) func(http.Handler) http.Handler {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RequireRecentAuth", maxAge)
+	ret0, _ := ret[0].(func(http.Handler) http.Handler)
+	return ret0
+}
+
+// RequireRecentAuth indicates an expected call of RequireRecentAuth.
+func (mr *MockHandlersMockRecorder) RequireRecentAuth(maxAge any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RequireRecentAuth", reflect.TypeOf((*MockHandlers)(nil).RequireRecentAuth), maxAge)
+}
+
+// RevokeOtherSessions mocks base method.
+func (m *MockHandlers) RevokeOtherSessions() http.HandlerFunc {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeOtherSessions")
+	ret0, _ := ret[0].(http.HandlerFunc)
+	return ret0
+}
+
+// RevokeOtherSessions indicates an expected call of RevokeOtherSessions.
+func (mr *MockHandlersMockRecorder) RevokeOtherSessions() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeOtherSessions", reflect.TypeOf((*MockHandlers)(nil).RevokeOtherSessions))
+}
+
+// RevokeSession mocks base method.
+func (m *MockHandlers) RevokeSession() http.HandlerFunc {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeSession")
+	ret0, _ := ret[0].(http.HandlerFunc)
+	return ret0
+}
+
+// RevokeSession indicates an expected call of RevokeSession.
+func (mr *MockHandlersMockRecorder) RevokeSession() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeSession", reflect.TypeOf((*MockHandlers)(nil).RevokeSession))
+}
+
+// Sessions mocks base method.
+func (m *MockHandlers) Sessions() http.HandlerFunc {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Sessions")
+	ret0, _ := ret[0].(http.HandlerFunc)
+	return ret0
+}
+
+// Sessions indicates an expected call of Sessions.
+func (mr *MockHandlersMockRecorder) Sessions() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Sessions", reflect.TypeOf((*MockHandlers)(nil).Sessions))
+}
+
 // SetXSRFToken mocks base method.
 func (m *MockHandlers) SetXSRFToken(next http.Handler) http.Handler {
 	m.ctrl.T.Helper()
@@ -12,6 +12,7 @@ package mock_cookie
 import (
 	http "net/http"
 	reflect "reflect"
+	time "time"
 
 	ccc "github.com/cccteam/ccc"
 	cookie "github.com/cccteam/session/cookie"
@@ -42,6 +43,20 @@ func (m *MockHandler) EXPECT() *MockHandlerMockRecorder {
 	return m.recorder
 }
 
+// AuthCookieName mocks base method.
+func (m *MockHandler) AuthCookieName() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AuthCookieName")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// AuthCookieName indicates an expected call of AuthCookieName.
+func (mr *MockHandlerMockRecorder) AuthCookieName() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuthCookieName", reflect.TypeOf((*MockHandler)(nil).AuthCookieName))
+}
+
 // Cookie mocks base method.
 func (m *MockHandler) Cookie() *cookie.Client {
 	m.ctrl.T.Helper()
@@ -97,6 +112,20 @@ func (mr *MockHandlerMockRecorder) NewAuthCookie(w, sameSiteStrict, sessionID an
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewAuthCookie", reflect.TypeOf((*MockHandler)(nil).NewAuthCookie), w, sameSiteStrict, sessionID)
 }
 
+// NewPersistentAuthCookie mocks base method.
+func (m *MockHandler) NewPersistentAuthCookie(w http.ResponseWriter, sameSiteStrict bool, sessionID ccc.UUID, lifetime time.Duration) *cookie.Values {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NewPersistentAuthCookie", w, sameSiteStrict, sessionID, lifetime)
+	ret0, _ := ret[0].(*cookie.Values)
+	return ret0
+}
+
+// NewPersistentAuthCookie indicates an expected call of NewPersistentAuthCookie.
+func (mr *MockHandlerMockRecorder) NewPersistentAuthCookie(w, sameSiteStrict, sessionID, lifetime any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewPersistentAuthCookie", reflect.TypeOf((*MockHandler)(nil).NewPersistentAuthCookie), w, sameSiteStrict, sessionID, lifetime)
+}
+
 // ReadAuthCookie mocks base method.
 func (m *MockHandler) ReadAuthCookie(r *http.Request) (*cookie.Values, bool, error) {
 	m.ctrl.T.Helper()
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/cccteam/ccc"
 	"github.com/cccteam/ccc/accesstypes"
@@ -16,6 +17,7 @@ import (
 	"github.com/cccteam/session/internal/basesession"
 	internalcookie "github.com/cccteam/session/internal/cookie"
 	"github.com/cccteam/session/internal/util"
+	"github.com/cccteam/session/sessioninfo"
 	"github.com/cccteam/session/sessionstorage"
 	"github.com/go-playground/errors/v5"
 )
@@ -38,6 +40,7 @@ type OIDCAzure struct {
 // NewOIDCAzure creates a new OIDCAzure.
 // cookieKey: A Base64-encoded string representing at least 32 bytes
 // of cryptographically secure random data.
+// Keys replaced by cookieKey can be passed with WithRetiredCookieKeys.
 func NewOIDCAzure(
 	storage sessionstorage.OIDCStore, userRoleManager UserRoleManager,
 	cookieKey string,
@@ -46,7 +49,10 @@ func NewOIDCAzure(
 ) (*OIDCAzure, error) {
 	var cookieOpts []internalcookie.Option
 	for _, opt := range options {
-		if o, ok := opt.(CookieOption); ok {
+		switch o := any(opt).(type) {
+		case CookieOption:
+			cookieOpts = append(cookieOpts, internalcookie.Option(o))
+		case oidcCookieOption:
 			cookieOpts = append(cookieOpts, internalcookie.Option(o))
 		}
 	}
@@ -72,6 +78,7 @@ func NewOIDCAzure(
 			o(oidc)
 		}
 	}
+	baseSession.ApplySessionLimit()
 
 	return &OIDCAzure{
 		userRoleManager: userRoleManager,
@@ -154,9 +161,15 @@ func (o *OIDCAzure) CallbackOIDC() http.HandlerFunc {
 		}
 
 		// user is successfully authenticated, start a new session
+		ctx = o.baseSession.ClientContext(ctx, r)
 		sessionID, err := o.startNewSession(ctx, w, claims.Username, oidcSID)
 		if err != nil {
-			http.Redirect(w, r, fmt.Sprintf("%s?message=%s", o.oidc.LoginURL(), url.QueryEscape("Internal Server Error")), http.StatusFound)
+			message := "Internal Server Error"
+			if httpio.HasConflict(err) {
+				// The user has reached the maximum number of active sessions
+				message = httpio.Message(err)
+			}
+			http.Redirect(w, r, fmt.Sprintf("%s?message=%s", o.oidc.LoginURL(), url.QueryEscape(message)), http.StatusFound)
 
 			return errors.Wrap(err, "OIDCAzure.startNewSession()")
 		}
@@ -194,14 +207,47 @@ func (o *OIDCAzure) FrontChannelLogout() http.HandlerFunc {
 			return httpio.NewEncoder(w).BadRequestMessage(ctx, "missing sid query parameter")
 		}
 
+		// The sessions are listed before they are destroyed, and only when the hook is set
+		var sessions []*sessioninfo.SessionInfo
+		if o.baseSession.Hooks.FrontChannelLogout != nil {
+			var err error
+			if sessions, err = o.sessionsOIDC(ctx, sid); err != nil {
+				logger.FromReq(r).Error(errors.Wrap(err, "OIDCAzure.sessionsOIDC()"))
+			}
+		}
+
 		if err := o.storage.DestroySessionOIDC(ctx, sid); err != nil {
 			logger.FromReq(r).Info(errors.Wrap(err, "sessionstorage.OIDCStore.DestroySessionOIDC()"))
+
+			return httpio.NewEncoder(w).Ok(nil)
 		}
 
+		o.baseSession.RunHook(ctx, o.baseSession.Hooks.FrontChannelLogout, sessions...)
+
 		return httpio.NewEncoder(w).Ok(nil)
 	})
 }
 
+// sessionsOIDC returns the active sessions of the user that owns the OIDC sid, which are
+// the sessions a front-channel logout destroys
+func (o *OIDCAzure) sessionsOIDC(ctx context.Context, oidcSID string) ([]*sessioninfo.SessionInfo, error) {
+	username, err := o.storage.UsernameOIDC(ctx, oidcSID)
+	if err != nil {
+		if httpio.HasNotFound(err) {
+			return nil, nil
+		}
+
+		return nil, errors.Wrap(err, "sessionstorage.OIDCStore.UsernameOIDC()")
+	}
+
+	sessions, err := o.baseSession.ActiveUserSessions(ctx, username)
+	if err != nil {
+		return nil, errors.Wrap(err, "basesession.BaseSession.ActiveUserSessions()")
+	}
+
+	return sessions, nil
+}
+
 // assignUserRoles ensures that the user is assigned to the specified roles ONLY
 // returns true if the user has at least one assigned role (after the operation is complete)
 func (o *OIDCAzure) assignUserRoles(ctx context.Context, username accesstypes.User, roles []string) (hasRole bool, err error) {
@@ -251,7 +297,7 @@ func (o *OIDCAzure) assignUserRoles(ctx context.Context, username accesstypes.Us
 // startNewSession starts a new session for the given username and returns the session ID
 func (o *OIDCAzure) startNewSession(ctx context.Context, w http.ResponseWriter, username, oidcSID string) (ccc.UUID, error) {
 	// Create new Session in database
-	id, err := o.storage.NewSession(ctx, username, oidcSID)
+	id, err := o.storage.NewSession(basesession.WithAuthMethod(ctx, sessioninfo.AuthMethodOIDC), username, oidcSID)
 	if err != nil {
 		return ccc.NilUUID, errors.Wrap(err, "sessionstorage.OIDCStore.NewSession()")
 	}
@@ -261,9 +307,33 @@ func (o *OIDCAzure) startNewSession(ctx context.Context, w http.ResponseWriter,
 	// write new XSRF Token Cookie to match the new SessionID
 	o.baseSession.CookieHandler.CreateXSRFTokenCookie(w, id)
 
+	o.baseSession.SessionCreated(ctx, id)
+
 	return id, nil
 }
 
+// Sessions is the handler that lists the active sessions of the current user
+func (o *OIDCAzure) Sessions() http.HandlerFunc {
+	return o.baseSession.Sessions()
+}
+
+// RevokeSession is the handler that destroys one session of the current user, given by the RouterSessionID path parameter
+func (o *OIDCAzure) RevokeSession() http.HandlerFunc {
+	return o.baseSession.RevokeSession()
+}
+
+// RevokeOtherSessions is the handler that destroys every session of the current user except the current session
+func (o *OIDCAzure) RevokeOtherSessions() http.HandlerFunc {
+	return o.baseSession.RevokeOtherSessions()
+}
+
+// RequireRecentAuth returns a middleware that rejects the request with a ReauthenticationRequired Unauthorized
+// error when the user of the session last authenticated more than maxAge ago.
+// ValidateSession handler must be called before calling RequireRecentAuth
+func (o *OIDCAzure) RequireRecentAuth(maxAge time.Duration) func(next http.Handler) http.Handler {
+	return o.baseSession.RequireRecentAuth(maxAge)
+}
+
 // API provides programatic access to OIDCAzure
 func (o *OIDCAzure) API() *OIDCAzureAPI {
 	return newOIDCAzureAPI(o)
@@ -290,6 +360,31 @@ func (p *OIDCAzureAPI) ValidateSession(ctx context.Context) (context.Context, er
 	return ctx, nil
 }
 
+// RotateSession moves the current session to a new session ID, keeping its data, and writes
+// new Auth and XSRF Token cookies for it. Call it after a change in privilege, such as a login,
+// to defend against session fixation. The returned context holds the new session ID.
+func (p *OIDCAzureAPI) RotateSession(ctx context.Context, w http.ResponseWriter) (context.Context, error) {
+	ctx, err := p.oidc.baseSession.RotateSessionAPI(ctx, w)
+	if err != nil {
+		return ctx, errors.Wrap(err, "basesession.BaseSession.RotateSessionAPI()")
+	}
+
+	return ctx, nil
+}
+
+// SessionAssertion returns a PASETO v4.public assertion of the current session that expires after lifetime,
+// signed by the signer set with WithAssertionSigner. Other services verify it with cookie.Verifier for
+// cookie.SessionAssertionPurpose, and read the username from its subject and the session ID from
+// cookie.AssertionSessionID. ValidateSession must be called first.
+func (p *OIDCAzureAPI) SessionAssertion(ctx context.Context, lifetime time.Duration) (string, error) {
+	assertion, err := p.oidc.baseSession.SessionAssertion(ctx, lifetime)
+	if err != nil {
+		return "", errors.Wrap(err, "basesession.BaseSession.SessionAssertion()")
+	}
+
+	return assertion, nil
+}
+
 // Cookie returns the underlying cookie.Client
 func (p *OIDCAzureAPI) Cookie() *cookie.Client {
 	return p.oidc.baseSession.CookieHandler.Cookie()
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"slices"
 	"testing"
 	"time"
 
@@ -21,6 +22,7 @@ import (
 	"github.com/cccteam/session/mock/mock_cookie"
 	"github.com/cccteam/session/mock/mock_session"
 	"github.com/cccteam/session/sessioninfo"
+	"github.com/cccteam/session/sessionstorage"
 	"github.com/cccteam/session/sessionstorage/mock/mock_sessionstorage"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/errors/v5"
@@ -336,11 +338,20 @@ func TestOIDCAzure_CallbackOIDC(t *testing.T) {
 
 func TestOIDCAzure_FrontChannelLogout(t *testing.T) {
 	t.Parallel()
+
+	sessions := []*sessioninfo.SessionInfo{
+		{ID: ccc.Must(ccc.NewUUID()), Username: "testUser", CreatedAt: time.Now()},
+		{ID: ccc.Must(ccc.NewUUID()), Username: "testUser", CreatedAt: time.Now()},
+	}
+	filter := sessionstorage.SessionFilter{State: sessionstorage.SessionStateActive, IdleTimeout: time.Minute}
+
 	tests := []struct {
 		name           string
 		reqURL         string
+		withHook       bool
 		prepare        func(*mock_sessionstorage.MockOIDCStore)
 		expectedStatus int
+		wantHookIDs    []ccc.UUID
 	}{
 		{
 			name:           "fails to get sid from request",
@@ -363,6 +374,39 @@ func TestOIDCAzure_FrontChannelLogout(t *testing.T) {
 			},
 			expectedStatus: http.StatusOK,
 		},
+		{
+			name:     "success logging out, calls the hook for the destroyed sessions",
+			reqURL:   "/testPath?sid=testSID",
+			withHook: true,
+			prepare: func(storage *mock_sessionstorage.MockOIDCStore) {
+				storage.EXPECT().UsernameOIDC(gomock.Any(), "testSID").Return("testUser", nil).Times(1)
+				storage.EXPECT().UserSessions(gomock.Any(), "testUser", filter, sessionstorage.Page{}).Return(&sessionstorage.SessionPage{Sessions: sessions}, nil).Times(1)
+				storage.EXPECT().DestroySessionOIDC(gomock.Any(), "testSID").Return(nil).Times(1)
+			},
+			expectedStatus: http.StatusOK,
+			wantHookIDs:    []ccc.UUID{sessions[0].ID, sessions[1].ID},
+		},
+		{
+			name:     "success logging out an unknown sid, skips the hook",
+			reqURL:   "/testPath?sid=testSID",
+			withHook: true,
+			prepare: func(storage *mock_sessionstorage.MockOIDCStore) {
+				storage.EXPECT().UsernameOIDC(gomock.Any(), "testSID").Return("", httpio.NewNotFoundMessage("not found")).Times(1)
+				storage.EXPECT().DestroySessionOIDC(gomock.Any(), "testSID").Return(nil).Times(1)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:     "fails to destroy session in db, skips the hook",
+			reqURL:   "/testPath?sid=testSID",
+			withHook: true,
+			prepare: func(storage *mock_sessionstorage.MockOIDCStore) {
+				storage.EXPECT().UsernameOIDC(gomock.Any(), "testSID").Return("testUser", nil).Times(1)
+				storage.EXPECT().UserSessions(gomock.Any(), "testUser", filter, sessionstorage.Page{}).Return(&sessionstorage.SessionPage{Sessions: sessions}, nil).Times(1)
+				storage.EXPECT().DestroySessionOIDC(gomock.Any(), "testSID").Return(errors.New("failed to destroy session in db")).Times(1)
+			},
+			expectedStatus: http.StatusOK,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -390,6 +434,13 @@ func TestOIDCAzure_FrontChannelLogout(t *testing.T) {
 				oidc: authenticator,
 			}
 
+			var hookIDs []ccc.UUID
+			if tt.withHook {
+				a.baseSession.Hooks.FrontChannelLogout = func(_ context.Context, sessInfo *sessioninfo.SessionInfo) {
+					hookIDs = append(hookIDs, sessInfo.ID)
+				}
+			}
+
 			if tt.prepare != nil {
 				tt.prepare(sessionStorage)
 			}
@@ -401,8 +452,8 @@ func TestOIDCAzure_FrontChannelLogout(t *testing.T) {
 			if recorder.Code != tt.expectedStatus {
 				t.Errorf("App.FrontChannelLogout() = %v, want %v", recorder.Code, tt.expectedStatus)
 			}
-			if tt.expectedStatus != http.StatusOK {
-				return
+			if !slices.Equal(hookIDs, tt.wantHookIDs) {
+				t.Errorf("FrontChannelLogout hook called for %v, want %v", hookIDs, tt.wantHookIDs)
 			}
 		})
 	}
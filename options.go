@@ -1,16 +1,20 @@
 package session
 
 import (
+	"net/http"
+	"net/netip"
 	"time"
 
 	"github.com/cccteam/ccc/securehash"
+	"github.com/cccteam/session/cookie"
 	"github.com/cccteam/session/internal/azureoidc"
 	"github.com/cccteam/session/internal/basesession"
-	"github.com/cccteam/session/internal/cookie"
+	internalcookie "github.com/cccteam/session/internal/cookie"
+	"github.com/cccteam/session/sessionstorage"
 )
 
 // CookieOption defines a function signature for setting cookie client options.
-type CookieOption cookie.Option
+type CookieOption internalcookie.Option
 
 func (CookieOption) isOIDCAzureOption() {}
 func (CookieOption) isPasswordOption()  {}
@@ -18,22 +22,67 @@ func (CookieOption) isPreauthOption()   {}
 
 // WithCookieName sets the cookie name for the session cookie.
 func WithCookieName(name string) CookieOption {
-	return CookieOption(cookie.WithCookieName(name))
+	return CookieOption(internalcookie.WithCookieName(name))
 }
 
 // WithCookieDomain sets the domain for the session cookie.
 func WithCookieDomain(domain string) CookieOption {
-	return CookieOption(cookie.WithCookieDomain(domain))
+	return CookieOption(internalcookie.WithCookieDomain(domain))
 }
 
 // WithXSRFCookieName sets the cookie name for the XSRF cookie.
 func WithXSRFCookieName(name string) CookieOption {
-	return CookieOption(cookie.WithXSRFCookieName(name))
+	return CookieOption(internalcookie.WithXSRFCookieName(name))
 }
 
 // WithXSRFHeaderName sets the header name for the XSRF header.
 func WithXSRFHeaderName(name string) CookieOption {
-	return CookieOption(cookie.WithXSRFHeaderName(name))
+	return CookieOption(internalcookie.WithXSRFHeaderName(name))
+}
+
+// WithCookiePath sets the path of the session, XSRF and OIDC cookies. The OIDC callback must be
+// under the path. (default: /)
+func WithCookiePath(path string) CookieOption {
+	return CookieOption(internalcookie.WithCookiePath(path))
+}
+
+// CookiePrefix is a cookie name prefix that browsers enforce requirements for.
+type CookiePrefix = internalcookie.Prefix
+
+const (
+	// HostCookiePrefix ("__Host-") requires the cookies to be Secure, without a domain and with the path /.
+	HostCookiePrefix = internalcookie.HostPrefix
+	// SecureCookiePrefix ("__Secure-") requires the cookies to be Secure.
+	SecureCookiePrefix = internalcookie.SecurePrefix
+)
+
+// WithCookiePrefix adds prefix to the names of the session, XSRF and OIDC cookies, so that browsers
+// only accept them when they meet its requirements. The constructor returns an error when the cookie
+// domain or path do not meet them. The prefix is not added to cookies that are not Secure (insecurecookie
+// build tag), as browsers would reject them. (default: none)
+func WithCookiePrefix(prefix CookiePrefix) CookieOption {
+	return CookieOption(internalcookie.WithCookiePrefix(prefix))
+}
+
+// WithCookiePartitioned sets the Partitioned attribute on the session, XSRF and OIDC cookies, so that browsers
+// keep them separately for each top-level site (CHIPS) when the application is embedded in another site.
+// It is only set on Secure cookies. (default: false)
+func WithCookiePartitioned(partitioned bool) CookieOption {
+	return CookieOption(internalcookie.WithCookiePartitioned(partitioned))
+}
+
+// WithCookieSameSite sets the SameSite attribute of the session and XSRF cookies. The session cookie is
+// still written with SameSite=None during an OIDC login. (default: http.SameSiteStrictMode)
+func WithCookieSameSite(sameSite http.SameSite) CookieOption {
+	return CookieOption(internalcookie.WithCookieSameSite(sameSite))
+}
+
+// WithRetiredCookieKeys sets the cookie keys that were replaced by the cookieKey passed to the constructor.
+// New cookies are encrypted with cookieKey, but cookies encrypted with a retired key are still accepted and
+// are encrypted with cookieKey when they are written again, so the key can be rotated without logging out users.
+// Remove a retired key once the cookies it encrypted have expired.
+func WithRetiredCookieKeys(keys ...string) CookieOption {
+	return CookieOption(internalcookie.WithRetiredKeys(keys...))
 }
 
 // BaseSessionOption defines a function signature for setting session options.
@@ -73,6 +122,152 @@ func WithSessionTimeout(d time.Duration) BaseSessionOption {
 	})
 }
 
+var defaultRememberMeTimeout = time.Hour * 24 * 30
+
+// WithRememberMeTimeout sets how long a session created with rememberMe stays valid without activity.
+// Its Auth Cookie persists across browser restarts and expires d after it was last written. (default: 720h)
+func WithRememberMeTimeout(d time.Duration) BaseSessionOption {
+	return BaseSessionOption(func(b *basesession.BaseSession) {
+		b.RememberMeTimeout = d
+	})
+}
+
+// WithMaxSessionLifetime sets the maximum time a session is valid for after it is created, regardless of activity.
+// Sessions older than d are expired and the user has to log in again. (default: 0, disabled)
+func WithMaxSessionLifetime(d time.Duration) BaseSessionOption {
+	return BaseSessionOption(func(b *basesession.BaseSession) {
+		b.MaxSessionLifetime = d
+	})
+}
+
+// WithSessionRotation rotates the session ID once it has been in use for d, limiting how long a stolen
+// session cookie stays useful. ValidateSession rotates the session of requests with a safe method
+// (GET, HEAD, OPTIONS or TRACE) and writes new Auth and XSRF Token cookies. (default: 0, disabled)
+func WithSessionRotation(d time.Duration) BaseSessionOption {
+	return BaseSessionOption(func(b *basesession.BaseSession) {
+		b.RotationInterval = d
+	})
+}
+
+// WithTrustedProxies sets the proxies, such as a load balancer, that are trusted to report the client IP
+// in the X-Forwarded-For header. The IP recorded on a new session is the last address in the header that
+// is not a trusted proxy, or the address of the connection when it did not come from one. (default: none)
+func WithTrustedProxies(proxies ...netip.Prefix) BaseSessionOption {
+	return BaseSessionOption(func(b *basesession.BaseSession) {
+		b.TrustedProxies = proxies
+	})
+}
+
+// WithMaxSessionsPerUser limits the number of active sessions of a user to n. When a user with n active
+// sessions logs in, policy either expires their oldest sessions or rejects the login with a Conflict error.
+// The limit is enforced atomically with the creation of the new session. (default: 0, disabled)
+func WithMaxSessionsPerUser(n int, policy sessionstorage.SessionLimitPolicy) BaseSessionOption {
+	return BaseSessionOption(func(b *basesession.BaseSession) {
+		b.MaxSessionsPerUser = n
+		b.SessionLimitPolicy = policy
+	})
+}
+
+// WithActivityThrottle sets the minimum time between two activity updates of a session. (default: 5s)
+func WithActivityThrottle(d time.Duration) BaseSessionOption {
+	return BaseSessionOption(func(b *basesession.BaseSession) {
+		b.ActivityThrottle = d
+	})
+}
+
+// WithActivityWriter buffers session activity in w and writes it in batches, instead of updating
+// the store during the request. w should be created for the store passed to the constructor,
+// and closed on shutdown so buffered activity is written.
+func WithActivityWriter(w *sessionstorage.ActivityWriter) BaseSessionOption {
+	return BaseSessionOption(func(b *basesession.BaseSession) {
+		b.ActivityWriter = w
+	})
+}
+
+// WithAssertionSigner sets the signer of the session assertions issued by SessionAssertion on each API(),
+// which other services can verify with only the public key of signer. (default: none)
+func WithAssertionSigner(signer *cookie.Signer) BaseSessionOption {
+	return BaseSessionOption(func(b *basesession.BaseSession) {
+		b.AssertionSigner = signer
+	})
+}
+
+// WithImpersonationAuthorizer enables impersonation, allowing it when authorize returns nil. The Impersonate
+// handlers and API methods return a Forbidden error when authorize refuses, or when this option is not set.
+func WithImpersonationAuthorizer(authorize ImpersonationAuthorizer) BaseSessionOption {
+	return BaseSessionOption(func(b *basesession.BaseSession) {
+		b.ImpersonationAuthorizer = authorize
+	})
+}
+
+// WithSessionCreatedHook sets a hook called when a login creates a session.
+func WithSessionCreatedHook(h SessionHook) BaseSessionOption {
+	return BaseSessionOption(func(b *basesession.BaseSession) {
+		b.Hooks.Created = h
+	})
+}
+
+// WithSessionValidatedHook sets a hook called when ValidateSession finds the session of a request valid.
+func WithSessionValidatedHook(h SessionHook) BaseSessionOption {
+	return BaseSessionOption(func(b *basesession.BaseSession) {
+		b.Hooks.Validated = h
+	})
+}
+
+// WithSessionExpiredHook sets a hook called when a request uses a session that has been idle for longer
+// than the session timeout or has exceeded the maximum session lifetime. The session is then marked as
+// expired, so the hook is called once per session. Sessions that are never used again are not reported.
+func WithSessionExpiredHook(h SessionHook) BaseSessionOption {
+	return BaseSessionOption(func(b *basesession.BaseSession) {
+		b.Hooks.Expired = h
+	})
+}
+
+// WithLogoutHook sets a hook called when a session is destroyed by Logout, or when an active session
+// is revoked by its user with RevokeSession or RevokeOtherSessions.
+func WithLogoutHook(h SessionHook) BaseSessionOption {
+	return BaseSessionOption(func(b *basesession.BaseSession) {
+		b.Hooks.LoggedOut = h
+	})
+}
+
+// WithDestroyAllUserSessionsHook sets a hook called for each active session destroyed by DestroyAllUserSessions,
+// including when a user is deleted or deactivated.
+func WithDestroyAllUserSessionsHook(h SessionHook) BaseSessionOption {
+	return BaseSessionOption(func(b *basesession.BaseSession) {
+		b.Hooks.UserSessionsDestroyed = h
+	})
+}
+
+// WithFrontChannelLogoutHook sets a hook called for each active session destroyed by an OIDC front-channel logout.
+func WithFrontChannelLogoutHook(h SessionHook) BaseSessionOption {
+	return BaseSessionOption(func(b *basesession.BaseSession) {
+		b.Hooks.FrontChannelLogout = h
+	})
+}
+
+// oidcCookieOption defines a function signature for setting options of the OIDC cookie.
+type oidcCookieOption internalcookie.Option
+
+func (oidcCookieOption) isOIDCAzureOption() {}
+
+// WithOIDCCookieName sets the cookie name for the OIDC cookie, which holds the state of a login. (default: OIDC)
+func WithOIDCCookieName(name string) OIDCAzureOption {
+	return oidcCookieOption(internalcookie.WithOIDCCookieName(name))
+}
+
+// WithOIDCCookieLifetime sets how long a user has to complete a login at the identity provider. (default: 10m)
+func WithOIDCCookieLifetime(d time.Duration) OIDCAzureOption {
+	return oidcCookieOption(internalcookie.WithOIDCCookieLifetime(d))
+}
+
+// WithOIDCCookieSameSite sets the SameSite attribute of the OIDC cookie. The cookie must be sent with the
+// request of the identity provider to the callback, e.g. http.SameSiteNoneMode for a form post response.
+// (default: http.SameSiteDefaultMode)
+func WithOIDCCookieSameSite(sameSite http.SameSite) OIDCAzureOption {
+	return oidcCookieOption(internalcookie.WithOIDCCookieSameSite(sameSite))
+}
+
 // OIDCOption defines a function signature for setting OIDC options.
 type OIDCOption func(*azureoidc.OIDC)
 
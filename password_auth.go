@@ -3,6 +3,7 @@ package session
 import (
 	"context"
 	"net/http"
+	"time"
 
 	"github.com/cccteam/ccc"
 	"github.com/cccteam/ccc/resource"
@@ -22,6 +23,10 @@ import (
 const (
 	// RouterSessionUserID is a constant used for matching the SessionUserID in the router path
 	RouterSessionUserID = "sessionUserID"
+	// RouterSessionID is a constant used for matching the ID of the session to revoke in the router path
+	RouterSessionID = basesession.RouterSessionID
+	// ReauthenticationRequired is the message of the Unauthorized error returned by RequireRecentAuth
+	ReauthenticationRequired = basesession.ReauthenticationRequired
 )
 
 // PasswordOption defines the interface for functional options used when creating a new Password.
@@ -42,11 +47,13 @@ type PasswordAuth struct {
 // NewPasswordAuth creates a new PasswordAuth.
 // cookieKey: A Base64-encoded string representing at least 32 bytes
 // of cryptographically secure random data.
+// Keys replaced by cookieKey can be passed with WithRetiredCookieKeys.
 func NewPasswordAuth(storage sessionstorage.PasswordAuthStore, cookieKey string, options ...PasswordOption) (*PasswordAuth, error) {
 	baseSession := &basesession.BaseSession{
-		Handle:         httpio.Log,
-		SessionTimeout: defaultSessionTimeout,
-		Storage:        storage,
+		Handle:            httpio.Log,
+		SessionTimeout:    defaultSessionTimeout,
+		RememberMeTimeout: defaultRememberMeTimeout,
+		Storage:           storage,
 	}
 
 	var cookieOpts []internalcookie.Option
@@ -58,6 +65,7 @@ func NewPasswordAuth(storage sessionstorage.PasswordAuthStore, cookieKey string,
 			o(baseSession)
 		}
 	}
+	baseSession.ApplySessionLimit()
 
 	cookieClient, err := internalcookie.NewCookieClient(cookieKey, cookieOpts...)
 	if err != nil {
@@ -105,10 +113,13 @@ func (p *PasswordAuth) StartSession(next http.Handler) http.Handler {
 }
 
 // Login validates the username and password and establishes the session cookie.
+// When rememberMe is set in the request, the session uses the remember-me timeout
+// and its cookie persists across browser restarts.
 func (p *PasswordAuth) Login() http.HandlerFunc {
 	type request struct {
-		Username string `json:"username"`
-		Password string `json:"password"`
+		Username   string `json:"username"`
+		Password   string `json:"password"`
+		RememberMe bool   `json:"rememberMe"`
 	}
 
 	decoder := newDecoder[request]()
@@ -123,7 +134,8 @@ func (p *PasswordAuth) Login() http.HandlerFunc {
 			return httpio.NewEncoder(w).ClientMessage(ctx, err)
 		}
 
-		if err := p.loginAPI(ctx, w, req.Username, req.Password); err != nil {
+		ctx = p.baseSession.ClientContext(ctx, r)
+		if err := p.loginAPI(ctx, w, req.Username, req.Password, req.RememberMe); err != nil {
 			return httpio.NewEncoder(w).ClientMessage(ctx, err)
 		}
 
@@ -131,7 +143,7 @@ func (p *PasswordAuth) Login() http.HandlerFunc {
 	})
 }
 
-func (p *PasswordAuth) loginAPI(ctx context.Context, w http.ResponseWriter, username, password string) error {
+func (p *PasswordAuth) loginAPI(ctx context.Context, w http.ResponseWriter, username, password string, rememberMe bool) error {
 	// Validate credentials
 	user, err := p.storage.UserByUserName(ctx, username)
 	if err != nil {
@@ -142,9 +154,9 @@ func (p *PasswordAuth) loginAPI(ctx context.Context, w http.ResponseWriter, user
 	}
 
 	// user is successfully authenticated, start a new session
-	sessionID, err := p.startNewSession(ctx, w, user.Username)
+	sessionID, err := p.baseSession.NewSession(ctx, w, p.storage, user.Username, sessioninfo.AuthMethodPassword, rememberMe)
 	if err != nil {
-		return errors.Wrap(err, "PasswordAuth.startNewSession()")
+		return errors.Wrap(err, "basesession.BaseSession.NewSession()")
 	}
 
 	// Log the association between the sessionID and Username
@@ -197,6 +209,15 @@ func (p *PasswordAuth) ValidateSession(next http.Handler) http.Handler {
 			return httpio.NewEncoder(w).UnauthorizedMessage(ctx, "Session Expired")
 		}
 
+		if err := p.checkImpersonator(ctx); err != nil {
+			return httpio.NewEncoder(w).ClientMessage(ctx, err)
+		}
+
+		ctx, err = p.baseSession.RotateSessionIfDue(ctx, w, r)
+		if err != nil {
+			return httpio.NewEncoder(w).ClientMessage(ctx, err)
+		}
+
 		// Store user info in context
 		ctx = context.WithValue(ctx, sessioninfo.CtxUserInfo, &sessioninfo.UserInfo{
 			ID:       user.ID,
@@ -206,17 +227,14 @@ func (p *PasswordAuth) ValidateSession(next http.Handler) http.Handler {
 
 		next.ServeHTTP(w, r.WithContext(ctx))
 
+		p.baseSession.FlushSessionData(ctx)
+
 		return nil
 	})
 }
 
 // Authenticated is the handler that reports if the session is authenticated
 func (p *PasswordAuth) Authenticated() http.HandlerFunc {
-	type response struct {
-		Authenticated bool   `json:"authenticated"`
-		Username      string `json:"username"`
-	}
-
 	return p.baseSession.Handle(func(w http.ResponseWriter, r *http.Request) error {
 		ctx, span := tracer.Start(r.Context())
 		defer span.End()
@@ -224,7 +242,7 @@ func (p *PasswordAuth) Authenticated() http.HandlerFunc {
 		ctx, err := p.baseSession.ValidateSessionAPI(ctx)
 		if err != nil {
 			if httpio.HasUnauthorized(err) {
-				return httpio.NewEncoder(w).Ok(response{})
+				return httpio.NewEncoder(w).Ok(basesession.AuthenticatedResponse{})
 			}
 
 			return httpio.NewEncoder(w).ClientMessage(ctx, err)
@@ -241,16 +259,38 @@ func (p *PasswordAuth) Authenticated() http.HandlerFunc {
 			return httpio.NewEncoder(w).UnauthorizedMessage(ctx, "Session Expired")
 		}
 
-		// set response values
-		res := response{
-			Authenticated: true,
-			Username:      sessInfo.Username,
+		if err := p.checkImpersonator(ctx); err != nil {
+			return httpio.NewEncoder(w).ClientMessage(ctx, err)
 		}
 
-		return httpio.NewEncoder(w).Ok(res)
+		return httpio.NewEncoder(w).Ok(basesession.NewAuthenticatedResponse(sessInfo))
 	})
 }
 
+// checkImpersonator returns an Unauthorized error when the session in ctx is impersonated by a user
+// that is disabled or no longer exists, so that an impersonation ends with the access of its impersonator.
+func (p *PasswordAuth) checkImpersonator(ctx context.Context) error {
+	impersonator := sessioninfo.FromCtx(ctx).Impersonator
+	if impersonator == nil {
+		return nil
+	}
+
+	user, err := p.storage.UserByUserName(ctx, impersonator.Username)
+	if err != nil {
+		if httpio.HasNotFound(err) {
+			return httpio.NewUnauthorizedMessageWithError(err, "Session Expired")
+		}
+
+		return errors.Wrap(err, "sessionstorage.PasswordAuthStore.UserByUserName()")
+	}
+
+	if user.Disabled {
+		return httpio.NewUnauthorizedMessage("Session Expired")
+	}
+
+	return nil
+}
+
 // ChangeUsername handles modifications to the username
 func (p *PasswordAuth) ChangeUsername() http.HandlerFunc {
 	type request struct {
@@ -383,22 +423,6 @@ func (p *PasswordAuth) ActivateUser() http.HandlerFunc {
 	})
 }
 
-// startNewSession starts a new session for the given username and returns the session ID
-func (p *PasswordAuth) startNewSession(ctx context.Context, w http.ResponseWriter, username string) (ccc.UUID, error) {
-	// Create new Session in database
-	id, err := p.storage.NewSession(ctx, username)
-	if err != nil {
-		return ccc.NilUUID, errors.Wrap(err, "sessionstorage.PreauthStore.NewSession()")
-	}
-
-	p.baseSession.CookieHandler.NewAuthCookie(w, true, id)
-
-	// write new XSRF Token Cookie to match the new SessionID
-	p.baseSession.CookieHandler.CreateXSRFTokenCookie(w, id)
-
-	return id, nil
-}
-
 func (p *PasswordAuth) setPasswordHash(ctx context.Context, userID ccc.UUID, password string) error {
 	newHash, err := p.hasher.Hash(password)
 	if err != nil {
@@ -503,8 +527,8 @@ func (p *PasswordAuth) deleteSessionUser(ctx context.Context, sessionUserID ccc.
 		return errors.Wrap(err, "sessionstorage.PasswordAuthStore.DeleteUser()")
 	}
 
-	if err := p.storage.DestroyAllUserSessions(ctx, user.Username); err != nil {
-		return errors.Wrap(err, "sessionstorage.PasswordAuthStore.DestroyAllUserSessions()")
+	if err := p.baseSession.DestroyAllUserSessions(ctx, p.storage, user.Username); err != nil {
+		return errors.Wrap(err, "basesession.BaseSession.DestroyAllUserSessions()")
 	}
 
 	return nil
@@ -525,8 +549,8 @@ func (p *PasswordAuth) deactivateSessionUser(ctx context.Context, sessionUserID
 		return errors.Wrap(err, "sessionstorage.PasswordAuthStore.DeactivateUser()")
 	}
 
-	if err := p.storage.DestroyAllUserSessions(ctx, user.Username); err != nil {
-		return errors.Wrap(err, "sessionstorage.PasswordAuthStore.DestroyAllUserSessions()")
+	if err := p.baseSession.DestroyAllUserSessions(ctx, p.storage, user.Username); err != nil {
+		return errors.Wrap(err, "basesession.BaseSession.DestroyAllUserSessions()")
 	}
 
 	return nil
@@ -541,6 +565,98 @@ func (p *PasswordAuth) activateSessionUser(ctx context.Context, sessionUserUUID
 	return nil
 }
 
+// Sessions is the handler that lists the active sessions of the current user
+func (p *PasswordAuth) Sessions() http.HandlerFunc {
+	return p.baseSession.Sessions()
+}
+
+// RevokeSession is the handler that destroys one session of the current user, given by the RouterSessionID path parameter
+func (p *PasswordAuth) RevokeSession() http.HandlerFunc {
+	return p.baseSession.RevokeSession()
+}
+
+// RevokeOtherSessions is the handler that destroys every session of the current user except the current session
+func (p *PasswordAuth) RevokeOtherSessions() http.HandlerFunc {
+	return p.baseSession.RevokeOtherSessions()
+}
+
+// RequireRecentAuth returns a middleware that rejects the request with a ReauthenticationRequired Unauthorized
+// error when the user of the session last authenticated more than maxAge ago, and impersonated sessions with a
+// Forbidden error. ValidateSession handler must be called before calling RequireRecentAuth
+func (p *PasswordAuth) RequireRecentAuth(maxAge time.Duration) func(next http.Handler) http.Handler {
+	return p.baseSession.RequireRecentAuth(maxAge)
+}
+
+// Reauthenticate is the handler that validates the password of the current user and records the time as
+// their last authentication in the session, without starting a new session, to satisfy RequireRecentAuth.
+// ValidateSession handler must be called before calling Reauthenticate
+func (p *PasswordAuth) Reauthenticate() http.HandlerFunc {
+	type request struct {
+		Password string `json:"password"`
+	}
+
+	decoder := newDecoder[request]()
+
+	return p.baseSession.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		ctx, span := tracer.Start(r.Context())
+		defer span.End()
+
+		req, err := decoder.Decode(r)
+		if err != nil {
+			return httpio.NewEncoder(w).ClientMessage(ctx, err)
+		}
+
+		if err := p.API().Reauthenticate(ctx, req.Password); err != nil {
+			return httpio.NewEncoder(w).ClientMessage(ctx, err)
+		}
+
+		return httpio.NewEncoder(w).Ok(nil)
+	})
+}
+
+// Impersonate is the handler that starts a session as the user given by "username" in the request body,
+// recording the current user as the impersonator. The impersonation must be allowed by the authorizer set
+// with WithImpersonationAuthorizer. ValidateSession handler must be called before calling Impersonate
+func (p *PasswordAuth) Impersonate() http.HandlerFunc {
+	type request struct {
+		Username string `json:"username"`
+	}
+
+	decoder := newDecoder[request]()
+
+	return p.baseSession.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		ctx, span := tracer.Start(r.Context())
+		defer span.End()
+
+		req, err := decoder.Decode(r)
+		if err != nil {
+			return httpio.NewEncoder(w).ClientMessage(ctx, err)
+		}
+
+		ctx = p.baseSession.ClientContext(ctx, r)
+		if _, err := p.API().Impersonate(ctx, w, req.Username); err != nil {
+			return httpio.NewEncoder(w).ClientMessage(ctx, err)
+		}
+
+		return httpio.NewEncoder(w).Ok(nil)
+	})
+}
+
+// EndImpersonation is the handler that ends the impersonated session and returns to the session of the
+// impersonator. ValidateSession handler must be called before calling EndImpersonation
+func (p *PasswordAuth) EndImpersonation() http.HandlerFunc {
+	return p.baseSession.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		ctx, span := tracer.Start(r.Context())
+		defer span.End()
+
+		if _, err := p.API().EndImpersonation(ctx, w); err != nil {
+			return httpio.NewEncoder(w).ClientMessage(ctx, err)
+		}
+
+		return httpio.NewEncoder(w).Ok(nil)
+	})
+}
+
 // API provides programatic access to PasswordAuth handler internals
 func (p *PasswordAuth) API() *PasswordAuthAPI {
 	return newPasswordAuthAPI(p)
@@ -580,16 +696,16 @@ func (p *PasswordAuthAPI) ValidateCredentials(ctx context.Context, username, pas
 	return p.passwordAuth.validateCredentials(ctx, user, password)
 }
 
-// Login validates the username and password and creates a new session for the user.
-func (p *PasswordAuthAPI) Login(ctx context.Context, w http.ResponseWriter, username, password string) error {
-	return p.passwordAuth.loginAPI(ctx, w, username, password)
+// Login validates the username and password and creates a new session for the user. When rememberMe is true,
+// the session stays valid for the remember-me timeout without activity and its Auth Cookie persists across browser restarts.
+func (p *PasswordAuthAPI) Login(ctx context.Context, w http.ResponseWriter, username, password string, rememberMe bool) error {
+	return p.passwordAuth.loginAPI(ctx, w, username, password, rememberMe)
 }
 
 // Logout destroys the current session
 func (p *PasswordAuthAPI) Logout(ctx context.Context) error {
-	// Destroy session in database
-	if err := p.passwordAuth.baseSession.Storage.DestroySession(ctx, sessioninfo.IDFromCtx(ctx)); err != nil {
-		return errors.Wrap(err, "sessionstorage.BaseStore.DestroySession()")
+	if err := p.passwordAuth.baseSession.LogoutAPI(ctx); err != nil {
+		return errors.Wrap(err, "basesession.BaseSession.LogoutAPI()")
 	}
 
 	return nil
@@ -619,6 +735,18 @@ func (p *PasswordAuthAPI) ValidateSession(ctx context.Context) (context.Context,
 	return ctx, nil
 }
 
+// RotateSession moves the current session to a new session ID, keeping its data, and writes
+// new Auth and XSRF Token cookies for it. Call it after a change in privilege, such as a login,
+// to defend against session fixation. The returned context holds the new session ID.
+func (p *PasswordAuthAPI) RotateSession(ctx context.Context, w http.ResponseWriter) (context.Context, error) {
+	ctx, err := p.passwordAuth.baseSession.RotateSessionAPI(ctx, w)
+	if err != nil {
+		return ctx, errors.Wrap(err, "basesession.BaseSession.RotateSessionAPI()")
+	}
+
+	return ctx, nil
+}
+
 // ChangeSessionUserUsername handles modifications to a user username.
 // The user record and every active session row for that user are updated atomically,
 // preserving the acting session and any other sessions the user has open.
@@ -656,15 +784,97 @@ func (p *PasswordAuthAPI) ActivateSessionUser(ctx context.Context, sessionUserUU
 	return p.passwordAuth.activateSessionUser(ctx, sessionUserUUID)
 }
 
+// Reauthenticate validates the password of the user of the current session and records the time as their
+// last authentication in the session, without starting a new session. ValidateSession must be called first.
+func (p *PasswordAuthAPI) Reauthenticate(ctx context.Context, password string) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	sessInfo := sessioninfo.FromCtx(ctx)
+
+	user, err := p.passwordAuth.storage.UserByUserName(ctx, sessInfo.Username)
+	if err != nil {
+		return httpio.NewUnauthorizedMessageWithError(err, "Invalid Credentials")
+	}
+	if err := p.passwordAuth.validateCredentials(ctx, user, password); err != nil {
+		return err
+	}
+
+	if err := p.passwordAuth.storage.UpdateSessionAuthentication(ctx, sessInfo.ID); err != nil {
+		return errors.Wrap(err, "sessionstorage.PasswordAuthStore.UpdateSessionAuthentication()")
+	}
+
+	return nil
+}
+
+// Impersonate starts a session as username for the user of the current session and writes new Auth and XSRF
+// Token cookies for it. The session records the current user as its impersonator, available with
+// sessioninfo.ImpersonatorFromCtx, and the current session is kept for EndImpersonation. A disabled user can
+// not be impersonated. It returns a Forbidden error unless the authorizer set with WithImpersonationAuthorizer
+// allows the impersonation. ValidateSession must be called first.
+func (p *PasswordAuthAPI) Impersonate(ctx context.Context, w http.ResponseWriter, username string) (ccc.UUID, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	checkTarget := func(ctx context.Context, username string) (string, error) {
+		user, err := p.passwordAuth.storage.UserByUserName(ctx, username)
+		if err != nil {
+			return "", errors.Wrap(err, "sessionstorage.PasswordAuthStore.UserByUserName()")
+		}
+		if user.Disabled {
+			return "", httpio.NewBadRequestMessagef("user %q is disabled", user.Username)
+		}
+
+		return user.Username, nil
+	}
+
+	sessionID, err := p.passwordAuth.baseSession.ImpersonateAPI(ctx, w, p.passwordAuth.storage, username, checkTarget)
+	if err != nil {
+		return ccc.NilUUID, errors.Wrap(err, "basesession.BaseSession.ImpersonateAPI()")
+	}
+
+	return sessionID, nil
+}
+
+// EndImpersonation destroys the current impersonated session and writes Auth and XSRF Token cookies for the
+// session of the impersonator. The returned context holds the session of the impersonator.
+func (p *PasswordAuthAPI) EndImpersonation(ctx context.Context, w http.ResponseWriter) (context.Context, error) {
+	ctx, err := p.passwordAuth.baseSession.EndImpersonationAPI(ctx, w)
+	if err != nil {
+		return ctx, errors.Wrap(err, "basesession.BaseSession.EndImpersonationAPI()")
+	}
+
+	return ctx, nil
+}
+
 // DestroyAllUserSessions destroys all sessions for a given user
 func (p *PasswordAuthAPI) DestroyAllUserSessions(ctx context.Context, username string) error {
-	if err := p.passwordAuth.storage.DestroyAllUserSessions(ctx, username); err != nil {
-		return errors.Wrap(err, "sessionstorage.PreauthStore.DestroyAllUserSessions()")
+	if err := p.passwordAuth.baseSession.DestroyAllUserSessions(ctx, p.passwordAuth.storage, username); err != nil {
+		return errors.Wrap(err, "basesession.BaseSession.DestroyAllUserSessions()")
 	}
 
 	return nil
 }
 
+// UserSessions returns a page of the sessions for username that match filter, newest first.
+// When filter.IdleTimeout is zero, the session timeout is used so that timed out sessions are reported as expired.
+func (p *PasswordAuthAPI) UserSessions(ctx context.Context, username string, filter sessionstorage.SessionFilter, page sessionstorage.Page) (*sessionstorage.SessionPage, error) {
+	return p.passwordAuth.baseSession.UserSessions(ctx, username, filter, page)
+}
+
+// SessionAssertion returns a PASETO v4.public assertion of the current session that expires after lifetime,
+// signed by the signer set with WithAssertionSigner. Other services verify it with cookie.Verifier for
+// cookie.SessionAssertionPurpose, and read the username from its subject and the session ID from
+// cookie.AssertionSessionID. ValidateSession must be called first.
+func (p *PasswordAuthAPI) SessionAssertion(ctx context.Context, lifetime time.Duration) (string, error) {
+	assertion, err := p.passwordAuth.baseSession.SessionAssertion(ctx, lifetime)
+	if err != nil {
+		return "", errors.Wrap(err, "basesession.BaseSession.SessionAssertion()")
+	}
+
+	return assertion, nil
+}
+
 // Cookie returns the underlying cookie.Client
 func (p *PasswordAuthAPI) Cookie() *cookie.Client {
 	return p.passwordAuth.baseSession.CookieHandler.Cookie()
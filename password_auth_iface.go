@@ -24,8 +24,14 @@ type PasswordAuthHandlers interface {
 	DeactivateUser() http.HandlerFunc
 	// DeleteUser handles deleting a user account.
 	DeleteUser() http.HandlerFunc
+	// EndImpersonation ends an impersonated session and returns to the session of the impersonator.
+	EndImpersonation() http.HandlerFunc
+	// Impersonate starts a session as another user, recording the current user as the impersonator.
+	Impersonate() http.HandlerFunc
 	// Login validates the username and password.
 	Login() http.HandlerFunc
+	// Reauthenticate validates the password of the current user and refreshes the last authentication of the session.
+	Reauthenticate() http.HandlerFunc
 	// ValidateSession checks the sessionID in the database to validate that it has not expired
 	// and updates the last activity timestamp if it is still valid.
 	ValidateSession(next http.Handler) http.Handler
@@ -19,6 +19,7 @@ import (
 	"github.com/cccteam/session/internal/dbtype"
 	"github.com/cccteam/session/mock/mock_cookie"
 	"github.com/cccteam/session/sessioninfo"
+	"github.com/cccteam/session/sessionstorage"
 	"github.com/cccteam/session/sessionstorage/mock/mock_sessionstorage"
 	"github.com/go-playground/errors/v5"
 	gomock "go.uber.org/mock/gomock"
@@ -285,6 +286,38 @@ func TestPasswordAuth_ValidateSession(t *testing.T) {
 			wantStatusCode: http.StatusUnauthorized,
 			wantMessage:    true,
 		},
+		{
+			name: "fails on disabled impersonator",
+			prepare: func(storage *mock_sessionstorage.MockPasswordAuthStore) {
+				storage.EXPECT().Session(gomock.Any(), gomock.Any()).Return(&sessioninfo.SessionInfo{
+					ID:           ccc.Must(ccc.NewUUID()),
+					Username:     "user",
+					CreatedAt:    time.Now(),
+					UpdatedAt:    time.Now(),
+					Impersonator: &sessioninfo.Impersonator{Username: "admin", SessionID: ccc.Must(ccc.NewUUID())},
+				}, nil)
+				storage.EXPECT().UserByUserName(gomock.Any(), "user").Return(&dbtype.SessionUser{Username: "user"}, nil)
+				storage.EXPECT().UserByUserName(gomock.Any(), "admin").Return(&dbtype.SessionUser{Username: "admin", Disabled: true}, nil)
+			},
+			wantStatusCode: http.StatusUnauthorized,
+			wantMessage:    true,
+		},
+		{
+			name: "fails on missing impersonator",
+			prepare: func(storage *mock_sessionstorage.MockPasswordAuthStore) {
+				storage.EXPECT().Session(gomock.Any(), gomock.Any()).Return(&sessioninfo.SessionInfo{
+					ID:           ccc.Must(ccc.NewUUID()),
+					Username:     "user",
+					CreatedAt:    time.Now(),
+					UpdatedAt:    time.Now(),
+					Impersonator: &sessioninfo.Impersonator{Username: "admin", SessionID: ccc.Must(ccc.NewUUID())},
+				}, nil)
+				storage.EXPECT().UserByUserName(gomock.Any(), "user").Return(&dbtype.SessionUser{Username: "user"}, nil)
+				storage.EXPECT().UserByUserName(gomock.Any(), "admin").Return(nil, httpio.NewNotFoundMessage("user not found"))
+			},
+			wantStatusCode: http.StatusUnauthorized,
+			wantMessage:    true,
+		},
 		{
 			name: "success",
 			prepare: func(storage *mock_sessionstorage.MockPasswordAuthStore) {
@@ -1231,6 +1264,199 @@ func TestPasswordAuth_API_ChangeSessionUserUsername(t *testing.T) {
 	}
 }
 
+func TestPasswordAuth_ValidateSession_impersonatorRemoved(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		// remove acts on the impersonator while the impersonation is in progress
+		remove         func(ctx context.Context, p *PasswordAuth, store sessionstorage.PasswordAuthStore, impersonator *dbtype.SessionUser) error
+		wantExpired    bool
+		wantStatusCode int
+	}{
+		{
+			name: "impersonator still active",
+			remove: func(context.Context, *PasswordAuth, sessionstorage.PasswordAuthStore, *dbtype.SessionUser) error {
+				return nil
+			},
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name: "impersonator deactivated",
+			remove: func(ctx context.Context, p *PasswordAuth, _ sessionstorage.PasswordAuthStore, impersonator *dbtype.SessionUser) error {
+				return p.API().DeactivateSessionUser(ctx, impersonator.ID)
+			},
+			wantExpired:    true,
+			wantStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name: "impersonator deleted",
+			remove: func(ctx context.Context, p *PasswordAuth, _ sessionstorage.PasswordAuthStore, impersonator *dbtype.SessionUser) error {
+				return p.API().DeleteSessionUser(ctx, impersonator.ID)
+			},
+			wantExpired:    true,
+			wantStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name: "impersonator disabled without destroying its sessions",
+			remove: func(ctx context.Context, _ *PasswordAuth, store sessionstorage.PasswordAuthStore, impersonator *dbtype.SessionUser) error {
+				return store.DeactivateUser(ctx, impersonator.ID)
+			},
+			wantStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name: "impersonator deleted without destroying its sessions",
+			remove: func(ctx context.Context, _ *PasswordAuth, store sessionstorage.PasswordAuthStore, impersonator *dbtype.SessionUser) error {
+				return store.DeleteUser(ctx, impersonator.ID)
+			},
+			wantStatusCode: http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := t.Context()
+			store := sessionstorage.NewMemoryPasswordAuth()
+			users := make(map[string]*dbtype.SessionUser)
+			for _, username := range []string{"admin", "manager", "user"} {
+				user, err := store.CreateUser(ctx, &dbtype.InsertSessionUser{Username: username})
+				if err != nil {
+					t.Fatalf("PasswordAuthStore.CreateUser() error = %v", err)
+				}
+				users[username] = user
+			}
+
+			adminSession, err := store.NewSession(ctx, "admin")
+			if err != nil {
+				t.Fatalf("PasswordAuthStore.NewSession() error = %v", err)
+			}
+			impersonator := &sessioninfo.Impersonator{Username: "admin", SessionID: adminSession}
+			impersonation, err := store.NewSession(context.WithValue(ctx, sessioninfo.CtxImpersonator, impersonator), "user")
+			if err != nil {
+				t.Fatalf("PasswordAuthStore.NewSession() error = %v", err)
+			}
+
+			p, err := NewPasswordAuth(store, cookieKey)
+			if err != nil {
+				t.Fatalf("NewPasswordAuth() error = %v", err)
+			}
+
+			managerCtx := context.WithValue(ctx, sessioninfo.CtxUserInfo, &sessioninfo.UserInfo{ID: users["manager"].ID, Username: "manager"})
+			if err := tt.remove(managerCtx, p, store, users["admin"]); err != nil {
+				t.Fatalf("remove() error = %v", err)
+			}
+			session, err := store.Session(ctx, impersonation)
+			if err != nil {
+				t.Fatalf("PasswordAuthStore.Session() error = %v", err)
+			}
+			if session.Expired != tt.wantExpired {
+				t.Errorf("PasswordAuthStore.Session().Expired = %v, want %v", session.Expired, tt.wantExpired)
+			}
+
+			handler := p.ValidateSession(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+			req, err := createHTTPRequest(http.MethodGet, nil, &sessioninfo.SessionInfo{ID: impersonation}, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if got := rr.Code; got != tt.wantStatusCode {
+				t.Errorf("response.Code = %v, want %v", got, tt.wantStatusCode)
+			}
+		})
+	}
+}
+
+func TestPasswordAuth_API_Reauthenticate(t *testing.T) {
+	t.Parallel()
+
+	hasher := securehash.New(securehash.Argon2())
+	validHash, err := hasher.Hash("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionID := ccc.Must(ccc.NewUUID())
+
+	tests := []struct {
+		name     string
+		password string
+		prepare  func(storage *mock_sessionstorage.MockPasswordAuthStore)
+		wantErr  bool
+	}{
+		{
+			name:     "fails on user not found",
+			password: "password",
+			prepare: func(storage *mock_sessionstorage.MockPasswordAuthStore) {
+				storage.EXPECT().UserByUserName(gomock.Any(), "user").Return(nil, errors.New("not found"))
+			},
+			wantErr: true,
+		},
+		{
+			name:     "fails on invalid password",
+			password: "wrong_password",
+			prepare: func(storage *mock_sessionstorage.MockPasswordAuthStore) {
+				storage.EXPECT().UserByUserName(gomock.Any(), "user").Return(&dbtype.SessionUser{Username: "user", PasswordHash: validHash}, nil)
+			},
+			wantErr: true,
+		},
+		{
+			name:     "fails on disabled user",
+			password: "password",
+			prepare: func(storage *mock_sessionstorage.MockPasswordAuthStore) {
+				storage.EXPECT().UserByUserName(gomock.Any(), "user").Return(&dbtype.SessionUser{Username: "user", PasswordHash: validHash, Disabled: true}, nil)
+			},
+			wantErr: true,
+		},
+		{
+			name:     "fails on storage update",
+			password: "password",
+			prepare: func(storage *mock_sessionstorage.MockPasswordAuthStore) {
+				storage.EXPECT().UserByUserName(gomock.Any(), "user").Return(&dbtype.SessionUser{Username: "user", PasswordHash: validHash}, nil)
+				storage.EXPECT().UpdateSessionAuthentication(gomock.Any(), sessionID).Return(errors.New("db error"))
+			},
+			wantErr: true,
+		},
+		{
+			name:     "success",
+			password: "password",
+			prepare: func(storage *mock_sessionstorage.MockPasswordAuthStore) {
+				storage.EXPECT().UserByUserName(gomock.Any(), "user").Return(&dbtype.SessionUser{Username: "user", PasswordHash: validHash}, nil)
+				storage.EXPECT().UpdateSessionAuthentication(gomock.Any(), sessionID).Return(nil)
+			},
+			wantErr: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ctrl := gomock.NewController(t)
+
+			storage := mock_sessionstorage.NewMockPasswordAuthStore(ctrl)
+			p, err := NewPasswordAuth(storage, cookieKey)
+			if err != nil {
+				t.Fatalf("NewPasswordAuth() error=%v", err)
+			}
+			p.storage = storage
+			p.hasher = hasher
+
+			if tt.prepare != nil {
+				tt.prepare(storage)
+			}
+
+			ctx := context.WithValue(t.Context(), sessioninfo.CtxSessionInfo, &sessioninfo.SessionInfo{ID: sessionID, Username: "user"})
+			err = p.API().Reauthenticate(ctx, tt.password)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("PasswordAuth.API.Reauthenticate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestPasswordAuth_ChangeSessionUserHash(t *testing.T) {
 	t.Parallel()
 
@@ -3,6 +3,7 @@ package session
 import (
 	"context"
 	"net/http"
+	"time"
 
 	"github.com/cccteam/ccc"
 	"github.com/cccteam/ccc/tracer"
@@ -32,11 +33,13 @@ type Preauth struct {
 // NewPreauth creates a new PreauthSession instance.
 // cookieKey: A Base64-encoded string representing at least 32 bytes
 // of cryptographically secure random data.
+// Keys replaced by cookieKey can be passed with WithRetiredCookieKeys.
 func NewPreauth(storage sessionstorage.PreauthStore, cookieKey string, options ...PreauthOption) (*Preauth, error) {
 	baseSession := &basesession.BaseSession{
-		Handle:         httpio.Log,
-		SessionTimeout: defaultSessionTimeout,
-		Storage:        storage,
+		Handle:            httpio.Log,
+		SessionTimeout:    defaultSessionTimeout,
+		RememberMeTimeout: defaultRememberMeTimeout,
+		Storage:           storage,
 	}
 
 	var cookieOpts []internalcookie.Option
@@ -48,6 +51,7 @@ func NewPreauth(storage sessionstorage.PreauthStore, cookieKey string, options .
 			o(baseSession)
 		}
 	}
+	baseSession.ApplySessionLimit()
 	cookieClient, err := internalcookie.NewCookieClient(cookieKey, cookieOpts...)
 	if err != nil {
 		return nil, errors.Wrap(err, "cookie.NewCookieClient()")
@@ -64,7 +68,7 @@ func NewPreauth(storage sessionstorage.PreauthStore, cookieKey string, options .
 //
 // Deprecated: Use p.API().Login() instead
 func (p *Preauth) NewSession(ctx context.Context, w http.ResponseWriter, _ *http.Request, username string) (ccc.UUID, error) {
-	return p.API().Login(ctx, w, username)
+	return p.API().Login(ctx, w, username, false)
 }
 
 // Authenticated is the handler reports if the session is authenticated
@@ -100,6 +104,71 @@ func (p *Preauth) ValidateXSRFToken(next http.Handler) http.Handler {
 	return p.baseSession.ValidateXSRFToken(next)
 }
 
+// Sessions is the handler that lists the active sessions of the current user
+func (p *Preauth) Sessions() http.HandlerFunc {
+	return p.baseSession.Sessions()
+}
+
+// RevokeSession is the handler that destroys one session of the current user, given by the RouterSessionID path parameter
+func (p *Preauth) RevokeSession() http.HandlerFunc {
+	return p.baseSession.RevokeSession()
+}
+
+// RevokeOtherSessions is the handler that destroys every session of the current user except the current session
+func (p *Preauth) RevokeOtherSessions() http.HandlerFunc {
+	return p.baseSession.RevokeOtherSessions()
+}
+
+// RequireRecentAuth returns a middleware that rejects the request with a ReauthenticationRequired Unauthorized
+// error when the user of the session last authenticated more than maxAge ago, and impersonated sessions with a
+// Forbidden error. ValidateSession handler must be called before calling RequireRecentAuth
+func (p *Preauth) RequireRecentAuth(maxAge time.Duration) func(next http.Handler) http.Handler {
+	return p.baseSession.RequireRecentAuth(maxAge)
+}
+
+// Impersonate is the handler that starts a session as the user given by "username" in the request body,
+// recording the current user as the impersonator. The impersonation must be allowed by the authorizer set
+// with WithImpersonationAuthorizer. ValidateSession handler must be called before calling Impersonate
+func (p *Preauth) Impersonate() http.HandlerFunc {
+	type request struct {
+		Username string `json:"username"`
+	}
+
+	decoder := newDecoder[request]()
+
+	return p.baseSession.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		ctx, span := tracer.Start(r.Context())
+		defer span.End()
+
+		req, err := decoder.Decode(r)
+		if err != nil {
+			return httpio.NewEncoder(w).ClientMessage(ctx, err)
+		}
+
+		ctx = p.baseSession.ClientContext(ctx, r)
+		if _, err := p.API().Impersonate(ctx, w, req.Username); err != nil {
+			return httpio.NewEncoder(w).ClientMessage(ctx, err)
+		}
+
+		return httpio.NewEncoder(w).Ok(nil)
+	})
+}
+
+// EndImpersonation is the handler that ends the impersonated session and returns to the session of the
+// impersonator. ValidateSession handler must be called before calling EndImpersonation
+func (p *Preauth) EndImpersonation() http.HandlerFunc {
+	return p.baseSession.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		ctx, span := tracer.Start(r.Context())
+		defer span.End()
+
+		if _, err := p.API().EndImpersonation(ctx, w); err != nil {
+			return httpio.NewEncoder(w).ClientMessage(ctx, err)
+		}
+
+		return httpio.NewEncoder(w).Ok(nil)
+	})
+}
+
 // API provides programatic access to Preauth handler internals
 func (p *Preauth) API() *PreauthAPI {
 	return newPreauthAPI(p)
@@ -116,23 +185,18 @@ func newPreauthAPI(preauth *Preauth) *PreauthAPI {
 	}
 }
 
-// Login creates a new session for a pre-authenticated user.
-func (p *PreauthAPI) Login(ctx context.Context, w http.ResponseWriter, username string) (ccc.UUID, error) {
+// Login creates a new session for a pre-authenticated user. When rememberMe is true, the session stays
+// valid for the remember-me timeout without activity and its Auth Cookie persists across browser restarts.
+// The session records the client IP and User-Agent when ctx is from a request that went through StartSession.
+func (p *PreauthAPI) Login(ctx context.Context, w http.ResponseWriter, username string, rememberMe bool) (ccc.UUID, error) {
 	ctx, span := tracer.Start(ctx)
 	defer span.End()
 
-	// Create new Session in database
-	sessionID, err := p.preauth.storage.NewSession(ctx, username)
+	sessionID, err := p.preauth.baseSession.NewSession(ctx, w, p.preauth.storage, username, sessioninfo.AuthMethodPreauth, rememberMe)
 	if err != nil {
-		return ccc.NilUUID, errors.Wrap(err, "sessionstorage.PreauthStore.NewSession()")
+		return ccc.NilUUID, errors.Wrap(err, "basesession.BaseSession.NewSession()")
 	}
 
-	// Write new Auth Cookie
-	p.preauth.baseSession.CookieHandler.NewAuthCookie(w, true, sessionID)
-
-	// Write new XSRF Token Cookie to match the new SessionID
-	p.preauth.baseSession.CookieHandler.CreateXSRFTokenCookie(w, sessionID)
-
 	// Log the association between the sessionID and Username
 	logger.FromCtx(ctx).AddRequestAttribute("Username", username).AddRequestAttribute(string(internalcookie.SessionID), sessionID)
 
@@ -141,9 +205,8 @@ func (p *PreauthAPI) Login(ctx context.Context, w http.ResponseWriter, username
 
 // Logout destroys the current session
 func (p *PreauthAPI) Logout(ctx context.Context) error {
-	// Destroy session in database
-	if err := p.preauth.baseSession.Storage.DestroySession(ctx, sessioninfo.IDFromCtx(ctx)); err != nil {
-		return errors.Wrap(err, "sessionstorage.BaseStore.DestroySession()")
+	if err := p.preauth.baseSession.LogoutAPI(ctx); err != nil {
+		return errors.Wrap(err, "basesession.BaseSession.LogoutAPI()")
 	}
 
 	return nil
@@ -173,15 +236,74 @@ func (p *PreauthAPI) ValidateSession(ctx context.Context) (context.Context, erro
 	return ctx, nil
 }
 
+// RotateSession moves the current session to a new session ID, keeping its data, and writes
+// new Auth and XSRF Token cookies for it. Call it after a change in privilege, such as a login,
+// to defend against session fixation. The returned context holds the new session ID.
+func (p *PreauthAPI) RotateSession(ctx context.Context, w http.ResponseWriter) (context.Context, error) {
+	ctx, err := p.preauth.baseSession.RotateSessionAPI(ctx, w)
+	if err != nil {
+		return ctx, errors.Wrap(err, "basesession.BaseSession.RotateSessionAPI()")
+	}
+
+	return ctx, nil
+}
+
+// Impersonate starts a session as username for the user of the current session and writes new Auth and XSRF
+// Token cookies for it. The session records the current user as its impersonator, available with
+// sessioninfo.ImpersonatorFromCtx, and the current session is kept for EndImpersonation. It returns a Forbidden
+// error unless the authorizer set with WithImpersonationAuthorizer allows the impersonation.
+// ValidateSession must be called first.
+func (p *PreauthAPI) Impersonate(ctx context.Context, w http.ResponseWriter, username string) (ccc.UUID, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	sessionID, err := p.preauth.baseSession.ImpersonateAPI(ctx, w, p.preauth.storage, username, nil)
+	if err != nil {
+		return ccc.NilUUID, errors.Wrap(err, "basesession.BaseSession.ImpersonateAPI()")
+	}
+
+	return sessionID, nil
+}
+
+// EndImpersonation destroys the current impersonated session and writes Auth and XSRF Token cookies for the
+// session of the impersonator. The returned context holds the session of the impersonator.
+func (p *PreauthAPI) EndImpersonation(ctx context.Context, w http.ResponseWriter) (context.Context, error) {
+	ctx, err := p.preauth.baseSession.EndImpersonationAPI(ctx, w)
+	if err != nil {
+		return ctx, errors.Wrap(err, "basesession.BaseSession.EndImpersonationAPI()")
+	}
+
+	return ctx, nil
+}
+
 // DestroyAllUserSessions destroys all sessions for a given user
 func (p *PreauthAPI) DestroyAllUserSessions(ctx context.Context, username string) error {
-	if err := p.preauth.storage.DestroyAllUserSessions(ctx, username); err != nil {
-		return errors.Wrap(err, "sessionstorage.PreauthStore.DestroyAllUserSessions()")
+	if err := p.preauth.baseSession.DestroyAllUserSessions(ctx, p.preauth.storage, username); err != nil {
+		return errors.Wrap(err, "basesession.BaseSession.DestroyAllUserSessions()")
 	}
 
 	return nil
 }
 
+// UserSessions returns a page of the sessions for username that match filter, newest first.
+// When filter.IdleTimeout is zero, the session timeout is used so that timed out sessions are reported as expired.
+func (p *PreauthAPI) UserSessions(ctx context.Context, username string, filter sessionstorage.SessionFilter, page sessionstorage.Page) (*sessionstorage.SessionPage, error) {
+	return p.preauth.baseSession.UserSessions(ctx, username, filter, page)
+}
+
+// SessionAssertion returns a PASETO v4.public assertion of the current session that expires after lifetime,
+// signed by the signer set with WithAssertionSigner. Other services verify it with cookie.Verifier for
+// cookie.SessionAssertionPurpose, and read the username from its subject and the session ID from
+// cookie.AssertionSessionID. ValidateSession must be called first.
+func (p *PreauthAPI) SessionAssertion(ctx context.Context, lifetime time.Duration) (string, error) {
+	assertion, err := p.preauth.baseSession.SessionAssertion(ctx, lifetime)
+	if err != nil {
+		return "", errors.Wrap(err, "basesession.BaseSession.SessionAssertion()")
+	}
+
+	return assertion, nil
+}
+
 // Cookie returns the underlying cookie.Client
 func (p *PreauthAPI) Cookie() *cookie.Client {
 	return p.preauth.baseSession.CookieHandler.Cookie()
@@ -13,5 +13,7 @@ var _ PreauthHandlers = &Preauth{}
 // PreauthHandlers defines the interface for pre-authentication session handlers.
 type PreauthHandlers interface {
 	basesession.Handlers
+	EndImpersonation() http.HandlerFunc
+	Impersonate() http.HandlerFunc
 	NewSession(ctx context.Context, w http.ResponseWriter, r *http.Request, username string) (ccc.UUID, error)
 }
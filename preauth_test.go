@@ -6,12 +6,14 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/cccteam/ccc"
 	"github.com/cccteam/session/cookie"
 	"github.com/cccteam/session/internal/basesession"
 	internalcookie "github.com/cccteam/session/internal/cookie"
 	"github.com/cccteam/session/mock/mock_cookie"
+	"github.com/cccteam/session/sessionstorage"
 	"github.com/cccteam/session/sessionstorage/mock/mock_sessionstorage"
 	gomock "go.uber.org/mock/gomock"
 )
@@ -22,6 +24,7 @@ func TestPreauthAPI_Login(t *testing.T) {
 	tests := []struct {
 		name       string
 		username   string
+		rememberMe bool
 		prepare    func(*mock_sessionstorage.MockPreauthStore, *mock_cookie.MockHandler)
 		wantErr    bool
 		expectedID ccc.UUID
@@ -56,6 +59,28 @@ func TestPreauthAPI_Login(t *testing.T) {
 			},
 			expectedID: ccc.Must(ccc.UUIDFromString("123e4567-e89b-12d3-a456-426614174000")),
 		},
+		{
+			name:       "remember me creates a persistent session and cookie",
+			username:   "test_user",
+			rememberMe: true,
+			prepare: func(mockStorage *mock_sessionstorage.MockPreauthStore, mockCookies *mock_cookie.MockHandler) {
+				mockStorage.EXPECT().
+					NewPersistentSession(gomock.Any(), "test_user", time.Hour).
+					Return(ccc.Must(ccc.UUIDFromString("123e4567-e89b-12d3-a456-426614174000")), nil).
+					Times(1)
+
+				mockCookies.EXPECT().
+					NewPersistentAuthCookie(gomock.Any(), true, ccc.Must(ccc.UUIDFromString("123e4567-e89b-12d3-a456-426614174000")), time.Hour).
+					Return(cookie.NewValues()).
+					Times(1)
+
+				mockCookies.EXPECT().
+					CreateXSRFTokenCookie(gomock.Any(), gomock.Any()).
+					Return().
+					Times(1)
+			},
+			expectedID: ccc.Must(ccc.UUIDFromString("123e4567-e89b-12d3-a456-426614174000")),
+		},
 		{
 			name:     "failed session creation",
 			username: "test_user",
@@ -91,13 +116,14 @@ func TestPreauthAPI_Login(t *testing.T) {
 			preauth := &Preauth{
 				storage: mockStorage,
 				baseSession: &basesession.BaseSession{
-					CookieHandler: mockCookies,
-					Storage:       mockStorage,
+					CookieHandler:     mockCookies,
+					Storage:           mockStorage,
+					RememberMeTimeout: time.Hour,
 				},
 			}
 
 			// Call Login and capture the result
-			id, err := preauth.API().Login(context.Background(), w, tt.username)
+			id, err := preauth.API().Login(context.Background(), w, tt.username, tt.rememberMe)
 
 			// Validate the results
 			if (err != nil) != tt.wantErr {
@@ -118,3 +144,163 @@ func TestPreauthAPI_Login(t *testing.T) {
 		})
 	}
 }
+
+func TestPreauthAPI_UserSessions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name            string
+		filter          sessionstorage.SessionFilter
+		wantIdleTimeout time.Duration
+	}{
+		{
+			name:            "defaults the idle timeout to the session timeout",
+			filter:          sessionstorage.SessionFilter{State: sessionstorage.SessionStateActive},
+			wantIdleTimeout: time.Hour,
+		},
+		{
+			name:            "keeps an explicit idle timeout",
+			filter:          sessionstorage.SessionFilter{State: sessionstorage.SessionStateActive, IdleTimeout: time.Minute},
+			wantIdleTimeout: time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			mockStorage := mock_sessionstorage.NewMockPreauthStore(ctrl)
+			page := sessionstorage.Page{Size: 10}
+			want := &sessionstorage.SessionPage{NextCursor: "next"}
+			mockStorage.EXPECT().
+				UserSessions(gomock.Any(), "test_user", sessionstorage.SessionFilter{State: tt.filter.State, IdleTimeout: tt.wantIdleTimeout}, page).
+				Return(want, nil).
+				Times(1)
+
+			preauth := &Preauth{
+				storage: mockStorage,
+				baseSession: &basesession.BaseSession{
+					SessionTimeout: time.Hour,
+					Storage:        mockStorage,
+				},
+			}
+
+			got, err := preauth.API().UserSessions(context.Background(), "test_user", tt.filter, page)
+			if err != nil {
+				t.Fatalf("UserSessions() error = %v", err)
+			}
+			if got != want {
+				t.Errorf("UserSessions() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestPreauth_statelessStore(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		revoke     func(t *testing.T, p *Preauth, denylist *sessionstorage.MemoryDenylist, cookies []*http.Cookie) []*http.Cookie
+		wantStatus int
+	}{
+		{
+			name:       "session is read from the auth cookie",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "logout expires the session in the auth cookie",
+			revoke: func(t *testing.T, p *Preauth, _ *sessionstorage.MemoryDenylist, cookies []*http.Cookie) []*http.Cookie {
+				t.Helper()
+
+				return statelessRequest(t, p.StartSession(p.ValidateSession(p.Logout())), cookies, http.StatusOK)
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "impersonation is refused and keeps the session",
+			revoke: func(t *testing.T, p *Preauth, _ *sessionstorage.MemoryDenylist, cookies []*http.Cookie) []*http.Cookie {
+				t.Helper()
+
+				return statelessRequest(t, p.StartSession(p.ValidateSession(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if _, err := p.API().Impersonate(r.Context(), w, "other_user"); err == nil {
+						t.Error("PreauthAPI.Impersonate() error = nil, want error")
+					}
+				}))), cookies, http.StatusOK)
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "denied session is not valid with an old auth cookie",
+			revoke: func(t *testing.T, p *Preauth, denylist *sessionstorage.MemoryDenylist, cookies []*http.Cookie) []*http.Cookie {
+				t.Helper()
+
+				if err := denylist.DenyUserSessions(context.Background(), "test_user", time.Now()); err != nil {
+					t.Fatalf("MemoryDenylist.DenyUserSessions() error = %v", err)
+				}
+
+				return cookies
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			denylist := sessionstorage.NewMemoryDenylist(time.Hour)
+			p, err := NewPreauth(sessionstorage.NewStatelessPreauth(denylist), cookieKey,
+				WithImpersonationAuthorizer(func(context.Context, string, string) error { return nil }),
+			)
+			if err != nil {
+				t.Fatalf("NewPreauth() error = %v", err)
+			}
+
+			cookies := statelessRequest(t, p.StartSession(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if _, err := p.API().Login(r.Context(), w, "test_user", false); err != nil {
+					t.Errorf("PreauthAPI.Login() error = %v", err)
+				}
+			})), nil, http.StatusOK)
+
+			if tt.revoke != nil {
+				cookies = tt.revoke(t, p, denylist, cookies)
+			}
+
+			statelessRequest(t, p.StartSession(p.ValidateSession(p.Authenticated())), cookies, tt.wantStatus)
+		})
+	}
+}
+
+// statelessRequest serves a request with cookies and returns the cookies of the response merged into them
+func statelessRequest(t *testing.T, handler http.Handler, cookies []*http.Cookie, wantStatus int) []*http.Cookie {
+	t.Helper()
+
+	r := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/", http.NoBody)
+	for _, c := range cookies {
+		r.AddCookie(c)
+	}
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, r)
+
+	if w.Code != wantStatus {
+		t.Fatalf("status = %v, want %v, body = %s", w.Code, wantStatus, w.Body.String())
+	}
+
+	merged := make(map[string]*http.Cookie)
+	for _, c := range cookies {
+		merged[c.Name] = c
+	}
+	for _, c := range w.Result().Cookies() {
+		merged[c.Name] = &http.Cookie{Name: c.Name, Value: c.Value}
+	}
+
+	result := make([]*http.Cookie, 0, len(merged))
+	for _, c := range merged {
+		result = append(result, c)
+	}
+
+	return result
+}
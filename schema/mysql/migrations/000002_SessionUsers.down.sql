@@ -0,0 +1 @@
+DROP TABLE `SessionUsers`;
\ No newline at end of file
@@ -0,0 +1,20 @@
+-- Table: SessionUsers
+
+-- DROP TABLE `SessionUsers`;
+
+-- MySQL collations do not match PostgreSQL casefold(normalize()) so
+-- `NormalizedUsername` is computed by the storage driver when `Username` is written.
+CREATE TABLE `SessionUsers` (
+  `Id`                 CHAR(36) NOT NULL,
+  `Username`           VARCHAR(255) NOT NULL,
+  `NormalizedUsername` VARCHAR(255) NOT NULL,
+  `PasswordHash`       VARCHAR(255),
+  `Disabled`           BOOLEAN NOT NULL DEFAULT FALSE,
+  CONSTRAINT `SessionUsers_pkey` PRIMARY KEY (`Id`)
+) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_bin;
+
+-- DROP INDEX `SessionUsers_NormalizedUsername_idx` ON `SessionUsers`;
+
+CREATE UNIQUE INDEX `SessionUsers_NormalizedUsername_idx`
+    ON `SessionUsers`
+    (`NormalizedUsername`);
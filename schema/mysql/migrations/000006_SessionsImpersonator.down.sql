@@ -0,0 +1,3 @@
+ALTER TABLE `Sessions`
+    DROP COLUMN `ImpersonatorUsername`,
+    DROP COLUMN `ImpersonatorSessionId`;
@@ -0,0 +1,2 @@
+ALTER TABLE `Sessions`
+    DROP COLUMN `LastAuthenticatedAt`;
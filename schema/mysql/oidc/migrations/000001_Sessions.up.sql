@@ -0,0 +1,26 @@
+-- Table: Sessions
+
+-- DROP TABLE `Sessions`;
+
+CREATE TABLE `Sessions`
+(
+    `Id` CHAR(36) NOT NULL,
+    `OidcSid` VARCHAR(255) NOT NULL,
+    `Username` VARCHAR(255) NOT NULL,
+    `CreatedAt` DATETIME(6) NOT NULL,
+    `UpdatedAt` DATETIME(6) NOT NULL,
+    `Expired` BOOLEAN NOT NULL,
+    CONSTRAINT `Sessions_pkey` PRIMARY KEY (`Id`)
+) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_bin;
+
+-- DROP INDEX `Sessions_OidcSid_idx` ON `Sessions`;
+
+CREATE INDEX `Sessions_OidcSid_idx`
+    ON `Sessions`
+    (`OidcSid` ASC);
+
+-- DROP INDEX `Sessions_Expired_idx` ON `Sessions`;
+
+CREATE INDEX `Sessions_Expired_idx`
+    ON `Sessions`
+    (`Expired` ASC);
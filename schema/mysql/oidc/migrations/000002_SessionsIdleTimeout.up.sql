@@ -0,0 +1,5 @@
+-- A non-zero `IdleTimeoutSeconds` keeps a session, such as a remember-me session,
+-- valid for that many seconds without activity instead of the configured session timeout.
+
+ALTER TABLE `Sessions`
+    ADD COLUMN `IdleTimeoutSeconds` BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,4 @@
+ALTER TABLE `Sessions`
+    DROP COLUMN `ClientIp`,
+    DROP COLUMN `UserAgent`,
+    DROP COLUMN `AuthMethod`;
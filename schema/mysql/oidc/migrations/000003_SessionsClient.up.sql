@@ -0,0 +1,7 @@
+-- `ClientIp`, `UserAgent` and `AuthMethod` record where a session was created from and how
+-- the user authenticated. `CreatedAt` is the login time.
+
+ALTER TABLE `Sessions`
+    ADD COLUMN `ClientIp` VARCHAR(45) NOT NULL DEFAULT '',
+    ADD COLUMN `UserAgent` VARCHAR(1024) NOT NULL DEFAULT '',
+    ADD COLUMN `AuthMethod` VARCHAR(32) NOT NULL DEFAULT '';
@@ -0,0 +1,16 @@
+-- Table: SessionsData
+
+-- DROP TABLE `SessionsData`;
+
+-- `SessionsData` holds the server-side data of a session as a JSON value per `Name`.
+-- The rows follow their session when its `Id` is rotated and are deleted with it.
+CREATE TABLE `SessionsData`
+(
+    `SessionId` CHAR(36) NOT NULL,
+    `Name` VARCHAR(255) NOT NULL,
+    `Value` JSON NOT NULL,
+    `UpdatedAt` DATETIME(6) NOT NULL,
+    CONSTRAINT `SessionsData_pkey` PRIMARY KEY (`SessionId`, `Name`),
+    CONSTRAINT `SessionsData_SessionId_fkey` FOREIGN KEY (`SessionId`)
+        REFERENCES `Sessions` (`Id`) ON UPDATE CASCADE ON DELETE CASCADE
+) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_bin;
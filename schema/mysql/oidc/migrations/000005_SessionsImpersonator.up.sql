@@ -0,0 +1,6 @@
+-- `ImpersonatorUsername` and `ImpersonatorSessionId` record the user, and the session of that user,
+-- that started an impersonated session. They are empty for other sessions.
+
+ALTER TABLE `Sessions`
+    ADD COLUMN `ImpersonatorUsername` VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN `ImpersonatorSessionId` VARCHAR(36) NOT NULL DEFAULT '';
@@ -0,0 +1,5 @@
+-- `LastAuthenticatedAt` is when the user last proved their identity in the session, at login or
+-- by re-authenticating. Sessions created before it was recorded have the epoch and use `CreatedAt`.
+
+ALTER TABLE `Sessions`
+    ADD COLUMN `LastAuthenticatedAt` DATETIME(6) NOT NULL DEFAULT '1970-01-01 00:00:00';
@@ -0,0 +1,2 @@
+DROP INDEX `Sessions_OidcSid_idx` ON `Sessions`;
+ALTER TABLE `Sessions` DROP COLUMN `OidcSid`;
@@ -0,0 +1,11 @@
+-- Upgrades a `Sessions` table created by migrations/000001_Sessions
+-- to the columns and indexes of oidc/migrations/000001_Sessions.
+
+ALTER TABLE `Sessions`
+    ADD COLUMN `OidcSid` VARCHAR(255) NOT NULL DEFAULT '';
+
+-- DROP INDEX `Sessions_OidcSid_idx` ON `Sessions`;
+
+CREATE INDEX `Sessions_OidcSid_idx`
+    ON `Sessions`
+    (`OidcSid` ASC);
@@ -0,0 +1,10 @@
+BEGIN;
+
+-- "ImpersonatorUsername" and "ImpersonatorSessionId" record the user, and the session of that user,
+-- that started an impersonated session. They are empty for other sessions.
+
+ALTER TABLE "Sessions"
+    ADD COLUMN "ImpersonatorUsername" character varying NOT NULL DEFAULT '',
+    ADD COLUMN "ImpersonatorSessionId" character varying NOT NULL DEFAULT '';
+
+COMMIT;
@@ -0,0 +1,8 @@
+BEGIN;
+
+ALTER TABLE "Sessions"
+    DROP COLUMN "ClientIp",
+    DROP COLUMN "UserAgent",
+    DROP COLUMN "AuthMethod";
+
+COMMIT;
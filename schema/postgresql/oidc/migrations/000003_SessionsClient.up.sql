@@ -0,0 +1,11 @@
+BEGIN;
+
+-- "ClientIp", "UserAgent" and "AuthMethod" record where a session was created from and how
+-- the user authenticated. "CreatedAt" is the login time.
+
+ALTER TABLE "Sessions"
+    ADD COLUMN "ClientIp" character varying NOT NULL DEFAULT '',
+    ADD COLUMN "UserAgent" character varying NOT NULL DEFAULT '',
+    ADD COLUMN "AuthMethod" character varying NOT NULL DEFAULT '';
+
+COMMIT;
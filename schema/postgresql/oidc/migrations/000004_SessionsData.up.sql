@@ -0,0 +1,20 @@
+BEGIN;
+
+-- Table: SessionsData
+
+-- DROP TABLE "SessionsData";
+
+-- "SessionsData" holds the server-side data of a session as a JSON value per "Name".
+-- The rows follow their session when its "Id" is rotated and are deleted with it.
+CREATE TABLE "SessionsData"
+(
+    "SessionId" UUID NOT NULL,
+    "Name" character varying NOT NULL,
+    "Value" jsonb NOT NULL,
+    "UpdatedAt" timestamp without time zone NOT NULL,
+    CONSTRAINT "SessionsData_pkey" PRIMARY KEY ("SessionId", "Name"),
+    CONSTRAINT "SessionsData_SessionId_fkey" FOREIGN KEY ("SessionId")
+        REFERENCES "Sessions" ("Id") ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+COMMIT;
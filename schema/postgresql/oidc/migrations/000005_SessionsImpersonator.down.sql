@@ -0,0 +1,7 @@
+BEGIN;
+
+ALTER TABLE "Sessions"
+    DROP COLUMN "ImpersonatorUsername",
+    DROP COLUMN "ImpersonatorSessionId";
+
+COMMIT;
@@ -0,0 +1,15 @@
+// Package schema embeds the session storage migrations for each supported database.
+//
+// Each database directory contains a migrations tree for Preauth and PasswordAuth,
+// an oidc/migrations tree for OIDC, and an oidc/upgrade tree that converts a database
+// created from migrations into one usable by OIDC. The trees can be applied with any
+// migration tool, or with the Migrate functions in the sessionstorage package.
+package schema
+
+import "embed"
+
+// FS holds the migration files, rooted at the database directory names
+// (i.e. postgresql, spanner, sqlite, mysql).
+//
+//go:embed postgresql spanner sqlite mysql
+var FS embed.FS
@@ -0,0 +1,3 @@
+ALTER TABLE Sessions DROP COLUMN AuthMethod;
+ALTER TABLE Sessions DROP COLUMN UserAgent;
+ALTER TABLE Sessions DROP COLUMN ClientIp;
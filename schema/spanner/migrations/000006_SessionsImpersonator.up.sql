@@ -0,0 +1,2 @@
+ALTER TABLE Sessions ADD COLUMN ImpersonatorUsername STRING(MAX) NOT NULL DEFAULT ("");
+ALTER TABLE Sessions ADD COLUMN ImpersonatorSessionId STRING(MAX) NOT NULL DEFAULT ("");
@@ -0,0 +1 @@
+ALTER TABLE Sessions ADD COLUMN LastAuthenticatedAt TIMESTAMP NOT NULL DEFAULT (TIMESTAMP "1970-01-01T00:00:00Z");
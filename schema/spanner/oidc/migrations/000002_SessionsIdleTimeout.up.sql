@@ -0,0 +1 @@
+ALTER TABLE Sessions ADD COLUMN IdleTimeoutSeconds INT64 NOT NULL DEFAULT (0);
@@ -0,0 +1,3 @@
+ALTER TABLE Sessions ADD COLUMN ClientIp STRING(MAX) NOT NULL DEFAULT ("");
+ALTER TABLE Sessions ADD COLUMN UserAgent STRING(MAX) NOT NULL DEFAULT ("");
+ALTER TABLE Sessions ADD COLUMN AuthMethod STRING(MAX) NOT NULL DEFAULT ("");
@@ -0,0 +1,9 @@
+-- SessionsData holds the server-side data of a session as a JSON value per Name. It is interleaved
+-- in Sessions, so its key starts with the session Id and the rows are deleted with their session.
+CREATE TABLE SessionsData (
+    Id         STRING(36) NOT NULL,
+    Name       STRING(MAX) NOT NULL,
+    Value      JSON NOT NULL,
+    UpdatedAt  TIMESTAMP NOT NULL,
+) PRIMARY KEY (Id, Name),
+  INTERLEAVE IN PARENT Sessions ON DELETE CASCADE;
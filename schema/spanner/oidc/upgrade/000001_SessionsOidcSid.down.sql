@@ -0,0 +1,3 @@
+DROP INDEX SessionsByUsername;
+DROP INDEX SessionsByOidcSid;
+ALTER TABLE Sessions DROP COLUMN OidcSid;
@@ -0,0 +1,4 @@
+ALTER TABLE Sessions ADD COLUMN OidcSid STRING(MAX) NOT NULL DEFAULT ("");
+
+CREATE INDEX SessionsByOidcSid ON Sessions(OidcSid DESC);
+CREATE INDEX SessionsByUsername ON Sessions(Username);
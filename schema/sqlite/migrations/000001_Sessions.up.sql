@@ -0,0 +1,23 @@
+BEGIN;
+
+-- Table: Sessions
+
+-- DROP TABLE "Sessions";
+
+CREATE TABLE "Sessions"
+(
+    "Id" TEXT NOT NULL,
+    "Username" TEXT NOT NULL,
+    "CreatedAt" TIMESTAMP NOT NULL,
+    "UpdatedAt" TIMESTAMP NOT NULL,
+    "Expired" BOOLEAN NOT NULL,
+    CONSTRAINT "Sessions_pkey" PRIMARY KEY ("Id")
+);
+
+-- DROP INDEX "Sessions_Expired_idx";
+
+CREATE INDEX "Sessions_Expired_idx"
+    ON "Sessions"
+    ("Expired" ASC);
+
+COMMIT;
@@ -0,0 +1,24 @@
+BEGIN;
+
+-- Table: SessionUsers
+
+-- DROP TABLE "SessionUsers";
+
+-- SQLite has no Unicode aware casefold(normalize()) so "NormalizedUsername" is
+-- computed by the storage driver when "Username" is written.
+CREATE TABLE "SessionUsers" (
+  "Id"                 TEXT NOT NULL,
+  "Username"           TEXT NOT NULL,
+  "NormalizedUsername" TEXT NOT NULL,
+  "PasswordHash"       TEXT,
+  "Disabled"           BOOLEAN NOT NULL DEFAULT (FALSE),
+  CONSTRAINT "SessionUsers_pkey" PRIMARY KEY ("Id")
+);
+
+-- DROP INDEX "SessionUsers_NormalizedUsername_idx";
+
+CREATE UNIQUE INDEX "SessionUsers_NormalizedUsername_idx"
+    ON "SessionUsers"
+    ("NormalizedUsername");
+
+COMMIT;
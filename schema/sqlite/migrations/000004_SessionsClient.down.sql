@@ -0,0 +1,12 @@
+BEGIN;
+
+ALTER TABLE "Sessions"
+    DROP COLUMN "AuthMethod";
+
+ALTER TABLE "Sessions"
+    DROP COLUMN "UserAgent";
+
+ALTER TABLE "Sessions"
+    DROP COLUMN "ClientIp";
+
+COMMIT;
@@ -0,0 +1,20 @@
+BEGIN;
+
+-- Table: SessionsData
+
+-- DROP TABLE "SessionsData";
+
+-- "SessionsData" holds the server-side data of a session as a JSON value per "Name".
+-- Foreign keys are only enforced when enabled on the connection, so the storage
+-- driver also moves the rows when a session is rotated and deletes them when it is purged.
+CREATE TABLE "SessionsData" (
+  "SessionId" TEXT NOT NULL,
+  "Name"      TEXT NOT NULL,
+  "Value"     TEXT NOT NULL,
+  "UpdatedAt" TIMESTAMP NOT NULL,
+  CONSTRAINT "SessionsData_pkey" PRIMARY KEY ("SessionId", "Name"),
+  CONSTRAINT "SessionsData_SessionId_fkey" FOREIGN KEY ("SessionId")
+      REFERENCES "Sessions" ("Id") ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+COMMIT;
@@ -0,0 +1,9 @@
+BEGIN;
+
+ALTER TABLE "Sessions"
+    DROP COLUMN "ImpersonatorSessionId";
+
+ALTER TABLE "Sessions"
+    DROP COLUMN "ImpersonatorUsername";
+
+COMMIT;
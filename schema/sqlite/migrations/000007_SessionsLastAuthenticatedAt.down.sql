@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE "Sessions"
+    DROP COLUMN "LastAuthenticatedAt";
+
+COMMIT;
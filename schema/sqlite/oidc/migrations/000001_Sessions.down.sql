@@ -0,0 +1 @@
+DROP TABLE "Sessions";
\ No newline at end of file
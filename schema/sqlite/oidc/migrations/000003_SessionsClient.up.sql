@@ -0,0 +1,15 @@
+BEGIN;
+
+-- "ClientIp", "UserAgent" and "AuthMethod" record where a session was created from and how
+-- the user authenticated. "CreatedAt" is the login time.
+
+ALTER TABLE "Sessions"
+    ADD COLUMN "ClientIp" TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE "Sessions"
+    ADD COLUMN "UserAgent" TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE "Sessions"
+    ADD COLUMN "AuthMethod" TEXT NOT NULL DEFAULT '';
+
+COMMIT;
@@ -0,0 +1 @@
+DROP TABLE "SessionsData";
\ No newline at end of file
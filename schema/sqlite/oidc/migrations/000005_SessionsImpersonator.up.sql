@@ -0,0 +1,12 @@
+BEGIN;
+
+-- "ImpersonatorUsername" and "ImpersonatorSessionId" record the user, and the session of that user,
+-- that started an impersonated session. They are empty for other sessions.
+
+ALTER TABLE "Sessions"
+    ADD COLUMN "ImpersonatorUsername" TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE "Sessions"
+    ADD COLUMN "ImpersonatorSessionId" TEXT NOT NULL DEFAULT '';
+
+COMMIT;
@@ -0,0 +1,2 @@
+DROP INDEX "Sessions_OidcSid_idx";
+ALTER TABLE "Sessions" DROP COLUMN "OidcSid";
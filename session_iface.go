@@ -23,3 +23,10 @@ type UserRoleManager interface {
 
 // LogHandler defines the handler signature required for handling logs.
 type LogHandler = basesession.LogHandler
+
+// SessionHook is called on a transition of a session with the request context and the session information.
+type SessionHook = basesession.SessionHook
+
+// ImpersonationAuthorizer decides whether the user impersonator may impersonate the user target.
+// It returns an error to refuse the impersonation.
+type ImpersonationAuthorizer = basesession.ImpersonationAuthorizer
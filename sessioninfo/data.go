@@ -0,0 +1,166 @@
+package sessioninfo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/cccteam/ccc"
+	"github.com/go-playground/errors/v5"
+)
+
+// DataStore reads and writes the server-side data of sessions. It is implemented by the session storage.
+type DataStore interface {
+	// SessionData returns the data stored with the session by key.
+	SessionData(ctx context.Context, sessionID ccc.UUID) (map[string]json.RawMessage, error)
+	// UpdateSessionData writes the keys in set and removes the keys in deleted from the data stored with the session.
+	UpdateSessionData(ctx context.Context, sessionID ccc.UUID, set map[string]json.RawMessage, deleted []string) error
+}
+
+// SessionData is the server-side data of a session, stored as a JSON value per key. It is loaded on first
+// use and changes are kept in memory until Flush, which the ValidateSession middleware calls when the request ends.
+// Unlike the cookie values, the data is neither limited in size nor sent with every request.
+// It is safe for concurrent use.
+type SessionData struct {
+	store DataStore
+
+	mu        sync.Mutex
+	sessionID ccc.UUID
+	loaded    bool
+	values    map[string]json.RawMessage
+	set       map[string]json.RawMessage
+	deleted   map[string]bool
+}
+
+// NewSessionData returns the data of the session sessionID kept in store.
+func NewSessionData(store DataStore, sessionID ccc.UUID) *SessionData {
+	return &SessionData{
+		store:     store,
+		sessionID: sessionID,
+		set:       make(map[string]json.RawMessage),
+		deleted:   make(map[string]bool),
+	}
+}
+
+// DataFromRequest returns the session data from the request context.
+func DataFromRequest(r *http.Request) *SessionData {
+	return Data(r.Context())
+}
+
+// Data returns the session data from the context.
+func Data(ctx context.Context) *SessionData {
+	data, ok := ctx.Value(CtxSessionData).(*SessionData)
+	if !ok {
+		panic(fmt.Sprintf("failed to find %s in request context", CtxSessionData))
+	}
+
+	return data
+}
+
+// Get unmarshals the value stored under key into v. found is false when there is no value for key.
+func (d *SessionData) Get(ctx context.Context, key string, v any) (found bool, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	value, ok := d.set[key]
+	if !ok {
+		if d.deleted[key] {
+			return false, nil
+		}
+		if err := d.load(ctx); err != nil {
+			return false, err
+		}
+		if value, ok = d.values[key]; !ok {
+			return false, nil
+		}
+	}
+
+	if err := json.Unmarshal(value, v); err != nil {
+		return false, errors.Wrapf(err, "json.Unmarshal(): session data %q", key)
+	}
+
+	return true, nil
+}
+
+// Set stores v under key, marshaled as JSON.
+func (d *SessionData) Set(key string, v any) error {
+	if key == "" {
+		return errors.New("session data key must not be empty")
+	}
+
+	value, err := json.Marshal(v)
+	if err != nil {
+		return errors.Wrapf(err, "json.Marshal(): session data %q", key)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.set[key] = value
+	delete(d.deleted, key)
+
+	return nil
+}
+
+// Delete removes the value stored under key.
+func (d *SessionData) Delete(key string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.set, key)
+	d.deleted[key] = true
+}
+
+// SetSessionID moves the data to the new ID of a rotated session, keeping the unwritten changes.
+func (d *SessionData) SetSessionID(sessionID ccc.UUID) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.sessionID = sessionID
+}
+
+// Flush writes the changes made since the last Flush. It is a no-op when nothing changed.
+func (d *SessionData) Flush(ctx context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.set) == 0 && len(d.deleted) == 0 {
+		return nil
+	}
+
+	deleted := slices.Sorted(maps.Keys(d.deleted))
+	if err := d.store.UpdateSessionData(ctx, d.sessionID, d.set, deleted); err != nil {
+		return errors.Wrap(err, "sessioninfo.DataStore.UpdateSessionData()")
+	}
+
+	if d.loaded {
+		maps.Copy(d.values, d.set)
+		for _, key := range deleted {
+			delete(d.values, key)
+		}
+	}
+	d.set = make(map[string]json.RawMessage)
+	d.deleted = make(map[string]bool)
+
+	return nil
+}
+
+// load reads the stored values the first time they are needed. d.mu must be held.
+func (d *SessionData) load(ctx context.Context) error {
+	if d.loaded {
+		return nil
+	}
+
+	values, err := d.store.SessionData(ctx, d.sessionID)
+	if err != nil {
+		return errors.Wrap(err, "sessioninfo.DataStore.SessionData()")
+	}
+	d.values = values
+	d.loaded = true
+
+	return nil
+}
@@ -0,0 +1,161 @@
+package sessioninfo
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"testing"
+
+	"github.com/cccteam/ccc"
+	"github.com/go-playground/errors/v5"
+	"github.com/google/go-cmp/cmp"
+)
+
+// dataStore is a DataStore that keeps the data of a single session in memory
+type dataStore struct {
+	values  map[string]json.RawMessage
+	loads   int
+	updates int
+	err     error
+}
+
+func (s *dataStore) SessionData(_ context.Context, _ ccc.UUID) (map[string]json.RawMessage, error) {
+	s.loads++
+
+	return maps.Clone(s.values), s.err
+}
+
+func (s *dataStore) UpdateSessionData(_ context.Context, _ ccc.UUID, set map[string]json.RawMessage, deleted []string) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.updates++
+	maps.Copy(s.values, set)
+	for _, key := range deleted {
+		delete(s.values, key)
+	}
+
+	return nil
+}
+
+func TestSessionData(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := &dataStore{values: map[string]json.RawMessage{"theme": json.RawMessage(`"light"`), "step": json.RawMessage(`1`)}}
+	data := NewSessionData(store, ccc.Must(ccc.NewUUID()))
+
+	var theme string
+	if found, err := data.Get(ctx, "theme", &theme); err != nil || !found || theme != "light" {
+		t.Fatalf("SessionData.Get() = %q, %v, %v, want %q, true, nil", theme, found, err, "light")
+	}
+	if found, err := data.Get(ctx, "unknown", &theme); err != nil || found {
+		t.Errorf("SessionData.Get() of an unknown key found = %v, err = %v, want false, nil", found, err)
+	}
+
+	if err := data.Set("theme", "dark"); err != nil {
+		t.Fatalf("SessionData.Set() error = %v", err)
+	}
+	data.Delete("step")
+
+	// Changes are visible before they are written
+	if _, err := data.Get(ctx, "theme", &theme); err != nil || theme != "dark" {
+		t.Errorf("SessionData.Get() = %q, %v, want %q, nil", theme, err, "dark")
+	}
+	var step int
+	if found, err := data.Get(ctx, "step", &step); err != nil || found {
+		t.Errorf("SessionData.Get() of a deleted key found = %v, err = %v, want false, nil", found, err)
+	}
+	if store.updates != 0 {
+		t.Errorf("DataStore.UpdateSessionData() called %d times before Flush(), want 0", store.updates)
+	}
+
+	for range 2 {
+		if err := data.Flush(ctx); err != nil {
+			t.Fatalf("SessionData.Flush() error = %v", err)
+		}
+	}
+	if store.updates != 1 {
+		t.Errorf("DataStore.UpdateSessionData() called %d times, want 1", store.updates)
+	}
+	if diff := cmp.Diff(map[string]json.RawMessage{"theme": json.RawMessage(`"dark"`)}, store.values); diff != "" {
+		t.Errorf("stored data mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := data.Get(ctx, "theme", &theme); err != nil || theme != "dark" {
+		t.Errorf("SessionData.Get() after Flush() = %q, %v, want %q, nil", theme, err, "dark")
+	}
+	if store.loads != 1 {
+		t.Errorf("DataStore.SessionData() called %d times, want 1", store.loads)
+	}
+}
+
+func TestSessionData_errors(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("load error", func(t *testing.T) {
+		t.Parallel()
+
+		data := NewSessionData(&dataStore{err: errors.New("load failed")}, ccc.Must(ccc.NewUUID()))
+		var v string
+		if _, err := data.Get(ctx, "theme", &v); err == nil {
+			t.Errorf("SessionData.Get() error = nil, want an error")
+		}
+	})
+
+	t.Run("flush error keeps the changes", func(t *testing.T) {
+		t.Parallel()
+
+		store := &dataStore{values: map[string]json.RawMessage{}, err: errors.New("write failed")}
+		data := NewSessionData(store, ccc.Must(ccc.NewUUID()))
+		if err := data.Set("theme", "dark"); err != nil {
+			t.Fatalf("SessionData.Set() error = %v", err)
+		}
+		if err := data.Flush(ctx); err == nil {
+			t.Fatalf("SessionData.Flush() error = nil, want an error")
+		}
+
+		store.err = nil
+		if err := data.Flush(ctx); err != nil {
+			t.Fatalf("SessionData.Flush() error = %v", err)
+		}
+		if string(store.values["theme"]) != `"dark"` {
+			t.Errorf("stored theme = %s, want %q", store.values["theme"], `"dark"`)
+		}
+	})
+
+	t.Run("invalid values", func(t *testing.T) {
+		t.Parallel()
+
+		data := NewSessionData(&dataStore{values: map[string]json.RawMessage{"step": json.RawMessage(`"one"`)}}, ccc.Must(ccc.NewUUID()))
+		if err := data.Set("", "dark"); err == nil {
+			t.Errorf("SessionData.Set() with an empty key error = nil, want an error")
+		}
+		if err := data.Set("fn", func() {}); err == nil {
+			t.Errorf("SessionData.Set() of a func error = nil, want an error")
+		}
+		var step int
+		if _, err := data.Get(ctx, "step", &step); err == nil {
+			t.Errorf("SessionData.Get() into a mismatched type error = nil, want an error")
+		}
+	})
+}
+
+func TestData(t *testing.T) {
+	t.Parallel()
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("Data() did not panic without session data in the context")
+		}
+	}()
+
+	want := NewSessionData(&dataStore{}, ccc.Must(ccc.NewUUID()))
+	if got := Data(context.WithValue(context.Background(), CtxSessionData, want)); got != want {
+		t.Errorf("Data() = %p, want %p", got, want)
+	}
+
+	Data(context.Background())
+}
@@ -16,6 +16,14 @@ const (
 	CtxSessionInfo CTXKey = "sessionInfo"
 	// CtxUserInfo is the key used to store the UserInfo in the context.
 	CtxUserInfo CTXKey = "userInfo"
+	// CtxSessionExpiry is the key used to store the session Expiry in the context.
+	CtxSessionExpiry CTXKey = "sessionExpiry"
+	// CtxClient is the key used to store the Client creating a session in the context.
+	CtxClient CTXKey = "client"
+	// CtxSessionData is the key used to store the SessionData in the context.
+	CtxSessionData CTXKey = "sessionData"
+	// CtxImpersonator is the key used to store the Impersonator of a session in the context.
+	CtxImpersonator CTXKey = "impersonator"
 
 	// CTXSessionID is the key for storing SessionID in context
 	CTXSessionID CTXKey = "sessionID"
@@ -36,6 +44,39 @@ func FromCtx(ctx context.Context) *SessionInfo {
 	return sessionInfo
 }
 
+// ExpiryFromRequest returns the session expiry from the request context.
+func ExpiryFromRequest(r *http.Request) *Expiry {
+	return ExpiryFromCtx(r.Context())
+}
+
+// ExpiryFromCtx returns the session expiry from the context.
+func ExpiryFromCtx(ctx context.Context) *Expiry {
+	expiry, ok := ctx.Value(CtxSessionExpiry).(*Expiry)
+	if !ok {
+		panic(fmt.Sprintf("failed to find %s in request context", CtxSessionExpiry))
+	}
+
+	return expiry
+}
+
+// ClientFromCtx returns the client stored in the context, or an empty Client when there is none.
+func ClientFromCtx(ctx context.Context) *Client {
+	client, ok := ctx.Value(CtxClient).(*Client)
+	if !ok {
+		return &Client{}
+	}
+
+	return client
+}
+
+// ImpersonatorFromCtx returns the user impersonating the user of the session in the context.
+// ok is false when the session is not impersonated.
+func ImpersonatorFromCtx(ctx context.Context) (impersonator *Impersonator, ok bool) {
+	impersonator, ok = ctx.Value(CtxImpersonator).(*Impersonator)
+
+	return impersonator, ok
+}
+
 // IDFromRequest returns the sessionID from the request
 func IDFromRequest(r *http.Request) ccc.UUID {
 	return IDFromCtx(r.Context())
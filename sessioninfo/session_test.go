@@ -6,6 +6,7 @@ import (
 	"net/http/httptest"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/cccteam/ccc"
 )
@@ -97,3 +98,93 @@ func Test_userInfoFromRequest(t *testing.T) {
 		})
 	}
 }
+
+func Test_expiryFromRequest(t *testing.T) {
+	t.Parallel()
+	expiresAt := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name      string
+		r         *http.Request
+		want      *Expiry
+		wantPanic bool
+	}{
+		{
+			name:      "does not find expiry in request",
+			r:         httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/testPath", http.NoBody),
+			wantPanic: true,
+		},
+		{
+			name: "gets expiry from request",
+			r: func() *http.Request {
+				req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/testPath", http.NoBody)
+				req = req.WithContext(context.WithValue(context.Background(), CtxSessionExpiry, &Expiry{IdleExpiresAt: expiresAt}))
+
+				return req
+			}(),
+			want: &Expiry{IdleExpiresAt: expiresAt},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			defer func() {
+				if r := recover(); (r != nil) != tt.wantPanic {
+					t.Errorf("ExpiryFromRequest() panic = %v, wantPanic %v", r, tt.wantPanic)
+				}
+			}()
+
+			if got := ExpiryFromRequest(tt.r); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExpiryFromRequest() = %v, want %v", got, tt.want)
+			}
+			if got := ExpiryFromCtx(tt.r.Context()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExpiryFromCtx() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpiry_Remaining(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name         string
+		expiry       *Expiry
+		want         time.Duration
+		wantAbsolute bool
+	}{
+		{
+			name:   "idle only",
+			expiry: &Expiry{IdleExpiresAt: time.Now().Add(10 * time.Minute)},
+			want:   10 * time.Minute,
+		},
+		{
+			name:         "absolute limit comes first",
+			expiry:       &Expiry{IdleExpiresAt: time.Now().Add(10 * time.Minute), AbsoluteExpiresAt: time.Now().Add(time.Minute)},
+			want:         time.Minute,
+			wantAbsolute: true,
+		},
+		{
+			name:         "idle limit comes first",
+			expiry:       &Expiry{IdleExpiresAt: time.Now().Add(time.Minute), AbsoluteExpiresAt: time.Now().Add(10 * time.Minute)},
+			want:         time.Minute,
+			wantAbsolute: true,
+		},
+		{
+			name:   "expired",
+			expiry: &Expiry{IdleExpiresAt: time.Now().Add(-time.Minute)},
+			want:   0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tt.expiry.Remaining(); got > tt.want || got < tt.want-time.Second {
+				t.Errorf("Expiry.Remaining() = %v, want %v", got, tt.want)
+			}
+			if _, ok := tt.expiry.AbsoluteRemaining(); ok != tt.wantAbsolute {
+				t.Errorf("Expiry.AbsoluteRemaining() ok = %v, want %v", ok, tt.wantAbsolute)
+			}
+		})
+	}
+}
@@ -7,13 +7,91 @@ import (
 	"github.com/cccteam/ccc"
 )
 
+// AuthMethod is how the user of a session authenticated
+type AuthMethod string
+
+const (
+	// AuthMethodPassword is a session created by a username and password login.
+	AuthMethodPassword AuthMethod = "password"
+	// AuthMethodOIDC is a session created by an OIDC login.
+	AuthMethodOIDC AuthMethod = "oidc"
+	// AuthMethodPreauth is a session created for a pre-authenticated user.
+	AuthMethodPreauth AuthMethod = "preauth"
+	// AuthMethodImpersonation is a session started by another user to impersonate the user.
+	AuthMethodImpersonation AuthMethod = "impersonation"
+)
+
+// Client contains information about the client that is creating a session
+type Client struct {
+	// IP is the address of the client, taken from the X-Forwarded-For header
+	// when the request came through a trusted proxy.
+	IP         string
+	UserAgent  string
+	AuthMethod AuthMethod
+}
+
+// Impersonator is the user that started an impersonated session
+type Impersonator struct {
+	Username string
+	// SessionID is the session of the impersonator, which is resumed when the impersonation ends.
+	SessionID ccc.UUID
+}
+
 // SessionInfo struct contains information about a session
 type SessionInfo struct {
-	ID        ccc.UUID
-	Username  string
+	ID       ccc.UUID
+	Username string
+	// CreatedAt is the login time of the session.
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	Expired   bool
+	// IdleTimeout is how long the session stays valid without activity, such as for a
+	// remember-me session. It is zero when the session uses the configured session timeout.
+	IdleTimeout time.Duration
+	// ClientIP, UserAgent and AuthMethod record the client that created the session. They are
+	// empty for sessions created before they were recorded.
+	ClientIP   string
+	UserAgent  string
+	AuthMethod AuthMethod
+	// Impersonator is the user impersonating Username in the session. It is nil when the
+	// session is not impersonated.
+	Impersonator *Impersonator
+	// LastAuthenticatedAt is when the user last proved their identity in the session, at login
+	// or by re-authenticating.
+	LastAuthenticatedAt time.Time
+}
+
+// Expiry contains the times at which a session expires
+type Expiry struct {
+	// IdleExpiresAt is when the session expires if it is not used again.
+	IdleExpiresAt time.Time
+	// AbsoluteExpiresAt is when the session expires regardless of activity.
+	// It is the zero time when no maximum session lifetime is configured.
+	AbsoluteExpiresAt time.Time
+}
+
+// IdleRemaining returns the time left before the session expires if it is not used again.
+func (e *Expiry) IdleRemaining() time.Duration {
+	return max(time.Until(e.IdleExpiresAt), 0)
+}
+
+// AbsoluteRemaining returns the time left before the session expires regardless of activity.
+// ok is false when no maximum session lifetime is configured.
+func (e *Expiry) AbsoluteRemaining() (remaining time.Duration, ok bool) {
+	if e.AbsoluteExpiresAt.IsZero() {
+		return 0, false
+	}
+
+	return max(time.Until(e.AbsoluteExpiresAt), 0), true
+}
+
+// Remaining returns the time left before the session expires, whichever limit comes first.
+func (e *Expiry) Remaining() time.Duration {
+	if absolute, ok := e.AbsoluteRemaining(); ok {
+		return min(e.IdleRemaining(), absolute)
+	}
+
+	return e.IdleRemaining()
 }
 
 // UserInfo struct contains information about a user
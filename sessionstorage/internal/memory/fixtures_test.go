@@ -0,0 +1,112 @@
+package memory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cccteam/ccc"
+	"github.com/cccteam/ccc/securehash"
+	"github.com/cccteam/session/internal/dbtype"
+	"github.com/cccteam/session/sessionstorage/internal/normalize"
+)
+
+// fixture seeds the in-memory driver, mirroring the SQL fixtures used by the database driver tests.
+type fixture struct {
+	sessions []*session
+	users    []*dbtype.SessionUser
+}
+
+var (
+	// validSessions mirrors testdata/sessions_test/oidc_valid_sessions of the database drivers.
+	validSessions = []*session{
+		newSession("38bd570b-1280-421b-888e-a63f0ca35be7", "test user 1", "2019-02-01T05:10:20Z", "2020-01-02T08:05:03Z", false),
+		newSession("aa817d69-f550-474b-8eae-7b29da32e3a8", "test user 1", "2019-02-02T05:10:20Z", "2020-01-03T08:05:03Z", true),
+		newSession("eb0c72a4-1f32-469e-b51b-7baa589a944c", "test user 2", "2018-05-03T01:02:03Z", "2017-06-04T03:02:01Z", true),
+		newSession("095887e9-ab67-42c3-8090-6c50780606e3", "test user 2", "2018-05-04T01:02:03Z", "2017-06-05T03:02:01Z", false),
+		newSession("da8d6b11-8ef3-4134-8216-2dd0a94795ba", "test user 1", "2019-02-03T05:10:20Z", "2020-01-04T08:05:03Z", false),
+	}
+
+	// userSessions mirrors testdata/sessions_test/user_sessions of the database drivers.
+	userSessions = []*session{
+		newSession("11111111-1111-1111-1111-111111111111", "testUser", "2019-02-01T05:10:20Z", "2020-01-02T08:05:03Z", false),
+		newSession("22222222-2222-2222-2222-222222222222", "testUser", "2019-02-02T05:10:20Z", "2020-01-03T08:05:03Z", false),
+		newSession("33333333-3333-3333-3333-333333333333", "testUser", "2019-02-03T05:10:20Z", "2020-01-04T08:05:03Z", true),
+		newSession("44444444-4444-4444-4444-444444444444", "disableduser", "2019-02-04T05:10:20Z", "2020-01-05T08:05:03Z", false),
+	}
+
+	// validUsers mirrors testdata/users_test/valid_users of the database drivers.
+	validUsers = []*dbtype.SessionUser{
+		{
+			ID:           ccc.Must(ccc.UUIDFromString("27b43588-b743-4133-8730-e0439065a844")),
+			Username:     "testUser",
+			PasswordHash: mustHash("1$12288$3$1$k5UDxGNpdI0XrTY59KZvXg==.JNUcFFjrpbAj9pr1L8HkV8aNkeACBbc3SV0SSAjoPwM="),
+		},
+		{
+			ID:           ccc.Must(ccc.UUIDFromString("54918893-2342-4621-8673-79520a84b84f")),
+			Username:     "disableduser",
+			PasswordHash: mustHash("1$12288$3$1$k5UDxGNpdI0XrTY59KZvXg==.JNUcFFjrpbAj9pr1L8HkV8aNkeACBbc3SV0SSAjoPwM="),
+			Disabled:     true,
+		},
+	}
+)
+
+func newSession(id, username, createdAt, updatedAt string, expired bool) *session {
+	return &session{
+		Session: dbtype.Session{
+			ID:        ccc.Must(ccc.UUIDFromString(id)),
+			Username:  username,
+			CreatedAt: ccc.Must(time.Parse(time.RFC3339, createdAt)),
+			UpdatedAt: ccc.Must(time.Parse(time.RFC3339, updatedAt)),
+			Expired:   expired,
+		},
+		OidcSID: "oidc session " + id,
+	}
+}
+
+func mustHash(s string) *securehash.Hash {
+	hash := &securehash.Hash{}
+	if err := hash.UnmarshalText([]byte(s)); err != nil {
+		panic(err)
+	}
+
+	return hash
+}
+
+// prepareDriver creates a new SessionStorageDriver seeded with the given fixture.
+func prepareDriver(t *testing.T, f fixture) *SessionStorageDriver {
+	t.Helper()
+
+	d := NewSessionStorageDriver()
+	for _, s := range f.sessions {
+		sess := *s
+		d.sessions[sess.ID] = &sess
+	}
+	for _, u := range f.users {
+		user := *u
+		d.users[user.ID] = &user
+		d.normalizedUsername[normalize.Username(user.Username)] = user.ID
+	}
+
+	return d
+}
+
+// countSessions returns the number of sessions matching the predicate.
+func countSessions(d *SessionStorageDriver, match func(s *session) bool) int {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	var cnt int
+	for _, s := range d.sessions {
+		if match(s) {
+			cnt++
+		}
+	}
+
+	return cnt
+}
+
+func byUsername(username string, expired bool) func(s *session) bool {
+	return func(s *session) bool {
+		return s.Username == username && s.Expired == expired
+	}
+}
@@ -0,0 +1,527 @@
+// Package memory implements an in-memory session storage driver.
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/cccteam/ccc"
+	"github.com/cccteam/ccc/securehash"
+	"github.com/cccteam/ccc/tracer"
+	"github.com/cccteam/httpio"
+	"github.com/cccteam/session/internal/dbtype"
+	"github.com/cccteam/session/sessionstorage/internal/normalize"
+	"github.com/go-playground/errors/v5"
+)
+
+// session is a row of the in-memory Sessions table
+type session struct {
+	dbtype.Session
+	OidcSID string
+}
+
+// SessionStorageDriver represents the session storage implementation held in process memory.
+// It mirrors the behavior of the PostgreSQL driver and is safe for concurrent use.
+type SessionStorageDriver struct {
+	mu                 sync.RWMutex
+	sessions           map[ccc.UUID]*session
+	data               map[ccc.UUID]map[string]json.RawMessage
+	users              map[ccc.UUID]*dbtype.SessionUser
+	normalizedUsername map[string]ccc.UUID
+}
+
+// NewSessionStorageDriver creates a new SessionStorageDriver
+func NewSessionStorageDriver() *SessionStorageDriver {
+	return &SessionStorageDriver{
+		sessions:           make(map[ccc.UUID]*session),
+		data:               make(map[ccc.UUID]map[string]json.RawMessage),
+		users:              make(map[ccc.UUID]*dbtype.SessionUser),
+		normalizedUsername: make(map[string]ccc.UUID),
+	}
+}
+
+// SetSessionTableName is a no-op, the in-memory driver does not use tables.
+func (s *SessionStorageDriver) SetSessionTableName(_ string) {}
+
+// SetUserTableName is a no-op, the in-memory driver does not use tables.
+func (s *SessionStorageDriver) SetUserTableName(_ string) {}
+
+// Session returns the session information from the database for given sessionID
+func (s *SessionStorageDriver) Session(ctx context.Context, sessionID ccc.UUID) (*dbtype.Session, error) {
+	_, span := tracer.Start(ctx)
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sess, ok := s.sessions[sessionID]
+	if !ok {
+		return nil, httpio.NewNotFoundMessagef("session %q not found", sessionID)
+	}
+
+	session := sess.Session
+
+	return &session, nil
+}
+
+// UpdateSessionActivity updates the session activity column with the current time
+func (s *SessionStorageDriver) UpdateSessionActivity(ctx context.Context, sessionID ccc.UUID) error {
+	_, span := tracer.Start(ctx)
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.sessions[sessionID]
+	if !ok {
+		return httpio.NewNotFoundMessagef("session %q not found", sessionID)
+	}
+
+	sess.UpdatedAt = time.Now()
+
+	return nil
+}
+
+// UpdateSessionAuthentication sets the session activity and last authentication columns to the current time
+func (s *SessionStorageDriver) UpdateSessionAuthentication(ctx context.Context, sessionID ccc.UUID) error {
+	_, span := tracer.Start(ctx)
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.sessions[sessionID]
+	if !ok {
+		return httpio.NewNotFoundMessagef("session %q not found", sessionID)
+	}
+
+	now := time.Now()
+	sess.UpdatedAt = now
+	sess.LastAuthenticatedAt = now
+
+	return nil
+}
+
+// UpdateSessionsActivity sets the activity time of each session, skipping sessions that do not exist
+// or already have a later activity time
+func (s *SessionStorageDriver) UpdateSessionsActivity(ctx context.Context, activity []*dbtype.SessionActivity) error {
+	_, span := tracer.Start(ctx)
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, a := range activity {
+		if sess, ok := s.sessions[a.ID]; ok && sess.UpdatedAt.Before(a.UpdatedAt) {
+			sess.UpdatedAt = a.UpdatedAt
+		}
+	}
+
+	return nil
+}
+
+// InsertSession inserts a Session into database
+func (s *SessionStorageDriver) InsertSession(ctx context.Context, insertSession *dbtype.InsertSession) (ccc.UUID, error) {
+	_, span := tracer.Start(ctx)
+	defer span.End()
+
+	return s.insertSession(insertSession, "")
+}
+
+func (s *SessionStorageDriver) insertSession(insertSession *dbtype.InsertSession, oidcSID string) (ccc.UUID, error) {
+	id, err := ccc.NewUUID()
+	if err != nil {
+		return ccc.NilUUID, errors.Wrap(err, "ccc.NewUUID()")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if insertSession.Limit != nil {
+		if err := s.enforceLimit(insertSession.Username, insertSession.Limit); err != nil {
+			return ccc.NilUUID, err
+		}
+	}
+
+	s.sessions[id] = &session{
+		Session: dbtype.Session{
+			ID:                    id,
+			Username:              insertSession.Username,
+			CreatedAt:             insertSession.CreatedAt,
+			UpdatedAt:             insertSession.UpdatedAt,
+			Expired:               insertSession.Expired,
+			IdleTimeoutSeconds:    insertSession.IdleTimeoutSeconds,
+			ClientIP:              insertSession.ClientIP,
+			UserAgent:             insertSession.UserAgent,
+			AuthMethod:            insertSession.AuthMethod,
+			ImpersonatorUsername:  insertSession.ImpersonatorUsername,
+			ImpersonatorSessionID: insertSession.ImpersonatorSessionID,
+			LastAuthenticatedAt:   insertSession.LastAuthenticatedAt,
+		},
+		OidcSID: oidcSID,
+	}
+
+	return id, nil
+}
+
+// enforceLimit expires the oldest active sessions of username that do not fit in limit alongside a new session.
+// The caller must hold s.mu.
+func (s *SessionStorageDriver) enforceLimit(username string, limit *dbtype.InsertLimit) error {
+	var active []*session
+	for _, sess := range s.sessions {
+		if sess.Username == username && limit.Active(&sess.Session) {
+			active = append(active, sess)
+		}
+	}
+	// Oldest first
+	slices.SortFunc(active, func(a, b *session) int {
+		return dbtype.CompareSessions(&b.Session, &a.Session)
+	})
+
+	ids := make([]ccc.UUID, 0, len(active))
+	for _, sess := range active {
+		ids = append(ids, sess.ID)
+	}
+	evict, err := limit.Evict(ids)
+	if err != nil {
+		return errors.Wrap(err, "dbtype.InsertLimit.Evict()")
+	}
+	now := time.Now()
+	for _, id := range evict {
+		s.sessions[id].Expired = true
+		s.sessions[id].UpdatedAt = now
+	}
+
+	return nil
+}
+
+// DestroySession marks the session as expired
+func (s *SessionStorageDriver) DestroySession(ctx context.Context, sessionID ccc.UUID) error {
+	_, span := tracer.Start(ctx)
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Attempting to destroy a session that does not exist is something that
+	// can happen when a browser returns with old state. Erroring in this
+	// case is extra noise, so we will ignore instead.
+	if sess, ok := s.sessions[sessionID]; ok {
+		sess.Expired = true
+		sess.UpdatedAt = time.Now()
+	}
+
+	return nil
+}
+
+// RotateSession moves an active session to a new ID, keeping the rest of the row and its data
+func (s *SessionStorageDriver) RotateSession(ctx context.Context, sessionID ccc.UUID) (ccc.UUID, error) {
+	_, span := tracer.Start(ctx)
+	defer span.End()
+
+	id, err := ccc.NewUUID()
+	if err != nil {
+		return ccc.NilUUID, errors.Wrap(err, "ccc.NewUUID()")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.sessions[sessionID]
+	if !ok || sess.Expired {
+		return ccc.NilUUID, httpio.NewNotFoundMessagef("session %q not found", sessionID)
+	}
+
+	delete(s.sessions, sessionID)
+	sess.ID = id
+	s.sessions[id] = sess
+
+	if data, ok := s.data[sessionID]; ok {
+		delete(s.data, sessionID)
+		s.data[id] = data
+	}
+
+	return id, nil
+}
+
+// DestroyAllUserSessions destroys all sessions for a given user, including the sessions in which
+// the user impersonates another user
+func (s *SessionStorageDriver) DestroyAllUserSessions(ctx context.Context, username string) error {
+	_, span := tracer.Start(ctx)
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for _, sess := range s.sessions {
+		if sess.Username == username || sess.ImpersonatorUsername == username {
+			sess.Expired = true
+			sess.UpdatedAt = now
+		}
+	}
+
+	return nil
+}
+
+// User returns the user record associated with the user id
+func (s *SessionStorageDriver) User(ctx context.Context, id ccc.UUID) (*dbtype.SessionUser, error) {
+	_, span := tracer.Start(ctx)
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return nil, httpio.NewNotFoundMessagef("user id %q does not exist", id)
+	}
+
+	u := *user
+
+	return &u, nil
+}
+
+// UserByUserName returns the user record associated with the username
+func (s *SessionStorageDriver) UserByUserName(ctx context.Context, username string) (*dbtype.SessionUser, error) {
+	_, span := tracer.Start(ctx)
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	id, ok := s.normalizedUsername[normalize.Username(username)]
+	if !ok {
+		return nil, httpio.NewNotFoundMessagef("username %q does not exist", username)
+	}
+
+	u := *s.users[id]
+
+	return &u, nil
+}
+
+// CreateUser creates a new user
+func (s *SessionStorageDriver) CreateUser(ctx context.Context, insertUser *dbtype.InsertSessionUser) (*dbtype.SessionUser, error) {
+	_, span := tracer.Start(ctx)
+	defer span.End()
+
+	id, err := ccc.NewUUID()
+	if err != nil {
+		return nil, errors.Wrap(err, "ccc.NewUUID()")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	normalized := normalize.Username(insertUser.Username)
+	if _, ok := s.normalizedUsername[normalized]; ok {
+		return nil, httpio.NewConflictMessagef("username %q already exists", insertUser.Username)
+	}
+
+	s.users[id] = &dbtype.SessionUser{
+		ID:           id,
+		Username:     insertUser.Username,
+		PasswordHash: insertUser.PasswordHash,
+		Disabled:     insertUser.Disabled,
+	}
+	s.normalizedUsername[normalized] = id
+
+	u := *s.users[id]
+
+	return &u, nil
+}
+
+// SetUserUsername updates the user record and every active session row
+// for that user atomically.
+func (s *SessionStorageDriver) SetUserUsername(ctx context.Context, userID ccc.UUID, newUsername string) error {
+	_, span := tracer.Start(ctx)
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return httpio.NewNotFoundMessagef("user id %q does not exist", userID)
+	}
+
+	oldUsername := user.Username
+	oldNormalized := normalize.Username(oldUsername)
+	newNormalized := normalize.Username(newUsername)
+	if id, ok := s.normalizedUsername[newNormalized]; ok && id != userID {
+		return httpio.NewConflictMessagef("username %q already exists", newUsername)
+	}
+
+	delete(s.normalizedUsername, oldNormalized)
+	s.normalizedUsername[newNormalized] = userID
+	user.Username = newUsername
+
+	now := time.Now()
+	for _, sess := range s.sessions {
+		if sess.Username == oldUsername && !sess.Expired {
+			sess.Username = newUsername
+			sess.UpdatedAt = now
+		}
+	}
+
+	return nil
+}
+
+// SetUserPasswordHash updates the user password hash
+func (s *SessionStorageDriver) SetUserPasswordHash(ctx context.Context, userID ccc.UUID, hash *securehash.Hash) error {
+	_, span := tracer.Start(ctx)
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return httpio.NewNotFoundMessagef("user id %q does not exist", userID)
+	}
+
+	user.PasswordHash = hash
+
+	return nil
+}
+
+// DeactivateUser deactivates a user
+func (s *SessionStorageDriver) DeactivateUser(ctx context.Context, id ccc.UUID) error {
+	_, span := tracer.Start(ctx)
+	defer span.End()
+
+	return s.setUserDisabled(id, true)
+}
+
+// ActivateUser activates a user
+func (s *SessionStorageDriver) ActivateUser(ctx context.Context, id ccc.UUID) error {
+	_, span := tracer.Start(ctx)
+	defer span.End()
+
+	return s.setUserDisabled(id, false)
+}
+
+func (s *SessionStorageDriver) setUserDisabled(id ccc.UUID, disabled bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return httpio.NewNotFoundMessagef("user id %q does not exist", id)
+	}
+
+	user.Disabled = disabled
+
+	return nil
+}
+
+// DeleteUser deletes a user
+func (s *SessionStorageDriver) DeleteUser(ctx context.Context, id ccc.UUID) error {
+	_, span := tracer.Start(ctx)
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return httpio.NewNotFoundMessagef("user id %q does not exist", id)
+	}
+
+	delete(s.normalizedUsername, normalize.Username(user.Username))
+	delete(s.users, id)
+
+	return nil
+}
+
+// UserSessions returns up to query.Limit sessions for query.Username that match the query, newest first.
+func (s *SessionStorageDriver) UserSessions(ctx context.Context, query *dbtype.UserSessions) ([]*dbtype.Session, error) {
+	_, span := tracer.Start(ctx)
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var sessions []*dbtype.Session
+	for _, sess := range s.sessions {
+		if query.Matches(&sess.Session) {
+			session := sess.Session
+			sessions = append(sessions, &session)
+		}
+	}
+	slices.SortFunc(sessions, dbtype.CompareSessions)
+
+	return sessions[:min(len(sessions), query.Limit)], nil
+}
+
+// PurgeSessions deletes up to purge.Limit sessions that match the purge criteria
+// and returns the number of sessions deleted.
+func (s *SessionStorageDriver) PurgeSessions(ctx context.Context, purge *dbtype.PurgeSessions) (int64, error) {
+	_, span := tracer.Start(ctx)
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int64
+	for id, sess := range s.sessions {
+		if n >= int64(purge.Limit) {
+			break
+		}
+		if sess.Expired ||
+			sess.Idle(purge.IdleBefore, purge.Now) ||
+			(!purge.CreatedBefore.IsZero() && sess.CreatedAt.Before(purge.CreatedBefore)) {
+			delete(s.sessions, id)
+			delete(s.data, id)
+			n++
+		}
+	}
+
+	return n, nil
+}
+
+// SessionData returns the data stored with the session by key
+func (s *SessionStorageDriver) SessionData(ctx context.Context, sessionID ccc.UUID) (map[string]json.RawMessage, error) {
+	_, span := tracer.Start(ctx)
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	data := make(map[string]json.RawMessage, len(s.data[sessionID]))
+	for name, value := range s.data[sessionID] {
+		data[name] = slices.Clone(value)
+	}
+
+	return data, nil
+}
+
+// UpdateSessionData applies update to the data stored with the session
+func (s *SessionStorageDriver) UpdateSessionData(ctx context.Context, sessionID ccc.UUID, update *dbtype.SessionDataUpdate) error {
+	_, span := tracer.Start(ctx)
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.sessions[sessionID]; !ok {
+		return httpio.NewNotFoundMessagef("session %q not found", sessionID)
+	}
+
+	data, ok := s.data[sessionID]
+	if !ok {
+		data = make(map[string]json.RawMessage, len(update.Set))
+		s.data[sessionID] = data
+	}
+	for name, value := range update.Set {
+		data[name] = slices.Clone(value)
+	}
+	for _, name := range update.Deleted {
+		delete(data, name)
+	}
+
+	return nil
+}
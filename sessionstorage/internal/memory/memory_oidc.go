@@ -0,0 +1,59 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/cccteam/ccc"
+	"github.com/cccteam/ccc/tracer"
+	"github.com/cccteam/httpio"
+	"github.com/cccteam/session/internal/dbtype"
+)
+
+// InsertSessionOIDC inserts a Session into database
+func (s *SessionStorageDriver) InsertSessionOIDC(ctx context.Context, insertSession *dbtype.InsertOIDCSession) (ccc.UUID, error) {
+	_, span := tracer.Start(ctx)
+	defer span.End()
+
+	return s.insertSession(&insertSession.InsertSession, insertSession.OidcSID)
+}
+
+// DestroySessionOIDC marks the session as expired using the oidcSID
+func (s *SessionStorageDriver) DestroySessionOIDC(ctx context.Context, oidcSID string) error {
+	_, span := tracer.Start(ctx)
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	usernames := make(map[string]struct{})
+	for _, sess := range s.sessions {
+		if sess.OidcSID == oidcSID {
+			usernames[sess.Username] = struct{}{}
+		}
+	}
+
+	for _, sess := range s.sessions {
+		if _, ok := usernames[sess.Username]; ok && !sess.Expired {
+			sess.Expired = true
+		}
+	}
+
+	return nil
+}
+
+// UsernameOIDC returns the username of the session with the oidcSID
+func (s *SessionStorageDriver) UsernameOIDC(ctx context.Context, oidcSID string) (string, error) {
+	_, span := tracer.Start(ctx)
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, sess := range s.sessions {
+		if sess.OidcSID == oidcSID {
+			return sess.Username, nil
+		}
+	}
+
+	return "", httpio.NewNotFoundMessagef("session with oidc sid %q not found", oidcSID)
+}
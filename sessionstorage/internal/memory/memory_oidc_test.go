@@ -0,0 +1,77 @@
+package memory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cccteam/ccc"
+	"github.com/cccteam/session/internal/dbtype"
+)
+
+func TestSessionStorageDriver_InsertSessionOIDC(t *testing.T) {
+	t.Parallel()
+
+	c := prepareDriver(t, fixture{})
+
+	insert := &dbtype.InsertOIDCSession{
+		InsertSession: dbtype.InsertSession{
+			Username:  "test user 1",
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		},
+		OidcSID: "oidc session",
+	}
+
+	id, err := c.InsertSessionOIDC(t.Context(), insert)
+	if err != nil {
+		t.Fatalf("SessionStorageDriver.InsertSessionOIDC() error = %v", err)
+	}
+	if id == ccc.NilUUID {
+		t.Error("SessionStorageDriver.InsertSessionOIDC() id is nil, want valid UUID")
+	}
+	if got := c.sessions[id]; got.OidcSID != insert.OidcSID || got.Username != insert.Username {
+		t.Errorf("session = %v, want values from %v", got, insert)
+	}
+}
+
+func TestSessionStorageDriver_DestroySessionOIDC(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		oidcSID    string
+		wantActive map[string]int
+	}{
+		{
+			name:       "success without destroying the session (not found)",
+			oidcSID:    "oidc session 12345",
+			wantActive: map[string]int{"test user 1": 2, "test user 2": 1},
+		},
+		{
+			name:       "success destroying all sessions for the user",
+			oidcSID:    "oidc session aa817d69-f550-474b-8eae-7b29da32e3a8",
+			wantActive: map[string]int{"test user 1": 0, "test user 2": 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := prepareDriver(t, fixture{sessions: validSessions})
+
+			if err := c.DestroySessionOIDC(t.Context(), tt.oidcSID); err != nil {
+				t.Errorf("SessionStorageDriver.DestroySessionOIDC() error = %v", err)
+			}
+			for username, want := range tt.wantActive {
+				if got := countSessions(c, byUsername(username, false)); got != want {
+					t.Errorf("active sessions for %q = %d, want %d", username, got, want)
+				}
+			}
+			for _, s := range validSessions {
+				if got := c.sessions[s.ID].UpdatedAt; !got.Equal(s.UpdatedAt) {
+					t.Errorf("session %s UpdatedAt = %v, want unchanged %v", s.ID, got, s.UpdatedAt)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,831 @@
+package memory
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cccteam/ccc"
+	"github.com/cccteam/httpio"
+	"github.com/cccteam/session/internal/dbtype"
+)
+
+func TestSessionStorageDriver_Session(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		sessionID   ccc.UUID
+		wantSession *dbtype.Session
+		wantErr     bool
+	}{
+		{
+			name:        "success",
+			sessionID:   ccc.Must(ccc.UUIDFromString("eb0c72a4-1f32-469e-b51b-7baa589a944c")),
+			wantSession: &validSessions[2].Session,
+		},
+		{
+			name:      "not found",
+			sessionID: ccc.Must(ccc.NewUUID()),
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := prepareDriver(t, fixture{sessions: validSessions})
+
+			gotSession, err := c.Session(t.Context(), tt.sessionID)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SessionStorageDriver.Session() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !httpio.HasNotFound(err) {
+				t.Errorf("SessionStorageDriver.Session() error = %v, want NotFound", err)
+			}
+			if tt.wantSession != nil && *gotSession != *tt.wantSession {
+				t.Errorf("SessionStorageDriver.Session() = %v, want %v", gotSession, tt.wantSession)
+			}
+		})
+	}
+}
+
+func TestSessionStorageDriver_UpdateSessionActivity(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		sessionID ccc.UUID
+		wantErr   bool
+	}{
+		{
+			name:      "fails to find session",
+			sessionID: ccc.Must(ccc.UUIDFromString("ed0c72a4-1f32-469e-b51b-7baa589a945c")),
+			wantErr:   true,
+		},
+		{
+			name:      "success updating session activity",
+			sessionID: ccc.Must(ccc.UUIDFromString("eb0c72a4-1f32-469e-b51b-7baa589a944c")),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := prepareDriver(t, fixture{sessions: validSessions})
+
+			preExecTime := time.Now()
+			if err := c.UpdateSessionActivity(t.Context(), tt.sessionID); (err != nil) != tt.wantErr {
+				t.Errorf("SessionStorageDriver.UpdateSessionActivity() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && c.sessions[tt.sessionID].UpdatedAt.Before(preExecTime) {
+				t.Errorf("SessionStorageDriver.UpdateSessionActivity() UpdatedAt = %v, want after %v", c.sessions[tt.sessionID].UpdatedAt, preExecTime)
+			}
+		})
+	}
+}
+
+func TestSessionStorageDriver_UpdateSessionsActivity(t *testing.T) {
+	t.Parallel()
+
+	c := prepareDriver(t, fixture{sessions: validSessions})
+
+	updatedAt := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	existing := ccc.Must(ccc.UUIDFromString("eb0c72a4-1f32-469e-b51b-7baa589a944c"))
+	missing := ccc.Must(ccc.UUIDFromString("ed0c72a4-1f32-469e-b51b-7baa589a945c"))
+
+	activity := []*dbtype.SessionActivity{{ID: existing, UpdatedAt: updatedAt}, {ID: missing, UpdatedAt: updatedAt}}
+	if err := c.UpdateSessionsActivity(t.Context(), activity); err != nil {
+		t.Fatalf("SessionStorageDriver.UpdateSessionsActivity() error = %v", err)
+	}
+	if got := c.sessions[existing].UpdatedAt; !got.Equal(updatedAt) {
+		t.Errorf("SessionStorageDriver.UpdateSessionsActivity() UpdatedAt = %v, want %v", got, updatedAt)
+	}
+	if _, ok := c.sessions[missing]; ok {
+		t.Error("SessionStorageDriver.UpdateSessionsActivity() created a missing session")
+	}
+
+	stale := []*dbtype.SessionActivity{{ID: existing, UpdatedAt: updatedAt.Add(-time.Hour)}}
+	if err := c.UpdateSessionsActivity(t.Context(), stale); err != nil {
+		t.Fatalf("SessionStorageDriver.UpdateSessionsActivity() error = %v", err)
+	}
+	if got := c.sessions[existing].UpdatedAt; !got.Equal(updatedAt) {
+		t.Errorf("SessionStorageDriver.UpdateSessionsActivity() UpdatedAt = %v after stale activity, want %v", got, updatedAt)
+	}
+}
+
+func TestSessionStorageDriver_RotateSession(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		sessionID ccc.UUID
+		wantErr   bool
+	}{
+		{
+			name:      "success rotating an active session",
+			sessionID: ccc.Must(ccc.UUIDFromString("38bd570b-1280-421b-888e-a63f0ca35be7")),
+		},
+		{
+			name:      "fails to rotate an expired session",
+			sessionID: ccc.Must(ccc.UUIDFromString("aa817d69-f550-474b-8eae-7b29da32e3a8")),
+			wantErr:   true,
+		},
+		{
+			name:      "fails to rotate a missing session",
+			sessionID: ccc.Must(ccc.UUIDFromString("ed0c72a4-1f32-469e-b51b-7baa589a945c")),
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := prepareDriver(t, fixture{sessions: validSessions})
+			want, hadSession := c.sessions[tt.sessionID]
+			var wantSession session
+			if hadSession {
+				wantSession = *want
+			}
+
+			id, err := c.RotateSession(t.Context(), tt.sessionID)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SessionStorageDriver.RotateSession() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !httpio.HasNotFound(err) {
+					t.Errorf("SessionStorageDriver.RotateSession() error = %v, want not found", err)
+				}
+
+				return
+			}
+
+			if _, ok := c.sessions[tt.sessionID]; ok {
+				t.Error("SessionStorageDriver.RotateSession() kept the old session ID")
+			}
+			wantSession.ID = id
+			if got, ok := c.sessions[id]; !ok || *got != wantSession {
+				t.Errorf("SessionStorageDriver.RotateSession() session = %v, want %v", got, wantSession)
+			}
+		})
+	}
+}
+
+func TestSessionStorageDriver_InsertSession(t *testing.T) {
+	t.Parallel()
+
+	c := prepareDriver(t, fixture{sessions: validSessions})
+
+	insert := &dbtype.InsertSession{
+		Username:  "testuser",
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	id, err := c.InsertSession(t.Context(), insert)
+	if err != nil {
+		t.Fatalf("SessionStorageDriver.InsertSession() error = %v", err)
+	}
+	if id == ccc.NilUUID {
+		t.Error("SessionStorageDriver.InsertSession() id is nil, want valid UUID")
+	}
+	if got := len(c.sessions); got != len(validSessions)+1 {
+		t.Errorf("len(sessions) = %d, want %d", got, len(validSessions)+1)
+	}
+
+	got, err := c.Session(t.Context(), id)
+	if err != nil {
+		t.Fatalf("SessionStorageDriver.Session() error = %v", err)
+	}
+	if got.Username != insert.Username || !got.CreatedAt.Equal(insert.CreatedAt) || got.Expired {
+		t.Errorf("SessionStorageDriver.Session() = %v, want values from %v", got, insert)
+	}
+}
+
+func TestSessionStorageDriver_DestroySession(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		sessionID   ccc.UUID
+		wantExpired int
+	}{
+		{
+			name:        "success without destroying the session (not found)",
+			sessionID:   ccc.Must(ccc.UUIDFromString("52dd570b-1280-421b-888e-a63f0ca35be9")),
+			wantExpired: 2,
+		},
+		{
+			name:        "success destroying session",
+			sessionID:   ccc.Must(ccc.UUIDFromString("38bd570b-1280-421b-888e-a63f0ca35be7")),
+			wantExpired: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := prepareDriver(t, fixture{sessions: validSessions})
+
+			if err := c.DestroySession(t.Context(), tt.sessionID); err != nil {
+				t.Errorf("SessionStorageDriver.DestroySession() error = %v", err)
+			}
+			if got := countSessions(c, func(s *session) bool { return s.Expired }); got != tt.wantExpired {
+				t.Errorf("expired sessions = %d, want %d", got, tt.wantExpired)
+			}
+		})
+	}
+}
+
+func TestSessionStorageDriver_User(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		id       ccc.UUID
+		wantUser *dbtype.SessionUser
+		wantErr  bool
+	}{
+		{
+			name:     "success",
+			id:       validUsers[0].ID,
+			wantUser: validUsers[0],
+		},
+		{
+			name:    "not found",
+			id:      ccc.Must(ccc.NewUUID()),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := prepareDriver(t, fixture{users: validUsers})
+
+			gotUser, err := c.User(t.Context(), tt.id)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SessionStorageDriver.User() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !httpio.HasNotFound(err) {
+				t.Errorf("SessionStorageDriver.User() error = %v, want NotFound", err)
+			}
+			if tt.wantUser != nil && *gotUser != *tt.wantUser {
+				t.Errorf("SessionStorageDriver.User() = %v, want %v", gotUser, tt.wantUser)
+			}
+		})
+	}
+}
+
+func TestSessionStorageDriver_UserByUserName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		username string
+		wantUser *dbtype.SessionUser
+		wantErr  bool
+	}{
+		{
+			name:     "found user with case insensitive match",
+			username: "tESTUSer",
+			wantUser: validUsers[0],
+		},
+		{
+			name:     "success",
+			username: "testUser",
+			wantUser: validUsers[0],
+		},
+		{
+			name:     "not found",
+			username: "unknown",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := prepareDriver(t, fixture{users: validUsers})
+
+			gotUser, err := c.UserByUserName(t.Context(), tt.username)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SessionStorageDriver.UserByUserName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !httpio.HasNotFound(err) {
+				t.Errorf("SessionStorageDriver.UserByUserName() error = %v, want NotFound", err)
+			}
+			if tt.wantUser != nil && *gotUser != *tt.wantUser {
+				t.Errorf("SessionStorageDriver.UserByUserName() = %v, want %v", gotUser, tt.wantUser)
+			}
+		})
+	}
+}
+
+func TestSessionStorageDriver_CreateUser(t *testing.T) {
+	t.Parallel()
+
+	hash := mustHash("1$12288$3$1$k5UDxGNpdI0XrTY59KZvXg==.JNUcFFjrpbAj9pr1L8HkV8aNkeACBbc3SV0SSAjoPwM=")
+
+	tests := []struct {
+		name       string
+		username   string
+		wantErr    bool
+		wantErrMsg string
+	}{
+		{
+			name:     "success",
+			username: "newuser",
+		},
+		{
+			name:       "user already exists",
+			username:   "testuser",
+			wantErr:    true,
+			wantErrMsg: `username "testuser" already exists`,
+		},
+		{
+			name:       "user already exists after casefolding",
+			username:   "TESTUSER",
+			wantErr:    true,
+			wantErrMsg: `username "TESTUSER" already exists`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := prepareDriver(t, fixture{users: validUsers})
+
+			user, err := c.CreateUser(t.Context(), &dbtype.InsertSessionUser{Username: tt.username, PasswordHash: hash})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SessionStorageDriver.CreateUser() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !httpio.HasConflict(err) {
+					t.Errorf("SessionStorageDriver.CreateUser() error = %v, want Conflict", err)
+				}
+				if httpio.Message(err) != tt.wantErrMsg {
+					t.Errorf("SessionStorageDriver.CreateUser() error message = %s, want %s", httpio.Message(err), tt.wantErrMsg)
+				}
+
+				return
+			}
+			if _, err := c.UserByUserName(t.Context(), tt.username); err != nil {
+				t.Errorf("SessionStorageDriver.UserByUserName() error = %v", err)
+			}
+			if user.ID == ccc.NilUUID || user.Username != tt.username {
+				t.Errorf("SessionStorageDriver.CreateUser() = %v", user)
+			}
+		})
+	}
+}
+
+func TestSessionStorageDriver_SetUserUsername(t *testing.T) {
+	t.Parallel()
+
+	userID := validUsers[0].ID
+
+	tests := []struct {
+		name            string
+		id              ccc.UUID
+		newUsername     string
+		sessions        []*session
+		wantErr         bool
+		wantErrMsg      string
+		wantUsername    string
+		wantActive      map[string]int
+		wantExpiredUser map[string]int
+	}{
+		{
+			name:         "success updates user and active sessions, leaves expired and other users alone",
+			id:           userID,
+			newUsername:  "<username>",
+			sessions:     userSessions,
+			wantUsername: "<username>",
+			wantActive:   map[string]int{"<username>": 2, "testUser": 0, "disableduser": 1},
+			wantExpiredUser: map[string]int{
+				"testUser": 1,
+			},
+		},
+		{
+			name:         "success changing only the case of the username",
+			id:           userID,
+			newUsername:  "TESTUSER",
+			sessions:     userSessions,
+			wantUsername: "TESTUSER",
+			wantActive:   map[string]int{"TESTUSER": 2, "testUser": 0},
+		},
+		{
+			name:         "success when user has no active sessions",
+			id:           userID,
+			newUsername:  "<username>",
+			wantUsername: "<username>",
+		},
+		{
+			name:         "user not found",
+			id:           ccc.Must(ccc.NewUUID()),
+			newUsername:  "<username>",
+			sessions:     userSessions,
+			wantErr:      true,
+			wantUsername: "testUser",
+			wantActive:   map[string]int{"testUser": 2},
+		},
+		{
+			name:         "username already exists",
+			id:           userID,
+			newUsername:  "DisabledUser",
+			sessions:     userSessions,
+			wantErr:      true,
+			wantErrMsg:   `username "DisabledUser" already exists`,
+			wantUsername: "testUser",
+			wantActive:   map[string]int{"testUser": 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := prepareDriver(t, fixture{sessions: tt.sessions, users: validUsers})
+
+			err := c.SetUserUsername(t.Context(), tt.id, tt.newUsername)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SessionStorageDriver.SetUserUsername() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && tt.wantErrMsg != "" && httpio.Message(err) != tt.wantErrMsg {
+				t.Errorf("SessionStorageDriver.SetUserUsername() error message = %q, want %q", httpio.Message(err), tt.wantErrMsg)
+			}
+			if got := c.users[userID].Username; got != tt.wantUsername {
+				t.Errorf("user Username = %q, want %q", got, tt.wantUsername)
+			}
+			if _, err := c.UserByUserName(t.Context(), tt.wantUsername); err != nil {
+				t.Errorf("SessionStorageDriver.UserByUserName() error = %v", err)
+			}
+			for username, want := range tt.wantActive {
+				if got := countSessions(c, byUsername(username, false)); got != want {
+					t.Errorf("active sessions for %q = %d, want %d", username, got, want)
+				}
+			}
+			for username, want := range tt.wantExpiredUser {
+				if got := countSessions(c, byUsername(username, true)); got != want {
+					t.Errorf("expired sessions for %q = %d, want %d", username, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSessionStorageDriver_SetUserPasswordHash(t *testing.T) {
+	t.Parallel()
+
+	newHash := mustHash("1$12288$3$1$UdvSMfwCubeTKv05/UpxwA==.tr8oe8g0VvfjQp3XpJonme6edSA4diQLLrS64ksf/TM=")
+
+	tests := []struct {
+		name    string
+		id      ccc.UUID
+		wantErr bool
+	}{
+		{
+			name: "success",
+			id:   validUsers[0].ID,
+		},
+		{
+			name:    "user not found",
+			id:      ccc.Must(ccc.NewUUID()),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := prepareDriver(t, fixture{users: validUsers})
+
+			if err := c.SetUserPasswordHash(t.Context(), tt.id, newHash); (err != nil) != tt.wantErr {
+				t.Errorf("SessionStorageDriver.SetUserPasswordHash() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && c.users[tt.id].PasswordHash != newHash {
+				t.Errorf("PasswordHash = %v, want %v", c.users[tt.id].PasswordHash, newHash)
+			}
+		})
+	}
+}
+
+func TestSessionStorageDriver_ActivateDeactivateUser(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		id           ccc.UUID
+		activate     bool
+		wantDisabled bool
+		wantErr      bool
+	}{
+		{
+			name:         "deactivate",
+			id:           validUsers[0].ID,
+			wantDisabled: true,
+		},
+		{
+			name:     "activate",
+			id:       validUsers[1].ID,
+			activate: true,
+		},
+		{
+			name:    "deactivate user not found",
+			id:      ccc.Must(ccc.NewUUID()),
+			wantErr: true,
+		},
+		{
+			name:     "activate user not found",
+			id:       ccc.Must(ccc.NewUUID()),
+			activate: true,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := prepareDriver(t, fixture{users: validUsers})
+
+			var err error
+			if tt.activate {
+				err = c.ActivateUser(t.Context(), tt.id)
+			} else {
+				err = c.DeactivateUser(t.Context(), tt.id)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !httpio.HasNotFound(err) {
+					t.Errorf("error = %v, want NotFound", err)
+				}
+
+				return
+			}
+			if got := c.users[tt.id].Disabled; got != tt.wantDisabled {
+				t.Errorf("Disabled = %v, want %v", got, tt.wantDisabled)
+			}
+		})
+	}
+}
+
+func TestSessionStorageDriver_DeleteUser(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		id      ccc.UUID
+		wantErr bool
+	}{
+		{
+			name: "success",
+			id:   validUsers[0].ID,
+		},
+		{
+			name:    "user not found",
+			id:      ccc.Must(ccc.NewUUID()),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := prepareDriver(t, fixture{users: validUsers})
+
+			if err := c.DeleteUser(t.Context(), tt.id); (err != nil) != tt.wantErr {
+				t.Fatalf("SessionStorageDriver.DeleteUser() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if _, err := c.UserByUserName(t.Context(), validUsers[0].Username); !httpio.HasNotFound(err) {
+				t.Errorf("SessionStorageDriver.UserByUserName() error = %v, want NotFound", err)
+			}
+			if _, err := c.CreateUser(t.Context(), &dbtype.InsertSessionUser{Username: validUsers[0].Username}); err != nil {
+				t.Errorf("SessionStorageDriver.CreateUser() error = %v, want username to be reusable", err)
+			}
+		})
+	}
+}
+
+func TestSessionStorageDriver_DestroyAllUserSessions(t *testing.T) {
+	t.Parallel()
+
+	c := prepareDriver(t, fixture{sessions: validSessions})
+
+	if err := c.DestroyAllUserSessions(t.Context(), "test user 1"); err != nil {
+		t.Fatalf("SessionStorageDriver.DestroyAllUserSessions() error = %v", err)
+	}
+	if got := countSessions(c, byUsername("test user 1", false)); got != 0 {
+		t.Errorf("active sessions for test user 1 = %d, want 0", got)
+	}
+	if got := countSessions(c, byUsername("test user 2", false)); got != 1 {
+		t.Errorf("active sessions for test user 2 = %d, want 1", got)
+	}
+}
+
+func TestSessionStorageDriver_Concurrency(t *testing.T) {
+	t.Parallel()
+
+	c := prepareDriver(t, fixture{users: validUsers})
+	ctx := t.Context()
+
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Go(func() {
+			id, err := c.InsertSession(ctx, &dbtype.InsertSession{Username: "testUser", CreatedAt: time.Now(), UpdatedAt: time.Now()})
+			if err != nil {
+				t.Errorf("SessionStorageDriver.InsertSession() error = %v", err)
+
+				return
+			}
+			if err := c.UpdateSessionActivity(ctx, id); err != nil {
+				t.Errorf("SessionStorageDriver.UpdateSessionActivity() error = %v", err)
+			}
+			if _, err := c.UserByUserName(ctx, "testUser"); err != nil && !httpio.HasNotFound(err) {
+				t.Errorf("SessionStorageDriver.UserByUserName() error = %v", err)
+			}
+			if _, err := c.CreateUser(ctx, &dbtype.InsertSessionUser{Username: fmt.Sprintf("user%d", i%5)}); err != nil && !httpio.HasConflict(err) {
+				t.Errorf("SessionStorageDriver.CreateUser() error = %v", err)
+			}
+		})
+	}
+	wg.Wait()
+
+	if got := countSessions(c, byUsername("testUser", false)); got != 20 {
+		t.Errorf("active sessions = %d, want 20", got)
+	}
+	if got := len(c.users); got != len(validUsers)+5 {
+		t.Errorf("users = %d, want %d", got, len(validUsers)+5)
+	}
+}
+
+func TestSessionStorageDriver_PurgeSessions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		purge       *dbtype.PurgeSessions
+		wantDeleted int64
+		wantKept    []ccc.UUID
+	}{
+		{
+			name:        "deletes expired sessions",
+			purge:       &dbtype.PurgeSessions{Limit: 100},
+			wantDeleted: 2,
+			wantKept:    []ccc.UUID{validSessions[0].ID, validSessions[3].ID, validSessions[4].ID},
+		},
+		{
+			name: "deletes idle sessions",
+			purge: &dbtype.PurgeSessions{
+				IdleBefore: ccc.Must(time.Parse(time.RFC3339, "2019-01-01T00:00:00Z")),
+				Limit:      100,
+			},
+			wantDeleted: 3,
+			wantKept:    []ccc.UUID{validSessions[0].ID, validSessions[4].ID},
+		},
+		{
+			name: "deletes sessions older than the absolute age",
+			purge: &dbtype.PurgeSessions{
+				CreatedBefore: ccc.Must(time.Parse(time.RFC3339, "2019-02-02T00:00:00Z")),
+				Limit:         100,
+			},
+			wantDeleted: 4,
+			wantKept:    []ccc.UUID{validSessions[4].ID},
+		},
+		{
+			name:        "deletes at most limit rows",
+			purge:       &dbtype.PurgeSessions{Limit: 1},
+			wantDeleted: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := prepareDriver(t, fixture{sessions: validSessions})
+
+			got, err := c.PurgeSessions(t.Context(), tt.purge)
+			if err != nil {
+				t.Fatalf("SessionStorageDriver.PurgeSessions() error = %v", err)
+			}
+			if got != tt.wantDeleted {
+				t.Errorf("SessionStorageDriver.PurgeSessions() = %v, want %v", got, tt.wantDeleted)
+			}
+			if remaining := len(c.sessions); remaining != len(validSessions)-int(tt.wantDeleted) {
+				t.Errorf("remaining sessions = %d, want %d", remaining, len(validSessions)-int(tt.wantDeleted))
+			}
+			for _, id := range tt.wantKept {
+				if _, ok := c.sessions[id]; !ok {
+					t.Errorf("session %s was deleted, want kept", id)
+				}
+			}
+		})
+	}
+}
+
+func TestSessionStorageDriver_PurgeSessions_persistentSession(t *testing.T) {
+	t.Parallel()
+
+	c := NewSessionStorageDriver()
+	now := time.Now()
+	updatedAt := now.Add(-2 * time.Hour)
+	var ids []ccc.UUID
+	for _, idleTimeout := range []time.Duration{0, 3 * time.Hour} {
+		id, err := c.InsertSession(t.Context(), &dbtype.InsertSession{
+			Username:           "testUser",
+			CreatedAt:          updatedAt,
+			UpdatedAt:          updatedAt,
+			IdleTimeoutSeconds: int64(idleTimeout / time.Second),
+		})
+		if err != nil {
+			t.Fatalf("SessionStorageDriver.InsertSession() error = %v", err)
+		}
+		ids = append(ids, id)
+	}
+
+	// The persistent session is kept until its own idle timeout has passed
+	for _, tt := range []struct {
+		now  time.Time
+		want ccc.UUID
+	}{
+		{now: now, want: ids[0]},
+		{now: now.Add(2 * time.Hour), want: ids[1]},
+	} {
+		got, err := c.PurgeSessions(t.Context(), &dbtype.PurgeSessions{IdleBefore: tt.now.Add(-time.Hour), Now: tt.now, Limit: 100})
+		if err != nil {
+			t.Fatalf("SessionStorageDriver.PurgeSessions() error = %v", err)
+		}
+		if got != 1 {
+			t.Errorf("SessionStorageDriver.PurgeSessions() = %v, want 1", got)
+		}
+		if _, ok := c.sessions[tt.want]; ok {
+			t.Errorf("session %s was kept, want deleted", tt.want)
+		}
+	}
+}
+
+func TestSessionStorageDriver_UserSessions(t *testing.T) {
+	t.Parallel()
+
+	idleBefore := ccc.Must(time.Parse(time.RFC3339, "2020-01-03T12:00:00Z"))
+	tests := []struct {
+		name    string
+		query   *dbtype.UserSessions
+		wantIDs []string
+	}{
+		{
+			name:    "all sessions newest first",
+			query:   &dbtype.UserSessions{Username: "test user 1", Active: true, Expired: true, Limit: 10},
+			wantIDs: []string{"da8d6b11-8ef3-4134-8216-2dd0a94795ba", "aa817d69-f550-474b-8eae-7b29da32e3a8", "38bd570b-1280-421b-888e-a63f0ca35be7"},
+		},
+		{
+			name:    "active sessions",
+			query:   &dbtype.UserSessions{Username: "test user 1", Active: true, Limit: 10},
+			wantIDs: []string{"da8d6b11-8ef3-4134-8216-2dd0a94795ba", "38bd570b-1280-421b-888e-a63f0ca35be7"},
+		},
+		{
+			name:    "idle sessions are expired",
+			query:   &dbtype.UserSessions{Username: "test user 1", Expired: true, IdleBefore: idleBefore, Limit: 10},
+			wantIDs: []string{"aa817d69-f550-474b-8eae-7b29da32e3a8", "38bd570b-1280-421b-888e-a63f0ca35be7"},
+		},
+		{
+			name: "limit after cursor",
+			query: &dbtype.UserSessions{
+				Username: "test user 1",
+				Active:   true,
+				Expired:  true,
+				After: &dbtype.SessionCursor{
+					CreatedAt: ccc.Must(time.Parse(time.RFC3339, "2019-02-03T05:10:20Z")),
+					ID:        ccc.Must(ccc.UUIDFromString("da8d6b11-8ef3-4134-8216-2dd0a94795ba")),
+				},
+				Limit: 1,
+			},
+			wantIDs: []string{"aa817d69-f550-474b-8eae-7b29da32e3a8"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := prepareDriver(t, fixture{sessions: validSessions})
+
+			got, err := c.UserSessions(t.Context(), tt.query)
+			if err != nil {
+				t.Fatalf("SessionStorageDriver.UserSessions() error = %v", err)
+			}
+			gotIDs := make([]string, 0, len(got))
+			for _, s := range got {
+				gotIDs = append(gotIDs, s.ID.String())
+			}
+			if !slices.Equal(gotIDs, tt.wantIDs) {
+				t.Errorf("SessionStorageDriver.UserSessions() = %v, want %v", gotIDs, tt.wantIDs)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package mysql_test
+
+import (
+	"testing"
+
+	"github.com/cccteam/session/sessionstorage"
+	"github.com/cccteam/session/sessionstorage/internal/mysql"
+	mysqlstorage "github.com/cccteam/session/sessionstorage/mysql"
+	"github.com/cccteam/session/sessionstorage/storagetest"
+)
+
+func TestConformance(t *testing.T) {
+	t.Parallel()
+
+	storagetest.RunConformance(t, storagetest.Factory{
+		Preauth: func(t *testing.T) sessionstorage.PreauthStore {
+			return mysqlstorage.NewPreauth(mysql.PrepareDatabase(t, "file://../../../schema/mysql/migrations"))
+		},
+		PasswordAuth: func(t *testing.T) sessionstorage.PasswordAuthStore {
+			return mysqlstorage.NewPasswordAuth(mysql.PrepareDatabase(t, "file://../../../schema/mysql/migrations"))
+		},
+		OIDC: func(t *testing.T) sessionstorage.OIDCStore {
+			return mysqlstorage.NewOIDC(mysql.PrepareDatabase(t, "file://../../../schema/mysql/oidc/migrations"))
+		},
+	})
+}
@@ -0,0 +1,15 @@
+package mysql
+
+import "testing"
+
+// PrepareDatabase creates a migrated database for the tests in package mysql_test.
+func PrepareDatabase(t *testing.T, sourceURL ...string) Queryer {
+	t.Helper()
+
+	db, err := prepareDatabase(t.Context(), t, sourceURL...)
+	if err != nil {
+		t.Fatalf("prepareDatabase() error = %v", err)
+	}
+
+	return db.DB
+}
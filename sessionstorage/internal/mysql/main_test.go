@@ -0,0 +1,211 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"regexp"
+	"sync"
+	"testing"
+
+	"github.com/go-playground/errors/v5"
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/mysql" // database driver for the migrate package
+	_ "github.com/golang-migrate/migrate/v4/source/file"    // up/down script file source driver for the migrate package
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+const (
+	mysqlPort     = "3306/tcp"
+	mysqlPassword = "password"
+)
+
+var container *mysqlContainer
+
+// TestMain is a wrapper for the test suite. It creates a new mysqlContainer and runs the test suite.
+func TestMain(m *testing.M) {
+	ctx := context.Background()
+	c, err := newMySQLContainer(ctx, "8.4")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	container = c
+
+	exitCode := m.Run()
+
+	_ = c.root.Close()
+	if err := c.Terminate(ctx); err != nil {
+		fmt.Println(err)
+	}
+
+	os.Exit(exitCode)
+}
+
+// mysqlContainer represents a docker container running a MySQL instance.
+// db-initiator has no MySQL support, so this mirrors its PostgresContainer.
+type mysqlContainer struct {
+	testcontainers.Container
+	host string
+	port string
+	root *sql.DB
+
+	mu      sync.Mutex
+	dbCount int
+}
+
+// mysqlDatabase represents a MySQL database created and ready for migrations.
+type mysqlDatabase struct {
+	*sql.DB
+	connStr string
+}
+
+func newMySQLContainer(ctx context.Context, imageVersion string) (*mysqlContainer, error) {
+	req := testcontainers.ContainerRequest{
+		Image:        "mysql:" + imageVersion,
+		ExposedPorts: []string{mysqlPort},
+		Env: map[string]string{
+			"MYSQL_ROOT_PASSWORD": mysqlPassword,
+		},
+		WaitingFor: wait.ForSQL(mysqlPort, "mysql", func(host string, port string) string {
+			return mysqlDSN(host, port, "mysql")
+		}),
+	}
+
+	c, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		Started:          true,
+		ContainerRequest: req,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create container using ContainerRequest=%v", req)
+	}
+
+	host, err := c.Host(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "testcontainers.Container.Host()")
+	}
+
+	port, err := c.MappedPort(ctx, mysqlPort)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get external port for exposed port %s", mysqlPort)
+	}
+
+	root, err := sql.Open("mysql", mysqlDSN(host, port.Port(), "mysql"))
+	if err != nil {
+		return nil, errors.Wrap(err, "sql.Open()")
+	}
+
+	return &mysqlContainer{Container: c, host: host, port: port.Port(), root: root}, nil
+}
+
+var invalidDatabaseChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// CreateDatabase creates a new database with a name derived from dbName and returns a connection to it.
+func (c *mysqlContainer) CreateDatabase(ctx context.Context, dbName string) (*mysqlDatabase, error) {
+	c.mu.Lock()
+	c.dbCount++
+	dbName = fmt.Sprintf("db%d_%s", c.dbCount, invalidDatabaseChars.ReplaceAllString(dbName, "_"))
+	c.mu.Unlock()
+	if len(dbName) > 64 {
+		dbName = dbName[:64]
+	}
+
+	if _, err := c.root.ExecContext(ctx, fmt.Sprintf("CREATE DATABASE `%s`", dbName)); err != nil {
+		return nil, errors.Wrapf(err, "failed to create database=%q", dbName)
+	}
+
+	connStr := mysqlDSN(c.host, c.port, dbName)
+	db, err := sql.Open("mysql", connStr)
+	if err != nil {
+		return nil, errors.Wrap(err, "sql.Open()")
+	}
+
+	return &mysqlDatabase{DB: db, connStr: connStr}, nil
+}
+
+// MigrateUp will migrate all the way up, applying all up migrations from all sourceURL's
+func (db *mysqlDatabase) MigrateUp(sourceURL ...string) error {
+	for _, source := range sourceURL {
+		m, err := migrate.New(source, "mysql://"+db.connStr)
+		if err != nil {
+			return errors.Wrapf(err, "migrate.New(): fileURL=%s", source)
+		}
+
+		if _, _, err := m.Version(); err == nil {
+			if err := m.Force(-1); err != nil {
+				return errors.Wrapf(err, "migrate.Migrate.Force(): %s", source)
+			}
+		}
+
+		if err := m.Up(); err != nil {
+			return errors.Wrapf(err, "migrate.Migrate.Up(): %s", source)
+		}
+
+		if err, dbErr := m.Close(); err != nil {
+			return errors.Wrapf(err, "migrate.Migrate.Close(): source error: %s", source)
+		} else if dbErr != nil {
+			return errors.Wrapf(dbErr, "migrate.Migrate.Close(): database error: %s", source)
+		}
+	}
+
+	return nil
+}
+
+// MigrateDown will migrate all the way down
+func (db *mysqlDatabase) MigrateDown(sourceURL string) error {
+	m, err := migrate.New(sourceURL, "mysql://"+db.connStr)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create new migrate with fileURL=%s", sourceURL)
+	}
+
+	if err := m.Down(); err != nil {
+		return errors.Wrap(err, "migrate.Migrate.Down()")
+	}
+
+	if err, dbErr := m.Close(); err != nil {
+		return errors.Wrap(err, "migrate.Migrate.Close(): source error")
+	} else if dbErr != nil {
+		return errors.Wrap(dbErr, "migrate.Migrate.Close(): database error")
+	}
+
+	return nil
+}
+
+func mysqlDSN(host, port, database string) string {
+	return fmt.Sprintf("root:%s@tcp(%s:%s)/%s?parseTime=true&multiStatements=true", mysqlPassword, host, port, database)
+}
+
+// prepareDatabase creates a new database and runs migrations given a variadic param of sourceURL(s).
+func prepareDatabase(ctx context.Context, t *testing.T, sourceURL ...string) (*mysqlDatabase, error) {
+	db, err := container.CreateDatabase(ctx, t.Name())
+	if err != nil {
+		return nil, errors.Wrapf(err, "mysqlContainer.CreateDatabase()")
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	if err := db.MigrateUp(sourceURL...); err != nil {
+		return nil, errors.Wrapf(err, "mysqlDatabase.MigrateUp()")
+	}
+
+	return db, nil
+}
+
+// runAssertions executes a series of provided assertions. Each assertion is represented by a SQL query
+// that must return a boolean value. The query's result must be true for the assertion to pass.
+func runAssertions(ctx context.Context, t *testing.T, q Queryer, assertions []string) {
+	t.Helper()
+
+	for i, query := range assertions {
+		var isTrue bool
+		if err := q.QueryRowContext(ctx, query).Scan(&isTrue); err != nil {
+			t.Errorf("sql.Row.Scan(): Assertion %d for test %q failed. %v", i+1, t.Name(), err)
+
+			continue
+		}
+		if !isTrue {
+			t.Errorf("Assertion %d for test %q failed", i+1, t.Name())
+		}
+	}
+}
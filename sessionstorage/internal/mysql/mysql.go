@@ -0,0 +1,777 @@
+// Package mysql implements the session storage driver for MySQL and MariaDB.
+//
+// The driver is written against github.com/go-sql-driver/mysql, which registers itself
+// with database/sql under the name "mysql" when this package is imported. The DSN
+// must set parseTime=true so DATETIME columns scan into time.Time.
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cccteam/ccc"
+	"github.com/cccteam/ccc/securehash"
+	"github.com/cccteam/ccc/tracer"
+	"github.com/cccteam/httpio"
+	"github.com/cccteam/session/internal/dbtype"
+	"github.com/cccteam/session/sessionstorage/internal/normalize"
+	"github.com/georgysavva/scany/v2/sqlscan"
+	"github.com/go-playground/errors/v5"
+	"github.com/go-sql-driver/mysql"
+)
+
+// erDupEntry is the MySQL error number for a duplicate entry on a unique index.
+const erDupEntry = 1062
+
+// idleCondition selects idle sessions, as defined by dbtype.Session.Idle. Its arguments are
+// the IdleBefore and Now times.
+const idleCondition = `(UpdatedAt < ? AND (IdleTimeoutSeconds = 0 OR DATE_ADD(UpdatedAt, INTERVAL IdleTimeoutSeconds SECOND) < ?))`
+
+// SessionStorageDriver represents the session storage implementation for MySQL.
+type SessionStorageDriver struct {
+	conn             Queryer
+	sessionTableName string
+	userTableName    string
+}
+
+// NewSessionStorageDriver creates a new SessionStorageDriver
+func NewSessionStorageDriver(conn Queryer) *SessionStorageDriver {
+	return &SessionStorageDriver{
+		conn:             conn,
+		sessionTableName: "Sessions",
+		userTableName:    "SessionUsers",
+	}
+}
+
+// SetSessionTableName sets the name of the session table.
+func (s *SessionStorageDriver) SetSessionTableName(name string) {
+	s.sessionTableName = name
+}
+
+// dataTableName returns the name of the table holding the data of the sessions in the session table.
+func (s *SessionStorageDriver) dataTableName() string {
+	return s.sessionTableName + dbtype.SessionDataTableSuffix
+}
+
+// SetUserTableName sets the name of the user table.
+func (s *SessionStorageDriver) SetUserTableName(name string) {
+	s.userTableName = name
+}
+
+// Session returns the session information from the database for given sessionID
+func (s *SessionStorageDriver) Session(ctx context.Context, sessionID ccc.UUID) (*dbtype.Session, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	query := fmt.Sprintf(`
+		SELECT
+			Id,
+			Username,
+			CreatedAt,
+			UpdatedAt,
+			Expired,
+			IdleTimeoutSeconds,
+			ClientIp,
+			UserAgent,
+			AuthMethod,
+			ImpersonatorUsername,
+			ImpersonatorSessionId,
+			LastAuthenticatedAt
+		FROM %s
+		WHERE Id = ?
+	`, s.sessionTableName)
+
+	session := &dbtype.Session{}
+	if err := sqlscan.Get(ctx, s.conn, session, query, sessionID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, httpio.NewNotFoundMessagef("session %q not found", sessionID)
+		}
+
+		return nil, errors.Wrap(err, "sqlscan.Get()")
+	}
+
+	return session, nil
+}
+
+// UpdateSessionActivity updates the session activity column with the current time
+func (s *SessionStorageDriver) UpdateSessionActivity(ctx context.Context, sessionID ccc.UUID) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	query := fmt.Sprintf(`
+		UPDATE %s SET UpdatedAt = ?
+		WHERE Id = ?`, s.sessionTableName)
+
+	res, err := s.conn.ExecContext(ctx, query, time.Now(), sessionID)
+	if err != nil {
+		return errors.Wrap(err, "Queryer.ExecContext()")
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return errors.Wrap(err, "sql.Result.RowsAffected()")
+	} else if n == 0 {
+		if exists, err := s.rowExists(ctx, s.sessionTableName, sessionID); err != nil {
+			return err
+		} else if !exists {
+			return httpio.NewNotFoundMessagef("session %q not found", sessionID)
+		}
+	}
+
+	return nil
+}
+
+// UpdateSessionAuthentication sets the session activity and last authentication columns to the current time
+func (s *SessionStorageDriver) UpdateSessionAuthentication(ctx context.Context, sessionID ccc.UUID) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	query := fmt.Sprintf(`
+		UPDATE %s SET UpdatedAt = ?, LastAuthenticatedAt = ?
+		WHERE Id = ?`, s.sessionTableName)
+
+	now := time.Now()
+	res, err := s.conn.ExecContext(ctx, query, now, now, sessionID)
+	if err != nil {
+		return errors.Wrap(err, "Queryer.ExecContext()")
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return errors.Wrap(err, "sql.Result.RowsAffected()")
+	} else if n == 0 {
+		if exists, err := s.rowExists(ctx, s.sessionTableName, sessionID); err != nil {
+			return err
+		} else if !exists {
+			return httpio.NewNotFoundMessagef("session %q not found", sessionID)
+		}
+	}
+
+	return nil
+}
+
+// UpdateSessionsActivity sets the activity time of each session in a single statement,
+// skipping sessions that do not exist and never moving an activity time backwards
+func (s *SessionStorageDriver) UpdateSessionsActivity(ctx context.Context, activity []*dbtype.SessionActivity) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	if len(activity) == 0 {
+		return nil
+	}
+
+	var cases strings.Builder
+	caseArgs := make([]any, 0, 2*len(activity))
+	ids := make([]any, 0, len(activity))
+	for _, a := range activity {
+		cases.WriteString(" WHEN ? THEN ?")
+		caseArgs = append(caseArgs, a.ID, a.UpdatedAt)
+		ids = append(ids, a.ID)
+	}
+
+	query := fmt.Sprintf(`
+		UPDATE %s SET UpdatedAt = GREATEST(UpdatedAt, CASE Id%s END)
+		WHERE Id IN (%s)`, s.sessionTableName, cases.String(), placeholders(len(ids)))
+
+	if _, err := s.conn.ExecContext(ctx, query, append(caseArgs, ids...)...); err != nil {
+		return errors.Wrap(err, "Queryer.ExecContext()")
+	}
+
+	return nil
+}
+
+// InsertSession inserts a Session into database
+func (s *SessionStorageDriver) InsertSession(ctx context.Context, insertSession *dbtype.InsertSession) (ccc.UUID, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	id, err := ccc.NewUUID()
+	if err != nil {
+		return ccc.NilUUID, errors.Wrap(err, "ccc.NewUUID()")
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO %s
+			(Id, Username, CreatedAt, UpdatedAt, Expired, IdleTimeoutSeconds, ClientIp, UserAgent, AuthMethod, ImpersonatorUsername, ImpersonatorSessionId, LastAuthenticatedAt)
+		VALUES
+			(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, s.sessionTableName)
+
+	// DATETIME can not hold the zero time of an impersonated session, which has no authentication time
+	lastAuthenticatedAt := insertSession.LastAuthenticatedAt
+	if lastAuthenticatedAt.IsZero() {
+		lastAuthenticatedAt = time.Unix(0, 0).UTC()
+	}
+
+	args := []any{id, insertSession.Username, insertSession.CreatedAt, insertSession.UpdatedAt, insertSession.Expired, insertSession.IdleTimeoutSeconds, insertSession.ClientIP, insertSession.UserAgent, insertSession.AuthMethod, insertSession.ImpersonatorUsername, insertSession.ImpersonatorSessionID, lastAuthenticatedAt}
+	if err := s.insertSession(ctx, query, args, insertSession.Username, insertSession.Limit); err != nil {
+		return ccc.NilUUID, err
+	}
+
+	return id, nil
+}
+
+// insertSession runs the query that inserts a session. When limit is set, the oldest active
+// sessions of username that do not fit in it are expired in the same transaction.
+func (s *SessionStorageDriver) insertSession(ctx context.Context, query string, args []any, username string, limit *dbtype.InsertLimit) error {
+	if limit == nil {
+		if _, err := s.conn.ExecContext(ctx, query, args...); err != nil {
+			return errors.Wrap(err, "Queryer.ExecContext()")
+		}
+
+		return nil
+	}
+
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "Queryer.BeginTx()")
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	conditions := []string{`Username = ?`, `NOT Expired`}
+	selectArgs := []any{username}
+	if !limit.IdleBefore.IsZero() {
+		conditions = append(conditions, `NOT `+idleCondition)
+		selectArgs = append(selectArgs, limit.IdleBefore, limit.Now)
+	}
+	if !limit.CreatedBefore.IsZero() {
+		conditions = append(conditions, `CreatedAt >= ?`)
+		selectArgs = append(selectArgs, limit.CreatedBefore)
+	}
+
+	// The row locks serialize concurrent logins of the user
+	selectQuery := fmt.Sprintf(`
+		SELECT Id FROM %s
+		WHERE %s
+		ORDER BY CreatedAt, Id
+		FOR UPDATE`, s.sessionTableName, strings.Join(conditions, " AND "))
+
+	var active []ccc.UUID
+	if err := sqlscan.Select(ctx, tx, &active, selectQuery, selectArgs...); err != nil {
+		return errors.Wrap(err, "sqlscan.Select()")
+	}
+
+	evict, err := limit.Evict(active)
+	if err != nil {
+		return errors.Wrap(err, "dbtype.InsertLimit.Evict()")
+	}
+	if len(evict) > 0 {
+		expireQuery := fmt.Sprintf(`
+			UPDATE %s SET Expired = TRUE, UpdatedAt = ?
+			WHERE Id IN (%s)`, s.sessionTableName, placeholders(len(evict)))
+
+		evictArgs := make([]any, 0, len(evict)+1)
+		evictArgs = append(evictArgs, time.Now())
+		for _, sessionID := range evict {
+			evictArgs = append(evictArgs, sessionID)
+		}
+		if _, err := tx.ExecContext(ctx, expireQuery, evictArgs...); err != nil {
+			return errors.Wrap(err, "sql.Tx.ExecContext()")
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return errors.Wrap(err, "sql.Tx.ExecContext()")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, "sql.Tx.Commit()")
+	}
+
+	return nil
+}
+
+// DestroySession marks the session as expired
+func (s *SessionStorageDriver) DestroySession(ctx context.Context, sessionID ccc.UUID) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	query := fmt.Sprintf(`
+		UPDATE %s SET Expired = TRUE, UpdatedAt = ?
+		WHERE Id = ?`, s.sessionTableName)
+
+	// Attempting to destroy a session that does not exist is something that
+	// can happen when a browser returns with old state. Erroring in this
+	// case is extra noise, so we will ignore instead.
+	if _, err := s.conn.ExecContext(ctx, query, time.Now(), sessionID); err != nil {
+		return errors.Wrap(err, "Queryer.ExecContext()")
+	}
+
+	return nil
+}
+
+// RotateSession moves an active session to a new ID, keeping the rest of the row. The session data follows
+// through the ON UPDATE CASCADE foreign key
+func (s *SessionStorageDriver) RotateSession(ctx context.Context, sessionID ccc.UUID) (ccc.UUID, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	id, err := ccc.NewUUID()
+	if err != nil {
+		return ccc.NilUUID, errors.Wrap(err, "ccc.NewUUID()")
+	}
+
+	query := fmt.Sprintf(`
+		UPDATE %s SET Id = ?
+		WHERE Id = ? AND NOT Expired`, s.sessionTableName)
+
+	res, err := s.conn.ExecContext(ctx, query, id, sessionID)
+	if err != nil {
+		return ccc.NilUUID, errors.Wrap(err, "Queryer.ExecContext()")
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return ccc.NilUUID, errors.Wrap(err, "sql.Result.RowsAffected()")
+	} else if n == 0 {
+		return ccc.NilUUID, httpio.NewNotFoundMessagef("session %q not found", sessionID)
+	}
+
+	return id, nil
+}
+
+// User returns the user record associated with the user id
+func (s *SessionStorageDriver) User(ctx context.Context, id ccc.UUID) (*dbtype.SessionUser, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	query := fmt.Sprintf(`
+		SELECT
+			Id,
+			Username,
+			PasswordHash,
+			Disabled
+		FROM %s
+		WHERE Id = ?
+	`, s.userTableName)
+
+	user := &sessionUser{}
+	if err := sqlscan.Get(ctx, s.conn, user, query, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, httpio.NewNotFoundMessagef("user id %q does not exist", id)
+		}
+
+		return nil, errors.Wrap(err, "sqlscan.Get()")
+	}
+
+	return user.toSessionUser()
+}
+
+// UserByUserName returns the user record associated with the username
+func (s *SessionStorageDriver) UserByUserName(ctx context.Context, username string) (*dbtype.SessionUser, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	query := fmt.Sprintf(`
+		SELECT
+			Id,
+			Username,
+			PasswordHash,
+			Disabled
+		FROM %s
+		WHERE NormalizedUsername = ?
+	`, s.userTableName)
+
+	user := &sessionUser{}
+	if err := sqlscan.Get(ctx, s.conn, user, query, normalize.Username(username)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, httpio.NewNotFoundMessagef("username %q does not exist", username)
+		}
+
+		return nil, errors.Wrap(err, "sqlscan.Get()")
+	}
+
+	return user.toSessionUser()
+}
+
+// CreateUser creates a new user
+func (s *SessionStorageDriver) CreateUser(ctx context.Context, user *dbtype.InsertSessionUser) (*dbtype.SessionUser, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	id, err := ccc.NewUUID()
+	if err != nil {
+		return nil, errors.Wrap(err, "ccc.NewUUID()")
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO %s
+			(Id, Username, NormalizedUsername, PasswordHash, Disabled)
+		VALUES
+			(?, ?, ?, ?, ?)
+		`, s.userTableName)
+
+	if _, err := s.conn.ExecContext(ctx, query, id, user.Username, normalize.Username(user.Username), user.PasswordHash, user.Disabled); err != nil {
+		if isNormalizedUsernameConflict(err) {
+			return nil, httpio.NewConflictMessagef("username %q already exists", user.Username)
+		}
+
+		return nil, errors.Wrap(err, "Queryer.ExecContext()")
+	}
+
+	return s.User(ctx, id)
+}
+
+// SetUserUsername updates the user record and every active session row
+// for that user atomically. The user's current Username is read inside the transaction
+// with a row lock so concurrent username changes cannot leave session rows stranded
+// under a stale username.
+func (s *SessionStorageDriver) SetUserUsername(ctx context.Context, userID ccc.UUID, newUsername string) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "Queryer.BeginTx()")
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	selectQuery := fmt.Sprintf(`
+		SELECT Username FROM %s
+		WHERE Id = ?
+		FOR UPDATE`, s.userTableName)
+
+	var oldUsername string
+	if err := tx.QueryRowContext(ctx, selectQuery, userID).Scan(&oldUsername); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return httpio.NewNotFoundMessagef("user id %q does not exist", userID)
+		}
+
+		return errors.Wrap(err, "sql.Tx.QueryRowContext().Scan()")
+	}
+
+	userQuery := fmt.Sprintf(`
+		UPDATE %s SET Username = ?, NormalizedUsername = ?
+		WHERE Id = ?`, s.userTableName)
+
+	if _, err := tx.ExecContext(ctx, userQuery, newUsername, normalize.Username(newUsername), userID); err != nil {
+		if isNormalizedUsernameConflict(err) {
+			return httpio.NewConflictMessagef("username %q already exists", newUsername)
+		}
+
+		return errors.Wrap(err, "sql.Tx.ExecContext()")
+	}
+
+	sessionQuery := fmt.Sprintf(`
+		UPDATE %s SET Username = ?, UpdatedAt = ?
+		WHERE Username = ? AND Expired = FALSE`, s.sessionTableName)
+
+	if _, err := tx.ExecContext(ctx, sessionQuery, newUsername, time.Now(), oldUsername); err != nil {
+		return errors.Wrap(err, "sql.Tx.ExecContext()")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, "sql.Tx.Commit()")
+	}
+
+	return nil
+}
+
+// SetUserPasswordHash updates the user password hash
+func (s *SessionStorageDriver) SetUserPasswordHash(ctx context.Context, userID ccc.UUID, hash *securehash.Hash) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	query := fmt.Sprintf(`
+		UPDATE %s SET PasswordHash = ?
+		WHERE Id = ?`, s.userTableName)
+
+	return s.execUserUpdate(ctx, query, userID, hash, userID)
+}
+
+// DeactivateUser deactivates a user
+func (s *SessionStorageDriver) DeactivateUser(ctx context.Context, id ccc.UUID) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	query := fmt.Sprintf(`
+		UPDATE %s SET Disabled = TRUE
+		WHERE Id = ?`, s.userTableName)
+
+	return s.execUserUpdate(ctx, query, id, id)
+}
+
+// DeleteUser deletes a user
+func (s *SessionStorageDriver) DeleteUser(ctx context.Context, id ccc.UUID) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	query := fmt.Sprintf(`
+		DELETE FROM %s
+		WHERE Id = ?`, s.userTableName)
+
+	return s.execUserUpdate(ctx, query, id, id)
+}
+
+// ActivateUser activates a user
+func (s *SessionStorageDriver) ActivateUser(ctx context.Context, id ccc.UUID) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	query := fmt.Sprintf(`
+		UPDATE %s SET Disabled = FALSE
+		WHERE Id = ?`, s.userTableName)
+
+	return s.execUserUpdate(ctx, query, id, id)
+}
+
+// DestroyAllUserSessions destroys all sessions for a given user, including the sessions in which
+// the user impersonates another user
+func (s *SessionStorageDriver) DestroyAllUserSessions(ctx context.Context, username string) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	query := fmt.Sprintf(`
+		UPDATE %s
+		SET Expired = TRUE, UpdatedAt = ?
+		WHERE Username = ? OR ImpersonatorUsername = ?`, s.sessionTableName)
+
+	if _, err := s.conn.ExecContext(ctx, query, time.Now(), username, username); err != nil {
+		return errors.Wrap(err, "Queryer.ExecContext()")
+	}
+
+	return nil
+}
+
+// UserSessions returns up to query.Limit sessions for query.Username that match the query, newest first.
+func (s *SessionStorageDriver) UserSessions(ctx context.Context, query *dbtype.UserSessions) ([]*dbtype.Session, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	conditions := []string{`Username = ?`}
+	args := []any{query.Username}
+	switch {
+	case query.Active && query.Expired:
+	case query.Active:
+		conditions = append(conditions, `NOT Expired`)
+		if !query.IdleBefore.IsZero() {
+			conditions = append(conditions, `NOT `+idleCondition)
+			args = append(args, query.IdleBefore, query.Now)
+		}
+	case query.Expired:
+		if !query.IdleBefore.IsZero() {
+			conditions = append(conditions, `(Expired OR `+idleCondition+`)`)
+			args = append(args, query.IdleBefore, query.Now)
+		} else {
+			conditions = append(conditions, `Expired`)
+		}
+	default:
+		return nil, nil
+	}
+	if query.After != nil {
+		conditions = append(conditions, `(CreatedAt < ? OR (CreatedAt = ? AND Id < ?))`)
+		args = append(args, query.After.CreatedAt, query.After.CreatedAt, query.After.ID)
+	}
+	args = append(args, query.Limit)
+
+	stmt := fmt.Sprintf(`
+		SELECT
+			Id,
+			Username,
+			CreatedAt,
+			UpdatedAt,
+			Expired,
+			IdleTimeoutSeconds,
+			ClientIp,
+			UserAgent,
+			AuthMethod,
+			ImpersonatorUsername,
+			ImpersonatorSessionId,
+			LastAuthenticatedAt
+		FROM %s
+		WHERE %s
+		ORDER BY CreatedAt DESC, Id DESC
+		LIMIT ?`, s.sessionTableName, strings.Join(conditions, " AND "))
+
+	var sessions []*dbtype.Session
+	if err := sqlscan.Select(ctx, s.conn, &sessions, stmt, args...); err != nil {
+		return nil, errors.Wrap(err, "sqlscan.Select()")
+	}
+
+	return sessions, nil
+}
+
+// PurgeSessions deletes up to purge.Limit session rows that match the purge criteria
+// and returns the number of rows deleted.
+func (s *SessionStorageDriver) PurgeSessions(ctx context.Context, purge *dbtype.PurgeSessions) (int64, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	conditions := []string{`Expired`}
+	var args []any
+	if !purge.IdleBefore.IsZero() {
+		conditions = append(conditions, idleCondition)
+		args = append(args, purge.IdleBefore, purge.Now)
+	}
+	if !purge.CreatedBefore.IsZero() {
+		conditions = append(conditions, `CreatedAt < ?`)
+		args = append(args, purge.CreatedBefore)
+	}
+	args = append(args, purge.Limit)
+
+	// MySQL does not support LIMIT in an IN subquery, but it does support it on DELETE.
+	query := fmt.Sprintf(`
+		DELETE FROM %s
+		WHERE %s
+		LIMIT ?`, s.sessionTableName, strings.Join(conditions, " OR "))
+
+	res, err := s.conn.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, errors.Wrap(err, "Queryer.ExecContext()")
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, errors.Wrap(err, "sql.Result.RowsAffected()")
+	}
+
+	return n, nil
+}
+
+// execUserUpdate executes a statement against a single user row and reports
+// a NotFound error when the row does not exist. MySQL reports changed rows rather
+// than matched rows, so a zero count is confirmed with a lookup.
+func (s *SessionStorageDriver) execUserUpdate(ctx context.Context, query string, userID ccc.UUID, args ...any) error {
+	res, err := s.conn.ExecContext(ctx, query, args...)
+	if err != nil {
+		return errors.Wrap(err, "Queryer.ExecContext()")
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return errors.Wrap(err, "sql.Result.RowsAffected()")
+	} else if n == 0 {
+		if exists, err := s.rowExists(ctx, s.userTableName, userID); err != nil {
+			return err
+		} else if !exists {
+			return httpio.NewNotFoundMessagef("user id %q does not exist", userID)
+		}
+	}
+
+	return nil
+}
+
+// rowExists reports whether a row with the given id exists in table.
+func (s *SessionStorageDriver) rowExists(ctx context.Context, table string, id ccc.UUID) (bool, error) {
+	query := fmt.Sprintf(`SELECT COUNT(*) > 0 FROM %s WHERE Id = ?`, table)
+
+	var exists bool
+	if err := s.conn.QueryRowContext(ctx, query, id).Scan(&exists); err != nil {
+		return false, errors.Wrap(err, "Queryer.QueryRowContext().Scan()")
+	}
+
+	return exists, nil
+}
+
+// SessionData returns the data stored with the session by key
+func (s *SessionStorageDriver) SessionData(ctx context.Context, sessionID ccc.UUID) (map[string]json.RawMessage, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	query := fmt.Sprintf(`
+		SELECT Name, Value FROM %s
+		WHERE SessionId = ?`, s.dataTableName())
+
+	rows, err := s.conn.QueryContext(ctx, query, sessionID)
+	if err != nil {
+		return nil, errors.Wrap(err, "Queryer.QueryContext()")
+	}
+	defer rows.Close()
+
+	data := make(map[string]json.RawMessage)
+	for rows.Next() {
+		var name string
+		var value []byte
+		if err := rows.Scan(&name, &value); err != nil {
+			return nil, errors.Wrap(err, "sql.Rows.Scan()")
+		}
+		data[name] = value
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "sql.Rows.Err()")
+	}
+
+	return data, nil
+}
+
+// UpdateSessionData applies update to the data stored with the session
+func (s *SessionStorageDriver) UpdateSessionData(ctx context.Context, sessionID ccc.UUID, update *dbtype.SessionDataUpdate) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "Queryer.BeginTx()")
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// Locking the session keeps it from being rotated or deleted until the data is written
+	lockQuery := fmt.Sprintf(`
+		SELECT 1 FROM %s
+		WHERE Id = ?
+		LOCK IN SHARE MODE`, s.sessionTableName)
+
+	var found int
+	if err := tx.QueryRowContext(ctx, lockQuery, sessionID).Scan(&found); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return httpio.NewNotFoundMessagef("session %q not found", sessionID)
+		}
+
+		return errors.Wrap(err, "sql.Row.Scan()")
+	}
+
+	if len(update.Set) > 0 {
+		names := slices.Sorted(maps.Keys(update.Set))
+		rows := make([]string, 0, len(names))
+		args := make([]any, 0, 4*len(names))
+		for _, name := range names {
+			rows = append(rows, "(?, ?, ?, ?)")
+			args = append(args, sessionID, name, string(update.Set[name]), update.UpdatedAt)
+		}
+
+		setQuery := fmt.Sprintf(`
+			INSERT INTO %s (SessionId, Name, Value, UpdatedAt)
+			VALUES %s
+			ON DUPLICATE KEY UPDATE Value = VALUES(Value), UpdatedAt = VALUES(UpdatedAt)`, s.dataTableName(), strings.Join(rows, ", "))
+
+		if _, err := tx.ExecContext(ctx, setQuery, args...); err != nil {
+			return errors.Wrap(err, "sql.Tx.ExecContext()")
+		}
+	}
+
+	if len(update.Deleted) > 0 {
+		deleteQuery := fmt.Sprintf(`
+			DELETE FROM %s
+			WHERE SessionId = ? AND Name IN (%s)`, s.dataTableName(), placeholders(len(update.Deleted)))
+
+		args := make([]any, 0, len(update.Deleted)+1)
+		args = append(args, sessionID)
+		for _, name := range update.Deleted {
+			args = append(args, name)
+		}
+		if _, err := tx.ExecContext(ctx, deleteQuery, args...); err != nil {
+			return errors.Wrap(err, "sql.Tx.ExecContext()")
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, "sql.Tx.Commit()")
+	}
+
+	return nil
+}
+
+// placeholders returns n comma separated bind parameters.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// isNormalizedUsernameConflict reports whether err is a duplicate entry on the
+// NormalizedUsername unique index.
+func isNormalizedUsernameConflict(err error) bool {
+	var mysqlErr *mysql.MySQLError
+
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == erDupEntry && strings.Contains(mysqlErr.Message, "NormalizedUsername_idx")
+}
@@ -0,0 +1,83 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/cccteam/ccc"
+	"github.com/cccteam/ccc/tracer"
+	"github.com/cccteam/httpio"
+	"github.com/cccteam/session/internal/dbtype"
+	"github.com/go-playground/errors/v5"
+)
+
+// InsertSessionOIDC inserts a Session into database
+func (s *SessionStorageDriver) InsertSessionOIDC(ctx context.Context, insertSession *dbtype.InsertOIDCSession) (ccc.UUID, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	id, err := ccc.NewUUID()
+	if err != nil {
+		return ccc.NilUUID, errors.Wrap(err, "ccc.NewUUID()")
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO %s
+			(Id, OidcSid, Username, CreatedAt, UpdatedAt, Expired, IdleTimeoutSeconds, ClientIp, UserAgent, AuthMethod, ImpersonatorUsername, ImpersonatorSessionId, LastAuthenticatedAt)
+		VALUES
+			(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, s.sessionTableName)
+
+	args := []any{id, insertSession.OidcSID, insertSession.Username, insertSession.CreatedAt, insertSession.UpdatedAt, insertSession.Expired, insertSession.IdleTimeoutSeconds, insertSession.ClientIP, insertSession.UserAgent, insertSession.AuthMethod, insertSession.ImpersonatorUsername, insertSession.ImpersonatorSessionID, insertSession.LastAuthenticatedAt}
+	if err := s.insertSession(ctx, query, args, insertSession.Username, insertSession.Limit); err != nil {
+		return ccc.NilUUID, err
+	}
+
+	return id, nil
+}
+
+// DestroySessionOIDC marks the session as expired using the oidcSID
+func (s *SessionStorageDriver) DestroySessionOIDC(ctx context.Context, oidcSID string) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	query := fmt.Sprintf(`
+		UPDATE %[1]s AS s
+		JOIN (
+			SELECT Username
+			FROM %[1]s
+			WHERE OidcSid = ?
+		) AS o ON s.Username = o.Username
+		SET s.Expired = TRUE
+		WHERE NOT s.Expired`, s.sessionTableName)
+
+	if _, err := s.conn.ExecContext(ctx, query, oidcSID); err != nil {
+		return errors.Wrap(err, "Queryer.ExecContext()")
+	}
+
+	return nil
+}
+
+// UsernameOIDC returns the username of the session with the oidcSID
+func (s *SessionStorageDriver) UsernameOIDC(ctx context.Context, oidcSID string) (string, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	query := fmt.Sprintf(`
+		SELECT Username
+		FROM %s
+		WHERE OidcSid = ?
+		LIMIT 1`, s.sessionTableName)
+
+	var username string
+	if err := s.conn.QueryRowContext(ctx, query, oidcSID).Scan(&username); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", httpio.NewNotFoundMessagef("session with oidc sid %q not found", oidcSID)
+		}
+
+		return "", errors.Wrap(err, "Queryer.QueryRowContext()")
+	}
+
+	return username, nil
+}
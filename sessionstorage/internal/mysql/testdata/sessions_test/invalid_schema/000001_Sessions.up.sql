@@ -0,0 +1,13 @@
+-- Table: Sessions
+
+-- DROP TABLE Sessions;
+
+-- The schema is made invalid by leaving out the UpdatedAt and Expired columns.
+CREATE TABLE Sessions
+(
+    Id VARCHAR(255) NOT NULL,
+    OidcSid VARCHAR(255) NOT NULL,
+    Username VARCHAR(255) NOT NULL,
+    CreatedAt DATETIME(6) NOT NULL,
+    CONSTRAINT Sessions_pkey PRIMARY KEY (Id)
+);
@@ -0,0 +1,7 @@
+INSERT INTO Sessions (Id, OidcSid, Username, CreatedAt)
+    VALUES
+        ('38bd570b-1280-421b-888e-a63f0ca35be7', 'oidc session 1', 'test user 1', '2019-02-01 05:10:20+00:00'),
+        ('aa817d69-f550-474b-8eae-7b29da32e3a8', 'oidc session 4', 'test user 1', '2019-02-02 05:10:20+00:00'),
+        ('eb0c72a4-1f32-469e-b51b-7baa589a944c', 'oidc session 3', 'test user 2', '2018-05-03 01:02:03+00:00'),
+        ('095887e9-ab67-42c3-8090-6c50780606e3', 'oidc session 6', 'test user 2', '2018-05-04 01:02:03+00:00'),
+        ('da8d6b11-8ef3-4134-8216-2dd0a94795ba', 'oidc session 5', 'test user 1', '2019-02-03 05:10:20+00:00');
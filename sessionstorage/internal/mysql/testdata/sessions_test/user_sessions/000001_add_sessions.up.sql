@@ -0,0 +1,6 @@
+INSERT INTO Sessions (Id, Username, CreatedAt, UpdatedAt, Expired)
+    VALUES
+        ('11111111-1111-1111-1111-111111111111', 'testUser', '2019-02-01 05:10:20+00:00', '2020-01-02 08:05:03+00:00', false),
+        ('22222222-2222-2222-2222-222222222222', 'testUser', '2019-02-02 05:10:20+00:00', '2020-01-03 08:05:03+00:00', false),
+        ('33333333-3333-3333-3333-333333333333', 'testUser', '2019-02-03 05:10:20+00:00', '2020-01-04 08:05:03+00:00', true),
+        ('44444444-4444-4444-4444-444444444444', 'disableduser', '2019-02-04 05:10:20+00:00', '2020-01-05 08:05:03+00:00', false);
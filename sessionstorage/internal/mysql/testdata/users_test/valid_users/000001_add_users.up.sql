@@ -0,0 +1,4 @@
+INSERT INTO SessionUsers (Id, Username, NormalizedUsername, PasswordHash, Disabled)
+VALUES
+    ('27b43588-b743-4133-8730-e0439065a844', 'testUser', 'testuser', '1$12288$3$1$k5UDxGNpdI0XrTY59KZvXg==.JNUcFFjrpbAj9pr1L8HkV8aNkeACBbc3SV0SSAjoPwM=', FALSE),
+    ('54918893-2342-4621-8673-79520a84b84f', 'disableduser', 'disableduser', '1$12288$3$1$k5UDxGNpdI0XrTY59KZvXg==.JNUcFFjrpbAj9pr1L8HkV8aNkeACBbc3SV0SSAjoPwM=', TRUE);
@@ -0,0 +1,37 @@
+package mysql
+
+import (
+	"database/sql"
+
+	"github.com/cccteam/ccc"
+	"github.com/cccteam/ccc/securehash"
+	"github.com/cccteam/session/internal/dbtype"
+	"github.com/go-playground/errors/v5"
+)
+
+// sessionUser is the row representation of dbtype.SessionUser. go-sql-driver/mysql
+// returns VARCHAR columns as []byte, which securehash.Hash does not scan, so the
+// password hash is read as a string and decoded separately.
+type sessionUser struct {
+	ID           ccc.UUID       `db:"Id"`
+	Username     string         `db:"Username"`
+	PasswordHash sql.NullString `db:"PasswordHash"`
+	Disabled     bool           `db:"Disabled"`
+}
+
+func (u *sessionUser) toSessionUser() (*dbtype.SessionUser, error) {
+	user := &dbtype.SessionUser{
+		ID:       u.ID,
+		Username: u.Username,
+		Disabled: u.Disabled,
+	}
+
+	if u.PasswordHash.Valid {
+		user.PasswordHash = &securehash.Hash{}
+		if err := user.PasswordHash.Scan(u.PasswordHash.String); err != nil {
+			return nil, errors.Wrap(err, "securehash.Hash.Scan()")
+		}
+	}
+
+	return user, nil
+}
@@ -0,0 +1,15 @@
+// Package normalize implements the username normalization used by storage drivers
+// that cannot express it in their schema.
+package normalize
+
+import (
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Username returns the normalized form of username used for uniqueness checks and lookups.
+// It matches the PostgreSQL casefold(normalize(username)) and Spanner NORMALIZE_AND_CASEFOLD(username)
+// expressions: NFC normalization followed by full Unicode case folding.
+func Username(username string) string {
+	return cases.Fold().String(norm.NFC.String(username))
+}
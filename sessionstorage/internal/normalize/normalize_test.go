@@ -0,0 +1,43 @@
+package normalize
+
+import "testing"
+
+func TestUsername(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		username string
+		want     string
+	}{
+		{
+			name:     "ascii",
+			username: "TestUser",
+			want:     "testuser",
+		},
+		{
+			name:     "full case folding",
+			username: "Straße",
+			want:     "strasse",
+		},
+		{
+			name:     "decomposed input is composed before folding",
+			username: "Jose\u0301",
+			want:     "jos\u00e9",
+		},
+		{
+			name:     "composed input",
+			username: "JOS\u00c9",
+			want:     "jos\u00e9",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := Username(tt.username); got != tt.want {
+				t.Errorf("Username() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
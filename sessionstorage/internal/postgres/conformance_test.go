@@ -0,0 +1,25 @@
+package postgres_test
+
+import (
+	"testing"
+
+	"github.com/cccteam/session/sessionstorage"
+	"github.com/cccteam/session/sessionstorage/internal/postgres"
+	"github.com/cccteam/session/sessionstorage/storagetest"
+)
+
+func TestConformance(t *testing.T) {
+	t.Parallel()
+
+	storagetest.RunConformance(t, storagetest.Factory{
+		Preauth: func(t *testing.T) sessionstorage.PreauthStore {
+			return sessionstorage.NewPostgresPreauth(postgres.PrepareDatabase(t, "file://../../../schema/postgresql/migrations"))
+		},
+		PasswordAuth: func(t *testing.T) sessionstorage.PasswordAuthStore {
+			return sessionstorage.NewPostgresPassword(postgres.PrepareDatabase(t, "file://../../../schema/postgresql/migrations"))
+		},
+		OIDC: func(t *testing.T) sessionstorage.OIDCStore {
+			return sessionstorage.NewPostgresOIDC(postgres.PrepareDatabase(t, "file://../../../schema/postgresql/oidc/migrations"))
+		},
+	})
+}
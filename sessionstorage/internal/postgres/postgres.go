@@ -3,7 +3,11 @@ package postgres
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"maps"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/cccteam/ccc"
@@ -39,6 +43,11 @@ func (s *SessionStorageDriver) SetSessionTableName(name string) {
 	s.sessionTableName = name
 }
 
+// dataTableName returns the name of the table holding the data of the sessions in the session table.
+func (s *SessionStorageDriver) dataTableName() string {
+	return s.sessionTableName + dbtype.SessionDataTableSuffix
+}
+
 // SetUserTableName sets the name of the user table.
 func (s *SessionStorageDriver) SetUserTableName(name string) {
 	s.userTableName = name
@@ -55,7 +64,14 @@ func (s *SessionStorageDriver) Session(ctx context.Context, sessionID ccc.UUID)
 			"Username", 
 			"CreatedAt", 
 			"UpdatedAt", 
-			"Expired"
+			"Expired",
+			"IdleTimeoutSeconds",
+			"ClientIp",
+			"UserAgent",
+			"AuthMethod",
+			"ImpersonatorUsername",
+			"ImpersonatorSessionId",
+			"LastAuthenticatedAt"
 		FROM "%s"
 		WHERE "Id" = $1
 	`, s.sessionTableName)
@@ -93,6 +109,56 @@ func (s *SessionStorageDriver) UpdateSessionActivity(ctx context.Context, sessio
 	return nil
 }
 
+// UpdateSessionAuthentication sets the session activity and last authentication columns to the current time
+func (s *SessionStorageDriver) UpdateSessionAuthentication(ctx context.Context, sessionID ccc.UUID) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	query := fmt.Sprintf(`
+		UPDATE "%s" SET "UpdatedAt" = $1, "LastAuthenticatedAt" = $1
+		WHERE "Id" = $2`, s.sessionTableName)
+
+	res, err := s.conn.Exec(ctx, query, time.Now(), sessionID)
+	if err != nil {
+		return errors.Wrapf(err, "failed to update Sessions table for ID: %s", sessionID)
+	}
+
+	if cnt := res.RowsAffected(); cnt != 1 {
+		return httpio.NewNotFoundMessagef("session %q not found", sessionID)
+	}
+
+	return nil
+}
+
+// UpdateSessionsActivity sets the activity time of each session in a single statement,
+// skipping sessions that do not exist or already have a later activity time
+func (s *SessionStorageDriver) UpdateSessionsActivity(ctx context.Context, activity []*dbtype.SessionActivity) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	if len(activity) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(activity))
+	updatedAt := make([]time.Time, 0, len(activity))
+	for _, a := range activity {
+		ids = append(ids, a.ID.String())
+		updatedAt = append(updatedAt, a.UpdatedAt)
+	}
+
+	query := fmt.Sprintf(`
+		UPDATE "%s" AS s SET "UpdatedAt" = a."UpdatedAt"
+		FROM unnest($1::text[], $2::timestamp[]) AS a("Id", "UpdatedAt")
+		WHERE s."Id" = a."Id"::uuid AND s."UpdatedAt" < a."UpdatedAt"`, s.sessionTableName)
+
+	if _, err := s.conn.Exec(ctx, query, ids, updatedAt); err != nil {
+		return errors.Wrap(err, "Queryer.Exec()")
+	}
+
+	return nil
+}
+
 // InsertSession inserts a Session into database
 func (s *SessionStorageDriver) InsertSession(ctx context.Context, insertSession *dbtype.InsertSession) (ccc.UUID, error) {
 	ctx, span := tracer.Start(ctx)
@@ -105,18 +171,94 @@ func (s *SessionStorageDriver) InsertSession(ctx context.Context, insertSession
 
 	query := fmt.Sprintf(`
 		INSERT INTO "%s"
-			("Id", "Username", "CreatedAt", "UpdatedAt", "Expired")
+			("Id", "Username", "CreatedAt", "UpdatedAt", "Expired", "IdleTimeoutSeconds", "ClientIp", "UserAgent", "AuthMethod", "ImpersonatorUsername", "ImpersonatorSessionId", "LastAuthenticatedAt")
 		VALUES
-			($1, $2, $3, $4, $5)
+			($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 		`, s.sessionTableName)
 
-	if _, err := s.conn.Exec(ctx, query, id, insertSession.Username, insertSession.CreatedAt, insertSession.UpdatedAt, insertSession.Expired); err != nil {
-		return ccc.NilUUID, errors.Wrap(err, "Queryer.Exec()")
+	args := []any{id, insertSession.Username, insertSession.CreatedAt, insertSession.UpdatedAt, insertSession.Expired, insertSession.IdleTimeoutSeconds, insertSession.ClientIP, insertSession.UserAgent, insertSession.AuthMethod, insertSession.ImpersonatorUsername, insertSession.ImpersonatorSessionID, insertSession.LastAuthenticatedAt}
+	if err := s.insertSession(ctx, query, args, insertSession.Username, insertSession.Limit); err != nil {
+		return ccc.NilUUID, err
 	}
 
 	return id, nil
 }
 
+// insertSession runs the query that inserts a session. When limit is set, the oldest active
+// sessions of username that do not fit in it are expired in the same transaction.
+func (s *SessionStorageDriver) insertSession(ctx context.Context, query string, args []any, username string, limit *dbtype.InsertLimit) error {
+	if limit == nil {
+		if _, err := s.conn.Exec(ctx, query, args...); err != nil {
+			return errors.Wrap(err, "Queryer.Exec()")
+		}
+
+		return nil
+	}
+
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return errors.Wrap(err, "Queryer.Begin()")
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// Row locks do not stop a concurrent login from inserting another session,
+	// so logins of the same user are serialized with an advisory lock instead
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, s.sessionTableName+"/"+username); err != nil {
+		return errors.Wrap(err, "pgx.Tx.Exec()")
+	}
+
+	conditions := []string{`"Username" = $1`, `NOT "Expired"`}
+	selectArgs := []any{username}
+	if !limit.IdleBefore.IsZero() {
+		var condition string
+		condition, selectArgs = idle(selectArgs, limit.IdleBefore, limit.Now)
+		conditions = append(conditions, "NOT "+condition)
+	}
+	if !limit.CreatedBefore.IsZero() {
+		selectArgs = append(selectArgs, limit.CreatedBefore)
+		conditions = append(conditions, fmt.Sprintf(`"CreatedAt" >= $%d`, len(selectArgs)))
+	}
+
+	selectQuery := fmt.Sprintf(`
+		SELECT "Id" FROM "%s"
+		WHERE %s
+		ORDER BY "CreatedAt", "Id"`, s.sessionTableName, strings.Join(conditions, " AND "))
+
+	var active []ccc.UUID
+	if err := pgxscan.Select(ctx, tx, &active, selectQuery, selectArgs...); err != nil {
+		return errors.Wrap(err, "pgxscan.Select()")
+	}
+
+	evict, err := limit.Evict(active)
+	if err != nil {
+		return errors.Wrap(err, "dbtype.InsertLimit.Evict()")
+	}
+	if len(evict) > 0 {
+		ids := make([]string, 0, len(evict))
+		for _, sessionID := range evict {
+			ids = append(ids, sessionID.String())
+		}
+
+		expireQuery := fmt.Sprintf(`
+			UPDATE "%s" SET "Expired" = TRUE, "UpdatedAt" = $2
+			WHERE "Id" = ANY($1::uuid[])`, s.sessionTableName)
+
+		if _, err := tx.Exec(ctx, expireQuery, ids, time.Now()); err != nil {
+			return errors.Wrap(err, "pgx.Tx.Exec()")
+		}
+	}
+
+	if _, err := tx.Exec(ctx, query, args...); err != nil {
+		return errors.Wrap(err, "pgx.Tx.Exec()")
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return errors.Wrap(err, "pgx.Tx.Commit()")
+	}
+
+	return nil
+}
+
 // DestroySession marks the session as expired
 func (s *SessionStorageDriver) DestroySession(ctx context.Context, sessionID ccc.UUID) error {
 	ctx, span := tracer.Start(ctx)
@@ -136,6 +278,33 @@ func (s *SessionStorageDriver) DestroySession(ctx context.Context, sessionID ccc
 	return nil
 }
 
+// RotateSession moves an active session to a new ID, keeping the rest of the row. The session data follows
+// through the ON UPDATE CASCADE foreign key
+func (s *SessionStorageDriver) RotateSession(ctx context.Context, sessionID ccc.UUID) (ccc.UUID, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	id, err := ccc.NewUUID()
+	if err != nil {
+		return ccc.NilUUID, errors.Wrap(err, "ccc.NewUUID()")
+	}
+
+	query := fmt.Sprintf(`
+		UPDATE "%s" SET "Id" = $1
+		WHERE "Id" = $2 AND NOT "Expired"`, s.sessionTableName)
+
+	res, err := s.conn.Exec(ctx, query, id, sessionID)
+	if err != nil {
+		return ccc.NilUUID, errors.Wrap(err, "Queryer.Exec()")
+	}
+
+	if cnt := res.RowsAffected(); cnt != 1 {
+		return ccc.NilUUID, httpio.NewNotFoundMessagef("session %q not found", sessionID)
+	}
+
+	return id, nil
+}
+
 // User returns the user record associated with the user id
 func (s *SessionStorageDriver) User(ctx context.Context, id ccc.UUID) (*dbtype.SessionUser, error) {
 	ctx, span := tracer.Start(ctx)
@@ -209,7 +378,7 @@ func (s *SessionStorageDriver) CreateUser(ctx context.Context, user *dbtype.Inse
 
 	if _, err := s.conn.Exec(ctx, query, id, user.Username, user.PasswordHash, user.Disabled); err != nil {
 		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation && pgErr.ConstraintName == "SessionUsers_NormalizedUsername_idx" {
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation && pgErr.ConstraintName == s.userTableName+"_NormalizedUsername_idx" {
 			return nil, httpio.NewConflictMessagef("username %q already exists", user.Username)
 		}
 
@@ -253,7 +422,7 @@ func (s *SessionStorageDriver) SetUserUsername(ctx context.Context, userID ccc.U
 
 	if _, err := tx.Exec(ctx, userQuery, userID, newUsername); err != nil {
 		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation && pgErr.ConstraintName == "SessionUsers_NormalizedUsername_idx" {
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation && pgErr.ConstraintName == s.userTableName+"_NormalizedUsername_idx" {
 			return httpio.NewConflictMessagef("username %q already exists", newUsername)
 		}
 
@@ -347,7 +516,8 @@ func (s *SessionStorageDriver) ActivateUser(ctx context.Context, id ccc.UUID) er
 	return nil
 }
 
-// DestroyAllUserSessions destroys all sessions for a given user
+// DestroyAllUserSessions destroys all sessions for a given user, including the sessions in which
+// the user impersonates another user
 func (s *SessionStorageDriver) DestroyAllUserSessions(ctx context.Context, username string) error {
 	ctx, span := tracer.Start(ctx)
 	defer span.End()
@@ -355,7 +525,7 @@ func (s *SessionStorageDriver) DestroyAllUserSessions(ctx context.Context, usern
 	query := fmt.Sprintf(`
 		UPDATE "%s" 
 		SET "Expired" = TRUE, "UpdatedAt" = $2
-		WHERE "Username" = $1`, s.sessionTableName)
+		WHERE "Username" = $1 OR "ImpersonatorUsername" = $1`, s.sessionTableName)
 
 	if _, err := s.conn.Exec(ctx, query, username, time.Now()); err != nil {
 		return errors.Wrap(err, "Queryer.Exec()")
@@ -363,3 +533,196 @@ func (s *SessionStorageDriver) DestroyAllUserSessions(ctx context.Context, usern
 
 	return nil
 }
+
+// UserSessions returns up to query.Limit sessions for query.Username that match the query, newest first.
+func (s *SessionStorageDriver) UserSessions(ctx context.Context, query *dbtype.UserSessions) ([]*dbtype.Session, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	conditions := []string{`"Username" = $1`}
+	args := []any{query.Username, query.Limit}
+	switch {
+	case query.Active && query.Expired:
+	case query.Active:
+		conditions = append(conditions, `NOT "Expired"`)
+		if !query.IdleBefore.IsZero() {
+			var condition string
+			condition, args = idle(args, query.IdleBefore, query.Now)
+			conditions = append(conditions, "NOT "+condition)
+		}
+	case query.Expired:
+		if !query.IdleBefore.IsZero() {
+			var condition string
+			condition, args = idle(args, query.IdleBefore, query.Now)
+			conditions = append(conditions, `("Expired" OR `+condition+")")
+		} else {
+			conditions = append(conditions, `"Expired"`)
+		}
+	default:
+		return nil, nil
+	}
+	if query.After != nil {
+		args = append(args, query.After.CreatedAt, query.After.ID)
+		conditions = append(conditions, fmt.Sprintf(`("CreatedAt" < $%[1]d OR ("CreatedAt" = $%[1]d AND "Id" < $%[2]d))`, len(args)-1, len(args)))
+	}
+
+	stmt := fmt.Sprintf(`
+		SELECT
+			"Id",
+			"Username",
+			"CreatedAt",
+			"UpdatedAt",
+			"Expired",
+			"IdleTimeoutSeconds",
+			"ClientIp",
+			"UserAgent",
+			"AuthMethod",
+			"ImpersonatorUsername",
+			"ImpersonatorSessionId",
+			"LastAuthenticatedAt"
+		FROM "%s"
+		WHERE %s
+		ORDER BY "CreatedAt" DESC, "Id" DESC
+		LIMIT $2`, s.sessionTableName, strings.Join(conditions, " AND "))
+
+	var sessions []*dbtype.Session
+	if err := pgxscan.Select(ctx, s.conn, &sessions, stmt, args...); err != nil {
+		return nil, errors.Wrap(err, "pgxscan.Select()")
+	}
+
+	return sessions, nil
+}
+
+// PurgeSessions deletes up to purge.Limit session rows that match the purge criteria
+// and returns the number of rows deleted.
+func (s *SessionStorageDriver) PurgeSessions(ctx context.Context, purge *dbtype.PurgeSessions) (int64, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	conditions := []string{`"Expired"`}
+	args := []any{purge.Limit}
+	if !purge.IdleBefore.IsZero() {
+		var condition string
+		condition, args = idle(args, purge.IdleBefore, purge.Now)
+		conditions = append(conditions, condition)
+	}
+	if !purge.CreatedBefore.IsZero() {
+		args = append(args, purge.CreatedBefore)
+		conditions = append(conditions, fmt.Sprintf(`"CreatedAt" < $%d`, len(args)))
+	}
+
+	query := fmt.Sprintf(`
+		DELETE FROM "%[1]s"
+		WHERE "Id" IN (
+			SELECT "Id" FROM "%[1]s"
+			WHERE %[2]s
+			LIMIT $1
+		)`, s.sessionTableName, strings.Join(conditions, " OR "))
+
+	cmdTag, err := s.conn.Exec(ctx, query, args...)
+	if err != nil {
+		return 0, errors.Wrap(err, "Queryer.Exec()")
+	}
+
+	return cmdTag.RowsAffected(), nil
+}
+
+// idle appends the arguments of the condition that selects idle sessions, as defined by
+// dbtype.Session.Idle, to args and returns the condition and the arguments.
+func idle(args []any, idleBefore, now time.Time) (string, []any) {
+	args = append(args, idleBefore, now)
+
+	return fmt.Sprintf(`("UpdatedAt" < $%d AND ("IdleTimeoutSeconds" = 0 OR "UpdatedAt" + "IdleTimeoutSeconds" * INTERVAL '1 second' < $%d))`, len(args)-1, len(args)), args
+}
+
+// SessionData returns the data stored with the session by key
+func (s *SessionStorageDriver) SessionData(ctx context.Context, sessionID ccc.UUID) (map[string]json.RawMessage, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	query := fmt.Sprintf(`
+		SELECT "Name", "Value" FROM "%s"
+		WHERE "SessionId" = $1`, s.dataTableName())
+
+	rows, err := s.conn.Query(ctx, query, sessionID)
+	if err != nil {
+		return nil, errors.Wrap(err, "Queryer.Query()")
+	}
+	defer rows.Close()
+
+	data := make(map[string]json.RawMessage)
+	for rows.Next() {
+		var name string
+		var value []byte
+		if err := rows.Scan(&name, &value); err != nil {
+			return nil, errors.Wrap(err, "pgx.Rows.Scan()")
+		}
+		data[name] = value
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "pgx.Rows.Err()")
+	}
+
+	return data, nil
+}
+
+// UpdateSessionData applies update to the data stored with the session
+func (s *SessionStorageDriver) UpdateSessionData(ctx context.Context, sessionID ccc.UUID, update *dbtype.SessionDataUpdate) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return errors.Wrap(err, "Queryer.Begin()")
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// Locking the session keeps it from being rotated or deleted until the data is written
+	lockQuery := fmt.Sprintf(`
+		SELECT 1 FROM "%s"
+		WHERE "Id" = $1
+		FOR KEY SHARE`, s.sessionTableName)
+
+	var found int
+	if err := tx.QueryRow(ctx, lockQuery, sessionID).Scan(&found); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return httpio.NewNotFoundMessagef("session %q not found", sessionID)
+		}
+
+		return errors.Wrap(err, "pgx.Row.Scan()")
+	}
+
+	if len(update.Set) > 0 {
+		names := slices.Sorted(maps.Keys(update.Set))
+		values := make([]string, 0, len(names))
+		for _, name := range names {
+			values = append(values, string(update.Set[name]))
+		}
+
+		setQuery := fmt.Sprintf(`
+			INSERT INTO "%s" ("SessionId", "Name", "Value", "UpdatedAt")
+			SELECT $1, d."Name", d."Value", $4
+			FROM unnest($2::varchar[], $3::jsonb[]) AS d("Name", "Value")
+			ON CONFLICT ("SessionId", "Name") DO UPDATE SET "Value" = EXCLUDED."Value", "UpdatedAt" = EXCLUDED."UpdatedAt"`, s.dataTableName())
+
+		if _, err := tx.Exec(ctx, setQuery, sessionID, names, values, update.UpdatedAt); err != nil {
+			return errors.Wrap(err, "pgx.Tx.Exec()")
+		}
+	}
+
+	if len(update.Deleted) > 0 {
+		deleteQuery := fmt.Sprintf(`
+			DELETE FROM "%s"
+			WHERE "SessionId" = $1 AND "Name" = ANY($2)`, s.dataTableName())
+
+		if _, err := tx.Exec(ctx, deleteQuery, sessionID, update.Deleted); err != nil {
+			return errors.Wrap(err, "pgx.Tx.Exec()")
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return errors.Wrap(err, "pgx.Tx.Commit()")
+	}
+
+	return nil
+}
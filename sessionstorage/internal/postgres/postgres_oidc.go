@@ -6,8 +6,10 @@ import (
 
 	"github.com/cccteam/ccc"
 	"github.com/cccteam/ccc/tracer"
+	"github.com/cccteam/httpio"
 	"github.com/cccteam/session/internal/dbtype"
 	"github.com/go-playground/errors/v5"
+	"github.com/jackc/pgx/v5"
 )
 
 // InsertSessionOIDC inserts a Session into database
@@ -22,13 +24,14 @@ func (s *SessionStorageDriver) InsertSessionOIDC(ctx context.Context, insertSess
 
 	query := fmt.Sprintf(`
 		INSERT INTO "%s"
-			("Id", "OidcSid", "Username", "CreatedAt", "UpdatedAt", "Expired")
+			("Id", "OidcSid", "Username", "CreatedAt", "UpdatedAt", "Expired", "IdleTimeoutSeconds", "ClientIp", "UserAgent", "AuthMethod", "ImpersonatorUsername", "ImpersonatorSessionId", "LastAuthenticatedAt")
 		VALUES
-			($1, $2, $3, $4, $5, $6)
+			($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 		`, s.sessionTableName)
 
-	if _, err := s.conn.Exec(ctx, query, id, insertSession.OidcSID, insertSession.Username, insertSession.CreatedAt, insertSession.UpdatedAt, insertSession.Expired); err != nil {
-		return ccc.NilUUID, errors.Wrap(err, "Queryer.Exec()")
+	args := []any{id, insertSession.OidcSID, insertSession.Username, insertSession.CreatedAt, insertSession.UpdatedAt, insertSession.Expired, insertSession.IdleTimeoutSeconds, insertSession.ClientIP, insertSession.UserAgent, insertSession.AuthMethod, insertSession.ImpersonatorUsername, insertSession.ImpersonatorSessionID, insertSession.LastAuthenticatedAt}
+	if err := s.insertSession(ctx, query, args, insertSession.Username, insertSession.Limit); err != nil {
+		return ccc.NilUUID, err
 	}
 
 	return id, nil
@@ -53,3 +56,26 @@ func (s *SessionStorageDriver) DestroySessionOIDC(ctx context.Context, oidcSID s
 
 	return nil
 }
+
+// UsernameOIDC returns the username of the session with the oidcSID
+func (s *SessionStorageDriver) UsernameOIDC(ctx context.Context, oidcSID string) (string, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	query := fmt.Sprintf(`
+		SELECT "Username"
+		FROM "%s"
+		WHERE "OidcSid" = $1
+		LIMIT 1`, s.sessionTableName)
+
+	var username string
+	if err := s.conn.QueryRow(ctx, query, oidcSID).Scan(&username); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", httpio.NewNotFoundMessagef("session with oidc sid %q not found", oidcSID)
+		}
+
+		return "", errors.Wrap(err, "Queryer.QueryRow()")
+	}
+
+	return username, nil
+}
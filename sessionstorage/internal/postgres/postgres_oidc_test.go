@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/cccteam/httpio"
 	"github.com/cccteam/session/internal/dbtype"
 )
 
@@ -142,3 +143,63 @@ func Test_client_DestroySessionOIDC(t *testing.T) {
 		})
 	}
 }
+
+func Test_client_UsernameOIDC(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name         string
+		oidcSID      string
+		sourceURL    []string
+		want         string
+		wantErr      bool
+		wantNotFound bool
+	}{
+		{
+			name:    "fails to get username",
+			oidcSID: "oidc session 095887e9-ab67-42c3-8090-6c50780606e3",
+			wantErr: true,
+		},
+		{
+			name:         "unknown oidc sid",
+			oidcSID:      "oidc session4",
+			sourceURL:    []string{"file://../../../schema/postgresql/oidc/migrations", "file://testdata/sessions_test/oidc_valid_sessions"},
+			wantErr:      true,
+			wantNotFound: true,
+		},
+		{
+			name:      "success getting username",
+			oidcSID:   "oidc session 095887e9-ab67-42c3-8090-6c50780606e3",
+			sourceURL: []string{"file://../../../schema/postgresql/oidc/migrations", "file://testdata/sessions_test/oidc_valid_sessions"},
+			want:      "test user 2",
+		},
+		{
+			name:      "success getting username of an expired session",
+			oidcSID:   "oidc session aa817d69-f550-474b-8eae-7b29da32e3a8",
+			sourceURL: []string{"file://../../../schema/postgresql/oidc/migrations", "file://testdata/sessions_test/oidc_valid_sessions"},
+			want:      "test user 1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			conn, err := prepareDatabase(ctx, t, tt.sourceURL...)
+			if err != nil {
+				t.Fatalf("prepareDatabase() error = %v, wantErr %v", err, false)
+			}
+			c := NewSessionStorageDriver(conn.Pool)
+
+			got, err := c.UsernameOIDC(ctx, tt.oidcSID)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("client.UsernameOIDC() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantNotFound && !httpio.HasNotFound(err) {
+				t.Errorf("client.UsernameOIDC() error = %v, want NotFound", err)
+			}
+			if got != tt.want {
+				t.Errorf("client.UsernameOIDC() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package postgres
 
 import (
 	"fmt"
+	"slices"
 	"testing"
 	"time"
 
@@ -9,6 +10,7 @@ import (
 	"github.com/cccteam/ccc/securehash"
 	"github.com/cccteam/httpio"
 	"github.com/cccteam/session/internal/dbtype"
+	"github.com/google/go-cmp/cmp"
 )
 
 func TestClient_FullMigration(t *testing.T) {
@@ -183,6 +185,154 @@ func Test_client_UpdateSessionActivity(t *testing.T) {
 	}
 }
 
+func TestSessionStorageDriver_UpdateSessionsActivity(t *testing.T) {
+	t.Parallel()
+
+	updatedAt := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	activity := []*dbtype.SessionActivity{
+		{ID: ccc.Must(ccc.UUIDFromString("38bd570b-1280-421b-888e-a63f0ca35be7")), UpdatedAt: updatedAt},
+		{ID: ccc.Must(ccc.UUIDFromString("095887e9-ab67-42c3-8090-6c50780606e3")), UpdatedAt: updatedAt.Add(time.Hour)},
+		{ID: ccc.Must(ccc.UUIDFromString("ed0c72a4-1f32-469e-b51b-7baa589a945c")), UpdatedAt: updatedAt},
+	}
+
+	tests := []struct {
+		name      string
+		sourceURL []string
+		wantErr   bool
+	}{
+		{
+			name:      "fails to update session activity (invalid schema)",
+			sourceURL: []string{"file://testdata/sessions_test/invalid_schema"},
+			wantErr:   true,
+		},
+		{
+			name:      "success updating existing sessions and skipping missing sessions",
+			sourceURL: []string{"file://../../../schema/postgresql/oidc/migrations", "file://testdata/sessions_test/oidc_valid_sessions"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := t.Context()
+			conn, err := prepareDatabase(ctx, t, tt.sourceURL...)
+			if err != nil {
+				t.Fatalf("prepareDatabase() error = %v, wantErr %v", err, false)
+			}
+			c := NewSessionStorageDriver(conn.Pool)
+
+			if err := c.UpdateSessionsActivity(ctx, activity); (err != nil) != tt.wantErr {
+				t.Errorf("SessionStorageDriver.UpdateSessionsActivity() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			for _, a := range activity[:2] {
+				session, err := c.Session(ctx, a.ID)
+				if err != nil {
+					t.Fatalf("SessionStorageDriver.Session() error = %v", err)
+				}
+				if !session.UpdatedAt.Equal(a.UpdatedAt) {
+					t.Errorf("SessionStorageDriver.Session(%v).UpdatedAt = %v, want %v", a.ID, session.UpdatedAt, a.UpdatedAt)
+				}
+			}
+
+			stale := []*dbtype.SessionActivity{{ID: activity[0].ID, UpdatedAt: updatedAt.Add(-time.Hour)}}
+			if err := c.UpdateSessionsActivity(ctx, stale); err != nil {
+				t.Fatalf("SessionStorageDriver.UpdateSessionsActivity() error = %v", err)
+			}
+			session, err := c.Session(ctx, activity[0].ID)
+			if err != nil {
+				t.Fatalf("SessionStorageDriver.Session() error = %v", err)
+			}
+			if !session.UpdatedAt.Equal(updatedAt) {
+				t.Errorf("SessionStorageDriver.Session(%v).UpdatedAt = %v after stale activity, want %v", activity[0].ID, session.UpdatedAt, updatedAt)
+			}
+		})
+	}
+}
+
+func TestSessionStorageDriver_RotateSession(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		sourceURL    []string
+		sessionID    ccc.UUID
+		wantNotFound bool
+		wantErr      bool
+	}{
+		{
+			name:      "fails to rotate session (invalid schema)",
+			sourceURL: []string{"file://testdata/sessions_test/invalid_schema"},
+			sessionID: ccc.Must(ccc.UUIDFromString("38bd570b-1280-421b-888e-a63f0ca35be7")),
+			wantErr:   true,
+		},
+		{
+			name:      "success rotating an active session",
+			sourceURL: []string{"file://../../../schema/postgresql/oidc/migrations", "file://testdata/sessions_test/oidc_valid_sessions"},
+			sessionID: ccc.Must(ccc.UUIDFromString("38bd570b-1280-421b-888e-a63f0ca35be7")),
+		},
+		{
+			name:         "fails to rotate an expired session",
+			sourceURL:    []string{"file://../../../schema/postgresql/oidc/migrations", "file://testdata/sessions_test/oidc_valid_sessions"},
+			sessionID:    ccc.Must(ccc.UUIDFromString("aa817d69-f550-474b-8eae-7b29da32e3a8")),
+			wantNotFound: true,
+			wantErr:      true,
+		},
+		{
+			name:         "fails to rotate a missing session",
+			sourceURL:    []string{"file://../../../schema/postgresql/oidc/migrations", "file://testdata/sessions_test/oidc_valid_sessions"},
+			sessionID:    ccc.Must(ccc.UUIDFromString("ed0c72a4-1f32-469e-b51b-7baa589a945c")),
+			wantNotFound: true,
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := t.Context()
+			conn, err := prepareDatabase(ctx, t, tt.sourceURL...)
+			if err != nil {
+				t.Fatalf("prepareDatabase() error = %v, wantErr %v", err, false)
+			}
+			c := NewSessionStorageDriver(conn.Pool)
+
+			var want *dbtype.Session
+			if !tt.wantErr {
+				if want, err = c.Session(ctx, tt.sessionID); err != nil {
+					t.Fatalf("SessionStorageDriver.Session() error = %v", err)
+				}
+			}
+
+			id, err := c.RotateSession(ctx, tt.sessionID)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SessionStorageDriver.RotateSession() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantNotFound && !httpio.HasNotFound(err) {
+				t.Errorf("SessionStorageDriver.RotateSession() error = %v, want not found", err)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if _, err := c.Session(ctx, tt.sessionID); !httpio.HasNotFound(err) {
+				t.Errorf("SessionStorageDriver.Session(old ID) error = %v, want not found", err)
+			}
+			got, err := c.Session(ctx, id)
+			if err != nil {
+				t.Fatalf("SessionStorageDriver.Session(new ID) error = %v", err)
+			}
+			want.ID = id
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("SessionStorageDriver.Session(new ID) mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestSessionStorageDriver_InsertSession(t *testing.T) {
 	t.Parallel()
 
@@ -908,3 +1058,192 @@ func TestSessionStorageDriver_DestroyAllSessionsForUser(t *testing.T) {
 		})
 	}
 }
+
+func TestSessionStorageDriver_PurgeSessions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		purge          *dbtype.PurgeSessions
+		sourceURL      []string
+		wantDeleted    int64
+		wantErr        bool
+		postAssertions []string
+	}{
+		{
+			name:        "deletes expired sessions",
+			purge:       &dbtype.PurgeSessions{Limit: 100},
+			sourceURL:   []string{"file://../../../schema/postgresql/migrations", "file://testdata/sessions_test/valid_sessions"},
+			wantDeleted: 2,
+			postAssertions: []string{
+				`SELECT COUNT(*) = 3 FROM "Sessions"`,
+				`SELECT COUNT(*) = 0 FROM "Sessions" WHERE "Expired" = true`,
+			},
+		},
+		{
+			name: "deletes idle sessions",
+			purge: &dbtype.PurgeSessions{
+				IdleBefore: ccc.Must(time.Parse(time.RFC3339, "2019-01-01T00:00:00Z")),
+				Limit:      100,
+			},
+			sourceURL:   []string{"file://../../../schema/postgresql/migrations", "file://testdata/sessions_test/valid_sessions"},
+			wantDeleted: 3,
+			postAssertions: []string{
+				`SELECT COUNT(*) = 0 FROM "Sessions" WHERE "Id" = '095887e9-ab67-42c3-8090-6c50780606e3'`,
+				`SELECT COUNT(*) = 2 FROM "Sessions"`,
+			},
+		},
+		{
+			name: "deletes sessions older than the absolute age",
+			purge: &dbtype.PurgeSessions{
+				CreatedBefore: ccc.Must(time.Parse(time.RFC3339, "2019-02-02T00:00:00Z")),
+				Limit:         100,
+			},
+			sourceURL:   []string{"file://../../../schema/postgresql/migrations", "file://testdata/sessions_test/valid_sessions"},
+			wantDeleted: 4,
+			postAssertions: []string{
+				`SELECT COUNT(*) = 1 FROM "Sessions" WHERE "Id" = 'da8d6b11-8ef3-4134-8216-2dd0a94795ba'`,
+				`SELECT COUNT(*) = 1 FROM "Sessions"`,
+			},
+		},
+		{
+			name:        "deletes at most limit rows",
+			purge:       &dbtype.PurgeSessions{Limit: 1},
+			sourceURL:   []string{"file://../../../schema/postgresql/migrations", "file://testdata/sessions_test/valid_sessions"},
+			wantDeleted: 1,
+			postAssertions: []string{
+				`SELECT COUNT(*) = 4 FROM "Sessions"`,
+			},
+		},
+		{
+			name:        "deletes the data of deleted sessions",
+			purge:       &dbtype.PurgeSessions{Limit: 100},
+			sourceURL:   []string{"file://../../../schema/postgresql/migrations", "file://testdata/sessions_test/valid_sessions", "file://testdata/sessions_test/session_data"},
+			wantDeleted: 2,
+			postAssertions: []string{
+				`SELECT COUNT(*) = 1 FROM "SessionsData"`,
+				`SELECT COUNT(*) = 1 FROM "SessionsData" WHERE "SessionId" = '38bd570b-1280-421b-888e-a63f0ca35be7'`,
+			},
+		},
+		{
+			name:      "invalid schema",
+			purge:     &dbtype.PurgeSessions{Limit: 100},
+			sourceURL: []string{"file://testdata/sessions_test/invalid_schema"},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ctx := t.Context()
+			conn, err := prepareDatabase(ctx, t, tt.sourceURL...)
+			if err != nil {
+				t.Fatalf("prepareDatabase() error = %v, wantErr %v", err, false)
+			}
+			c := NewSessionStorageDriver(conn.Pool)
+
+			got, err := c.PurgeSessions(ctx, tt.purge)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SessionStorageDriver.PurgeSessions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.wantDeleted {
+				t.Errorf("SessionStorageDriver.PurgeSessions() = %v, want %v", got, tt.wantDeleted)
+			}
+			runAssertions(ctx, t, conn.Pool, tt.postAssertions)
+		})
+	}
+}
+
+func TestSessionStorageDriver_UserSessions(t *testing.T) {
+	t.Parallel()
+
+	idleBefore := ccc.Must(time.Parse(time.RFC3339, "2020-01-03T12:00:00Z"))
+	tests := []struct {
+		name      string
+		query     *dbtype.UserSessions
+		sourceURL []string
+		wantIDs   []string
+		wantErr   bool
+	}{
+		{
+			name:      "all sessions newest first",
+			query:     &dbtype.UserSessions{Username: "test user 1", Active: true, Expired: true, Limit: 10},
+			sourceURL: []string{"file://../../../schema/postgresql/migrations", "file://testdata/sessions_test/valid_sessions"},
+			wantIDs:   []string{"da8d6b11-8ef3-4134-8216-2dd0a94795ba", "aa817d69-f550-474b-8eae-7b29da32e3a8", "38bd570b-1280-421b-888e-a63f0ca35be7"},
+		},
+		{
+			name:      "active sessions",
+			query:     &dbtype.UserSessions{Username: "test user 1", Active: true, Limit: 10},
+			sourceURL: []string{"file://../../../schema/postgresql/migrations", "file://testdata/sessions_test/valid_sessions"},
+			wantIDs:   []string{"da8d6b11-8ef3-4134-8216-2dd0a94795ba", "38bd570b-1280-421b-888e-a63f0ca35be7"},
+		},
+		{
+			name:      "expired sessions",
+			query:     &dbtype.UserSessions{Username: "test user 1", Expired: true, Limit: 10},
+			sourceURL: []string{"file://../../../schema/postgresql/migrations", "file://testdata/sessions_test/valid_sessions"},
+			wantIDs:   []string{"aa817d69-f550-474b-8eae-7b29da32e3a8"},
+		},
+		{
+			name:      "idle sessions are not active",
+			query:     &dbtype.UserSessions{Username: "test user 1", Active: true, IdleBefore: idleBefore, Limit: 10},
+			sourceURL: []string{"file://../../../schema/postgresql/migrations", "file://testdata/sessions_test/valid_sessions"},
+			wantIDs:   []string{"da8d6b11-8ef3-4134-8216-2dd0a94795ba"},
+		},
+		{
+			name:      "idle sessions are expired",
+			query:     &dbtype.UserSessions{Username: "test user 1", Expired: true, IdleBefore: idleBefore, Limit: 10},
+			sourceURL: []string{"file://../../../schema/postgresql/migrations", "file://testdata/sessions_test/valid_sessions"},
+			wantIDs:   []string{"aa817d69-f550-474b-8eae-7b29da32e3a8", "38bd570b-1280-421b-888e-a63f0ca35be7"},
+		},
+		{
+			name:      "limit",
+			query:     &dbtype.UserSessions{Username: "test user 1", Active: true, Expired: true, Limit: 2},
+			sourceURL: []string{"file://../../../schema/postgresql/migrations", "file://testdata/sessions_test/valid_sessions"},
+			wantIDs:   []string{"da8d6b11-8ef3-4134-8216-2dd0a94795ba", "aa817d69-f550-474b-8eae-7b29da32e3a8"},
+		},
+		{
+			name: "after cursor",
+			query: &dbtype.UserSessions{
+				Username: "test user 1",
+				Active:   true,
+				Expired:  true,
+				After: &dbtype.SessionCursor{
+					CreatedAt: ccc.Must(time.Parse(time.RFC3339, "2019-02-02T05:10:20Z")),
+					ID:        ccc.Must(ccc.UUIDFromString("aa817d69-f550-474b-8eae-7b29da32e3a8")),
+				},
+				Limit: 10,
+			},
+			sourceURL: []string{"file://../../../schema/postgresql/migrations", "file://testdata/sessions_test/valid_sessions"},
+			wantIDs:   []string{"38bd570b-1280-421b-888e-a63f0ca35be7"},
+		},
+		{
+			name:      "invalid schema",
+			query:     &dbtype.UserSessions{Username: "test user 1", Active: true, Expired: true, Limit: 10},
+			sourceURL: []string{"file://testdata/sessions_test/invalid_schema"},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ctx := t.Context()
+			conn, err := prepareDatabase(ctx, t, tt.sourceURL...)
+			if err != nil {
+				t.Fatalf("prepareDatabase() error = %v, wantErr %v", err, false)
+			}
+			c := NewSessionStorageDriver(conn.Pool)
+
+			got, err := c.UserSessions(ctx, tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SessionStorageDriver.UserSessions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			gotIDs := make([]string, 0, len(got))
+			for _, s := range got {
+				gotIDs = append(gotIDs, s.ID.String())
+			}
+			if !tt.wantErr && !slices.Equal(gotIDs, tt.wantIDs) {
+				t.Errorf("SessionStorageDriver.UserSessions() = %v, want %v", gotIDs, tt.wantIDs)
+			}
+		})
+	}
+}
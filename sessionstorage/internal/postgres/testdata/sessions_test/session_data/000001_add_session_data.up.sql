@@ -0,0 +1,5 @@
+INSERT INTO "SessionsData" ("SessionId", "Name", "Value", "UpdatedAt")
+    VALUES
+        ('38bd570b-1280-421b-888e-a63f0ca35be7', 'theme', '"dark"', '2020-01-02 08:05:03+00:00'),
+        ('aa817d69-f550-474b-8eae-7b29da32e3a8', 'theme', '"light"', '2020-01-03 08:05:03+00:00'),
+        ('aa817d69-f550-474b-8eae-7b29da32e3a8', 'cart', '{"items": [1, 2]}', '2020-01-03 08:05:03+00:00');
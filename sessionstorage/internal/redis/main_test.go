@@ -0,0 +1,63 @@
+package redis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/cccteam/ccc"
+	"github.com/cccteam/session/internal/dbtype"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+const testSessionTimeout = time.Hour
+
+// prepareDriver starts an in-process Redis server and returns a driver connected to it.
+func prepareDriver(t *testing.T) (*SessionStorageDriver, *miniredis.Miniredis) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	return NewSessionStorageDriver(client, testSessionTimeout), server
+}
+
+// insertSessions inserts a session for each username, using oidcSIDs when given, and returns their ids.
+func insertSessions(t *testing.T, d *SessionStorageDriver, usernames []string, oidcSIDs ...string) []ccc.UUID {
+	t.Helper()
+
+	ids := make([]ccc.UUID, 0, len(usernames))
+	for i, username := range usernames {
+		insert := &dbtype.InsertOIDCSession{
+			InsertSession: dbtype.InsertSession{
+				Username:  username,
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+			},
+		}
+		if i < len(oidcSIDs) {
+			insert.OidcSID = oidcSIDs[i]
+		}
+
+		id, err := d.InsertSessionOIDC(t.Context(), insert)
+		if err != nil {
+			t.Fatalf("SessionStorageDriver.InsertSessionOIDC() error = %v", err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+// expired reports whether the session is marked as expired.
+func expired(t *testing.T, d *SessionStorageDriver, id ccc.UUID) bool {
+	t.Helper()
+
+	session, err := d.Session(t.Context(), id)
+	if err != nil {
+		t.Fatalf("SessionStorageDriver.Session() error = %v", err)
+	}
+
+	return session.Expired
+}
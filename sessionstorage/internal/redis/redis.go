@@ -0,0 +1,850 @@
+// Package redis implements a session storage driver for Redis and servers that speak the Redis protocol.
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cccteam/ccc"
+	"github.com/cccteam/ccc/securehash"
+	"github.com/cccteam/ccc/tracer"
+	"github.com/cccteam/httpio"
+	"github.com/cccteam/session/internal/dbtype"
+	"github.com/go-playground/errors/v5"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// Session hash fields
+const (
+	fieldID         = "Id"
+	fieldUsername   = "Username"
+	fieldOidcSID    = "OidcSid"
+	fieldCreatedAt  = "CreatedAt"
+	fieldUpdatedAt  = "UpdatedAt"
+	fieldExpired    = "Expired"
+	fieldClientIP   = "ClientIp"
+	fieldUserAgent  = "UserAgent"
+	fieldAuthMethod = "AuthMethod"
+	// fieldLastAuthenticatedAt is missing on sessions created before it was recorded.
+	fieldLastAuthenticatedAt = "LastAuthenticatedAt"
+	// fieldImpersonatorUsername and fieldImpersonatorSessionID are only set on impersonated sessions.
+	fieldImpersonatorUsername  = "ImpersonatorUsername"
+	fieldImpersonatorSessionID = "ImpersonatorSessionId"
+	// fieldIdleTimeout is only set on sessions with their own idle timeout.
+	fieldIdleTimeout = "IdleTimeoutSeconds"
+	// fieldDataPrefix starts the fields holding the session data, one JSON value per name.
+	fieldDataPrefix = "data:"
+)
+
+// maxTxRetries is the number of times an optimistic transaction is attempted before giving up.
+const maxTxRetries = 10
+
+var errUsersNotSupported = errors.New("session users are not supported by the redis driver")
+
+// timestampFormat is a fixed width RFC 3339 layout, so timestamps stored in UTC sort as strings.
+const timestampFormat = "2006-01-02T15:04:05.000000000Z07:00"
+
+// touchSession updates fields on the session hash only if it still exists, so a key that
+// expires between commands is never recreated without its other fields.
+// KEYS[1] is the session key, ARGV[1] the new TTL in milliseconds or 0 to keep the current TTL,
+// ARGV[2] an activity time that leaves the fields unchanged when the stored UpdatedAt is not
+// before it, or an empty string to always write them, and the remaining ARGV are field, value pairs.
+// A longer idle timeout stored on the session takes precedence over the new TTL.
+var touchSession = goredis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+local updatedAt = redis.call('HGET', KEYS[1], 'UpdatedAt')
+if ARGV[2] == '' or not updatedAt or updatedAt < ARGV[2] then
+	redis.call('HSET', KEYS[1], unpack(ARGV, 3))
+end
+local ttl = tonumber(ARGV[1])
+if ttl > 0 then
+	local idleTimeout = tonumber(redis.call('HGET', KEYS[1], 'IdleTimeoutSeconds') or '0') * 1000
+	redis.call('PEXPIRE', KEYS[1], math.max(ttl, idleTimeout))
+end
+return 1
+`)
+
+// updateSessionData writes and removes session data fields only if the session still exists.
+// KEYS[1] is the session key, ARGV[1] the number n of field, value pairs to write that follow it,
+// and the remaining ARGV are the fields to remove.
+var updateSessionData = goredis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+local n = tonumber(ARGV[1])
+if n > 0 then
+	redis.call('HSET', KEYS[1], unpack(ARGV, 2, 2 * n + 1))
+end
+if #ARGV > 2 * n + 1 then
+	redis.call('HDEL', KEYS[1], unpack(ARGV, 2 * n + 2))
+end
+return 1
+`)
+
+// extendExpiry sets the TTL of KEYS[1] to ARGV[1] milliseconds unless the key already lives longer,
+// so that an index is not shortened by a session with a shorter idle timeout than its others.
+// PTTL is -1 for a key without a TTL and -2 for a key that does not exist.
+var extendExpiry = goredis.NewScript(`
+local ttl = redis.call('PTTL', KEYS[1])
+if ttl ~= -2 and ttl < tonumber(ARGV[1]) then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return 1
+`)
+
+// SessionStorageDriver is the Redis implementation of the session storage driver.
+//
+// Each session is a hash stored under "<prefix>:<id>" that expires sessionTimeout, or its own
+// longer idle timeout, after its last activity. The session data is stored in the same hash,
+// so it expires, rotates and is deleted with the session. The sessions of a user and of an OIDC sid are indexed
+// by sets stored under "<prefix>:user:<username>" and "<prefix>:sid:<sid>", and the sessions in which a user
+// impersonates another user by sets stored under "<prefix>:impersonator:<username>", which expire with their
+// last session.
+type SessionStorageDriver struct {
+	client         goredis.UniversalClient
+	sessionTimeout time.Duration
+	prefix         string
+}
+
+// NewSessionStorageDriver creates a new SessionStorageDriver whose keys expire sessionTimeout after the last activity.
+func NewSessionStorageDriver(client goredis.UniversalClient, sessionTimeout time.Duration) *SessionStorageDriver {
+	return &SessionStorageDriver{
+		client:         client,
+		sessionTimeout: sessionTimeout,
+		prefix:         "Sessions",
+	}
+}
+
+// SetSessionTableName sets the key prefix used for sessions and their indexes.
+func (s *SessionStorageDriver) SetSessionTableName(name string) {
+	s.prefix = name
+}
+
+// SetUserTableName is a no-op, the redis driver does not store users.
+func (s *SessionStorageDriver) SetUserTableName(_ string) {}
+
+func (s *SessionStorageDriver) sessionKey(id string) string {
+	return s.prefix + ":" + id
+}
+
+func (s *SessionStorageDriver) userKey(username string) string {
+	return s.prefix + ":user:" + username
+}
+
+func (s *SessionStorageDriver) sidKey(oidcSID string) string {
+	return s.prefix + ":sid:" + oidcSID
+}
+
+func (s *SessionStorageDriver) impersonatorKey(username string) string {
+	return s.prefix + ":impersonator:" + username
+}
+
+// indexes returns the keys of the index sets that list a session of username, with the
+// optional oidcSID and impersonator.
+func (s *SessionStorageDriver) indexes(username, oidcSID, impersonator string) []string {
+	indexes := []string{s.userKey(username)}
+	if oidcSID != "" {
+		indexes = append(indexes, s.sidKey(oidcSID))
+	}
+	if impersonator != "" {
+		indexes = append(indexes, s.impersonatorKey(impersonator))
+	}
+
+	return indexes
+}
+
+// Session returns the session information from the database for given sessionID
+func (s *SessionStorageDriver) Session(ctx context.Context, sessionID ccc.UUID) (*dbtype.Session, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	fields, err := s.client.HGetAll(ctx, s.sessionKey(sessionID.String())).Result()
+	if err != nil {
+		return nil, errors.Wrap(err, "redis.UniversalClient.HGetAll()")
+	}
+	if len(fields) == 0 {
+		return nil, httpio.NewNotFoundMessagef("session %q not found", sessionID)
+	}
+
+	session, err := parseSession(fields)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid session %q", sessionID)
+	}
+
+	return session, nil
+}
+
+func parseSession(fields map[string]string) (*dbtype.Session, error) {
+	id, err := ccc.UUIDFromString(fields[fieldID])
+	if err != nil {
+		return nil, errors.Wrap(err, "ccc.UUIDFromString()")
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, fields[fieldCreatedAt])
+	if err != nil {
+		return nil, errors.Wrap(err, "time.Parse()")
+	}
+	updatedAt, err := time.Parse(time.RFC3339Nano, fields[fieldUpdatedAt])
+	if err != nil {
+		return nil, errors.Wrap(err, "time.Parse()")
+	}
+	expired, err := strconv.ParseBool(fields[fieldExpired])
+	if err != nil {
+		return nil, errors.Wrap(err, "strconv.ParseBool()")
+	}
+	var lastAuthenticatedAt time.Time
+	if v, ok := fields[fieldLastAuthenticatedAt]; ok {
+		if lastAuthenticatedAt, err = time.Parse(time.RFC3339Nano, v); err != nil {
+			return nil, errors.Wrap(err, "time.Parse()")
+		}
+	}
+
+	var idleTimeoutSeconds int64
+	if v, ok := fields[fieldIdleTimeout]; ok {
+		if idleTimeoutSeconds, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return nil, errors.Wrap(err, "strconv.ParseInt()")
+		}
+	}
+
+	return &dbtype.Session{
+		ID:                    id,
+		Username:              fields[fieldUsername],
+		CreatedAt:             createdAt,
+		UpdatedAt:             updatedAt,
+		Expired:               expired,
+		IdleTimeoutSeconds:    idleTimeoutSeconds,
+		ClientIP:              fields[fieldClientIP],
+		UserAgent:             fields[fieldUserAgent],
+		AuthMethod:            fields[fieldAuthMethod],
+		ImpersonatorUsername:  fields[fieldImpersonatorUsername],
+		ImpersonatorSessionID: fields[fieldImpersonatorSessionID],
+		LastAuthenticatedAt:   lastAuthenticatedAt,
+	}, nil
+}
+
+// timestamp formats t as stored on the session hash.
+func timestamp(t time.Time) string {
+	return t.UTC().Format(timestampFormat)
+}
+
+// ttl returns how long the key of a session lives after its last activity.
+func (s *SessionStorageDriver) ttl(idleTimeoutSeconds int64) time.Duration {
+	return max(s.sessionTimeout, time.Duration(idleTimeoutSeconds)*time.Second)
+}
+
+// InsertSession inserts a Session into database
+func (s *SessionStorageDriver) InsertSession(ctx context.Context, insertSession *dbtype.InsertSession) (ccc.UUID, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	return s.insertSession(ctx, insertSession, "")
+}
+
+func (s *SessionStorageDriver) insertSession(ctx context.Context, insertSession *dbtype.InsertSession, oidcSID string) (ccc.UUID, error) {
+	id, err := ccc.NewUUID()
+	if err != nil {
+		return ccc.NilUUID, errors.Wrap(err, "ccc.NewUUID()")
+	}
+
+	key := s.sessionKey(id.String())
+	indexes := s.indexes(insertSession.Username, oidcSID, insertSession.ImpersonatorUsername)
+	ttl := s.ttl(insertSession.IdleTimeoutSeconds)
+
+	values := []any{
+		fieldID, id.String(),
+		fieldUsername, insertSession.Username,
+		fieldOidcSID, oidcSID,
+		fieldCreatedAt, timestamp(insertSession.CreatedAt),
+		fieldUpdatedAt, timestamp(insertSession.UpdatedAt),
+		fieldExpired, strconv.FormatBool(insertSession.Expired),
+		fieldClientIP, insertSession.ClientIP,
+		fieldUserAgent, insertSession.UserAgent,
+		fieldAuthMethod, insertSession.AuthMethod,
+		fieldLastAuthenticatedAt, timestamp(insertSession.LastAuthenticatedAt),
+	}
+	if insertSession.IdleTimeoutSeconds != 0 {
+		values = append(values, fieldIdleTimeout, strconv.FormatInt(insertSession.IdleTimeoutSeconds, 10))
+	}
+	if insertSession.ImpersonatorUsername != "" {
+		values = append(values, fieldImpersonatorUsername, insertSession.ImpersonatorUsername, fieldImpersonatorSessionID, insertSession.ImpersonatorSessionID)
+	}
+
+	if insertSession.Limit != nil {
+		evict, err := s.indexLimitedSession(ctx, insertSession.Username, id, insertSession.Limit)
+		if err != nil {
+			return ccc.NilUUID, err
+		}
+		if _, err := s.expireSessions(ctx, evict); err != nil {
+			return ccc.NilUUID, err
+		}
+	}
+
+	// The keys may live on different cluster nodes, so they are pipelined rather than
+	// written in a transaction. The session hash is written last so that it is never
+	// visible without its index entries.
+	if _, err := s.client.Pipelined(ctx, func(pipe goredis.Pipeliner) error {
+		for _, index := range indexes {
+			pipe.SAdd(ctx, index, id.String())
+			s.extendIndexExpiry(ctx, pipe, index, ttl)
+		}
+		pipe.HSet(ctx, key, values...)
+		pipe.PExpire(ctx, key, ttl)
+
+		return nil
+	}); err != nil {
+		return ccc.NilUUID, errors.Wrap(err, "redis.UniversalClient.Pipelined()")
+	}
+
+	return id, nil
+}
+
+// indexLimitedSession adds id to the user index and removes the oldest active sessions of username
+// that do not fit in limit alongside it, returning the removed sessions for the caller to expire.
+// The index is updated in an optimistic transaction, so concurrent logins of the user are counted
+// one at a time.
+func (s *SessionStorageDriver) indexLimitedSession(ctx context.Context, username string, id ccc.UUID, limit *dbtype.InsertLimit) ([]string, error) {
+	index := s.userKey(username)
+
+	for range maxTxRetries {
+		var evict []string
+		err := s.client.Watch(ctx, func(tx *goredis.Tx) error {
+			ids, err := tx.SMembers(ctx, index).Result()
+			if err != nil {
+				return errors.Wrap(err, "redis.Tx.SMembers()")
+			}
+
+			// The session hashes may live on other cluster nodes, so they are read outside of the transaction
+			sessions, missing, err := s.sessions(ctx, ids)
+			if err != nil {
+				return err
+			}
+			sessions = slices.DeleteFunc(sessions, func(session *dbtype.Session) bool {
+				return !limit.Active(session)
+			})
+			// Oldest first
+			slices.SortFunc(sessions, func(a, b *dbtype.Session) int {
+				return dbtype.CompareSessions(b, a)
+			})
+
+			active := make([]ccc.UUID, 0, len(sessions))
+			for _, session := range sessions {
+				active = append(active, session.ID)
+			}
+			evictIDs, err := limit.Evict(active)
+			if err != nil {
+				return errors.Wrap(err, "dbtype.InsertLimit.Evict()")
+			}
+			for _, sessionID := range evictIDs {
+				evict = append(evict, sessionID.String())
+			}
+
+			if _, err := tx.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+				pipe.SAdd(ctx, index, id.String())
+				if len(evict) > 0 {
+					pipe.SRem(ctx, index, evict)
+				}
+				if len(missing) > 0 {
+					pipe.SRem(ctx, index, missing)
+				}
+
+				return nil
+			}); err != nil {
+				return errors.Wrap(err, "redis.Tx.TxPipelined()")
+			}
+
+			return nil
+		}, index)
+		if errors.Is(err, goredis.TxFailedErr) {
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "redis.UniversalClient.Watch()")
+		}
+
+		return evict, nil
+	}
+
+	return nil, errors.Newf("user index %q changed %d times while inserting a session", index, maxTxRetries)
+}
+
+// UpdateSessionActivity updates the session activity column with the current time
+// and extends the expiry of the session and its indexes.
+func (s *SessionStorageDriver) UpdateSessionActivity(ctx context.Context, sessionID ccc.UUID) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	found, err := s.touch(ctx, sessionID, time.Now(), false)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return httpio.NewNotFoundMessagef("session %q not found", sessionID)
+	}
+
+	return nil
+}
+
+// UpdateSessionAuthentication sets the session activity and last authentication columns to the current
+// time and extends the expiry of the session and its indexes.
+func (s *SessionStorageDriver) UpdateSessionAuthentication(ctx context.Context, sessionID ccc.UUID) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	now := time.Now()
+	found, err := s.touch(ctx, sessionID, now, false, fieldLastAuthenticatedAt, timestamp(now))
+	if err != nil {
+		return err
+	}
+	if !found {
+		return httpio.NewNotFoundMessagef("session %q not found", sessionID)
+	}
+
+	return nil
+}
+
+// UpdateSessionsActivity sets the activity time of each session and extends the expiry
+// of the sessions and their indexes, skipping sessions that do not exist. An activity time
+// that is not after the one stored on the session is not written.
+func (s *SessionStorageDriver) UpdateSessionsActivity(ctx context.Context, activity []*dbtype.SessionActivity) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	for _, a := range activity {
+		if _, err := s.touch(ctx, a.ID, a.UpdatedAt, true); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// touch sets the activity time of the session, and the field, value pairs in set, and extends the
+// expiry of the session and its indexes. When onlyLater is true the fields are only set if updatedAt
+// is after the stored activity time. It reports whether the session exists.
+func (s *SessionStorageDriver) touch(ctx context.Context, sessionID ccc.UUID, updatedAt time.Time, onlyLater bool, set ...any) (bool, error) {
+	key := s.sessionKey(sessionID.String())
+	var after string
+	if onlyLater {
+		after = timestamp(updatedAt)
+	}
+	args := append([]any{s.sessionTimeout.Milliseconds(), after, fieldUpdatedAt, timestamp(updatedAt)}, set...)
+	updated, err := touchSession.Run(ctx, s.client, []string{key}, args...).Int()
+	if err != nil {
+		return false, errors.Wrap(err, "redis.Script.Run()")
+	}
+	if updated == 0 {
+		return false, nil
+	}
+
+	fields, err := s.client.HMGet(ctx, key, fieldUsername, fieldOidcSID, fieldImpersonatorUsername, fieldIdleTimeout).Result()
+	if err != nil {
+		return false, errors.Wrap(err, "redis.UniversalClient.HMGet()")
+	}
+	username, ok := fields[0].(string)
+	if !ok {
+		return true, nil
+	}
+	oidcSID, _ := fields[1].(string)
+	impersonator, _ := fields[2].(string)
+
+	var idleTimeoutSeconds int64
+	if v, ok := fields[3].(string); ok {
+		idleTimeoutSeconds, _ = strconv.ParseInt(v, 10, 64)
+	}
+	ttl := s.ttl(idleTimeoutSeconds)
+
+	if _, err := s.client.Pipelined(ctx, func(pipe goredis.Pipeliner) error {
+		for _, index := range s.indexes(username, oidcSID, impersonator) {
+			s.extendIndexExpiry(ctx, pipe, index, ttl)
+		}
+
+		return nil
+	}); err != nil {
+		return false, errors.Wrap(err, "redis.UniversalClient.Pipelined()")
+	}
+
+	return true, nil
+}
+
+// extendIndexExpiry queues a command on pipe that extends the TTL of index to ttl.
+func (s *SessionStorageDriver) extendIndexExpiry(ctx context.Context, pipe goredis.Pipeliner, index string, ttl time.Duration) {
+	// Eval rather than EvalSha, since a pipeline cannot fall back when the script is not loaded
+	extendExpiry.Eval(ctx, pipe, []string{index}, ttl.Milliseconds())
+}
+
+// DestroySession marks the session as expired, and removes the sessions whose key has expired
+// from the indexes of the session. The session stays in its indexes until its own key expires,
+// so that it is still listed as an expired session.
+func (s *SessionStorageDriver) DestroySession(ctx context.Context, sessionID ccc.UUID) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	// Attempting to destroy a session that does not exist is something that
+	// can happen when a browser returns with old state. Erroring in this
+	// case is extra noise, so we will ignore instead.
+	missing, err := s.expireSessions(ctx, []string{sessionID.String()})
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return nil
+	}
+
+	fields, err := s.client.HMGet(ctx, s.sessionKey(sessionID.String()), fieldUsername, fieldOidcSID, fieldImpersonatorUsername).Result()
+	if err != nil {
+		return errors.Wrap(err, "redis.UniversalClient.HMGet()")
+	}
+	username, ok := fields[0].(string)
+	if !ok {
+		return nil
+	}
+	oidcSID, _ := fields[1].(string)
+	impersonator, _ := fields[2].(string)
+
+	for _, index := range s.indexes(username, oidcSID, impersonator) {
+		if err := s.pruneIndex(ctx, index); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// pruneIndex removes the sessions whose key has expired from index.
+func (s *SessionStorageDriver) pruneIndex(ctx context.Context, index string) error {
+	ids, err := s.client.SMembers(ctx, index).Result()
+	if err != nil {
+		return errors.Wrap(err, "redis.UniversalClient.SMembers()")
+	}
+
+	_, missing, err := s.sessions(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	return s.unindex(ctx, index, missing)
+}
+
+// RotateSession moves an active session to a new key, keeping its fields, and replaces
+// its ID in the user and sid indexes. The old key is watched while the new one is written,
+// so a rotation that races with a change to the session, such as it being destroyed or
+// rotated by another request, is undone and retried.
+func (s *SessionStorageDriver) RotateSession(ctx context.Context, sessionID ccc.UUID) (ccc.UUID, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	oldKey := s.sessionKey(sessionID.String())
+
+	for range maxTxRetries {
+		var id ccc.UUID
+		err := s.client.Watch(ctx, func(tx *goredis.Tx) error {
+			fields, err := tx.HGetAll(ctx, oldKey).Result()
+			if err != nil {
+				return errors.Wrap(err, "redis.Tx.HGetAll()")
+			}
+			if len(fields) == 0 {
+				return httpio.NewNotFoundMessagef("session %q not found", sessionID)
+			}
+
+			session, err := parseSession(fields)
+			if err != nil {
+				return errors.Wrapf(err, "invalid session %q", sessionID)
+			}
+			if session.Expired {
+				return httpio.NewNotFoundMessagef("session %q not found", sessionID)
+			}
+
+			if id, err = ccc.NewUUID(); err != nil {
+				return errors.Wrap(err, "ccc.NewUUID()")
+			}
+			fields[fieldID] = id.String()
+
+			ttl := s.ttl(session.IdleTimeoutSeconds)
+			indexes := s.indexes(session.Username, fields[fieldOidcSID], fields[fieldImpersonatorUsername])
+
+			// The new key may live on another cluster node, so it is written outside of the
+			// transaction. As in insertSession, the new hash is written after its index entries.
+			if _, err := s.client.Pipelined(ctx, func(pipe goredis.Pipeliner) error {
+				for _, index := range indexes {
+					pipe.SAdd(ctx, index, id.String())
+					s.extendIndexExpiry(ctx, pipe, index, ttl)
+				}
+				pipe.HSet(ctx, s.sessionKey(id.String()), fields)
+				pipe.PExpire(ctx, s.sessionKey(id.String()), ttl)
+
+				return nil
+			}); err != nil {
+				return errors.Wrap(err, "redis.UniversalClient.Pipelined()")
+			}
+
+			if _, err := tx.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+				pipe.Del(ctx, oldKey)
+
+				return nil
+			}); err != nil {
+				if err := s.removeSession(ctx, id.String(), indexes); err != nil {
+					return err
+				}
+
+				return errors.Wrap(err, "redis.Tx.TxPipelined()")
+			}
+
+			for _, index := range indexes {
+				if err := s.unindex(ctx, index, []string{sessionID.String()}); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}, oldKey)
+		if errors.Is(err, goredis.TxFailedErr) {
+			continue
+		}
+		if httpio.HasNotFound(err) {
+			return ccc.NilUUID, err
+		}
+		if err != nil {
+			return ccc.NilUUID, errors.Wrap(err, "redis.UniversalClient.Watch()")
+		}
+
+		return id, nil
+	}
+
+	return ccc.NilUUID, errors.Newf("session %q changed %d times while rotating it", sessionID, maxTxRetries)
+}
+
+// removeSession deletes the session key with the given id and removes it from indexes.
+func (s *SessionStorageDriver) removeSession(ctx context.Context, id string, indexes []string) error {
+	if _, err := s.client.Pipelined(ctx, func(pipe goredis.Pipeliner) error {
+		pipe.Del(ctx, s.sessionKey(id))
+		for _, index := range indexes {
+			pipe.SRem(ctx, index, id)
+		}
+
+		return nil
+	}); err != nil {
+		return errors.Wrap(err, "redis.UniversalClient.Pipelined()")
+	}
+
+	return nil
+}
+
+// DestroyAllUserSessions destroys all sessions for a given user, including the sessions in which
+// the user impersonates another user
+func (s *SessionStorageDriver) DestroyAllUserSessions(ctx context.Context, username string) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	if err := s.expireIndexedSessions(ctx, s.userKey(username)); err != nil {
+		return err
+	}
+
+	return s.expireIndexedSessions(ctx, s.impersonatorKey(username))
+}
+
+// expireIndexedSessions marks every session listed in the index set as expired, and removes
+// the sessions whose key has expired from the index.
+func (s *SessionStorageDriver) expireIndexedSessions(ctx context.Context, index string) error {
+	ids, err := s.client.SMembers(ctx, index).Result()
+	if err != nil {
+		return errors.Wrap(err, "redis.UniversalClient.SMembers()")
+	}
+
+	missing, err := s.expireSessions(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	return s.unindex(ctx, index, missing)
+}
+
+// expireSessions marks the sessions with the given ids as expired, returning the ids whose key has expired.
+func (s *SessionStorageDriver) expireSessions(ctx context.Context, ids []string) ([]string, error) {
+	now := timestamp(time.Now())
+	var missing []string
+	for _, id := range ids {
+		updated, err := touchSession.Run(ctx, s.client, []string{s.sessionKey(id)},
+			0, "", fieldExpired, strconv.FormatBool(true), fieldUpdatedAt, now,
+		).Int()
+		if err != nil {
+			return nil, errors.Wrap(err, "redis.Script.Run()")
+		}
+		if updated == 0 {
+			missing = append(missing, id)
+		}
+	}
+
+	return missing, nil
+}
+
+// unindex removes the ids of sessions whose key has expired from index.
+func (s *SessionStorageDriver) unindex(ctx context.Context, index string, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	if err := s.client.SRem(ctx, index, ids).Err(); err != nil {
+		return errors.Wrap(err, "redis.UniversalClient.SRem()")
+	}
+
+	return nil
+}
+
+// UserSessions returns up to query.Limit sessions for query.Username that match the query, newest first.
+// The sessions are read through the user index and filtered in process, and the sessions whose key
+// has expired are removed from the index.
+func (s *SessionStorageDriver) UserSessions(ctx context.Context, query *dbtype.UserSessions) ([]*dbtype.Session, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	ids, err := s.client.SMembers(ctx, s.userKey(query.Username)).Result()
+	if err != nil {
+		return nil, errors.Wrap(err, "redis.UniversalClient.SMembers()")
+	}
+
+	sessions, missing, err := s.sessions(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.unindex(ctx, s.userKey(query.Username), missing); err != nil {
+		return nil, err
+	}
+	sessions = slices.DeleteFunc(sessions, func(session *dbtype.Session) bool {
+		return !query.Matches(session)
+	})
+	slices.SortFunc(sessions, dbtype.CompareSessions)
+
+	return sessions[:min(len(sessions), query.Limit)], nil
+}
+
+// sessions returns the sessions with the given ids, and the ids of those whose key has expired.
+func (s *SessionStorageDriver) sessions(ctx context.Context, ids []string) ([]*dbtype.Session, []string, error) {
+	cmds := make([]*goredis.MapStringStringCmd, 0, len(ids))
+	if _, err := s.client.Pipelined(ctx, func(pipe goredis.Pipeliner) error {
+		for _, id := range ids {
+			cmds = append(cmds, pipe.HGetAll(ctx, s.sessionKey(id)))
+		}
+
+		return nil
+	}); err != nil {
+		return nil, nil, errors.Wrap(err, "redis.UniversalClient.Pipelined()")
+	}
+
+	sessions := make([]*dbtype.Session, 0, len(cmds))
+	var missing []string
+	for i, cmd := range cmds {
+		// Index entries outlive the sessions that expired before their index
+		if len(cmd.Val()) == 0 {
+			missing = append(missing, ids[i])
+
+			continue
+		}
+
+		session, err := parseSession(cmd.Val())
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "parseSession()")
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, missing, nil
+}
+
+// PurgeSessions is a no-op that returns zero, the redis driver relies on key expiry to remove sessions.
+func (s *SessionStorageDriver) PurgeSessions(ctx context.Context, _ *dbtype.PurgeSessions) (int64, error) {
+	_, span := tracer.Start(ctx)
+	defer span.End()
+
+	return 0, nil
+}
+
+// SessionData returns the data stored with the session by key
+func (s *SessionStorageDriver) SessionData(ctx context.Context, sessionID ccc.UUID) (map[string]json.RawMessage, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	fields, err := s.client.HGetAll(ctx, s.sessionKey(sessionID.String())).Result()
+	if err != nil {
+		return nil, errors.Wrap(err, "redis.UniversalClient.HGetAll()")
+	}
+
+	data := make(map[string]json.RawMessage)
+	for field, value := range fields {
+		if name, ok := strings.CutPrefix(field, fieldDataPrefix); ok {
+			data[name] = json.RawMessage(value)
+		}
+	}
+
+	return data, nil
+}
+
+// UpdateSessionData applies update to the data stored with the session
+func (s *SessionStorageDriver) UpdateSessionData(ctx context.Context, sessionID ccc.UUID, update *dbtype.SessionDataUpdate) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	args := make([]any, 0, 1+2*len(update.Set)+len(update.Deleted))
+	args = append(args, len(update.Set))
+	for _, name := range slices.Sorted(maps.Keys(update.Set)) {
+		args = append(args, fieldDataPrefix+name, string(update.Set[name]))
+	}
+	for _, name := range update.Deleted {
+		args = append(args, fieldDataPrefix+name)
+	}
+
+	updated, err := updateSessionData.Run(ctx, s.client, []string{s.sessionKey(sessionID.String())}, args...).Int()
+	if err != nil {
+		return errors.Wrap(err, "redis.Script.Run()")
+	}
+	if updated == 0 {
+		return httpio.NewNotFoundMessagef("session %q not found", sessionID)
+	}
+
+	return nil
+}
+
+// User is not supported by the redis driver.
+func (s *SessionStorageDriver) User(_ context.Context, _ ccc.UUID) (*dbtype.SessionUser, error) {
+	return nil, errUsersNotSupported
+}
+
+// UserByUserName is not supported by the redis driver.
+func (s *SessionStorageDriver) UserByUserName(_ context.Context, _ string) (*dbtype.SessionUser, error) {
+	return nil, errUsersNotSupported
+}
+
+// CreateUser is not supported by the redis driver.
+func (s *SessionStorageDriver) CreateUser(_ context.Context, _ *dbtype.InsertSessionUser) (*dbtype.SessionUser, error) {
+	return nil, errUsersNotSupported
+}
+
+// SetUserUsername is not supported by the redis driver.
+func (s *SessionStorageDriver) SetUserUsername(_ context.Context, _ ccc.UUID, _ string) error {
+	return errUsersNotSupported
+}
+
+// SetUserPasswordHash is not supported by the redis driver.
+func (s *SessionStorageDriver) SetUserPasswordHash(_ context.Context, _ ccc.UUID, _ *securehash.Hash) error {
+	return errUsersNotSupported
+}
+
+// ActivateUser is not supported by the redis driver.
+func (s *SessionStorageDriver) ActivateUser(_ context.Context, _ ccc.UUID) error {
+	return errUsersNotSupported
+}
+
+// DeactivateUser is not supported by the redis driver.
+func (s *SessionStorageDriver) DeactivateUser(_ context.Context, _ ccc.UUID) error {
+	return errUsersNotSupported
+}
+
+// DeleteUser is not supported by the redis driver.
+func (s *SessionStorageDriver) DeleteUser(_ context.Context, _ ccc.UUID) error {
+	return errUsersNotSupported
+}
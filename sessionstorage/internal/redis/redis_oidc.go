@@ -0,0 +1,91 @@
+package redis
+
+import (
+	"context"
+
+	"github.com/cccteam/ccc"
+	"github.com/cccteam/ccc/tracer"
+	"github.com/cccteam/httpio"
+	"github.com/cccteam/session/internal/dbtype"
+	"github.com/go-playground/errors/v5"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// InsertSessionOIDC inserts a Session into database and indexes it by its OIDC sid
+func (s *SessionStorageDriver) InsertSessionOIDC(ctx context.Context, insertSession *dbtype.InsertOIDCSession) (ccc.UUID, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	return s.insertSession(ctx, &insertSession.InsertSession, insertSession.OidcSID)
+}
+
+// DestroySessionOIDC marks every session of the user that owns the OIDC sid as expired,
+// matching the SQL drivers. Sessions whose key has expired are removed from the sid index.
+func (s *SessionStorageDriver) DestroySessionOIDC(ctx context.Context, oidcSID string) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	ids, err := s.client.SMembers(ctx, s.sidKey(oidcSID)).Result()
+	if err != nil {
+		return errors.Wrap(err, "redis.UniversalClient.SMembers()")
+	}
+
+	usernames := make(map[string]struct{})
+	var missing []string
+	for _, id := range ids {
+		username, err := s.client.HGet(ctx, s.sessionKey(id), fieldUsername).Result()
+		if err != nil {
+			if errors.Is(err, goredis.Nil) {
+				missing = append(missing, id)
+
+				continue
+			}
+
+			return errors.Wrap(err, "redis.UniversalClient.HGet()")
+		}
+		usernames[username] = struct{}{}
+	}
+
+	if err := s.unindex(ctx, s.sidKey(oidcSID), missing); err != nil {
+		return err
+	}
+
+	for username := range usernames {
+		if err := s.expireIndexedSessions(ctx, s.userKey(username)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UsernameOIDC returns the username of the session with the oidcSID, removing the sessions
+// whose key has expired from the sid index as it finds them
+func (s *SessionStorageDriver) UsernameOIDC(ctx context.Context, oidcSID string) (string, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	ids, err := s.client.SMembers(ctx, s.sidKey(oidcSID)).Result()
+	if err != nil {
+		return "", errors.Wrap(err, "redis.UniversalClient.SMembers()")
+	}
+
+	for _, id := range ids {
+		username, err := s.client.HGet(ctx, s.sessionKey(id), fieldUsername).Result()
+		if err != nil {
+			if errors.Is(err, goredis.Nil) {
+				if err := s.unindex(ctx, s.sidKey(oidcSID), []string{id}); err != nil {
+					return "", err
+				}
+
+				continue
+			}
+
+			return "", errors.Wrap(err, "redis.UniversalClient.HGet()")
+		}
+
+		return username, nil
+	}
+
+	return "", httpio.NewNotFoundMessagef("session with oidc sid %q not found", oidcSID)
+}
@@ -0,0 +1,44 @@
+package redis
+
+import (
+	"testing"
+)
+
+func TestSessionStorageDriver_DestroySessionOIDC(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		oidcSID     string
+		wantExpired []bool
+	}{
+		{
+			name:        "success without destroying the session (not found)",
+			oidcSID:     "oidc session 12345",
+			wantExpired: []bool{false, false, false},
+		},
+		{
+			name:        "success destroying the sessions of the user for the sid",
+			oidcSID:     "oidc session 1",
+			wantExpired: []bool{true, true, false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			d, _ := prepareDriver(t)
+			ids := insertSessions(t, d, []string{"test user 1", "test user 1", "test user 2"}, "oidc session 1", "oidc session 2", "oidc session 3")
+
+			if err := d.DestroySessionOIDC(t.Context(), tt.oidcSID); err != nil {
+				t.Fatalf("SessionStorageDriver.DestroySessionOIDC() error = %v", err)
+			}
+
+			for i, want := range tt.wantExpired {
+				if got := expired(t, d, ids[i]); got != want {
+					t.Errorf("session %d Expired = %v, want %v", i, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,596 @@
+package redis
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cccteam/ccc"
+	"github.com/cccteam/httpio"
+	"github.com/cccteam/session/internal/dbtype"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+func TestSessionStorageDriver_InsertSession(t *testing.T) {
+	t.Parallel()
+
+	d, server := prepareDriver(t)
+
+	insert := &dbtype.InsertSession{
+		Username:  "testuser",
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	id, err := d.InsertSession(t.Context(), insert)
+	if err != nil {
+		t.Fatalf("SessionStorageDriver.InsertSession() error = %v", err)
+	}
+	if id == ccc.NilUUID {
+		t.Error("SessionStorageDriver.InsertSession() id is nil, want valid UUID")
+	}
+
+	got, err := d.Session(t.Context(), id)
+	if err != nil {
+		t.Fatalf("SessionStorageDriver.Session() error = %v", err)
+	}
+	want := &dbtype.Session{ID: id, Username: insert.Username, CreatedAt: insert.CreatedAt, UpdatedAt: insert.UpdatedAt}
+	if got.ID != want.ID || got.Username != want.Username || !got.CreatedAt.Equal(want.CreatedAt) || !got.UpdatedAt.Equal(want.UpdatedAt) || got.Expired {
+		t.Errorf("SessionStorageDriver.Session() = %v, want %v", got, want)
+	}
+
+	for _, key := range []string{"Sessions:" + id.String(), "Sessions:user:testuser"} {
+		if ttl := server.TTL(key); ttl != testSessionTimeout {
+			t.Errorf("TTL(%s) = %v, want %v", key, ttl, testSessionTimeout)
+		}
+	}
+}
+
+func TestSessionStorageDriver_Session(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		fastForward time.Duration
+		unknownID   bool
+		wantErr     bool
+	}{
+		{
+			name: "success",
+		},
+		{
+			name:      "not found",
+			unknownID: true,
+			wantErr:   true,
+		},
+		{
+			name:        "not found after the session timeout",
+			fastForward: testSessionTimeout,
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			d, server := prepareDriver(t)
+			id := insertSessions(t, d, []string{"test user 1"})[0]
+			if tt.unknownID {
+				id = ccc.Must(ccc.NewUUID())
+			}
+			server.FastForward(tt.fastForward)
+
+			got, err := d.Session(t.Context(), id)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SessionStorageDriver.Session() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !httpio.HasNotFound(err) {
+				t.Errorf("SessionStorageDriver.Session() error = %v, want NotFound", err)
+			}
+			if !tt.wantErr && got.ID != id {
+				t.Errorf("SessionStorageDriver.Session() ID = %v, want %v", got.ID, id)
+			}
+		})
+	}
+}
+
+func TestSessionStorageDriver_UpdateSessionActivity(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		unknownID bool
+		wantErr   bool
+	}{
+		{
+			name:      "fails to find session",
+			unknownID: true,
+			wantErr:   true,
+		},
+		{
+			name: "success updating session activity",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			d, server := prepareDriver(t)
+			id := insertSessions(t, d, []string{"test user 1"}, "sid 1")[0]
+			if tt.unknownID {
+				id = ccc.Must(ccc.NewUUID())
+			}
+			server.FastForward(testSessionTimeout / 2)
+
+			preExecTime := time.Now()
+			err := d.UpdateSessionActivity(t.Context(), id)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SessionStorageDriver.UpdateSessionActivity() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !httpio.HasNotFound(err) {
+					t.Errorf("SessionStorageDriver.UpdateSessionActivity() error = %v, want NotFound", err)
+				}
+				if server.Exists("Sessions:" + id.String()) {
+					t.Errorf("SessionStorageDriver.UpdateSessionActivity() created a key for a missing session")
+				}
+
+				return
+			}
+
+			got, err := d.Session(t.Context(), id)
+			if err != nil {
+				t.Fatalf("SessionStorageDriver.Session() error = %v", err)
+			}
+			if got.UpdatedAt.Before(preExecTime) {
+				t.Errorf("SessionStorageDriver.UpdateSessionActivity() UpdatedAt = %v, want after %v", got.UpdatedAt, preExecTime)
+			}
+			if got.Username != "test user 1" {
+				t.Errorf("SessionStorageDriver.UpdateSessionActivity() Username = %q, want fields preserved", got.Username)
+			}
+			for _, key := range []string{"Sessions:" + id.String(), "Sessions:user:test user 1", "Sessions:sid:sid 1"} {
+				if ttl := server.TTL(key); ttl != testSessionTimeout {
+					t.Errorf("TTL(%s) = %v, want %v", key, ttl, testSessionTimeout)
+				}
+			}
+		})
+	}
+}
+
+func TestSessionStorageDriver_UpdateSessionsActivity(t *testing.T) {
+	t.Parallel()
+
+	d, server := prepareDriver(t)
+	id := insertSessions(t, d, []string{"test user 1"}, "sid 1")[0]
+	missing := ccc.Must(ccc.NewUUID())
+	server.FastForward(testSessionTimeout / 2)
+
+	updatedAt := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	activity := []*dbtype.SessionActivity{{ID: id, UpdatedAt: updatedAt}, {ID: missing, UpdatedAt: updatedAt}}
+	if err := d.UpdateSessionsActivity(t.Context(), activity); err != nil {
+		t.Fatalf("SessionStorageDriver.UpdateSessionsActivity() error = %v", err)
+	}
+
+	got, err := d.Session(t.Context(), id)
+	if err != nil {
+		t.Fatalf("SessionStorageDriver.Session() error = %v", err)
+	}
+	if !got.UpdatedAt.Equal(updatedAt) {
+		t.Errorf("SessionStorageDriver.Session() UpdatedAt = %v, want %v", got.UpdatedAt, updatedAt)
+	}
+	for _, key := range []string{"Sessions:" + id.String(), "Sessions:user:test user 1", "Sessions:sid:sid 1"} {
+		if ttl := server.TTL(key); ttl != testSessionTimeout {
+			t.Errorf("TTL(%q) = %v, want %v", key, ttl, testSessionTimeout)
+		}
+	}
+	if server.Exists("Sessions:" + missing.String()) {
+		t.Error("SessionStorageDriver.UpdateSessionsActivity() created a key for a missing session")
+	}
+
+	for _, tt := range []struct {
+		updatedAt time.Time
+		want      time.Time
+	}{
+		{updatedAt: updatedAt.Add(-time.Hour), want: updatedAt},
+		{updatedAt: updatedAt.Add(500 * time.Millisecond), want: updatedAt.Add(500 * time.Millisecond)},
+	} {
+		if err := d.UpdateSessionsActivity(t.Context(), []*dbtype.SessionActivity{{ID: id, UpdatedAt: tt.updatedAt}}); err != nil {
+			t.Fatalf("SessionStorageDriver.UpdateSessionsActivity() error = %v", err)
+		}
+		got, err := d.Session(t.Context(), id)
+		if err != nil {
+			t.Fatalf("SessionStorageDriver.Session() error = %v", err)
+		}
+		if !got.UpdatedAt.Equal(tt.want) {
+			t.Errorf("SessionStorageDriver.UpdateSessionsActivity(%v) UpdatedAt = %v, want %v", tt.updatedAt, got.UpdatedAt, tt.want)
+		}
+	}
+}
+
+func TestSessionStorageDriver_persistentSessionTTL(t *testing.T) {
+	t.Parallel()
+
+	d, server := prepareDriver(t)
+	idleTimeout := 2 * testSessionTimeout
+	persistent, err := d.InsertSession(t.Context(), &dbtype.InsertSession{
+		Username:           "test user 1",
+		CreatedAt:          time.Now(),
+		UpdatedAt:          time.Now(),
+		IdleTimeoutSeconds: int64(idleTimeout / time.Second),
+	})
+	if err != nil {
+		t.Fatalf("SessionStorageDriver.InsertSession() error = %v", err)
+	}
+	id := insertSessions(t, d, []string{"test user 1"})[0]
+
+	got, err := d.Session(t.Context(), persistent)
+	if err != nil {
+		t.Fatalf("SessionStorageDriver.Session() error = %v", err)
+	}
+	if got.IdleTimeout() != idleTimeout {
+		t.Errorf("SessionStorageDriver.Session() IdleTimeout = %v, want %v", got.IdleTimeout(), idleTimeout)
+	}
+
+	server.FastForward(testSessionTimeout / 2)
+	for _, sessionID := range []ccc.UUID{persistent, id} {
+		if err := d.UpdateSessionActivity(t.Context(), sessionID); err != nil {
+			t.Fatalf("SessionStorageDriver.UpdateSessionActivity() error = %v", err)
+		}
+	}
+
+	for key, want := range map[string]time.Duration{
+		"Sessions:" + persistent.String(): idleTimeout,
+		"Sessions:" + id.String():         testSessionTimeout,
+		// The index must not be shortened by the session with the shorter timeout
+		"Sessions:user:test user 1": idleTimeout,
+	} {
+		if ttl := server.TTL(key); ttl != want {
+			t.Errorf("TTL(%q) = %v, want %v", key, ttl, want)
+		}
+	}
+}
+
+func TestSessionStorageDriver_DestroySession(t *testing.T) {
+	t.Parallel()
+
+	d, _ := prepareDriver(t)
+	ids := insertSessions(t, d, []string{"test user 1", "test user 1"})
+
+	if err := d.DestroySession(t.Context(), ids[0]); err != nil {
+		t.Fatalf("SessionStorageDriver.DestroySession() error = %v", err)
+	}
+	if err := d.DestroySession(t.Context(), ccc.Must(ccc.NewUUID())); err != nil {
+		t.Errorf("SessionStorageDriver.DestroySession() error = %v for a missing session, want nil", err)
+	}
+
+	if !expired(t, d, ids[0]) {
+		t.Errorf("SessionStorageDriver.DestroySession() did not expire session %v", ids[0])
+	}
+	if expired(t, d, ids[1]) {
+		t.Errorf("SessionStorageDriver.DestroySession() expired session %v", ids[1])
+	}
+}
+
+func TestSessionStorageDriver_RotateSession(t *testing.T) {
+	t.Parallel()
+
+	d, server := prepareDriver(t)
+	ids := insertSessions(t, d, []string{"test user 1", "test user 1"}, "sid 1", "sid 2")
+	if err := d.DestroySession(t.Context(), ids[1]); err != nil {
+		t.Fatalf("SessionStorageDriver.DestroySession() error = %v", err)
+	}
+
+	want, err := d.Session(t.Context(), ids[0])
+	if err != nil {
+		t.Fatalf("SessionStorageDriver.Session() error = %v", err)
+	}
+
+	id, err := d.RotateSession(t.Context(), ids[0])
+	if err != nil {
+		t.Fatalf("SessionStorageDriver.RotateSession() error = %v", err)
+	}
+
+	if _, err := d.Session(t.Context(), ids[0]); !httpio.HasNotFound(err) {
+		t.Errorf("SessionStorageDriver.Session(old ID) error = %v, want not found", err)
+	}
+	got, err := d.Session(t.Context(), id)
+	if err != nil {
+		t.Fatalf("SessionStorageDriver.Session(new ID) error = %v", err)
+	}
+	want.ID = id
+	if *got != *want {
+		t.Errorf("SessionStorageDriver.Session(new ID) = %v, want %v", got, want)
+	}
+	for _, index := range []string{"Sessions:user:test user 1", "Sessions:sid:sid 1"} {
+		members, err := server.Members(index)
+		if err != nil {
+			t.Fatalf("Members(%q) error = %v", index, err)
+		}
+		if !slices.Contains(members, id.String()) || slices.Contains(members, ids[0].String()) {
+			t.Errorf("Members(%q) = %v, want %v in place of %v", index, members, id, ids[0])
+		}
+	}
+
+	for _, missing := range []ccc.UUID{ids[0], ids[1], ccc.Must(ccc.NewUUID())} {
+		if _, err := d.RotateSession(t.Context(), missing); !httpio.HasNotFound(err) {
+			t.Errorf("SessionStorageDriver.RotateSession(%v) error = %v, want not found", missing, err)
+		}
+	}
+}
+
+func TestSessionStorageDriver_RotateSession_concurrent(t *testing.T) {
+	t.Parallel()
+
+	d, server := prepareDriver(t)
+	sessionID := insertSessions(t, d, []string{"test user 1"})[0]
+
+	const rotations = 5
+	var wg sync.WaitGroup
+	ids := make([]ccc.UUID, rotations)
+	errs := make([]error, rotations)
+	for i := range rotations {
+		wg.Go(func() {
+			ids[i], errs[i] = d.RotateSession(t.Context(), sessionID)
+		})
+	}
+	wg.Wait()
+
+	var rotated []string
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			rotated = append(rotated, ids[i].String())
+		case !httpio.HasNotFound(err):
+			t.Errorf("SessionStorageDriver.RotateSession() error = %v, want nil or not found", err)
+		}
+	}
+	if len(rotated) != 1 {
+		t.Fatalf("SessionStorageDriver.RotateSession() succeeded %d times, want 1", len(rotated))
+	}
+
+	members, err := server.Members("Sessions:user:test user 1")
+	if err != nil {
+		t.Fatalf("Members() error = %v", err)
+	}
+	if !slices.Equal(members, rotated) {
+		t.Errorf("Members() = %v, want %v", members, rotated)
+	}
+}
+
+func TestSessionStorageDriver_RotateSession_destroyed(t *testing.T) {
+	t.Parallel()
+
+	d, server := prepareDriver(t)
+	sessionID := insertSessions(t, d, []string{"test user 1"})[0]
+
+	// Destroy the session from another client after RotateSession has read it.
+	other := NewSessionStorageDriver(goredis.NewClient(&goredis.Options{Addr: server.Addr()}), testSessionTimeout)
+	var once sync.Once
+	d.client.AddHook(processHook(func(ctx context.Context, cmd goredis.Cmder) {
+		if cmd.Name() == "hgetall" {
+			once.Do(func() {
+				if err := other.DestroySession(ctx, sessionID); err != nil {
+					t.Errorf("SessionStorageDriver.DestroySession() error = %v", err)
+				}
+			})
+		}
+	}))
+
+	if _, err := d.RotateSession(t.Context(), sessionID); !httpio.HasNotFound(err) {
+		t.Fatalf("SessionStorageDriver.RotateSession() error = %v, want not found", err)
+	}
+
+	if keys := server.Keys(); len(keys) != 2 {
+		t.Errorf("Keys() = %v, want only the destroyed session and its index", keys)
+	}
+	got, err := d.Session(t.Context(), sessionID)
+	if err != nil {
+		t.Fatalf("SessionStorageDriver.Session() error = %v", err)
+	}
+	if !got.Expired {
+		t.Error("SessionStorageDriver.Session().Expired = false, want true")
+	}
+}
+
+// processHook is a goredis.Hook that is called after each command has been processed.
+type processHook func(ctx context.Context, cmd goredis.Cmder)
+
+func (h processHook) DialHook(next goredis.DialHook) goredis.DialHook {
+	return next
+}
+
+func (h processHook) ProcessHook(next goredis.ProcessHook) goredis.ProcessHook {
+	return func(ctx context.Context, cmd goredis.Cmder) error {
+		err := next(ctx, cmd)
+		h(ctx, cmd)
+
+		return err
+	}
+}
+
+func (h processHook) ProcessPipelineHook(next goredis.ProcessPipelineHook) goredis.ProcessPipelineHook {
+	return next
+}
+
+func TestSessionStorageDriver_DestroyAllUserSessions(t *testing.T) {
+	t.Parallel()
+
+	d, _ := prepareDriver(t)
+	ids := insertSessions(t, d, []string{"test user 1", "test user 1", "test user 2"})
+
+	if err := d.DestroyAllUserSessions(t.Context(), "test user 1"); err != nil {
+		t.Fatalf("SessionStorageDriver.DestroyAllUserSessions() error = %v", err)
+	}
+
+	for i, want := range []bool{true, true, false} {
+		if got := expired(t, d, ids[i]); got != want {
+			t.Errorf("session %d Expired = %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestSessionStorageDriver_SetSessionTableName(t *testing.T) {
+	t.Parallel()
+
+	d, server := prepareDriver(t)
+	d.SetSessionTableName("AppSessions")
+	id := insertSessions(t, d, []string{"test user 1"})[0]
+
+	if !server.Exists("AppSessions:" + id.String()) {
+		t.Errorf("session key with the custom prefix does not exist, keys = %v", server.Keys())
+	}
+}
+
+func TestSessionStorageDriver_Users(t *testing.T) {
+	t.Parallel()
+
+	d, _ := prepareDriver(t)
+	if _, err := d.UserByUserName(t.Context(), "test user 1"); err == nil {
+		t.Errorf("SessionStorageDriver.UserByUserName() error = nil, want unsupported")
+	}
+}
+
+func TestSessionStorageDriver_UserSessions(t *testing.T) {
+	t.Parallel()
+
+	d, server := prepareDriver(t)
+	ids := insertSessions(t, d, []string{"test user 1", "test user 1", "test user 1", "test user 2"})
+	if err := d.DestroySession(t.Context(), ids[1]); err != nil {
+		t.Fatalf("SessionStorageDriver.DestroySession() error = %v", err)
+	}
+	// A session whose key expired is skipped even though the user index still lists it.
+	server.Del("Sessions:" + ids[0].String())
+
+	tests := []struct {
+		name    string
+		query   *dbtype.UserSessions
+		wantIDs []ccc.UUID
+	}{
+		{
+			name:    "all sessions newest first",
+			query:   &dbtype.UserSessions{Username: "test user 1", Active: true, Expired: true, Limit: 10},
+			wantIDs: []ccc.UUID{ids[2], ids[1]},
+		},
+		{
+			name:    "active sessions",
+			query:   &dbtype.UserSessions{Username: "test user 1", Active: true, Limit: 10},
+			wantIDs: []ccc.UUID{ids[2]},
+		},
+		{
+			name:    "limit",
+			query:   &dbtype.UserSessions{Username: "test user 1", Active: true, Expired: true, Limit: 1},
+			wantIDs: []ccc.UUID{ids[2]},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := d.UserSessions(t.Context(), tt.query)
+			if err != nil {
+				t.Fatalf("SessionStorageDriver.UserSessions() error = %v", err)
+			}
+			gotIDs := make([]ccc.UUID, 0, len(got))
+			for _, s := range got {
+				gotIDs = append(gotIDs, s.ID)
+			}
+			if !slices.Equal(gotIDs, tt.wantIDs) {
+				t.Errorf("SessionStorageDriver.UserSessions() = %v, want %v", gotIDs, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestSessionStorageDriver_unindexExpiredSessions(t *testing.T) {
+	t.Parallel()
+
+	// Sessions 0 and 2 have an expired key and session 1 is active. Indexes list the positions
+	// of the sessions they should still hold.
+	tests := []struct {
+		name    string
+		run     func(t *testing.T, d *SessionStorageDriver, ids []ccc.UUID)
+		indexes map[string][]int
+	}{
+		{
+			name: "user sessions",
+			run: func(t *testing.T, d *SessionStorageDriver, _ []ccc.UUID) {
+				if _, err := d.UserSessions(t.Context(), &dbtype.UserSessions{Username: "test user 1", Active: true, Expired: true, Limit: 10}); err != nil {
+					t.Fatalf("SessionStorageDriver.UserSessions() error = %v", err)
+				}
+			},
+			indexes: map[string][]int{"Sessions:user:test user 1": {1}, "Sessions:sid:sid 1": {0, 1}, "Sessions:sid:sid 2": {2}},
+		},
+		{
+			name: "insert session with a limit",
+			run: func(t *testing.T, d *SessionStorageDriver, _ []ccc.UUID) {
+				insert := &dbtype.InsertSession{Username: "test user 1", CreatedAt: time.Now(), UpdatedAt: time.Now(), Limit: &dbtype.InsertLimit{MaxSessions: 10}}
+				if _, err := d.InsertSession(t.Context(), insert); err != nil {
+					t.Fatalf("SessionStorageDriver.InsertSession() error = %v", err)
+				}
+			},
+			indexes: map[string][]int{"Sessions:user:test user 1": {1}, "Sessions:sid:sid 1": {0, 1}, "Sessions:sid:sid 2": {2}},
+		},
+		{
+			name: "destroy session",
+			run: func(t *testing.T, d *SessionStorageDriver, ids []ccc.UUID) {
+				if err := d.DestroySession(t.Context(), ids[1]); err != nil {
+					t.Fatalf("SessionStorageDriver.DestroySession() error = %v", err)
+				}
+			},
+			indexes: map[string][]int{"Sessions:user:test user 1": {1}, "Sessions:sid:sid 1": {1}, "Sessions:sid:sid 2": {2}},
+		},
+		{
+			name: "destroy all user sessions",
+			run: func(t *testing.T, d *SessionStorageDriver, _ []ccc.UUID) {
+				if err := d.DestroyAllUserSessions(t.Context(), "test user 1"); err != nil {
+					t.Fatalf("SessionStorageDriver.DestroyAllUserSessions() error = %v", err)
+				}
+			},
+			indexes: map[string][]int{"Sessions:user:test user 1": {1}, "Sessions:sid:sid 1": {0, 1}, "Sessions:sid:sid 2": {2}},
+		},
+		{
+			name: "destroy session oidc",
+			run: func(t *testing.T, d *SessionStorageDriver, _ []ccc.UUID) {
+				if err := d.DestroySessionOIDC(t.Context(), "sid 1"); err != nil {
+					t.Fatalf("SessionStorageDriver.DestroySessionOIDC() error = %v", err)
+				}
+			},
+			indexes: map[string][]int{"Sessions:user:test user 1": {1}, "Sessions:sid:sid 1": {1}, "Sessions:sid:sid 2": {2}},
+		},
+		{
+			name: "username oidc",
+			run: func(t *testing.T, d *SessionStorageDriver, _ []ccc.UUID) {
+				if _, err := d.UsernameOIDC(t.Context(), "sid 2"); !httpio.HasNotFound(err) {
+					t.Fatalf("SessionStorageDriver.UsernameOIDC() error = %v, want not found", err)
+				}
+			},
+			indexes: map[string][]int{"Sessions:user:test user 1": {0, 1, 2}, "Sessions:sid:sid 1": {0, 1}, "Sessions:sid:sid 2": {}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			d, server := prepareDriver(t)
+			ids := insertSessions(t, d, []string{"test user 1", "test user 1", "test user 1"}, "sid 1", "sid 1", "sid 2")
+			server.Del("Sessions:" + ids[0].String())
+			server.Del("Sessions:" + ids[2].String())
+
+			tt.run(t, d, ids)
+
+			for index, want := range tt.indexes {
+				members, err := d.client.SMembers(t.Context(), index).Result()
+				if err != nil {
+					t.Fatalf("SMembers(%q) error = %v", index, err)
+				}
+				for i, id := range ids {
+					if got, want := slices.Contains(members, id.String()), slices.Contains(want, i); got != want {
+						t.Errorf("SMembers(%q) contains session %d = %v, want %v", index, i, got, want)
+					}
+				}
+			}
+		})
+	}
+}
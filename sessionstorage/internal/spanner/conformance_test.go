@@ -0,0 +1,25 @@
+package spanner_test
+
+import (
+	"testing"
+
+	"github.com/cccteam/session/sessionstorage"
+	"github.com/cccteam/session/sessionstorage/internal/spanner"
+	"github.com/cccteam/session/sessionstorage/storagetest"
+)
+
+func TestConformance(t *testing.T) {
+	t.Parallel()
+
+	storagetest.RunConformance(t, storagetest.Factory{
+		Preauth: func(t *testing.T) sessionstorage.PreauthStore {
+			return sessionstorage.NewSpannerPreauth(spanner.PrepareDatabase(t, "file://../../../schema/spanner/migrations"))
+		},
+		PasswordAuth: func(t *testing.T) sessionstorage.PasswordAuthStore {
+			return sessionstorage.NewSpannerPasswordAuth(spanner.PrepareDatabase(t, "file://../../../schema/spanner/migrations"))
+		},
+		OIDC: func(t *testing.T) sessionstorage.OIDCStore {
+			return sessionstorage.NewSpannerOIDC(spanner.PrepareDatabase(t, "file://../../../schema/spanner/oidc/migrations"))
+		},
+	})
+}
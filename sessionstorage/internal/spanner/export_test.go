@@ -0,0 +1,19 @@
+package spanner
+
+import (
+	"testing"
+
+	"cloud.google.com/go/spanner"
+)
+
+// PrepareDatabase creates a migrated database for the tests in package spanner_test.
+func PrepareDatabase(t *testing.T, sourceURL ...string) *spanner.Client {
+	t.Helper()
+
+	db, err := prepareDatabase(t.Context(), t, sourceURL...)
+	if err != nil {
+		t.Fatalf("prepareDatabase() error = %v", err)
+	}
+
+	return db.Client
+}
@@ -3,6 +3,7 @@ package spanner
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
@@ -16,9 +17,14 @@ import (
 	"github.com/cccteam/spxscan"
 	"github.com/cccteam/spxscan/spxapi"
 	"github.com/go-playground/errors/v5"
+	"google.golang.org/api/iterator"
 	"google.golang.org/grpc/codes"
 )
 
+// idleCondition selects idle sessions, as defined by dbtype.Session.Idle, using the
+// @idleBefore and @now parameters.
+const idleCondition = "(UpdatedAt < @idleBefore AND (IdleTimeoutSeconds = 0 OR TIMESTAMP_ADD(UpdatedAt, INTERVAL IdleTimeoutSeconds SECOND) < @now))"
+
 // SessionStorageDriver represents the session storage implementation for Spanner.
 type SessionStorageDriver struct {
 	spanner          *spanner.Client
@@ -40,6 +46,11 @@ func (s *SessionStorageDriver) SetSessionTableName(name string) {
 	s.sessionTableName = name
 }
 
+// dataTableName returns the name of the table holding the data of the sessions in the session table.
+func (s *SessionStorageDriver) dataTableName() string {
+	return s.sessionTableName + dbtype.SessionDataTableSuffix
+}
+
 // SetUserTableName sets the name of the user table.
 func (s *SessionStorageDriver) SetUserTableName(name string) {
 	s.userTableName = name
@@ -56,7 +67,14 @@ func (s *SessionStorageDriver) Session(ctx context.Context, sessionID ccc.UUID)
 			Username,
 			CreatedAt,
 			UpdatedAt,
-			Expired
+			Expired,
+			IdleTimeoutSeconds,
+			ClientIp,
+			UserAgent,
+			AuthMethod,
+			ImpersonatorUsername,
+			ImpersonatorSessionId,
+			LastAuthenticatedAt
 		FROM %s
 		WHERE Id = @id
 	`, s.sessionTableName))
@@ -103,6 +121,71 @@ func (s *SessionStorageDriver) UpdateSessionActivity(ctx context.Context, sessio
 	return nil
 }
 
+// UpdateSessionAuthentication sets the session activity and last authentication columns to the current time
+func (s *SessionStorageDriver) UpdateSessionAuthentication(ctx context.Context, sessionID ccc.UUID) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	now := time.Now()
+	sessionUpdate := struct {
+		ID                  ccc.UUID  `spanner:"Id"`
+		UpdatedAt           time.Time `spanner:"UpdatedAt"`
+		LastAuthenticatedAt time.Time `spanner:"LastAuthenticatedAt"`
+	}{
+		ID:                  sessionID,
+		UpdatedAt:           now,
+		LastAuthenticatedAt: now,
+	}
+
+	mutation, err := spanner.UpdateStruct(s.sessionTableName, sessionUpdate)
+	if err != nil {
+		return errors.Wrap(err, "spanner.UpdateStruct()")
+	}
+
+	if _, err := s.spanner.Apply(ctx, []*spanner.Mutation{mutation}); err != nil {
+		if spanner.ErrCode(err) == codes.NotFound {
+			return httpio.NewNotFoundMessagef("session %q not found", sessionUpdate.ID)
+		}
+
+		return errors.Wrap(err, "spanner.Client.Apply()")
+	}
+
+	return nil
+}
+
+// UpdateSessionsActivity sets the activity time of each session in a single batch of DML
+// statements, skipping sessions that do not exist or already have a later activity time
+func (s *SessionStorageDriver) UpdateSessionsActivity(ctx context.Context, activity []*dbtype.SessionActivity) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	if len(activity) == 0 {
+		return nil
+	}
+
+	stmts := make([]spanner.Statement, 0, len(activity))
+	for _, a := range activity {
+		stmt := spanner.NewStatement(fmt.Sprintf(`
+			UPDATE %s SET UpdatedAt = @updatedAt
+			WHERE Id = @id AND UpdatedAt < @updatedAt`, s.sessionTableName))
+		stmt.Params["id"] = a.ID
+		stmt.Params["updatedAt"] = a.UpdatedAt
+		stmts = append(stmts, stmt)
+	}
+
+	if _, err := s.spanner.ReadWriteTransaction(ctx, func(ctx context.Context, txn *spanner.ReadWriteTransaction) error {
+		if _, err := txn.BatchUpdate(ctx, stmts); err != nil {
+			return errors.Wrap(err, "spanner.ReadWriteTransaction.BatchUpdate()")
+		}
+
+		return nil
+	}); err != nil {
+		return errors.Wrap(err, "spanner.Client.ReadWriteTransaction()")
+	}
+
+	return nil
+}
+
 // InsertSession inserts a Session into database
 func (s *SessionStorageDriver) InsertSession(ctx context.Context, insertSession *dbtype.InsertSession) (ccc.UUID, error) {
 	ctx, span := tracer.Start(ctx)
@@ -125,13 +208,83 @@ func (s *SessionStorageDriver) InsertSession(ctx context.Context, insertSession
 	if err != nil {
 		return ccc.NilUUID, errors.Wrap(err, "spanner.InsertStruct()")
 	}
-	if _, err := s.spanner.Apply(ctx, []*spanner.Mutation{mutation}); err != nil {
-		return ccc.NilUUID, errors.Wrap(err, "spanner.Client.Apply()")
+	if err := s.insertSession(ctx, mutation, insertSession.Username, insertSession.Limit); err != nil {
+		return ccc.NilUUID, err
 	}
 
 	return id, nil
 }
 
+// insertSession applies the mutation that inserts a session. When limit is set, the oldest active
+// sessions of username that do not fit in it are expired in the same transaction.
+func (s *SessionStorageDriver) insertSession(ctx context.Context, mutation *spanner.Mutation, username string, limit *dbtype.InsertLimit) error {
+	if limit == nil {
+		if _, err := s.spanner.Apply(ctx, []*spanner.Mutation{mutation}); err != nil {
+			return errors.Wrap(err, "spanner.Client.Apply()")
+		}
+
+		return nil
+	}
+
+	conditions := []string{"Username = @username", "NOT Expired"}
+	if !limit.IdleBefore.IsZero() {
+		conditions = append(conditions, "NOT "+idleCondition)
+	}
+	if !limit.CreatedBefore.IsZero() {
+		conditions = append(conditions, "CreatedAt >= @createdBefore")
+	}
+
+	stmt := spanner.NewStatement(fmt.Sprintf(`
+		SELECT Id FROM %s
+		WHERE %s
+		ORDER BY CreatedAt, Id`, s.sessionTableName, strings.Join(conditions, " AND ")))
+	stmt.Params["username"] = username
+	if !limit.IdleBefore.IsZero() {
+		stmt.Params["idleBefore"] = limit.IdleBefore
+		stmt.Params["now"] = limit.Now
+	}
+	if !limit.CreatedBefore.IsZero() {
+		stmt.Params["createdBefore"] = limit.CreatedBefore
+	}
+
+	_, err := s.spanner.ReadWriteTransaction(ctx, func(ctx context.Context, txn *spanner.ReadWriteTransaction) error {
+		var active []ccc.UUID
+		if err := txn.Query(ctx, stmt).Do(func(row *spanner.Row) error {
+			var id ccc.UUID
+			if err := row.Column(0, &id); err != nil {
+				return errors.Wrap(err, "spanner.Row.Column()")
+			}
+			active = append(active, id)
+
+			return nil
+		}); err != nil {
+			return errors.Wrap(err, "spanner.RowIterator.Do()")
+		}
+
+		evict, err := limit.Evict(active)
+		if err != nil {
+			return errors.Wrap(err, "dbtype.InsertLimit.Evict()")
+		}
+
+		now := time.Now()
+		mutations := make([]*spanner.Mutation, 0, len(evict)+1)
+		for _, sessionID := range evict {
+			mutations = append(mutations, spanner.Update(s.sessionTableName, []string{"Id", "Expired", "UpdatedAt"}, []any{sessionID, true, now}))
+		}
+
+		if err := txn.BufferWrite(append(mutations, mutation)); err != nil {
+			return errors.Wrap(err, "spanner.ReadWriteTransaction.BufferWrite()")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "spanner.Client.ReadWriteTransaction()")
+	}
+
+	return nil
+}
+
 // DestroySession marks the session as expired
 func (s *SessionStorageDriver) DestroySession(ctx context.Context, sessionID ccc.UUID) error {
 	ctx, span := tracer.Start(ctx)
@@ -164,6 +317,97 @@ func (s *SessionStorageDriver) DestroySession(ctx context.Context, sessionID ccc
 	return nil
 }
 
+// RotateSession moves an active session to a new ID, keeping the rest of the row and its data.
+// Spanner does not allow updating a primary key, so the rows are copied to the new ID and deleted.
+func (s *SessionStorageDriver) RotateSession(ctx context.Context, sessionID ccc.UUID) (ccc.UUID, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	id, err := ccc.NewUUID()
+	if err != nil {
+		return ccc.NilUUID, errors.Wrap(err, "ccc.NewUUID()")
+	}
+
+	_, err = s.spanner.ReadWriteTransaction(ctx, func(ctx context.Context, txn *spanner.ReadWriteTransaction) error {
+		stmt := spanner.NewStatement(fmt.Sprintf(`
+			SELECT * FROM %s
+			WHERE Id = @id AND NOT Expired`, s.sessionTableName))
+		stmt.Params["id"] = sessionID
+
+		iter := txn.Query(ctx, stmt)
+		defer iter.Stop()
+
+		row, err := iter.Next()
+		if err != nil {
+			if errors.Is(err, iterator.Done) {
+				return httpio.NewNotFoundMessagef("session %q not found", sessionID)
+			}
+
+			return errors.Wrap(err, "spanner.RowIterator.Next()")
+		}
+
+		// Deleting the session also deletes its data rows, so they are copied to the new ID as well
+		insert, err := copyRow(s.sessionTableName, row, id)
+		if err != nil {
+			return err
+		}
+		mutations := []*spanner.Mutation{
+			spanner.Delete(s.sessionTableName, spanner.Key{sessionID}),
+			insert,
+		}
+
+		dataStmt := spanner.NewStatement(fmt.Sprintf(`
+			SELECT * FROM %s
+			WHERE Id = @id`, s.dataTableName()))
+		dataStmt.Params["id"] = sessionID
+
+		if err := txn.Query(ctx, dataStmt).Do(func(row *spanner.Row) error {
+			insert, err := copyRow(s.dataTableName(), row, id)
+			if err != nil {
+				return err
+			}
+			mutations = append(mutations, insert)
+
+			return nil
+		}); err != nil {
+			return errors.Wrap(err, "spanner.RowIterator.Do()")
+		}
+
+		if err := txn.BufferWrite(mutations); err != nil {
+			return errors.Wrap(err, "spanner.ReadWriteTransaction.BufferWrite()")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return ccc.NilUUID, errors.Wrap(err, "spanner.Client.ReadWriteTransaction()")
+	}
+
+	return id, nil
+}
+
+// copyRow returns a mutation that inserts row into table with its Id column set to id. Every column
+// is copied, so that the session keeps all of its data whatever the schema variant.
+func copyRow(table string, row *spanner.Row, id ccc.UUID) (*spanner.Mutation, error) {
+	columns := row.ColumnNames()
+	values := make([]any, len(columns))
+	for i, column := range columns {
+		if column == "Id" {
+			values[i] = id
+
+			continue
+		}
+
+		var value spanner.GenericColumnValue
+		if err := row.Column(i, &value); err != nil {
+			return nil, errors.Wrap(err, "spanner.Row.Column()")
+		}
+		values[i] = value
+	}
+
+	return spanner.Insert(table, columns, values), nil
+}
+
 // User returns the user record associated with the user id
 func (s *SessionStorageDriver) User(ctx context.Context, id ccc.UUID) (*dbtype.SessionUser, error) {
 	ctx, span := tracer.Start(ctx)
@@ -243,7 +487,7 @@ func (s *SessionStorageDriver) CreateUser(ctx context.Context, insertUser *dbtyp
 	}
 
 	if _, err := s.spanner.Apply(ctx, []*spanner.Mutation{mutation}); err != nil {
-		if spanner.ErrCode(err) == codes.AlreadyExists && strings.Contains(err.Error(), "SessionUsersByNormalizedUsername") {
+		if spanner.ErrCode(err) == codes.AlreadyExists && strings.Contains(err.Error(), s.userTableName+"ByNormalizedUsername") {
 			return nil, httpio.NewConflictMessagef("username %q already exists", user.Username)
 		}
 
@@ -309,7 +553,7 @@ func (s *SessionStorageDriver) SetUserUsername(ctx context.Context, userID ccc.U
 			return httpio.NewNotFoundMessagef("user id %q does not exist", userID)
 		}
 
-		if spanner.ErrCode(err) == codes.AlreadyExists && strings.Contains(err.Error(), "SessionUsersByNormalizedUsername") {
+		if spanner.ErrCode(err) == codes.AlreadyExists && strings.Contains(err.Error(), s.userTableName+"ByNormalizedUsername") {
 			return httpio.NewConflictMessagef("username %q already exists", newUsername)
 		}
 
@@ -432,7 +676,8 @@ func (s *SessionStorageDriver) ActivateUser(ctx context.Context, id ccc.UUID) er
 	return nil
 }
 
-// DestroyAllUserSessions destroys all sessions for a given user
+// DestroyAllUserSessions destroys all sessions for a given user, including the sessions in which
+// the user impersonates another user
 func (s *SessionStorageDriver) DestroyAllUserSessions(ctx context.Context, username string) error {
 	ctx, span := tracer.Start(ctx)
 	defer span.End()
@@ -440,7 +685,7 @@ func (s *SessionStorageDriver) DestroyAllUserSessions(ctx context.Context, usern
 	stmt := spanner.NewStatement(fmt.Sprintf(`
 			UPDATE %s
 			SET Expired = TRUE, UpdatedAt = @updatedAt
-			WHERE Username = @username
+			WHERE Username = @username OR ImpersonatorUsername = @username
 	`, s.sessionTableName))
 	stmt.Params["username"] = username
 	stmt.Params["updatedAt"] = time.Now()
@@ -458,3 +703,177 @@ func (s *SessionStorageDriver) DestroyAllUserSessions(ctx context.Context, usern
 
 	return nil
 }
+
+// UserSessions returns up to query.Limit sessions for query.Username that match the query, newest first.
+func (s *SessionStorageDriver) UserSessions(ctx context.Context, query *dbtype.UserSessions) ([]*dbtype.Session, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	conditions := []string{"Username = @username"}
+	switch {
+	case query.Active && query.Expired:
+	case query.Active:
+		conditions = append(conditions, "NOT Expired")
+		if !query.IdleBefore.IsZero() {
+			conditions = append(conditions, "NOT "+idleCondition)
+		}
+	case query.Expired:
+		if !query.IdleBefore.IsZero() {
+			conditions = append(conditions, "(Expired OR "+idleCondition+")")
+		} else {
+			conditions = append(conditions, "Expired")
+		}
+	default:
+		return nil, nil
+	}
+	if query.After != nil {
+		conditions = append(conditions, "(CreatedAt < @afterCreatedAt OR (CreatedAt = @afterCreatedAt AND Id < @afterId))")
+	}
+
+	stmt := spanner.NewStatement(fmt.Sprintf(`
+		SELECT
+			Id,
+			Username,
+			CreatedAt,
+			UpdatedAt,
+			Expired,
+			IdleTimeoutSeconds,
+			ClientIp,
+			UserAgent,
+			AuthMethod,
+			ImpersonatorUsername,
+			ImpersonatorSessionId,
+			LastAuthenticatedAt
+		FROM %s
+		WHERE %s
+		ORDER BY CreatedAt DESC, Id DESC
+		LIMIT @limit`, s.sessionTableName, strings.Join(conditions, " AND ")))
+	stmt.Params["username"] = query.Username
+	stmt.Params["limit"] = query.Limit
+	if !query.IdleBefore.IsZero() && query.Active != query.Expired {
+		stmt.Params["idleBefore"] = query.IdleBefore
+		stmt.Params["now"] = query.Now
+	}
+	if query.After != nil {
+		stmt.Params["afterCreatedAt"] = query.After.CreatedAt
+		stmt.Params["afterId"] = query.After.ID
+	}
+
+	var sessions []*dbtype.Session
+	if err := spxscan.Select(ctx, s.spanner.Single(), &sessions, stmt); err != nil {
+		return nil, errors.Wrap(err, "spxscan.Select()")
+	}
+
+	return sessions, nil
+}
+
+// PurgeSessions deletes up to purge.Limit session rows that match the purge criteria
+// and returns the number of rows deleted.
+func (s *SessionStorageDriver) PurgeSessions(ctx context.Context, purge *dbtype.PurgeSessions) (int64, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	conditions := []string{"Expired"}
+	if !purge.IdleBefore.IsZero() {
+		conditions = append(conditions, idleCondition)
+	}
+	if !purge.CreatedBefore.IsZero() {
+		conditions = append(conditions, "CreatedAt < @createdBefore")
+	}
+
+	stmt := spanner.NewStatement(fmt.Sprintf(`
+			DELETE FROM %[1]s
+			WHERE Id IN (
+				SELECT Id FROM %[1]s
+				WHERE %[2]s
+				LIMIT @limit
+			)`, s.sessionTableName, strings.Join(conditions, " OR ")))
+	stmt.Params["limit"] = purge.Limit
+	if !purge.IdleBefore.IsZero() {
+		stmt.Params["idleBefore"] = purge.IdleBefore
+		stmt.Params["now"] = purge.Now
+	}
+	if !purge.CreatedBefore.IsZero() {
+		stmt.Params["createdBefore"] = purge.CreatedBefore
+	}
+
+	var deleteCount int64
+	_, err := s.spanner.ReadWriteTransaction(ctx, func(ctx context.Context, txn *spanner.ReadWriteTransaction) error {
+		var err error
+		if deleteCount, err = txn.Update(ctx, stmt); err != nil {
+			return errors.Wrap(err, "spanner.ReadWriteTransaction.Update()")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "spanner.Client.ReadWriteTransaction()")
+	}
+
+	return deleteCount, nil
+}
+
+// SessionData returns the data stored with the session by key
+func (s *SessionStorageDriver) SessionData(ctx context.Context, sessionID ccc.UUID) (map[string]json.RawMessage, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	stmt := spanner.NewStatement(fmt.Sprintf(`
+		SELECT Name, TO_JSON_STRING(Value) AS Value
+		FROM %s
+		WHERE Id = @id`, s.dataTableName()))
+	stmt.Params["id"] = sessionID
+
+	var rows []*struct {
+		Name  string `spanner:"Name"`
+		Value string `spanner:"Value"`
+	}
+	if err := spxscan.Select(ctx, s.spanner.Single(), &rows, stmt); err != nil {
+		return nil, errors.Wrap(err, "spxscan.Select()")
+	}
+
+	data := make(map[string]json.RawMessage, len(rows))
+	for _, row := range rows {
+		data[row.Name] = json.RawMessage(row.Value)
+	}
+
+	return data, nil
+}
+
+// UpdateSessionData applies update to the data stored with the session
+func (s *SessionStorageDriver) UpdateSessionData(ctx context.Context, sessionID ccc.UUID, update *dbtype.SessionDataUpdate) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	_, err := s.spanner.ReadWriteTransaction(ctx, func(ctx context.Context, txn *spanner.ReadWriteTransaction) error {
+		if _, err := txn.ReadRow(ctx, s.sessionTableName, spanner.Key{sessionID}, []string{"Id"}); err != nil {
+			if spanner.ErrCode(err) == codes.NotFound {
+				return httpio.NewNotFoundMessagef("session %q not found", sessionID)
+			}
+
+			return errors.Wrap(err, "spanner.ReadWriteTransaction.ReadRow()")
+		}
+
+		mutations := make([]*spanner.Mutation, 0, len(update.Set)+len(update.Deleted))
+		for name, value := range update.Set {
+			mutations = append(mutations, spanner.InsertOrUpdate(s.dataTableName(),
+				[]string{"Id", "Name", "Value", "UpdatedAt"},
+				[]any{sessionID, name, spanner.NullJSON{Value: value, Valid: true}, update.UpdatedAt},
+			))
+		}
+		for _, name := range update.Deleted {
+			mutations = append(mutations, spanner.Delete(s.dataTableName(), spanner.Key{sessionID, name}))
+		}
+
+		if err := txn.BufferWrite(mutations); err != nil {
+			return errors.Wrap(err, "spanner.ReadWriteTransaction.BufferWrite()")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "spanner.Client.ReadWriteTransaction()")
+	}
+
+	return nil
+}
@@ -7,7 +7,10 @@ import (
 	"cloud.google.com/go/spanner"
 	"github.com/cccteam/ccc"
 	"github.com/cccteam/ccc/tracer"
+	"github.com/cccteam/httpio"
 	"github.com/cccteam/session/internal/dbtype"
+	"github.com/cccteam/spxscan"
+	"github.com/cccteam/spxscan/spxapi"
 	"github.com/go-playground/errors/v5"
 )
 
@@ -33,8 +36,8 @@ func (s *SessionStorageDriver) InsertSessionOIDC(ctx context.Context, insertSess
 	if err != nil {
 		return ccc.NilUUID, errors.Wrap(err, "spanner.InsertStruct()")
 	}
-	if _, err := s.spanner.Apply(ctx, []*spanner.Mutation{mutation}); err != nil {
-		return ccc.NilUUID, errors.Wrap(err, "spanner.Client.Apply()")
+	if err := s.insertSession(ctx, mutation, insertSession.Username, insertSession.Limit); err != nil {
+		return ccc.NilUUID, err
 	}
 
 	return id, nil
@@ -69,3 +72,30 @@ func (s *SessionStorageDriver) DestroySessionOIDC(ctx context.Context, oidcSID s
 
 	return nil
 }
+
+// UsernameOIDC returns the username of the session with the oidcSID
+func (s *SessionStorageDriver) UsernameOIDC(ctx context.Context, oidcSID string) (string, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	stmt := spanner.NewStatement(fmt.Sprintf(`
+		SELECT Username
+		FROM %s
+		WHERE OidcSid = @oidcSID
+		LIMIT 1
+	`, s.sessionTableName))
+	stmt.Params["oidcSID"] = oidcSID
+
+	session := &struct {
+		Username string `spanner:"Username"`
+	}{}
+	if err := spxscan.Get(ctx, s.spanner.Single(), session, stmt); err != nil {
+		if errors.Is(err, spxapi.ErrNotFound) {
+			return "", httpio.NewNotFoundMessagef("session with oidc sid %q not found", oidcSID)
+		}
+
+		return "", errors.Wrap(err, "spxscan.Get()")
+	}
+
+	return session.Username, nil
+}
@@ -0,0 +1,83 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/errors/v5"
+)
+
+// prepareDatabase creates a new database file and runs the up migrations found in each of the given directories.
+func prepareDatabase(ctx context.Context, t *testing.T, sourceDir ...string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "session.db")+"?_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, errors.Wrap(err, "sql.Open()")
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	for _, dir := range sourceDir {
+		if err := runMigrations(ctx, db, dir, ".up.sql"); err != nil {
+			return nil, err
+		}
+	}
+
+	return db, nil
+}
+
+// migrateDown runs the down migrations found in sourceDir in reverse order.
+func migrateDown(ctx context.Context, db *sql.DB, sourceDir string) error {
+	return runMigrations(ctx, db, sourceDir, ".down.sql")
+}
+
+func runMigrations(ctx context.Context, db *sql.DB, sourceDir, suffix string) error {
+	entries, err := os.ReadDir(sourceDir)
+	if err != nil {
+		return errors.Wrap(err, "os.ReadDir()")
+	}
+
+	var files []string
+	for _, e := range entries {
+		if strings.HasSuffix(e.Name(), suffix) {
+			files = append(files, filepath.Join(sourceDir, e.Name()))
+		}
+	}
+	slices.Sort(files)
+	if suffix == ".down.sql" {
+		slices.Reverse(files)
+	}
+
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return errors.Wrap(err, "os.ReadFile()")
+		}
+		if _, err := db.ExecContext(ctx, string(b)); err != nil {
+			return errors.Wrapf(err, "sql.DB.ExecContext(): %s", f)
+		}
+	}
+
+	return nil
+}
+
+// runAssertions executes a series of provided assertions. Each assertion is represented by a SQL query
+// that must return a boolean value. The query's result must be true for the assertion to pass.
+func runAssertions(ctx context.Context, t *testing.T, q Queryer, assertions []string) {
+	t.Helper()
+
+	for i, query := range assertions {
+		var isTrue bool
+		if err := q.QueryRowContext(ctx, query).Scan(&isTrue); err != nil {
+			t.Errorf("sql.Row.Scan(): Assertion %d for test %q failed. %v", i+1, t.Name(), err)
+
+			continue
+		}
+		if !isTrue {
+			t.Errorf("Assertion %d for test %q failed", i+1, t.Name())
+		}
+	}
+}
@@ -0,0 +1,15 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Queryer is an interface that wraps the basic BeginTx, QueryContext, QueryRowContext, and ExecContext methods.
+// It is satisfied by *sql.DB.
+type Queryer interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
@@ -0,0 +1,203 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cccteam/httpio"
+	"github.com/cccteam/session/internal/dbtype"
+)
+
+func Test_client_InsertSessionOIDC(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		insertSession  *dbtype.InsertOIDCSession
+		sourceURL      []string
+		wantErr        bool
+		preAssertions  []string
+		postAssertions []string
+	}{
+		{
+			name: "success creating session",
+			insertSession: &dbtype.InsertOIDCSession{
+				OidcSID: "oidc session",
+				InsertSession: dbtype.InsertSession{
+					Username:  "test user 2",
+					CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+					UpdatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+				},
+			},
+			sourceURL: []string{"../../../schema/sqlite/oidc/migrations"},
+			preAssertions: []string{
+				`SELECT COUNT(*) = 0 FROM "Sessions"`,
+			},
+			postAssertions: []string{
+				`SELECT COUNT(*) = 1 FROM "Sessions"
+				 WHERE "Id" = '%s'
+					 AND "Username" = 'test user 2'
+					 AND "OidcSid" = 'oidc session'
+					 AND "CreatedAt" = '2024-01-02 03:04:05+00:00'
+					 AND "UpdatedAt" = '2024-01-02 03:04:05+00:00'
+					 AND "Expired" = false`,
+			},
+			wantErr: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			conn, err := prepareDatabase(ctx, t, tt.sourceURL...)
+			if err != nil {
+				t.Fatalf("prepareDatabase() error = %v, wantErr %v", err, false)
+			}
+			c := NewSessionStorageDriver(conn)
+
+			runAssertions(ctx, t, conn, tt.preAssertions)
+
+			id, err := c.InsertSessionOIDC(ctx, tt.insertSession)
+			if err != nil != tt.wantErr {
+				t.Errorf("client.InsertSession() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			for i, query := range tt.postAssertions {
+				if strings.Contains(query, "%s") {
+					tt.postAssertions[i] = fmt.Sprintf(query, id.String())
+				}
+			}
+
+			runAssertions(ctx, t, conn, tt.postAssertions)
+		})
+	}
+}
+
+func Test_client_DestroySessionOIDC(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name           string
+		oidcSID        string
+		sourceURL      []string
+		wantErr        bool
+		preAssertions  []string
+		postAssertions []string
+	}{
+		{
+			name:    "fails to destroy session",
+			oidcSID: "oidc session 38bd570b-1280-421b-888e-a63f0ca35be7",
+			wantErr: true,
+		},
+		{
+			name:      "success without destroying sessions",
+			oidcSID:   "oidc session4",
+			sourceURL: []string{"../../../schema/sqlite/oidc/migrations", "testdata/sessions_test/oidc_valid_sessions"},
+			preAssertions: []string{
+				`SELECT COUNT(*) = 0 FROM "Sessions" WHERE "OidcSid" = 'oidc session4'`,
+				`SELECT COUNT(*) = 3 FROM "Sessions" WHERE "Expired" = false`,
+			},
+			postAssertions: []string{
+				`SELECT COUNT(*) = 3 FROM "Sessions" WHERE "Expired" = false`,
+			},
+		},
+		{
+			name:      "success destroying sessions",
+			oidcSID:   "oidc session aa817d69-f550-474b-8eae-7b29da32e3a8",
+			sourceURL: []string{"../../../schema/sqlite/oidc/migrations", "testdata/sessions_test/oidc_valid_sessions"},
+			preAssertions: []string{
+				`SELECT "Username" = 'test user 1' FROM "Sessions" WHERE "OidcSid" = 'oidc session aa817d69-f550-474b-8eae-7b29da32e3a8'`,
+				`SELECT COUNT(*) = 1               FROM "Sessions" WHERE "Username" = 'test user 1' AND "Expired" = true`,
+				`SELECT COUNT(*) = 2               FROM "Sessions" WHERE "Username" = 'test user 1' AND "Expired" = false`,
+				`SELECT COUNT(*) = 1               FROM "Sessions" WHERE "Username" <> 'test user 1' AND "Expired" = false`,
+			},
+			postAssertions: []string{
+				`SELECT COUNT(*) = 3 FROM "Sessions" WHERE "Username" = 'test user 1' AND "Expired" = true`,
+				`SELECT COUNT(*) = 0 FROM "Sessions" WHERE "Username" = 'test user 1' AND "Expired" = false`,
+				`SELECT COUNT(*) = 1 FROM "Sessions" WHERE "Username" <> 'test user 1' AND "Expired" = false`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			conn, err := prepareDatabase(ctx, t, tt.sourceURL...)
+			if err != nil {
+				t.Fatalf("prepareDatabase() error = %v, wantErr %v", err, false)
+			}
+			c := NewSessionStorageDriver(conn)
+
+			runAssertions(ctx, t, conn, tt.preAssertions)
+			if err := c.DestroySessionOIDC(ctx, tt.oidcSID); (err != nil) != tt.wantErr {
+				t.Errorf("client.DestroySessionOIDC() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			runAssertions(ctx, t, conn, tt.postAssertions)
+		})
+	}
+}
+
+func Test_client_UsernameOIDC(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name         string
+		oidcSID      string
+		sourceURL    []string
+		want         string
+		wantErr      bool
+		wantNotFound bool
+	}{
+		{
+			name:    "fails to get username",
+			oidcSID: "oidc session 095887e9-ab67-42c3-8090-6c50780606e3",
+			wantErr: true,
+		},
+		{
+			name:         "unknown oidc sid",
+			oidcSID:      "oidc session4",
+			sourceURL:    []string{"../../../schema/sqlite/oidc/migrations", "testdata/sessions_test/oidc_valid_sessions"},
+			wantErr:      true,
+			wantNotFound: true,
+		},
+		{
+			name:      "success getting username",
+			oidcSID:   "oidc session 095887e9-ab67-42c3-8090-6c50780606e3",
+			sourceURL: []string{"../../../schema/sqlite/oidc/migrations", "testdata/sessions_test/oidc_valid_sessions"},
+			want:      "test user 2",
+		},
+		{
+			name:      "success getting username of an expired session",
+			oidcSID:   "oidc session aa817d69-f550-474b-8eae-7b29da32e3a8",
+			sourceURL: []string{"../../../schema/sqlite/oidc/migrations", "testdata/sessions_test/oidc_valid_sessions"},
+			want:      "test user 1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			conn, err := prepareDatabase(ctx, t, tt.sourceURL...)
+			if err != nil {
+				t.Fatalf("prepareDatabase() error = %v, wantErr %v", err, false)
+			}
+			c := NewSessionStorageDriver(conn)
+
+			got, err := c.UsernameOIDC(ctx, tt.oidcSID)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("client.UsernameOIDC() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantNotFound && !httpio.HasNotFound(err) {
+				t.Errorf("client.UsernameOIDC() error = %v, want NotFound", err)
+			}
+			if got != tt.want {
+				t.Errorf("client.UsernameOIDC() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,1299 @@
+package sqlite
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/cccteam/ccc"
+	"github.com/cccteam/ccc/securehash"
+	"github.com/cccteam/httpio"
+	"github.com/cccteam/session/internal/dbtype"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestClient_FullMigration(t *testing.T) {
+	t.Parallel()
+
+	type args struct {
+		sourceURL string
+	}
+	tests := []struct {
+		name string
+		args args
+	}{
+		{
+			name: "FullMigration OIDC",
+			args: args{
+				sourceURL: "../../../schema/sqlite/oidc/migrations",
+			},
+		},
+		{
+			name: "FullMigration",
+			args: args{
+				sourceURL: "../../../schema/sqlite/migrations",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			db, err := prepareDatabase(t.Context(), t, tt.args.sourceURL)
+			if (err != nil) != false {
+				t.Fatalf("prepareDatabase() error = %v", err)
+			}
+
+			if err := migrateDown(t.Context(), db, tt.args.sourceURL); err != nil {
+				t.Fatalf("migrateDown() error = %v, wantErr %v", err, false)
+			}
+		})
+	}
+}
+
+func TestSessionStorageDriver_SetSessionTableName(t *testing.T) {
+	t.Parallel()
+	c := NewSessionStorageDriver(nil)
+	c.SetSessionTableName("NewSessionTable")
+	if c.sessionTableName != "NewSessionTable" {
+		t.Errorf("SetSessionTableName() = %v, want %v", c.sessionTableName, "NewSessionTable")
+	}
+}
+
+func TestSessionStorageDriver_SetUserTableName(t *testing.T) {
+	t.Parallel()
+	c := NewSessionStorageDriver(nil)
+	c.SetUserTableName("NewUserTable")
+	if c.userTableName != "NewUserTable" {
+		t.Errorf("SetUserTableName() = %v, want %v", c.userTableName, "NewUserTable")
+	}
+}
+
+func TestSessionStorageDriver_Session(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		sessionID   ccc.UUID
+		sourceURL   []string
+		wantSession *dbtype.Session
+		wantErr     bool
+	}{
+		{
+			name:      "success",
+			sessionID: ccc.Must(ccc.UUIDFromString("eb0c72a4-1f32-469e-b51b-7baa589a944c")),
+			sourceURL: []string{"../../../schema/sqlite/oidc/migrations", "testdata/sessions_test/oidc_valid_sessions"},
+			wantSession: &dbtype.Session{
+				ID:       ccc.Must(ccc.UUIDFromString("eb0c72a4-1f32-469e-b51b-7baa589a944c")),
+				Username: "test user 2",
+				Expired:  true,
+			},
+		},
+		{
+			name:      "not found",
+			sessionID: ccc.Must(ccc.NewUUID()),
+			sourceURL: []string{"../../../schema/sqlite/oidc/migrations", "testdata/sessions_test/oidc_valid_sessions"},
+			wantErr:   true,
+		},
+		{
+			name:      "invalid schema",
+			sessionID: ccc.Must(ccc.UUIDFromString("eb0c72a4-1f32-469e-b51b-7baa589a944c")),
+			sourceURL: []string{"testdata/sessions_test/invalid_schema"},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ctx := t.Context()
+			conn, err := prepareDatabase(ctx, t, tt.sourceURL...)
+			if err != nil {
+				t.Fatalf("prepareDatabase() error = %v, wantErr %v", err, false)
+			}
+			c := NewSessionStorageDriver(conn)
+
+			gotSession, err := c.Session(ctx, tt.sessionID)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SessionStorageDriver.Session() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantSession != nil {
+				if gotSession.ID != tt.wantSession.ID {
+					t.Errorf("SessionStorageDriver.Session() gotSession.ID = %v, want %v", gotSession.ID, tt.wantSession.ID)
+				}
+				if gotSession.Username != tt.wantSession.Username {
+					t.Errorf("SessionStorageDriver.Session() gotSession.Username = %v, want %v", gotSession.Username, tt.wantSession.Username)
+				}
+				if gotSession.Expired != tt.wantSession.Expired {
+					t.Errorf("SessionStorageDriver.Session() gotSession.Expired = %v, want %v", gotSession.Expired, tt.wantSession.Expired)
+				}
+			}
+		})
+	}
+}
+
+func Test_client_UpdateSessionActivity(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		sessionID ccc.UUID
+		sourceURL []string
+		wantErr   bool
+	}{
+		{
+			name:      "fails to update session activity (invalid schema)",
+			sessionID: ccc.Must(ccc.UUIDFromString("eb0c72a4-1f32-469e-b51b-7baa589a944c")),
+			sourceURL: []string{"testdata/sessions_test/invalid_schema"},
+			wantErr:   true,
+		},
+		{
+			name:      "fails to find session",
+			sessionID: ccc.Must(ccc.UUIDFromString("ed0c72a4-1f32-469e-b51b-7baa589a945c")),
+			sourceURL: []string{"../../../schema/sqlite/oidc/migrations", "testdata/sessions_test/oidc_valid_sessions"},
+			wantErr:   true,
+		},
+		{
+			name:      "success updating session activity",
+			sessionID: ccc.Must(ccc.UUIDFromString("eb0c72a4-1f32-469e-b51b-7baa589a944c")),
+			sourceURL: []string{"../../../schema/sqlite/oidc/migrations", "testdata/sessions_test/oidc_valid_sessions"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := t.Context()
+			conn, err := prepareDatabase(ctx, t, tt.sourceURL...)
+			if err != nil {
+				t.Fatalf("prepareDatabase() error = %v, wantErr %v", err, false)
+			}
+			c := NewSessionStorageDriver(conn)
+
+			preExecTime := time.Now()
+			if !tt.wantErr {
+				runAssertions(ctx, t, conn, []string{fmt.Sprintf(`SELECT "UpdatedAt" < '%s' FROM "Sessions" WHERE  "Id" = '%s'`, timestamp(preExecTime), tt.sessionID)})
+			}
+			if err := c.UpdateSessionActivity(ctx, tt.sessionID); (err != nil) != tt.wantErr {
+				t.Errorf("client.UpdateSessionActivity() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr {
+				runAssertions(ctx, t, conn, []string{fmt.Sprintf(`SELECT "UpdatedAt" > '%s' FROM "Sessions" WHERE  "Id" = '%s'`, timestamp(preExecTime), tt.sessionID)})
+			}
+		})
+	}
+}
+
+func TestSessionStorageDriver_UpdateSessionsActivity(t *testing.T) {
+	t.Parallel()
+
+	updatedAt := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	activity := []*dbtype.SessionActivity{
+		{ID: ccc.Must(ccc.UUIDFromString("38bd570b-1280-421b-888e-a63f0ca35be7")), UpdatedAt: updatedAt},
+		{ID: ccc.Must(ccc.UUIDFromString("095887e9-ab67-42c3-8090-6c50780606e3")), UpdatedAt: updatedAt.Add(time.Hour)},
+		{ID: ccc.Must(ccc.UUIDFromString("ed0c72a4-1f32-469e-b51b-7baa589a945c")), UpdatedAt: updatedAt},
+	}
+
+	tests := []struct {
+		name      string
+		sourceURL []string
+		wantErr   bool
+	}{
+		{
+			name:      "fails to update session activity (invalid schema)",
+			sourceURL: []string{"testdata/sessions_test/invalid_schema"},
+			wantErr:   true,
+		},
+		{
+			name:      "success updating existing sessions and skipping missing sessions",
+			sourceURL: []string{"../../../schema/sqlite/oidc/migrations", "testdata/sessions_test/oidc_valid_sessions"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := t.Context()
+			conn, err := prepareDatabase(ctx, t, tt.sourceURL...)
+			if err != nil {
+				t.Fatalf("prepareDatabase() error = %v, wantErr %v", err, false)
+			}
+			c := NewSessionStorageDriver(conn)
+
+			if err := c.UpdateSessionsActivity(ctx, activity); (err != nil) != tt.wantErr {
+				t.Errorf("SessionStorageDriver.UpdateSessionsActivity() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			for _, a := range activity[:2] {
+				session, err := c.Session(ctx, a.ID)
+				if err != nil {
+					t.Fatalf("SessionStorageDriver.Session() error = %v", err)
+				}
+				if !session.UpdatedAt.Equal(a.UpdatedAt) {
+					t.Errorf("SessionStorageDriver.Session(%v).UpdatedAt = %v, want %v", a.ID, session.UpdatedAt, a.UpdatedAt)
+				}
+			}
+
+			stale := []*dbtype.SessionActivity{{ID: activity[0].ID, UpdatedAt: updatedAt.Add(-time.Hour)}}
+			if err := c.UpdateSessionsActivity(ctx, stale); err != nil {
+				t.Fatalf("SessionStorageDriver.UpdateSessionsActivity() error = %v", err)
+			}
+			session, err := c.Session(ctx, activity[0].ID)
+			if err != nil {
+				t.Fatalf("SessionStorageDriver.Session() error = %v", err)
+			}
+			if !session.UpdatedAt.Equal(updatedAt) {
+				t.Errorf("SessionStorageDriver.Session(%v).UpdatedAt = %v after stale activity, want %v", activity[0].ID, session.UpdatedAt, updatedAt)
+			}
+		})
+	}
+}
+
+func TestSessionStorageDriver_RotateSession(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		sourceURL    []string
+		sessionID    ccc.UUID
+		wantNotFound bool
+		wantErr      bool
+	}{
+		{
+			name:      "success rotating an active session",
+			sourceURL: []string{"../../../schema/sqlite/oidc/migrations", "testdata/sessions_test/oidc_valid_sessions"},
+			sessionID: ccc.Must(ccc.UUIDFromString("38bd570b-1280-421b-888e-a63f0ca35be7")),
+		},
+		{
+			name:         "fails to rotate an expired session",
+			sourceURL:    []string{"../../../schema/sqlite/oidc/migrations", "testdata/sessions_test/oidc_valid_sessions"},
+			sessionID:    ccc.Must(ccc.UUIDFromString("aa817d69-f550-474b-8eae-7b29da32e3a8")),
+			wantNotFound: true,
+			wantErr:      true,
+		},
+		{
+			name:         "fails to rotate a missing session",
+			sourceURL:    []string{"../../../schema/sqlite/oidc/migrations", "testdata/sessions_test/oidc_valid_sessions"},
+			sessionID:    ccc.Must(ccc.UUIDFromString("ed0c72a4-1f32-469e-b51b-7baa589a945c")),
+			wantNotFound: true,
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := t.Context()
+			conn, err := prepareDatabase(ctx, t, tt.sourceURL...)
+			if err != nil {
+				t.Fatalf("prepareDatabase() error = %v, wantErr %v", err, false)
+			}
+			c := NewSessionStorageDriver(conn)
+
+			var want *dbtype.Session
+			if !tt.wantErr {
+				if want, err = c.Session(ctx, tt.sessionID); err != nil {
+					t.Fatalf("SessionStorageDriver.Session() error = %v", err)
+				}
+			}
+
+			id, err := c.RotateSession(ctx, tt.sessionID)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SessionStorageDriver.RotateSession() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantNotFound && !httpio.HasNotFound(err) {
+				t.Errorf("SessionStorageDriver.RotateSession() error = %v, want not found", err)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if _, err := c.Session(ctx, tt.sessionID); !httpio.HasNotFound(err) {
+				t.Errorf("SessionStorageDriver.Session(old ID) error = %v, want not found", err)
+			}
+			got, err := c.Session(ctx, id)
+			if err != nil {
+				t.Fatalf("SessionStorageDriver.Session(new ID) error = %v", err)
+			}
+			want.ID = id
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("SessionStorageDriver.Session(new ID) mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSessionStorageDriver_InsertSession(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		insertSession  *dbtype.InsertSession
+		sourceURL      []string
+		wantErr        bool
+		preAssertions  []string
+		postAssertions []string
+	}{
+		{
+			name: "success",
+			insertSession: &dbtype.InsertSession{
+				Username:  "testuser",
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+				Expired:   false,
+			},
+			sourceURL: []string{"../../../schema/sqlite/migrations", "testdata/sessions_test/valid_sessions"},
+			preAssertions: []string{
+				`SELECT COUNT(*) = 5 FROM "Sessions"`,
+			},
+			postAssertions: []string{
+				`SELECT COUNT(*) = 6 FROM "Sessions"`,
+			},
+		},
+		{
+			name: "invalid schema",
+			insertSession: &dbtype.InsertSession{
+				Username:  "testuser",
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+				Expired:   false,
+			},
+			sourceURL: []string{"testdata/sessions_test/invalid_schema"},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ctx := t.Context()
+			conn, err := prepareDatabase(ctx, t, tt.sourceURL...)
+			if err != nil {
+				t.Fatalf("prepareDatabase() error = %v, wantErr %v", err, false)
+			}
+			c := NewSessionStorageDriver(conn)
+
+			runAssertions(ctx, t, conn, tt.preAssertions)
+			id, err := c.InsertSession(ctx, tt.insertSession)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SessionStorageDriver.InsertSession() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if !tt.wantErr {
+				if id == ccc.NilUUID {
+					t.Error("SessionStorageDriver.InsertSession() id is nil, want valid UUID")
+				}
+				runAssertions(ctx, t, conn, []string{fmt.Sprintf(`SELECT COUNT(*) = 1 FROM "Sessions" WHERE "Id" = '%s'`, id)})
+			}
+			runAssertions(ctx, t, conn, tt.postAssertions)
+		})
+	}
+}
+
+func Test_client_DestroySession(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		sessionID      ccc.UUID
+		sourceURL      []string
+		wantErr        bool
+		preAssertions  []string
+		postAssertions []string
+	}{
+		{
+			name:      "fails to destroy session (invalid schema)",
+			sessionID: ccc.Must(ccc.UUIDFromString("38bd570b-1280-421b-888e-a63f0ca35be7")),
+			sourceURL: []string{"testdata/sessions_test/invalid_schema"},
+			wantErr:   true,
+		},
+		{
+			name:      "success without destroying the session (not found)",
+			sessionID: ccc.Must(ccc.UUIDFromString("52dd570b-1280-421b-888e-a63f0ca35be9")),
+			sourceURL: []string{"../../../schema/sqlite/oidc/migrations", "testdata/sessions_test/oidc_valid_sessions"},
+			preAssertions: []string{
+				`SELECT COUNT(*) = 3 FROM "Sessions" WHERE "Expired" = false`,
+				`SELECT COUNT(*) = 0 FROM "Sessions" WHERE "Id" = '52dd570b-1280-421b-888e-a63f0ca35be9'`,
+			},
+			postAssertions: []string{
+				`SELECT COUNT(*) = 3 FROM "Sessions" WHERE "Expired" = false`,
+			},
+		},
+		{
+			name:      "success destroying session",
+			sessionID: ccc.Must(ccc.UUIDFromString("38bd570b-1280-421b-888e-a63f0ca35be7")),
+			sourceURL: []string{"../../../schema/sqlite/oidc/migrations", "testdata/sessions_test/oidc_valid_sessions"},
+			preAssertions: []string{
+				`SELECT "Expired" = false FROM "Sessions" WHERE "Id" = '38bd570b-1280-421b-888e-a63f0ca35be7'`,
+			},
+			postAssertions: []string{
+				`SELECT "Expired" = true FROM "Sessions" WHERE "Id" = '38bd570b-1280-421b-888e-a63f0ca35be7'`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := t.Context()
+			conn, err := prepareDatabase(ctx, t, tt.sourceURL...)
+			if err != nil {
+				t.Fatalf("prepareDatabase() error = %v, wantErr %v", err, false)
+			}
+			c := NewSessionStorageDriver(conn)
+
+			runAssertions(ctx, t, conn, tt.preAssertions)
+			if err := c.DestroySession(ctx, tt.sessionID); (err != nil) != tt.wantErr {
+				t.Errorf("client.DestroySession() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			runAssertions(ctx, t, conn, tt.postAssertions)
+		})
+	}
+}
+
+func TestSessionStorageDriver_User(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		id             ccc.UUID
+		sourceURL      []string
+		wantUser       *dbtype.SessionUser
+		wantErr        bool
+		preAssertions  []string
+		postAssertions []string
+	}{
+		{
+			name:      "success",
+			id:        ccc.Must(ccc.UUIDFromString("27b43588-b743-4133-8730-e0439065a844")),
+			sourceURL: []string{"../../../schema/sqlite/migrations", "testdata/users_test/valid_users"},
+			wantUser: &dbtype.SessionUser{
+				ID:       ccc.Must(ccc.UUIDFromString("27b43588-b743-4133-8730-e0439065a844")),
+				Username: "testUser",
+				Disabled: false,
+			},
+			preAssertions: []string{
+				`SELECT COUNT(*) = 1 
+				FROM "SessionUsers" 
+				WHERE "Id" = '27b43588-b743-4133-8730-e0439065a844'`,
+			},
+		},
+		{
+			name:      "not found",
+			id:        ccc.Must(ccc.NewUUID()),
+			sourceURL: []string{"../../../schema/sqlite/migrations", "testdata/users_test/valid_users"},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ctx := t.Context()
+			conn, err := prepareDatabase(ctx, t, tt.sourceURL...)
+			if err != nil {
+				t.Fatalf("prepareDatabase() error = %v, wantErr %v", err, false)
+			}
+			c := NewSessionStorageDriver(conn)
+
+			runAssertions(ctx, t, conn, tt.preAssertions)
+			gotUser, err := c.User(ctx, tt.id)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SessionStorageDriver.User() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantUser != nil {
+				if gotUser.ID != tt.wantUser.ID {
+					t.Errorf("SessionStorageDriver.User() gotUser.ID = %v, want %v", gotUser.ID, tt.wantUser.ID)
+				}
+				if gotUser.Username != tt.wantUser.Username {
+					t.Errorf("SessionStorageDriver.User() gotUser.Username = %v, want %v", gotUser.Username, tt.wantUser.Username)
+				}
+				if gotUser.Disabled != tt.wantUser.Disabled {
+					t.Errorf("SessionStorageDriver.User() gotUser.Disabled = %v, want %v", gotUser.Disabled, tt.wantUser.Disabled)
+				}
+			}
+			runAssertions(ctx, t, conn, tt.postAssertions)
+		})
+	}
+}
+
+func TestSessionStorageDriver_UserByUserName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		username       string
+		sourceURL      []string
+		wantUser       *dbtype.SessionUser
+		wantErr        bool
+		preAssertions  []string
+		postAssertions []string
+	}{
+		{
+			name:      "found user with case insensitive match",
+			username:  "tESTUSer",
+			sourceURL: []string{"../../../schema/sqlite/migrations", "testdata/users_test/valid_users"},
+			wantUser: &dbtype.SessionUser{
+				ID:       ccc.Must(ccc.UUIDFromString("27b43588-b743-4133-8730-e0439065a844")),
+				Username: "testUser",
+				Disabled: false,
+			},
+			preAssertions: []string{
+				`SELECT COUNT(*) = 1 
+				FROM "SessionUsers" 
+				WHERE "Username" = 'testUser'`,
+			},
+		},
+		{
+			name:      "success",
+			username:  "testUser",
+			sourceURL: []string{"../../../schema/sqlite/migrations", "testdata/users_test/valid_users"},
+			wantUser: &dbtype.SessionUser{
+				ID:       ccc.Must(ccc.UUIDFromString("27b43588-b743-4133-8730-e0439065a844")),
+				Username: "testUser",
+				Disabled: false,
+			},
+			preAssertions: []string{
+				`SELECT COUNT(*) = 1 
+				FROM "SessionUsers" 
+				WHERE "Username" = 'testUser'`,
+			},
+		},
+		{
+			name:      "not found",
+			username:  "nonexistent",
+			sourceURL: []string{"../../../schema/sqlite/migrations", "testdata/users_test/valid_users"},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ctx := t.Context()
+			conn, err := prepareDatabase(ctx, t, tt.sourceURL...)
+			if err != nil {
+				t.Fatalf("prepareDatabase() error = %v, wantErr %v", err, false)
+			}
+			c := NewSessionStorageDriver(conn)
+
+			runAssertions(ctx, t, conn, tt.preAssertions)
+			gotUser, err := c.UserByUserName(ctx, tt.username)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SessionStorageDriver.UserByUserName() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantUser != nil {
+				if gotUser.ID != tt.wantUser.ID {
+					t.Errorf("SessionStorageDriver.UserByUserName() gotUser.ID = %v, want %v", gotUser.ID, tt.wantUser.ID)
+				}
+				if gotUser.Username != tt.wantUser.Username {
+					t.Errorf("SessionStorageDriver.UserByUserName() gotUser.Username = %v, want %v", gotUser.Username, tt.wantUser.Username)
+				}
+				if gotUser.Disabled != tt.wantUser.Disabled {
+					t.Errorf("SessionStorageDriver.UserByUserName() gotUser.Disabled = %v, want %v", gotUser.Disabled, tt.wantUser.Disabled)
+				}
+			}
+			runAssertions(ctx, t, conn, tt.postAssertions)
+		})
+	}
+}
+
+func TestSessionStorageDriver_CreateUser(t *testing.T) {
+	t.Parallel()
+
+	hash, err := securehash.New(securehash.Argon2()).Hash("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name           string
+		username       string
+		hash           *securehash.Hash
+		sourceURL      []string
+		wantErr        bool
+		wantErrMsg     string
+		preAssertions  []string
+		postAssertions []string
+	}{
+		{
+			name:      "success",
+			username:  "newuser",
+			hash:      hash,
+			sourceURL: []string{"../../../schema/sqlite/migrations", "testdata/users_test/valid_users"},
+			preAssertions: []string{
+				`SELECT COUNT(*) = 0 FROM "SessionUsers" WHERE "Username" = 'newuser'`,
+			},
+			postAssertions: []string{
+				`SELECT COUNT(*) = 1 FROM "SessionUsers" WHERE "Username" = 'newuser'`,
+			},
+		},
+		{
+			name:       "user already exists",
+			username:   "testuser",
+			hash:       hash,
+			sourceURL:  []string{"../../../schema/sqlite/migrations", "testdata/users_test/valid_users"},
+			wantErr:    true,
+			wantErrMsg: `username "testuser" already exists`,
+		},
+		{
+			name:      "stores the normalized username",
+			username:  "Stra\u00dfe",
+			hash:      hash,
+			sourceURL: []string{"../../../schema/sqlite/migrations", "testdata/users_test/valid_users"},
+			postAssertions: []string{
+				`SELECT "NormalizedUsername" = 'strasse' FROM "SessionUsers" WHERE "Username" = 'Straße'`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ctx := t.Context()
+			conn, err := prepareDatabase(ctx, t, tt.sourceURL...)
+			if err != nil {
+				t.Fatalf("prepareDatabase() error = %v, wantErr %v", err, false)
+			}
+			c := NewSessionStorageDriver(conn)
+
+			user := &dbtype.InsertSessionUser{
+				Username:     tt.username,
+				PasswordHash: tt.hash,
+				Disabled:     false,
+			}
+
+			runAssertions(ctx, t, conn, tt.preAssertions)
+			_, err = c.CreateUser(ctx, user)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SessionStorageDriver.CreateUser() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && tt.wantErrMsg != "" && httpio.Message(err) != tt.wantErrMsg {
+				t.Errorf("SessionStorageDriver.CreateUser() error message = %s, want %s", httpio.Message(err), tt.wantErrMsg)
+			}
+			runAssertions(ctx, t, conn, tt.postAssertions)
+		})
+	}
+}
+
+func TestSessionStorageDriver_SetUserUsername(t *testing.T) {
+	t.Parallel()
+
+	userID := ccc.Must(ccc.UUIDFromString("27b43588-b743-4133-8730-e0439065a844"))
+	fullFixtures := []string{
+		"../../../schema/sqlite/migrations",
+		"testdata/users_test/valid_users",
+		"testdata/sessions_test/user_sessions",
+	}
+	usersOnlyFixtures := []string{
+		"../../../schema/sqlite/migrations",
+		"testdata/users_test/valid_users",
+	}
+
+	tests := []struct {
+		name           string
+		id             ccc.UUID
+		newUsername    string
+		sourceURL      []string
+		wantErr        bool
+		wantErrMsg     string
+		preAssertions  []string
+		postAssertions []string
+	}{
+		{
+			name:        "success updates user and active sessions, leaves expired and other users alone",
+			id:          userID,
+			newUsername: "<username>",
+			sourceURL:   fullFixtures,
+			preAssertions: []string{
+				`SELECT "Username" = 'testUser' FROM "SessionUsers" WHERE "Id" = '27b43588-b743-4133-8730-e0439065a844'`,
+				`SELECT COUNT(*) = 2 FROM "Sessions" WHERE "Username" = 'testUser' AND "Expired" = FALSE`,
+				`SELECT COUNT(*) = 1 FROM "Sessions" WHERE "Username" = 'testUser' AND "Expired" = TRUE`,
+			},
+			postAssertions: []string{
+				`SELECT "Username" = '<username>' FROM "SessionUsers" WHERE "Id" = '27b43588-b743-4133-8730-e0439065a844'`,
+				`SELECT COUNT(*) = 2 FROM "Sessions" WHERE "Username" = '<username>' AND "Expired" = FALSE`,
+				`SELECT COUNT(*) = 1 FROM "Sessions" WHERE "Username" = 'testUser' AND "Expired" = TRUE`,
+				`SELECT COUNT(*) = 0 FROM "Sessions" WHERE "Username" = 'testUser' AND "Expired" = FALSE`,
+				`SELECT COUNT(*) = 1 FROM "Sessions" WHERE "Username" = 'disableduser' AND "Expired" = FALSE`,
+			},
+		},
+		{
+			name:        "success when user has no active sessions",
+			id:          userID,
+			newUsername: "<username>",
+			sourceURL:   usersOnlyFixtures,
+			postAssertions: []string{
+				`SELECT "Username" = '<username>' FROM "SessionUsers" WHERE "Id" = '27b43588-b743-4133-8730-e0439065a844'`,
+			},
+		},
+		{
+			name:        "user not found",
+			id:          ccc.Must(ccc.NewUUID()),
+			newUsername: "<username>",
+			sourceURL:   fullFixtures,
+			wantErr:     true,
+			postAssertions: []string{
+				`SELECT COUNT(*) = 2 FROM "Sessions" WHERE "Username" = 'testUser' AND "Expired" = FALSE`,
+			},
+		},
+		{
+			name:        "username already exists",
+			id:          userID,
+			newUsername: "disableduser",
+			sourceURL:   fullFixtures,
+			wantErr:     true,
+			wantErrMsg:  `username "disableduser" already exists`,
+			postAssertions: []string{
+				`SELECT "Username" = 'testUser' FROM "SessionUsers" WHERE "Id" = '27b43588-b743-4133-8730-e0439065a844'`,
+				`SELECT COUNT(*) = 2 FROM "Sessions" WHERE "Username" = 'testUser' AND "Expired" = FALSE`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ctx := t.Context()
+			conn, err := prepareDatabase(ctx, t, tt.sourceURL...)
+			if err != nil {
+				t.Fatalf("prepareDatabase() error = %v, wantErr %v", err, false)
+			}
+			c := NewSessionStorageDriver(conn)
+
+			runAssertions(ctx, t, conn, tt.preAssertions)
+			err = c.SetUserUsername(ctx, tt.id, tt.newUsername)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SessionStorageDriver.SetUserUsername() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && tt.wantErrMsg != "" && httpio.Message(err) != tt.wantErrMsg {
+				t.Errorf("SessionStorageDriver.SetUserUsername() error message = %q, want %q", httpio.Message(err), tt.wantErrMsg)
+			}
+			runAssertions(ctx, t, conn, tt.postAssertions)
+		})
+	}
+}
+
+func TestSessionStorageDriver_SetUserPasswordHash(t *testing.T) {
+	t.Parallel()
+
+	newHash := &securehash.Hash{}
+	if err := newHash.UnmarshalText([]byte("1$12288$3$1$UdvSMfwCubeTKv05/UpxwA==.tr8oe8g0VvfjQp3XpJonme6edSA4diQLLrS64ksf/TM=")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name           string
+		id             ccc.UUID
+		hash           *securehash.Hash
+		sourceURL      []string
+		wantErr        bool
+		preAssertions  []string
+		postAssertions []string
+	}{
+		{
+			name:      "success",
+			id:        ccc.Must(ccc.UUIDFromString("27b43588-b743-4133-8730-e0439065a844")),
+			hash:      newHash,
+			sourceURL: []string{"../../../schema/sqlite/migrations", "testdata/users_test/valid_users"},
+			preAssertions: []string{
+				`
+					SELECT "PasswordHash" = '1$12288$3$1$k5UDxGNpdI0XrTY59KZvXg==.JNUcFFjrpbAj9pr1L8HkV8aNkeACBbc3SV0SSAjoPwM=' 
+					FROM "SessionUsers" 
+					WHERE "Id" = '27b43588-b743-4133-8730-e0439065a844'
+				`,
+			},
+			postAssertions: []string{
+				`
+					SELECT "PasswordHash" = '1$12288$3$1$UdvSMfwCubeTKv05/UpxwA==.tr8oe8g0VvfjQp3XpJonme6edSA4diQLLrS64ksf/TM='
+					FROM "SessionUsers"
+					WHERE "Id" = '27b43588-b743-4133-8730-e0439065a844'
+				`,
+			},
+		},
+		{
+			name:      "user not found",
+			id:        ccc.Must(ccc.NewUUID()),
+			hash:      newHash,
+			sourceURL: []string{"../../../schema/sqlite/migrations", "testdata/users_test/valid_users"},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ctx := t.Context()
+			conn, err := prepareDatabase(ctx, t, tt.sourceURL...)
+			if err != nil {
+				t.Fatalf("prepareDatabase() error = %v, wantErr %v", err, false)
+			}
+			c := NewSessionStorageDriver(conn)
+
+			runAssertions(ctx, t, conn, tt.preAssertions)
+			err = c.SetUserPasswordHash(ctx, tt.id, tt.hash)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SessionStorageDriver.SetUserPasswordHash() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			runAssertions(ctx, t, conn, tt.postAssertions)
+		})
+	}
+}
+
+func TestSessionStorageDriver_DeactivateUser(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		id             ccc.UUID
+		sourceURL      []string
+		wantErr        bool
+		preAssertions  []string
+		postAssertions []string
+	}{
+		{
+			name:      "success",
+			id:        ccc.Must(ccc.UUIDFromString("27b43588-b743-4133-8730-e0439065a844")),
+			sourceURL: []string{"../../../schema/sqlite/migrations", "testdata/users_test/valid_users"},
+			preAssertions: []string{
+				`SELECT "Disabled" = false FROM "SessionUsers" WHERE "Id" = '27b43588-b743-4133-8730-e0439065a844'`,
+			},
+			postAssertions: []string{
+				`SELECT "Disabled" = true FROM "SessionUsers" WHERE "Id" = '27b43588-b743-4133-8730-e0439065a844'`,
+			},
+		},
+		{
+			name:      "user not found",
+			id:        ccc.Must(ccc.NewUUID()),
+			sourceURL: []string{"../../../schema/sqlite/migrations", "testdata/users_test/valid_users"},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := t.Context()
+			conn, err := prepareDatabase(ctx, t, tt.sourceURL...)
+			if err != nil {
+				t.Fatalf("prepareDatabase() error = %v, wantErr %v", err, false)
+			}
+			c := NewSessionStorageDriver(conn)
+
+			runAssertions(ctx, t, conn, tt.preAssertions)
+			err = c.DeactivateUser(ctx, tt.id)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SessionStorageDriver.DeactivateUser() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			runAssertions(ctx, t, conn, tt.postAssertions)
+		})
+	}
+}
+
+func TestSessionStorageDriver_DeleteUser(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		id             ccc.UUID
+		sourceURL      []string
+		wantErr        bool
+		preAssertions  []string
+		postAssertions []string
+	}{
+		{
+			name:      "success",
+			id:        ccc.Must(ccc.UUIDFromString("27b43588-b743-4133-8730-e0439065a844")),
+			sourceURL: []string{"../../../schema/sqlite/migrations", "testdata/users_test/valid_users"},
+			preAssertions: []string{
+				`SELECT COUNT(*) = 1 FROM "SessionUsers" WHERE "Id" = '27b43588-b743-4133-8730-e0439065a844'`,
+			},
+			postAssertions: []string{
+				`SELECT COUNT(*) = 0 FROM "SessionUsers" WHERE "Id" = '27b43588-b743-4133-8730-e0439065a844'`,
+			},
+		},
+		{
+			name:      "user not found",
+			id:        ccc.Must(ccc.NewUUID()),
+			sourceURL: []string{"../../../schema/sqlite/migrations", "testdata/users_test/valid_users"},
+			preAssertions: []string{
+				`SELECT COUNT(*) = 2 FROM "SessionUsers"`,
+			},
+			postAssertions: []string{
+				`SELECT COUNT(*) = 2 FROM "SessionUsers"`,
+			},
+			wantErr: true,
+		},
+		{
+			name:    "error on invalid scheam",
+			id:      ccc.Must(ccc.NewUUID()),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := t.Context()
+			conn, err := prepareDatabase(ctx, t, tt.sourceURL...)
+			if err != nil {
+				t.Fatalf("prepareDatabase() error = %v, wantErr %v", err, false)
+			}
+			c := NewSessionStorageDriver(conn)
+
+			runAssertions(ctx, t, conn, tt.preAssertions)
+			err = c.DeleteUser(ctx, tt.id)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SessionStorageDriver.DeleteUser() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			runAssertions(ctx, t, conn, tt.postAssertions)
+		})
+	}
+}
+
+func TestSessionStorageDriver_ActivateUser(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		id             ccc.UUID
+		sourceURL      []string
+		wantErr        bool
+		preAssertions  []string
+		postAssertions []string
+	}{
+		{
+			name:      "success",
+			id:        ccc.Must(ccc.UUIDFromString("54918893-2342-4621-8673-79520a84b84f")),
+			sourceURL: []string{"../../../schema/sqlite/migrations", "testdata/users_test/valid_users"},
+			preAssertions: []string{
+				`SELECT "Disabled" = true FROM "SessionUsers" WHERE "Id" = '54918893-2342-4621-8673-79520a84b84f'`,
+			},
+			postAssertions: []string{
+				`SELECT "Disabled" = false FROM "SessionUsers" WHERE "Id" = '54918893-2342-4621-8673-79520a84b84f'`,
+			},
+		},
+		{
+			name:      "user not found",
+			id:        ccc.Must(ccc.NewUUID()),
+			sourceURL: []string{"../../../schema/sqlite/migrations", "testdata/users_test/valid_users"},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := t.Context()
+			conn, err := prepareDatabase(ctx, t, tt.sourceURL...)
+			if err != nil {
+				t.Fatalf("prepareDatabase() error = %v, wantErr %v", err, false)
+			}
+			c := NewSessionStorageDriver(conn)
+
+			runAssertions(ctx, t, conn, tt.preAssertions)
+			err = c.ActivateUser(ctx, tt.id)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SessionStorageDriver.ActivateUser() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			runAssertions(ctx, t, conn, tt.postAssertions)
+		})
+	}
+}
+
+func TestSessionStorageDriver_DestroyAllSessionsForUser(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		username       string
+		sourceURL      []string
+		wantErr        bool
+		preAssertions  []string
+		postAssertions []string
+	}{
+		{
+			name:      "success",
+			username:  "test user 1",
+			sourceURL: []string{"../../../schema/sqlite/migrations", "testdata/sessions_test/valid_sessions"},
+			preAssertions: []string{
+				`SELECT COUNT(*) = 2 FROM "Sessions" WHERE "Username" = 'test user 1' AND "Expired" = false`,
+			},
+			postAssertions: []string{
+				`SELECT COUNT(*) = 0 FROM "Sessions" WHERE "Username" = 'test user 1' AND "Expired" = false`,
+			},
+		},
+		{
+			name:      "user has no sessions",
+			username:  "no_sessions_user",
+			sourceURL: []string{"../../../schema/sqlite/migrations", "testdata/sessions_test/valid_sessions"},
+			preAssertions: []string{
+				`SELECT COUNT(*) = 0 FROM "Sessions" WHERE "Username" = 'no_sessions_user'`,
+			},
+			postAssertions: []string{
+				`SELECT COUNT(*) = 0 FROM "Sessions" WHERE "Username" = 'no_sessions_user'`,
+			},
+		},
+		{
+			name:      "invalid schema",
+			username:  "test user",
+			sourceURL: []string{"testdata/sessions_test/invalid_schema"},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ctx := t.Context()
+			conn, err := prepareDatabase(ctx, t, tt.sourceURL...)
+			if err != nil {
+				t.Fatalf("prepareDatabase() error = %v, wantErr %v", err, false)
+			}
+			c := NewSessionStorageDriver(conn)
+
+			runAssertions(ctx, t, conn, tt.preAssertions)
+			err = c.DestroyAllUserSessions(ctx, tt.username)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SessionStorageDriver.DestroyAllSessionsForUser() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			runAssertions(ctx, t, conn, tt.postAssertions)
+		})
+	}
+}
+
+func TestSessionStorageDriver_PurgeSessions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		purge          *dbtype.PurgeSessions
+		sourceURL      []string
+		wantDeleted    int64
+		wantErr        bool
+		postAssertions []string
+	}{
+		{
+			name:        "deletes expired sessions",
+			purge:       &dbtype.PurgeSessions{Limit: 100},
+			sourceURL:   []string{"../../../schema/sqlite/migrations", "testdata/sessions_test/valid_sessions"},
+			wantDeleted: 2,
+			postAssertions: []string{
+				`SELECT COUNT(*) = 3 FROM "Sessions"`,
+				`SELECT COUNT(*) = 0 FROM "Sessions" WHERE "Expired" = true`,
+			},
+		},
+		{
+			name: "deletes idle sessions",
+			purge: &dbtype.PurgeSessions{
+				IdleBefore: ccc.Must(time.Parse(time.RFC3339, "2019-01-01T00:00:00Z")),
+				Limit:      100,
+			},
+			sourceURL:   []string{"../../../schema/sqlite/migrations", "testdata/sessions_test/valid_sessions"},
+			wantDeleted: 3,
+			postAssertions: []string{
+				`SELECT COUNT(*) = 0 FROM "Sessions" WHERE "Id" = '095887e9-ab67-42c3-8090-6c50780606e3'`,
+				`SELECT COUNT(*) = 2 FROM "Sessions"`,
+			},
+		},
+		{
+			name: "deletes sessions older than the absolute age",
+			purge: &dbtype.PurgeSessions{
+				CreatedBefore: ccc.Must(time.Parse(time.RFC3339, "2019-02-02T00:00:00Z")),
+				Limit:         100,
+			},
+			sourceURL:   []string{"../../../schema/sqlite/migrations", "testdata/sessions_test/valid_sessions"},
+			wantDeleted: 4,
+			postAssertions: []string{
+				`SELECT COUNT(*) = 1 FROM "Sessions" WHERE "Id" = 'da8d6b11-8ef3-4134-8216-2dd0a94795ba'`,
+				`SELECT COUNT(*) = 1 FROM "Sessions"`,
+			},
+		},
+		{
+			name:        "deletes at most limit rows",
+			purge:       &dbtype.PurgeSessions{Limit: 1},
+			sourceURL:   []string{"../../../schema/sqlite/migrations", "testdata/sessions_test/valid_sessions"},
+			wantDeleted: 1,
+			postAssertions: []string{
+				`SELECT COUNT(*) = 4 FROM "Sessions"`,
+			},
+		},
+		{
+			name:        "deletes the data of deleted sessions",
+			purge:       &dbtype.PurgeSessions{Limit: 100},
+			sourceURL:   []string{"../../../schema/sqlite/migrations", "testdata/sessions_test/valid_sessions", "testdata/sessions_test/session_data"},
+			wantDeleted: 2,
+			postAssertions: []string{
+				`SELECT COUNT(*) = 1 FROM "SessionsData"`,
+				`SELECT COUNT(*) = 1 FROM "SessionsData" WHERE "SessionId" = '38bd570b-1280-421b-888e-a63f0ca35be7'`,
+			},
+		},
+		{
+			name:      "invalid schema",
+			purge:     &dbtype.PurgeSessions{Limit: 100},
+			sourceURL: []string{"testdata/sessions_test/invalid_schema"},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ctx := t.Context()
+			conn, err := prepareDatabase(ctx, t, tt.sourceURL...)
+			if err != nil {
+				t.Fatalf("prepareDatabase() error = %v, wantErr %v", err, false)
+			}
+			c := NewSessionStorageDriver(conn)
+
+			got, err := c.PurgeSessions(ctx, tt.purge)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SessionStorageDriver.PurgeSessions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.wantDeleted {
+				t.Errorf("SessionStorageDriver.PurgeSessions() = %v, want %v", got, tt.wantDeleted)
+			}
+			runAssertions(ctx, t, conn, tt.postAssertions)
+		})
+	}
+}
+
+func TestSessionStorageDriver_UserSessions(t *testing.T) {
+	t.Parallel()
+
+	idleBefore := ccc.Must(time.Parse(time.RFC3339, "2020-01-03T12:00:00Z"))
+	tests := []struct {
+		name      string
+		query     *dbtype.UserSessions
+		sourceURL []string
+		wantIDs   []string
+		wantErr   bool
+	}{
+		{
+			name:      "all sessions newest first",
+			query:     &dbtype.UserSessions{Username: "test user 1", Active: true, Expired: true, Limit: 10},
+			sourceURL: []string{"../../../schema/sqlite/migrations", "testdata/sessions_test/valid_sessions"},
+			wantIDs:   []string{"da8d6b11-8ef3-4134-8216-2dd0a94795ba", "aa817d69-f550-474b-8eae-7b29da32e3a8", "38bd570b-1280-421b-888e-a63f0ca35be7"},
+		},
+		{
+			name:      "active sessions",
+			query:     &dbtype.UserSessions{Username: "test user 1", Active: true, Limit: 10},
+			sourceURL: []string{"../../../schema/sqlite/migrations", "testdata/sessions_test/valid_sessions"},
+			wantIDs:   []string{"da8d6b11-8ef3-4134-8216-2dd0a94795ba", "38bd570b-1280-421b-888e-a63f0ca35be7"},
+		},
+		{
+			name:      "expired sessions",
+			query:     &dbtype.UserSessions{Username: "test user 1", Expired: true, Limit: 10},
+			sourceURL: []string{"../../../schema/sqlite/migrations", "testdata/sessions_test/valid_sessions"},
+			wantIDs:   []string{"aa817d69-f550-474b-8eae-7b29da32e3a8"},
+		},
+		{
+			name:      "idle sessions are not active",
+			query:     &dbtype.UserSessions{Username: "test user 1", Active: true, IdleBefore: idleBefore, Limit: 10},
+			sourceURL: []string{"../../../schema/sqlite/migrations", "testdata/sessions_test/valid_sessions"},
+			wantIDs:   []string{"da8d6b11-8ef3-4134-8216-2dd0a94795ba"},
+		},
+		{
+			name:      "idle sessions are expired",
+			query:     &dbtype.UserSessions{Username: "test user 1", Expired: true, IdleBefore: idleBefore, Limit: 10},
+			sourceURL: []string{"../../../schema/sqlite/migrations", "testdata/sessions_test/valid_sessions"},
+			wantIDs:   []string{"aa817d69-f550-474b-8eae-7b29da32e3a8", "38bd570b-1280-421b-888e-a63f0ca35be7"},
+		},
+		{
+			name:      "limit",
+			query:     &dbtype.UserSessions{Username: "test user 1", Active: true, Expired: true, Limit: 2},
+			sourceURL: []string{"../../../schema/sqlite/migrations", "testdata/sessions_test/valid_sessions"},
+			wantIDs:   []string{"da8d6b11-8ef3-4134-8216-2dd0a94795ba", "aa817d69-f550-474b-8eae-7b29da32e3a8"},
+		},
+		{
+			name: "after cursor",
+			query: &dbtype.UserSessions{
+				Username: "test user 1",
+				Active:   true,
+				Expired:  true,
+				After: &dbtype.SessionCursor{
+					CreatedAt: ccc.Must(time.Parse(time.RFC3339, "2019-02-02T05:10:20Z")),
+					ID:        ccc.Must(ccc.UUIDFromString("aa817d69-f550-474b-8eae-7b29da32e3a8")),
+				},
+				Limit: 10,
+			},
+			sourceURL: []string{"../../../schema/sqlite/migrations", "testdata/sessions_test/valid_sessions"},
+			wantIDs:   []string{"38bd570b-1280-421b-888e-a63f0ca35be7"},
+		},
+		{
+			name:      "invalid schema",
+			query:     &dbtype.UserSessions{Username: "test user 1", Active: true, Expired: true, Limit: 10},
+			sourceURL: []string{"testdata/sessions_test/invalid_schema"},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ctx := t.Context()
+			conn, err := prepareDatabase(ctx, t, tt.sourceURL...)
+			if err != nil {
+				t.Fatalf("prepareDatabase() error = %v, wantErr %v", err, false)
+			}
+			c := NewSessionStorageDriver(conn)
+
+			got, err := c.UserSessions(ctx, tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SessionStorageDriver.UserSessions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			gotIDs := make([]string, 0, len(got))
+			for _, s := range got {
+				gotIDs = append(gotIDs, s.ID.String())
+			}
+			if !tt.wantErr && !slices.Equal(gotIDs, tt.wantIDs) {
+				t.Errorf("SessionStorageDriver.UserSessions() = %v, want %v", gotIDs, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestSessionStorageDriver_PurgeSessions_persistentSession(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	conn, err := prepareDatabase(ctx, t, "../../../schema/sqlite/migrations")
+	if err != nil {
+		t.Fatalf("prepareDatabase() error = %v", err)
+	}
+	c := NewSessionStorageDriver(conn)
+
+	now := time.Now()
+	updatedAt := now.Add(-2 * time.Hour)
+	var ids []ccc.UUID
+	for _, idleTimeout := range []time.Duration{0, 3 * time.Hour} {
+		id, err := c.InsertSession(ctx, &dbtype.InsertSession{
+			Username:           "test user 1",
+			CreatedAt:          updatedAt,
+			UpdatedAt:          updatedAt,
+			IdleTimeoutSeconds: int64(idleTimeout / time.Second),
+		})
+		if err != nil {
+			t.Fatalf("SessionStorageDriver.InsertSession() error = %v", err)
+		}
+		ids = append(ids, id)
+	}
+
+	// The persistent session is kept until its own idle timeout has passed
+	for _, tt := range []struct {
+		now  time.Time
+		want ccc.UUID
+	}{
+		{now: now, want: ids[0]},
+		{now: now.Add(2 * time.Hour), want: ids[1]},
+	} {
+		got, err := c.PurgeSessions(ctx, &dbtype.PurgeSessions{IdleBefore: tt.now.Add(-time.Hour), Now: tt.now, Limit: 100})
+		if err != nil {
+			t.Fatalf("SessionStorageDriver.PurgeSessions() error = %v", err)
+		}
+		if got != 1 {
+			t.Errorf("SessionStorageDriver.PurgeSessions() = %v, want 1", got)
+		}
+		if _, err := c.Session(ctx, tt.want); !httpio.HasNotFound(err) {
+			t.Errorf("SessionStorageDriver.Session() error = %v, want session %v purged", err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+-- Table: Sessions
+
+-- DROP TABLE Sessions;
+
+-- SQLite does not enforce column types, so the schema is made invalid by
+-- leaving out the "UpdatedAt" and "Expired" columns.
+CREATE TABLE "Sessions"
+(
+    "Id" TEXT NOT NULL,
+    "OidcSid" TEXT NOT NULL,
+    "Username" TEXT NOT NULL,
+    "CreatedAt" TIMESTAMP NOT NULL,
+    CONSTRAINT "Sessions_pkey" PRIMARY KEY ("Id")
+);
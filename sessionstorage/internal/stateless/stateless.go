@@ -0,0 +1,391 @@
+// Package stateless implements a session storage driver that keeps the session of a request in its
+// Auth Cookie, with an optional denylist of revoked sessions.
+package stateless
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/cccteam/ccc"
+	"github.com/cccteam/ccc/securehash"
+	"github.com/cccteam/ccc/tracer"
+	"github.com/cccteam/httpio"
+	"github.com/cccteam/session/internal/cookiesession"
+	"github.com/cccteam/session/internal/dbtype"
+	"github.com/go-playground/errors/v5"
+)
+
+var (
+	errNoCookieSession           = errors.New("stateless sessions require the StartSession handler")
+	errDenylistRequired          = errors.New("revoking sessions other than the session of the request requires a denylist")
+	errUsersNotSupported         = errors.New("users are not supported by the stateless driver")
+	errOIDCNotSupported          = errors.New("OIDC sessions are not supported by the stateless driver")
+	errActivityNotSupported      = errors.New("writing session activity outside of a request is not supported by the stateless driver")
+	errImpersonationNotSupported = errors.New("impersonation is not supported by the stateless driver, which would replace the session of the impersonator")
+)
+
+// Denylist records revoked sessions, which a stateless session store can not delete
+type Denylist interface {
+	// DenySession revokes the session with sessionID
+	DenySession(ctx context.Context, sessionID ccc.UUID) error
+	// DenyUserSessions revokes the sessions of username created before createdBefore
+	DenyUserSessions(ctx context.Context, username string, createdBefore time.Time) error
+	// Denied reports whether the session with sessionID, of username and created at createdAt, was revoked
+	Denied(ctx context.Context, sessionID ccc.UUID, username string, createdAt time.Time) (bool, error)
+}
+
+// SessionStorageDriver represents the session storage implementation that keeps the session of a request
+// in its Auth Cookie. It only knows the session of the request in ctx, read from the Auth Cookie.
+type SessionStorageDriver struct {
+	denylist Denylist
+}
+
+// NewSessionStorageDriver creates a new SessionStorageDriver. denylist can be nil, in which case
+// only the session of a request can be destroyed, by writing it back to its Auth Cookie.
+func NewSessionStorageDriver(denylist Denylist) *SessionStorageDriver {
+	return &SessionStorageDriver{
+		denylist: denylist,
+	}
+}
+
+// SetSessionTableName is a no-op, the stateless driver does not use tables.
+func (s *SessionStorageDriver) SetSessionTableName(_ string) {}
+
+// SetUserTableName is a no-op, the stateless driver does not use tables.
+func (s *SessionStorageDriver) SetUserTableName(_ string) {}
+
+// Session returns the session of the request in ctx when it has sessionID and was not revoked
+func (s *SessionStorageDriver) Session(ctx context.Context, sessionID ccc.UUID) (*dbtype.Session, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	cs, ok := cookiesession.FromCtx(ctx)
+	if !ok {
+		return nil, errNoCookieSession
+	}
+
+	var session *dbtype.Session
+	_ = cs.View(func(state cookiesession.State) error {
+		session = state.Session
+
+		return nil
+	})
+	if session == nil || session.ID != sessionID {
+		return nil, httpio.NewNotFoundMessagef("session %q not found", sessionID)
+	}
+
+	if s.denylist != nil && !session.Expired {
+		denied, err := s.denylist.Denied(ctx, session.ID, session.Username, session.CreatedAt)
+		if err != nil {
+			return nil, errors.Wrap(err, "Denylist.Denied()")
+		}
+		session.Expired = denied
+	}
+
+	return session, nil
+}
+
+// update calls update with the session of the request in ctx when it has sessionID.
+// It returns a NotFound error when the request does not have the session.
+func (s *SessionStorageDriver) update(ctx context.Context, sessionID ccc.UUID, update func(state *cookiesession.State)) error {
+	cs, ok := cookiesession.FromCtx(ctx)
+	if !ok {
+		return errNoCookieSession
+	}
+
+	return cs.Update(func(state *cookiesession.State) (bool, error) {
+		if state.Session == nil || state.Session.ID != sessionID {
+			return false, httpio.NewNotFoundMessagef("session %q not found", sessionID)
+		}
+		update(state)
+
+		return true, nil
+	})
+}
+
+// UpdateSessionActivity sets the activity time of the session to the current time
+func (s *SessionStorageDriver) UpdateSessionActivity(ctx context.Context, sessionID ccc.UUID) error {
+	_, span := tracer.Start(ctx)
+	defer span.End()
+
+	return s.update(ctx, sessionID, func(state *cookiesession.State) {
+		state.Session.UpdatedAt = time.Now()
+	})
+}
+
+// UpdateSessionAuthentication sets the activity and last authentication times of the session to the current time
+func (s *SessionStorageDriver) UpdateSessionAuthentication(ctx context.Context, sessionID ccc.UUID) error {
+	_, span := tracer.Start(ctx)
+	defer span.End()
+
+	return s.update(ctx, sessionID, func(state *cookiesession.State) {
+		now := time.Now()
+		state.Session.UpdatedAt = now
+		state.Session.LastAuthenticatedAt = now
+	})
+}
+
+// UpdateSessionsActivity is not supported by the stateless driver, as sessions are only known during their requests
+func (s *SessionStorageDriver) UpdateSessionsActivity(_ context.Context, _ []*dbtype.SessionActivity) error {
+	return errActivityNotSupported
+}
+
+// InsertSession replaces the session of the request in ctx with a new session. The limit on the
+// sessions of a user is not applied, as the other sessions of the user are not known. Impersonated
+// sessions are rejected, as the session of the impersonator could not be resumed.
+func (s *SessionStorageDriver) InsertSession(ctx context.Context, insertSession *dbtype.InsertSession) (ccc.UUID, error) {
+	_, span := tracer.Start(ctx)
+	defer span.End()
+
+	if insertSession.ImpersonatorUsername != "" {
+		return ccc.NilUUID, httpio.NewBadRequestMessageWithError(errImpersonationNotSupported, "impersonation is not supported")
+	}
+
+	cs, ok := cookiesession.FromCtx(ctx)
+	if !ok {
+		return ccc.NilUUID, errNoCookieSession
+	}
+
+	id, err := ccc.NewUUID()
+	if err != nil {
+		return ccc.NilUUID, errors.Wrap(err, "ccc.NewUUID()")
+	}
+
+	_ = cs.Update(func(state *cookiesession.State) (bool, error) {
+		state.Session = &dbtype.Session{
+			ID:                  id,
+			Username:            insertSession.Username,
+			CreatedAt:           insertSession.CreatedAt,
+			UpdatedAt:           insertSession.UpdatedAt,
+			Expired:             insertSession.Expired,
+			IdleTimeoutSeconds:  insertSession.IdleTimeoutSeconds,
+			ClientIP:            insertSession.ClientIP,
+			UserAgent:           insertSession.UserAgent,
+			AuthMethod:          insertSession.AuthMethod,
+			LastAuthenticatedAt: insertSession.LastAuthenticatedAt,
+		}
+		state.Data = nil
+
+		return true, nil
+	})
+
+	return id, nil
+}
+
+// DestroySession marks the session as expired. A session other than the session of the request in ctx
+// can only be destroyed with a denylist.
+func (s *SessionStorageDriver) DestroySession(ctx context.Context, sessionID ccc.UUID) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	err := s.update(ctx, sessionID, func(state *cookiesession.State) {
+		state.Session.Expired = true
+		state.Session.UpdatedAt = time.Now()
+	})
+	if err != nil && !httpio.HasNotFound(err) {
+		return err
+	}
+
+	if s.denylist == nil {
+		if err != nil {
+			return errDenylistRequired
+		}
+
+		return nil
+	}
+
+	if err := s.denylist.DenySession(ctx, sessionID); err != nil {
+		return errors.Wrap(err, "Denylist.DenySession()")
+	}
+
+	return nil
+}
+
+// RotateSession moves the session of the request in ctx to a new ID, keeping its data, and returns
+// the new ID. The old ID is denied when there is a denylist.
+func (s *SessionStorageDriver) RotateSession(ctx context.Context, sessionID ccc.UUID) (ccc.UUID, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	id, err := ccc.NewUUID()
+	if err != nil {
+		return ccc.NilUUID, errors.Wrap(err, "ccc.NewUUID()")
+	}
+
+	var expired bool
+	if err := s.update(ctx, sessionID, func(state *cookiesession.State) {
+		if expired = state.Session.Expired; !expired {
+			state.Session.ID = id
+		}
+	}); err != nil {
+		return ccc.NilUUID, err
+	}
+	if expired {
+		return ccc.NilUUID, httpio.NewNotFoundMessagef("session %q not found", sessionID)
+	}
+
+	if s.denylist != nil {
+		if err := s.denylist.DenySession(ctx, sessionID); err != nil {
+			return ccc.NilUUID, errors.Wrap(err, "Denylist.DenySession()")
+		}
+	}
+
+	return id, nil
+}
+
+// DestroyAllUserSessions denies the sessions of username created until now. It requires a denylist.
+func (s *SessionStorageDriver) DestroyAllUserSessions(ctx context.Context, username string) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	if s.denylist == nil {
+		return errDenylistRequired
+	}
+
+	if err := s.denylist.DenyUserSessions(ctx, username, time.Now()); err != nil {
+		return errors.Wrap(err, "Denylist.DenyUserSessions()")
+	}
+
+	return nil
+}
+
+// UserSessions returns the session of the request in ctx when it matches the query, as the
+// other sessions of the user are not known.
+func (s *SessionStorageDriver) UserSessions(ctx context.Context, query *dbtype.UserSessions) ([]*dbtype.Session, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	cs, ok := cookiesession.FromCtx(ctx)
+	if !ok {
+		return nil, errNoCookieSession
+	}
+
+	var sessionID ccc.UUID
+	_ = cs.View(func(state cookiesession.State) error {
+		if state.Session != nil {
+			sessionID = state.Session.ID
+		}
+
+		return nil
+	})
+	if sessionID == ccc.NilUUID {
+		return nil, nil
+	}
+
+	session, err := s.Session(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if query.Limit < 1 || !query.Matches(session) {
+		return nil, nil
+	}
+
+	return []*dbtype.Session{session}, nil
+}
+
+// PurgeSessions is a no-op, the stateless driver does not store sessions.
+func (s *SessionStorageDriver) PurgeSessions(_ context.Context, _ *dbtype.PurgeSessions) (int64, error) {
+	return 0, nil
+}
+
+// SessionData returns the data stored with the session of the request in ctx
+func (s *SessionStorageDriver) SessionData(ctx context.Context, sessionID ccc.UUID) (map[string]json.RawMessage, error) {
+	_, span := tracer.Start(ctx)
+	defer span.End()
+
+	cs, ok := cookiesession.FromCtx(ctx)
+	if !ok {
+		return nil, errNoCookieSession
+	}
+
+	data := make(map[string]json.RawMessage)
+	_ = cs.View(func(state cookiesession.State) error {
+		if state.Session != nil && state.Session.ID == sessionID {
+			for name, value := range state.Data {
+				data[name] = slices.Clone(value)
+			}
+		}
+
+		return nil
+	})
+
+	return data, nil
+}
+
+// UpdateSessionData applies update to the data stored with the session of the request in ctx
+func (s *SessionStorageDriver) UpdateSessionData(ctx context.Context, sessionID ccc.UUID, update *dbtype.SessionDataUpdate) error {
+	_, span := tracer.Start(ctx)
+	defer span.End()
+
+	return s.update(ctx, sessionID, func(state *cookiesession.State) {
+		data := maps.Clone(state.Data)
+		if data == nil {
+			data = make(map[string]json.RawMessage, len(update.Set))
+		}
+		for name, value := range update.Set {
+			data[name] = slices.Clone(value)
+		}
+		for _, name := range update.Deleted {
+			delete(data, name)
+		}
+		state.Data = data
+	})
+}
+
+// User is not supported by the stateless driver.
+func (s *SessionStorageDriver) User(_ context.Context, _ ccc.UUID) (*dbtype.SessionUser, error) {
+	return nil, errUsersNotSupported
+}
+
+// UserByUserName is not supported by the stateless driver.
+func (s *SessionStorageDriver) UserByUserName(_ context.Context, _ string) (*dbtype.SessionUser, error) {
+	return nil, errUsersNotSupported
+}
+
+// CreateUser is not supported by the stateless driver.
+func (s *SessionStorageDriver) CreateUser(_ context.Context, _ *dbtype.InsertSessionUser) (*dbtype.SessionUser, error) {
+	return nil, errUsersNotSupported
+}
+
+// SetUserUsername is not supported by the stateless driver.
+func (s *SessionStorageDriver) SetUserUsername(_ context.Context, _ ccc.UUID, _ string) error {
+	return errUsersNotSupported
+}
+
+// SetUserPasswordHash is not supported by the stateless driver.
+func (s *SessionStorageDriver) SetUserPasswordHash(_ context.Context, _ ccc.UUID, _ *securehash.Hash) error {
+	return errUsersNotSupported
+}
+
+// ActivateUser is not supported by the stateless driver.
+func (s *SessionStorageDriver) ActivateUser(_ context.Context, _ ccc.UUID) error {
+	return errUsersNotSupported
+}
+
+// DeactivateUser is not supported by the stateless driver.
+func (s *SessionStorageDriver) DeactivateUser(_ context.Context, _ ccc.UUID) error {
+	return errUsersNotSupported
+}
+
+// DeleteUser is not supported by the stateless driver.
+func (s *SessionStorageDriver) DeleteUser(_ context.Context, _ ccc.UUID) error {
+	return errUsersNotSupported
+}
+
+// InsertSessionOIDC is not supported by the stateless driver.
+func (s *SessionStorageDriver) InsertSessionOIDC(_ context.Context, _ *dbtype.InsertOIDCSession) (ccc.UUID, error) {
+	return ccc.NilUUID, errOIDCNotSupported
+}
+
+// DestroySessionOIDC is not supported by the stateless driver.
+func (s *SessionStorageDriver) DestroySessionOIDC(_ context.Context, _ string) error {
+	return errOIDCNotSupported
+}
+
+// UsernameOIDC is not supported by the stateless driver.
+func (s *SessionStorageDriver) UsernameOIDC(_ context.Context, _ string) (string, error) {
+	return "", errOIDCNotSupported
+}
@@ -0,0 +1,427 @@
+package stateless
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cccteam/ccc"
+	"github.com/cccteam/httpio"
+	"github.com/cccteam/session/internal/cookiesession"
+	"github.com/cccteam/session/internal/dbtype"
+)
+
+// testDenylist records the denied sessions
+type testDenylist struct {
+	sessions map[ccc.UUID]bool
+}
+
+func (d *testDenylist) DenySession(_ context.Context, sessionID ccc.UUID) error {
+	d.sessions[sessionID] = true
+
+	return nil
+}
+
+func (d *testDenylist) DenyUserSessions(_ context.Context, _ string, _ time.Time) error {
+	return nil
+}
+
+func (d *testDenylist) Denied(_ context.Context, sessionID ccc.UUID, _ string, _ time.Time) (bool, error) {
+	return d.sessions[sessionID], nil
+}
+
+// newCookieSessionCtx returns a context holding session and data, as read from an Auth Cookie
+func newCookieSessionCtx(t *testing.T, session *dbtype.Session, data map[string]json.RawMessage) (context.Context, *cookiesession.Session) {
+	t.Helper()
+
+	var encoded string
+	if session != nil {
+		b, err := json.Marshal(cookiesession.State{Session: session, Data: data})
+		if err != nil {
+			t.Fatalf("json.Marshal() error = %v", err)
+		}
+		encoded = string(b)
+	}
+
+	ctx, cs, err := cookiesession.NewContext(context.Background(), encoded)
+	if err != nil {
+		t.Fatalf("cookiesession.NewContext() error = %v", err)
+	}
+
+	return ctx, cs
+}
+
+func newTestSession() *dbtype.Session {
+	now := time.Now()
+
+	return &dbtype.Session{
+		ID:        ccc.Must(ccc.NewUUID()),
+		Username:  "test_user",
+		CreatedAt: now.Add(-time.Minute),
+		UpdatedAt: now.Add(-time.Minute),
+	}
+}
+
+func TestSessionStorageDriver_RotateSession(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		denylist   bool
+		expired    bool
+		wantErr    bool
+		wantDenied bool
+	}{
+		{
+			name: "rotates without a denylist",
+		},
+		{
+			name:       "denies the old ID with a denylist",
+			denylist:   true,
+			wantDenied: true,
+		},
+		{
+			name:    "expired session is not rotated",
+			expired: true,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			session := newTestSession()
+			session.Expired = tt.expired
+			oldID := session.ID
+			ctx, _ := newCookieSessionCtx(t, session, nil)
+
+			denylist := &testDenylist{sessions: make(map[ccc.UUID]bool)}
+			s := NewSessionStorageDriver(nil)
+			if tt.denylist {
+				s = NewSessionStorageDriver(denylist)
+			}
+
+			newID, err := s.RotateSession(ctx, oldID)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SessionStorageDriver.RotateSession() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if newID == oldID {
+				t.Errorf("SessionStorageDriver.RotateSession() = %v, want a new ID", newID)
+			}
+			if got, err := s.Session(ctx, newID); err != nil || got.Username != "test_user" {
+				t.Errorf("SessionStorageDriver.Session(newID) = %+v, %v, want the session of test_user", got, err)
+			}
+			if _, err := s.Session(ctx, oldID); !httpio.HasNotFound(err) {
+				t.Errorf("SessionStorageDriver.Session(oldID) error = %v, want NotFound", err)
+			}
+			if denylist.sessions[oldID] != tt.wantDenied {
+				t.Errorf("old ID denied = %v, want %v", denylist.sessions[oldID], tt.wantDenied)
+			}
+		})
+	}
+}
+
+func TestSessionStorageDriver_DestroySession(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		denylist    bool
+		other       bool
+		wantErr     error
+		wantExpired bool
+		wantDenied  bool
+	}{
+		{
+			name:        "session of the request without a denylist",
+			wantExpired: true,
+		},
+		{
+			name:        "session of the request with a denylist",
+			denylist:    true,
+			wantExpired: true,
+			wantDenied:  true,
+		},
+		{
+			name:    "other session without a denylist",
+			other:   true,
+			wantErr: errDenylistRequired,
+		},
+		{
+			name:       "other session with a denylist",
+			denylist:   true,
+			other:      true,
+			wantDenied: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			session := newTestSession()
+			ctx, cs := newCookieSessionCtx(t, session, nil)
+
+			denylist := &testDenylist{sessions: make(map[ccc.UUID]bool)}
+			s := NewSessionStorageDriver(nil)
+			if tt.denylist {
+				s = NewSessionStorageDriver(denylist)
+			}
+
+			destroyID := session.ID
+			if tt.other {
+				destroyID = ccc.Must(ccc.NewUUID())
+			}
+
+			err := s.DestroySession(ctx, destroyID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SessionStorageDriver.DestroySession() error = %v, want %v", err, tt.wantErr)
+			}
+
+			var expired bool
+			_ = cs.View(func(state cookiesession.State) error {
+				expired = state.Session.Expired
+
+				return nil
+			})
+			if expired != tt.wantExpired {
+				t.Errorf("session of the request expired = %v, want %v", expired, tt.wantExpired)
+			}
+			if denylist.sessions[destroyID] != tt.wantDenied {
+				t.Errorf("session denied = %v, want %v", denylist.sessions[destroyID], tt.wantDenied)
+			}
+		})
+	}
+}
+
+func TestSessionStorageDriver_SessionData(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		data    map[string]json.RawMessage
+		update  *dbtype.SessionDataUpdate
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:   "set on a session without data",
+			update: &dbtype.SessionDataUpdate{Set: map[string]json.RawMessage{"theme": json.RawMessage(`"dark"`)}},
+			want:   map[string]string{"theme": `"dark"`},
+		},
+		{
+			name: "set and delete",
+			data: map[string]json.RawMessage{"theme": json.RawMessage(`"dark"`), "cart": json.RawMessage(`[1]`)},
+			update: &dbtype.SessionDataUpdate{
+				Set:     map[string]json.RawMessage{"theme": json.RawMessage(`"light"`)},
+				Deleted: []string{"cart"},
+			},
+			want: map[string]string{"theme": `"light"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			session := newTestSession()
+			ctx, _ := newCookieSessionCtx(t, session, tt.data)
+			s := NewSessionStorageDriver(nil)
+
+			if err := s.UpdateSessionData(ctx, session.ID, tt.update); err != nil {
+				t.Fatalf("SessionStorageDriver.UpdateSessionData() error = %v", err)
+			}
+
+			got, err := s.SessionData(ctx, session.ID)
+			if err != nil {
+				t.Fatalf("SessionStorageDriver.SessionData() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("SessionStorageDriver.SessionData() = %s, want %v", got, tt.want)
+			}
+			for name, want := range tt.want {
+				if string(got[name]) != want {
+					t.Errorf("SessionStorageDriver.SessionData()[%q] = %s, want %s", name, got[name], want)
+				}
+			}
+
+			if got, err := s.SessionData(ctx, ccc.Must(ccc.NewUUID())); err != nil || len(got) != 0 {
+				t.Errorf("SessionStorageDriver.SessionData() of another session = %s, %v, want no data", got, err)
+			}
+		})
+	}
+}
+
+func TestSessionStorageDriver_UserSessions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		expired bool
+		query   dbtype.UserSessions
+		want    int
+	}{
+		{
+			name:  "active session of the user",
+			query: dbtype.UserSessions{Username: "test_user", Active: true, Limit: 10},
+			want:  1,
+		},
+		{
+			name:  "other user",
+			query: dbtype.UserSessions{Username: "other_user", Active: true, Expired: true, Limit: 10},
+		},
+		{
+			name:    "expired session not listed as active",
+			expired: true,
+			query:   dbtype.UserSessions{Username: "test_user", Active: true, Limit: 10},
+		},
+		{
+			name:    "expired session listed as expired",
+			expired: true,
+			query:   dbtype.UserSessions{Username: "test_user", Expired: true, Limit: 10},
+			want:    1,
+		},
+		{
+			name:  "no limit",
+			query: dbtype.UserSessions{Username: "test_user", Active: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			session := newTestSession()
+			session.Expired = tt.expired
+			ctx, _ := newCookieSessionCtx(t, session, nil)
+
+			got, err := NewSessionStorageDriver(nil).UserSessions(ctx, &tt.query)
+			if err != nil {
+				t.Fatalf("SessionStorageDriver.UserSessions() error = %v", err)
+			}
+			if len(got) != tt.want {
+				t.Errorf("SessionStorageDriver.UserSessions() = %d sessions, want %d", len(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestSessionStorageDriver_InsertSession(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		insertSession *dbtype.InsertSession
+		wantErr       bool
+	}{
+		{
+			name:          "replaces the session of the request",
+			insertSession: &dbtype.InsertSession{Username: "new_user", CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		},
+		{
+			name: "impersonated session is rejected",
+			insertSession: &dbtype.InsertSession{
+				Username:              "new_user",
+				CreatedAt:             time.Now(),
+				UpdatedAt:             time.Now(),
+				ImpersonatorUsername:  "test_user",
+				ImpersonatorSessionID: ccc.Must(ccc.NewUUID()).String(),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			session := newTestSession()
+			ctx, _ := newCookieSessionCtx(t, session, map[string]json.RawMessage{"theme": json.RawMessage(`"dark"`)})
+			s := NewSessionStorageDriver(nil)
+
+			id, err := s.InsertSession(ctx, tt.insertSession)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SessionStorageDriver.InsertSession() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				// The session of the request is kept
+				if got, err := s.Session(ctx, session.ID); err != nil || got.Username != "test_user" {
+					t.Errorf("SessionStorageDriver.Session() = %+v, %v, want the session of test_user", got, err)
+				}
+
+				return
+			}
+
+			if got, err := s.Session(ctx, id); err != nil || got.Username != "new_user" {
+				t.Errorf("SessionStorageDriver.Session() = %+v, %v, want the session of new_user", got, err)
+			}
+			if got, err := s.SessionData(ctx, id); err != nil || len(got) != 0 {
+				t.Errorf("SessionStorageDriver.SessionData() = %s, %v, want no data", got, err)
+			}
+		})
+	}
+}
+
+func TestSessionStorageDriver_noCookieSession(t *testing.T) {
+	t.Parallel()
+
+	s := NewSessionStorageDriver(&testDenylist{sessions: make(map[ccc.UUID]bool)})
+	ctx := context.Background()
+	id := ccc.Must(ccc.NewUUID())
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{
+			name: "Session",
+			call: func() error { _, err := s.Session(ctx, id); return err },
+		},
+		{
+			name: "UpdateSessionActivity",
+			call: func() error { return s.UpdateSessionActivity(ctx, id) },
+		},
+		{
+			name: "UpdateSessionAuthentication",
+			call: func() error { return s.UpdateSessionAuthentication(ctx, id) },
+		},
+		{
+			name: "InsertSession",
+			call: func() error { _, err := s.InsertSession(ctx, &dbtype.InsertSession{Username: "test_user"}); return err },
+		},
+		{
+			name: "DestroySession",
+			call: func() error { return s.DestroySession(ctx, id) },
+		},
+		{
+			name: "RotateSession",
+			call: func() error { _, err := s.RotateSession(ctx, id); return err },
+		},
+		{
+			name: "UserSessions",
+			call: func() error {
+				_, err := s.UserSessions(ctx, &dbtype.UserSessions{Username: "test_user", Active: true, Limit: 1})
+				return err
+			},
+		},
+		{
+			name: "SessionData",
+			call: func() error { _, err := s.SessionData(ctx, id); return err },
+		},
+		{
+			name: "UpdateSessionData",
+			call: func() error { return s.UpdateSessionData(ctx, id, &dbtype.SessionDataUpdate{}) },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if err := tt.call(); !errors.Is(err, errNoCookieSession) {
+				t.Errorf("SessionStorageDriver.%s() error = %v, want %v", tt.name, err, errNoCookieSession)
+			}
+		})
+	}
+}
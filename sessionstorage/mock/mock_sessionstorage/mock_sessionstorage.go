@@ -11,7 +11,9 @@ package mock_sessionstorage
 
 import (
 	context "context"
+	json "encoding/json"
 	reflect "reflect"
+	time "time"
 
 	ccc "github.com/cccteam/ccc"
 	securehash "github.com/cccteam/ccc/securehash"
@@ -58,6 +60,21 @@ func (mr *MockBaseStoreMockRecorder) DestroySession(ctx, sessionID any) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DestroySession", reflect.TypeOf((*MockBaseStore)(nil).DestroySession), ctx, sessionID)
 }
 
+// RotateSession mocks base method.
+func (m *MockBaseStore) RotateSession(ctx context.Context, sessionID ccc.UUID) (ccc.UUID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RotateSession", ctx, sessionID)
+	ret0, _ := ret[0].(ccc.UUID)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RotateSession indicates an expected call of RotateSession.
+func (mr *MockBaseStoreMockRecorder) RotateSession(ctx, sessionID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateSession", reflect.TypeOf((*MockBaseStore)(nil).RotateSession), ctx, sessionID)
+}
+
 // Session mocks base method.
 func (m *MockBaseStore) Session(ctx context.Context, sessionID ccc.UUID) (*sessioninfo.SessionInfo, error) {
 	m.ctrl.T.Helper()
@@ -73,6 +90,33 @@ func (mr *MockBaseStoreMockRecorder) Session(ctx, sessionID any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Session", reflect.TypeOf((*MockBaseStore)(nil).Session), ctx, sessionID)
 }
 
+// SessionData mocks base method.
+func (m *MockBaseStore) SessionData(ctx context.Context, sessionID ccc.UUID) (map[string]json.RawMessage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SessionData", ctx, sessionID)
+	ret0, _ := ret[0].(map[string]json.RawMessage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SessionData indicates an expected call of SessionData.
+func (mr *MockBaseStoreMockRecorder) SessionData(ctx, sessionID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SessionData", reflect.TypeOf((*MockBaseStore)(nil).SessionData), ctx, sessionID)
+}
+
+// SetSessionLimit mocks base method.
+func (m *MockBaseStore) SetSessionLimit(limit dbtype.SessionLimit) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetSessionLimit", limit)
+}
+
+// SetSessionLimit indicates an expected call of SetSessionLimit.
+func (mr *MockBaseStoreMockRecorder) SetSessionLimit(limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetSessionLimit", reflect.TypeOf((*MockBaseStore)(nil).SetSessionLimit), limit)
+}
+
 // SetSessionTableName mocks base method.
 func (m *MockBaseStore) SetSessionTableName(name string) {
 	m.ctrl.T.Helper()
@@ -111,6 +155,49 @@ func (mr *MockBaseStoreMockRecorder) UpdateSessionActivity(ctx, sessionID any) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSessionActivity", reflect.TypeOf((*MockBaseStore)(nil).UpdateSessionActivity), ctx, sessionID)
 }
 
+// UpdateSessionAuthentication mocks base method.
+func (m *MockBaseStore) UpdateSessionAuthentication(ctx context.Context, sessionID ccc.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateSessionAuthentication", ctx, sessionID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateSessionAuthentication indicates an expected call of UpdateSessionAuthentication.
+func (mr *MockBaseStoreMockRecorder) UpdateSessionAuthentication(ctx, sessionID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSessionAuthentication", reflect.TypeOf((*MockBaseStore)(nil).UpdateSessionAuthentication), ctx, sessionID)
+}
+
+// UpdateSessionData mocks base method.
+func (m *MockBaseStore) UpdateSessionData(ctx context.Context, sessionID ccc.UUID, set map[string]json.RawMessage, deleted []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateSessionData", ctx, sessionID, set, deleted)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateSessionData indicates an expected call of UpdateSessionData.
+func (mr *MockBaseStoreMockRecorder) UpdateSessionData(ctx, sessionID, set, deleted any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSessionData", reflect.TypeOf((*MockBaseStore)(nil).UpdateSessionData), ctx, sessionID, set, deleted)
+}
+
+// UserSessions mocks base method.
+func (m *MockBaseStore) UserSessions(ctx context.Context, username string, filter dbtype.SessionFilter, page dbtype.Page) (*dbtype.SessionPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UserSessions", ctx, username, filter, page)
+	ret0, _ := ret[0].(*dbtype.SessionPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UserSessions indicates an expected call of UserSessions.
+func (mr *MockBaseStoreMockRecorder) UserSessions(ctx, username, filter, page any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UserSessions", reflect.TypeOf((*MockBaseStore)(nil).UserSessions), ctx, username, filter, page)
+}
+
 // MockPreauthStore is a mock of PreauthStore interface.
 type MockPreauthStore struct {
 	ctrl     *gomock.Controller
@@ -163,6 +250,21 @@ func (mr *MockPreauthStoreMockRecorder) DestroySession(ctx, sessionID any) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DestroySession", reflect.TypeOf((*MockPreauthStore)(nil).DestroySession), ctx, sessionID)
 }
 
+// NewPersistentSession mocks base method.
+func (m *MockPreauthStore) NewPersistentSession(ctx context.Context, username string, idleTimeout time.Duration) (ccc.UUID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NewPersistentSession", ctx, username, idleTimeout)
+	ret0, _ := ret[0].(ccc.UUID)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// NewPersistentSession indicates an expected call of NewPersistentSession.
+func (mr *MockPreauthStoreMockRecorder) NewPersistentSession(ctx, username, idleTimeout any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewPersistentSession", reflect.TypeOf((*MockPreauthStore)(nil).NewPersistentSession), ctx, username, idleTimeout)
+}
+
 // NewSession mocks base method.
 func (m *MockPreauthStore) NewSession(ctx context.Context, username string) (ccc.UUID, error) {
 	m.ctrl.T.Helper()
@@ -178,6 +280,21 @@ func (mr *MockPreauthStoreMockRecorder) NewSession(ctx, username any) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewSession", reflect.TypeOf((*MockPreauthStore)(nil).NewSession), ctx, username)
 }
 
+// RotateSession mocks base method.
+func (m *MockPreauthStore) RotateSession(ctx context.Context, sessionID ccc.UUID) (ccc.UUID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RotateSession", ctx, sessionID)
+	ret0, _ := ret[0].(ccc.UUID)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RotateSession indicates an expected call of RotateSession.
+func (mr *MockPreauthStoreMockRecorder) RotateSession(ctx, sessionID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateSession", reflect.TypeOf((*MockPreauthStore)(nil).RotateSession), ctx, sessionID)
+}
+
 // Session mocks base method.
 func (m *MockPreauthStore) Session(ctx context.Context, sessionID ccc.UUID) (*sessioninfo.SessionInfo, error) {
 	m.ctrl.T.Helper()
@@ -193,6 +310,33 @@ func (mr *MockPreauthStoreMockRecorder) Session(ctx, sessionID any) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Session", reflect.TypeOf((*MockPreauthStore)(nil).Session), ctx, sessionID)
 }
 
+// SessionData mocks base method.
+func (m *MockPreauthStore) SessionData(ctx context.Context, sessionID ccc.UUID) (map[string]json.RawMessage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SessionData", ctx, sessionID)
+	ret0, _ := ret[0].(map[string]json.RawMessage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SessionData indicates an expected call of SessionData.
+func (mr *MockPreauthStoreMockRecorder) SessionData(ctx, sessionID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SessionData", reflect.TypeOf((*MockPreauthStore)(nil).SessionData), ctx, sessionID)
+}
+
+// SetSessionLimit mocks base method.
+func (m *MockPreauthStore) SetSessionLimit(limit dbtype.SessionLimit) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetSessionLimit", limit)
+}
+
+// SetSessionLimit indicates an expected call of SetSessionLimit.
+func (mr *MockPreauthStoreMockRecorder) SetSessionLimit(limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetSessionLimit", reflect.TypeOf((*MockPreauthStore)(nil).SetSessionLimit), limit)
+}
+
 // SetSessionTableName mocks base method.
 func (m *MockPreauthStore) SetSessionTableName(name string) {
 	m.ctrl.T.Helper()
@@ -231,6 +375,49 @@ func (mr *MockPreauthStoreMockRecorder) UpdateSessionActivity(ctx, sessionID any
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSessionActivity", reflect.TypeOf((*MockPreauthStore)(nil).UpdateSessionActivity), ctx, sessionID)
 }
 
+// UpdateSessionAuthentication mocks base method.
+func (m *MockPreauthStore) UpdateSessionAuthentication(ctx context.Context, sessionID ccc.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateSessionAuthentication", ctx, sessionID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateSessionAuthentication indicates an expected call of UpdateSessionAuthentication.
+func (mr *MockPreauthStoreMockRecorder) UpdateSessionAuthentication(ctx, sessionID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSessionAuthentication", reflect.TypeOf((*MockPreauthStore)(nil).UpdateSessionAuthentication), ctx, sessionID)
+}
+
+// UpdateSessionData mocks base method.
+func (m *MockPreauthStore) UpdateSessionData(ctx context.Context, sessionID ccc.UUID, set map[string]json.RawMessage, deleted []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateSessionData", ctx, sessionID, set, deleted)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateSessionData indicates an expected call of UpdateSessionData.
+func (mr *MockPreauthStoreMockRecorder) UpdateSessionData(ctx, sessionID, set, deleted any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSessionData", reflect.TypeOf((*MockPreauthStore)(nil).UpdateSessionData), ctx, sessionID, set, deleted)
+}
+
+// UserSessions mocks base method.
+func (m *MockPreauthStore) UserSessions(ctx context.Context, username string, filter dbtype.SessionFilter, page dbtype.Page) (*dbtype.SessionPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UserSessions", ctx, username, filter, page)
+	ret0, _ := ret[0].(*dbtype.SessionPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UserSessions indicates an expected call of UserSessions.
+func (mr *MockPreauthStoreMockRecorder) UserSessions(ctx, username, filter, page any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UserSessions", reflect.TypeOf((*MockPreauthStore)(nil).UserSessions), ctx, username, filter, page)
+}
+
 // MockPasswordAuthStore is a mock of PasswordAuthStore interface.
 type MockPasswordAuthStore struct {
 	ctrl     *gomock.Controller
@@ -340,6 +527,21 @@ func (mr *MockPasswordAuthStoreMockRecorder) DestroySession(ctx, sessionID any)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DestroySession", reflect.TypeOf((*MockPasswordAuthStore)(nil).DestroySession), ctx, sessionID)
 }
 
+// NewPersistentSession mocks base method.
+func (m *MockPasswordAuthStore) NewPersistentSession(ctx context.Context, username string, idleTimeout time.Duration) (ccc.UUID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NewPersistentSession", ctx, username, idleTimeout)
+	ret0, _ := ret[0].(ccc.UUID)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// NewPersistentSession indicates an expected call of NewPersistentSession.
+func (mr *MockPasswordAuthStoreMockRecorder) NewPersistentSession(ctx, username, idleTimeout any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewPersistentSession", reflect.TypeOf((*MockPasswordAuthStore)(nil).NewPersistentSession), ctx, username, idleTimeout)
+}
+
 // NewSession mocks base method.
 func (m *MockPasswordAuthStore) NewSession(ctx context.Context, username string) (ccc.UUID, error) {
 	m.ctrl.T.Helper()
@@ -355,6 +557,21 @@ func (mr *MockPasswordAuthStoreMockRecorder) NewSession(ctx, username any) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewSession", reflect.TypeOf((*MockPasswordAuthStore)(nil).NewSession), ctx, username)
 }
 
+// RotateSession mocks base method.
+func (m *MockPasswordAuthStore) RotateSession(ctx context.Context, sessionID ccc.UUID) (ccc.UUID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RotateSession", ctx, sessionID)
+	ret0, _ := ret[0].(ccc.UUID)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RotateSession indicates an expected call of RotateSession.
+func (mr *MockPasswordAuthStoreMockRecorder) RotateSession(ctx, sessionID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateSession", reflect.TypeOf((*MockPasswordAuthStore)(nil).RotateSession), ctx, sessionID)
+}
+
 // Session mocks base method.
 func (m *MockPasswordAuthStore) Session(ctx context.Context, sessionID ccc.UUID) (*sessioninfo.SessionInfo, error) {
 	m.ctrl.T.Helper()
@@ -370,6 +587,33 @@ func (mr *MockPasswordAuthStoreMockRecorder) Session(ctx, sessionID any) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Session", reflect.TypeOf((*MockPasswordAuthStore)(nil).Session), ctx, sessionID)
 }
 
+// SessionData mocks base method.
+func (m *MockPasswordAuthStore) SessionData(ctx context.Context, sessionID ccc.UUID) (map[string]json.RawMessage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SessionData", ctx, sessionID)
+	ret0, _ := ret[0].(map[string]json.RawMessage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SessionData indicates an expected call of SessionData.
+func (mr *MockPasswordAuthStoreMockRecorder) SessionData(ctx, sessionID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SessionData", reflect.TypeOf((*MockPasswordAuthStore)(nil).SessionData), ctx, sessionID)
+}
+
+// SetSessionLimit mocks base method.
+func (m *MockPasswordAuthStore) SetSessionLimit(limit dbtype.SessionLimit) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetSessionLimit", limit)
+}
+
+// SetSessionLimit indicates an expected call of SetSessionLimit.
+func (mr *MockPasswordAuthStoreMockRecorder) SetSessionLimit(limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetSessionLimit", reflect.TypeOf((*MockPasswordAuthStore)(nil).SetSessionLimit), limit)
+}
+
 // SetSessionTableName mocks base method.
 func (m *MockPasswordAuthStore) SetSessionTableName(name string) {
 	m.ctrl.T.Helper()
@@ -436,6 +680,34 @@ func (mr *MockPasswordAuthStoreMockRecorder) UpdateSessionActivity(ctx, sessionI
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSessionActivity", reflect.TypeOf((*MockPasswordAuthStore)(nil).UpdateSessionActivity), ctx, sessionID)
 }
 
+// UpdateSessionAuthentication mocks base method.
+func (m *MockPasswordAuthStore) UpdateSessionAuthentication(ctx context.Context, sessionID ccc.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateSessionAuthentication", ctx, sessionID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateSessionAuthentication indicates an expected call of UpdateSessionAuthentication.
+func (mr *MockPasswordAuthStoreMockRecorder) UpdateSessionAuthentication(ctx, sessionID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSessionAuthentication", reflect.TypeOf((*MockPasswordAuthStore)(nil).UpdateSessionAuthentication), ctx, sessionID)
+}
+
+// UpdateSessionData mocks base method.
+func (m *MockPasswordAuthStore) UpdateSessionData(ctx context.Context, sessionID ccc.UUID, set map[string]json.RawMessage, deleted []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateSessionData", ctx, sessionID, set, deleted)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateSessionData indicates an expected call of UpdateSessionData.
+func (mr *MockPasswordAuthStoreMockRecorder) UpdateSessionData(ctx, sessionID, set, deleted any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSessionData", reflect.TypeOf((*MockPasswordAuthStore)(nil).UpdateSessionData), ctx, sessionID, set, deleted)
+}
+
 // User mocks base method.
 func (m *MockPasswordAuthStore) User(ctx context.Context, id ccc.UUID) (*dbtype.SessionUser, error) {
 	m.ctrl.T.Helper()
@@ -466,6 +738,21 @@ func (mr *MockPasswordAuthStoreMockRecorder) UserByUserName(ctx, username any) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UserByUserName", reflect.TypeOf((*MockPasswordAuthStore)(nil).UserByUserName), ctx, username)
 }
 
+// UserSessions mocks base method.
+func (m *MockPasswordAuthStore) UserSessions(ctx context.Context, username string, filter dbtype.SessionFilter, page dbtype.Page) (*dbtype.SessionPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UserSessions", ctx, username, filter, page)
+	ret0, _ := ret[0].(*dbtype.SessionPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UserSessions indicates an expected call of UserSessions.
+func (mr *MockPasswordAuthStoreMockRecorder) UserSessions(ctx, username, filter, page any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UserSessions", reflect.TypeOf((*MockPasswordAuthStore)(nil).UserSessions), ctx, username, filter, page)
+}
+
 // MockOIDCStore is a mock of OIDCStore interface.
 type MockOIDCStore struct {
 	ctrl     *gomock.Controller
@@ -533,6 +820,21 @@ func (mr *MockOIDCStoreMockRecorder) NewSession(ctx, username, oidcSID any) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewSession", reflect.TypeOf((*MockOIDCStore)(nil).NewSession), ctx, username, oidcSID)
 }
 
+// RotateSession mocks base method.
+func (m *MockOIDCStore) RotateSession(ctx context.Context, sessionID ccc.UUID) (ccc.UUID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RotateSession", ctx, sessionID)
+	ret0, _ := ret[0].(ccc.UUID)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RotateSession indicates an expected call of RotateSession.
+func (mr *MockOIDCStoreMockRecorder) RotateSession(ctx, sessionID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateSession", reflect.TypeOf((*MockOIDCStore)(nil).RotateSession), ctx, sessionID)
+}
+
 // Session mocks base method.
 func (m *MockOIDCStore) Session(ctx context.Context, sessionID ccc.UUID) (*sessioninfo.SessionInfo, error) {
 	m.ctrl.T.Helper()
@@ -548,6 +850,33 @@ func (mr *MockOIDCStoreMockRecorder) Session(ctx, sessionID any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Session", reflect.TypeOf((*MockOIDCStore)(nil).Session), ctx, sessionID)
 }
 
+// SessionData mocks base method.
+func (m *MockOIDCStore) SessionData(ctx context.Context, sessionID ccc.UUID) (map[string]json.RawMessage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SessionData", ctx, sessionID)
+	ret0, _ := ret[0].(map[string]json.RawMessage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SessionData indicates an expected call of SessionData.
+func (mr *MockOIDCStoreMockRecorder) SessionData(ctx, sessionID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SessionData", reflect.TypeOf((*MockOIDCStore)(nil).SessionData), ctx, sessionID)
+}
+
+// SetSessionLimit mocks base method.
+func (m *MockOIDCStore) SetSessionLimit(limit dbtype.SessionLimit) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetSessionLimit", limit)
+}
+
+// SetSessionLimit indicates an expected call of SetSessionLimit.
+func (mr *MockOIDCStoreMockRecorder) SetSessionLimit(limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetSessionLimit", reflect.TypeOf((*MockOIDCStore)(nil).SetSessionLimit), limit)
+}
+
 // SetSessionTableName mocks base method.
 func (m *MockOIDCStore) SetSessionTableName(name string) {
 	m.ctrl.T.Helper()
@@ -586,6 +915,64 @@ func (mr *MockOIDCStoreMockRecorder) UpdateSessionActivity(ctx, sessionID any) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSessionActivity", reflect.TypeOf((*MockOIDCStore)(nil).UpdateSessionActivity), ctx, sessionID)
 }
 
+// UpdateSessionAuthentication mocks base method.
+func (m *MockOIDCStore) UpdateSessionAuthentication(ctx context.Context, sessionID ccc.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateSessionAuthentication", ctx, sessionID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateSessionAuthentication indicates an expected call of UpdateSessionAuthentication.
+func (mr *MockOIDCStoreMockRecorder) UpdateSessionAuthentication(ctx, sessionID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSessionAuthentication", reflect.TypeOf((*MockOIDCStore)(nil).UpdateSessionAuthentication), ctx, sessionID)
+}
+
+// UpdateSessionData mocks base method.
+func (m *MockOIDCStore) UpdateSessionData(ctx context.Context, sessionID ccc.UUID, set map[string]json.RawMessage, deleted []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateSessionData", ctx, sessionID, set, deleted)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateSessionData indicates an expected call of UpdateSessionData.
+func (mr *MockOIDCStoreMockRecorder) UpdateSessionData(ctx, sessionID, set, deleted any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSessionData", reflect.TypeOf((*MockOIDCStore)(nil).UpdateSessionData), ctx, sessionID, set, deleted)
+}
+
+// UserSessions mocks base method.
+func (m *MockOIDCStore) UserSessions(ctx context.Context, username string, filter dbtype.SessionFilter, page dbtype.Page) (*dbtype.SessionPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UserSessions", ctx, username, filter, page)
+	ret0, _ := ret[0].(*dbtype.SessionPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UserSessions indicates an expected call of UserSessions.
+func (mr *MockOIDCStoreMockRecorder) UserSessions(ctx, username, filter, page any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UserSessions", reflect.TypeOf((*MockOIDCStore)(nil).UserSessions), ctx, username, filter, page)
+}
+
+// UsernameOIDC mocks base method.
+func (m *MockOIDCStore) UsernameOIDC(ctx context.Context, oidcSID string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UsernameOIDC", ctx, oidcSID)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UsernameOIDC indicates an expected call of UsernameOIDC.
+func (mr *MockOIDCStoreMockRecorder) UsernameOIDC(ctx, oidcSID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UsernameOIDC", reflect.TypeOf((*MockOIDCStore)(nil).UsernameOIDC), ctx, oidcSID)
+}
+
 // Mockdb is a mock of db interface.
 type Mockdb struct {
 	ctrl     *gomock.Controller
@@ -739,6 +1126,36 @@ func (mr *MockdbMockRecorder) InsertSessionOIDC(ctx, session any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertSessionOIDC", reflect.TypeOf((*Mockdb)(nil).InsertSessionOIDC), ctx, session)
 }
 
+// PurgeSessions mocks base method.
+func (m *Mockdb) PurgeSessions(ctx context.Context, purge *dbtype.PurgeSessions) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeSessions", ctx, purge)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeSessions indicates an expected call of PurgeSessions.
+func (mr *MockdbMockRecorder) PurgeSessions(ctx, purge any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeSessions", reflect.TypeOf((*Mockdb)(nil).PurgeSessions), ctx, purge)
+}
+
+// RotateSession mocks base method.
+func (m *Mockdb) RotateSession(ctx context.Context, sessionID ccc.UUID) (ccc.UUID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RotateSession", ctx, sessionID)
+	ret0, _ := ret[0].(ccc.UUID)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RotateSession indicates an expected call of RotateSession.
+func (mr *MockdbMockRecorder) RotateSession(ctx, sessionID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateSession", reflect.TypeOf((*Mockdb)(nil).RotateSession), ctx, sessionID)
+}
+
 // Session mocks base method.
 func (m *Mockdb) Session(ctx context.Context, sessionID ccc.UUID) (*dbtype.Session, error) {
 	m.ctrl.T.Helper()
@@ -754,6 +1171,21 @@ func (mr *MockdbMockRecorder) Session(ctx, sessionID any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Session", reflect.TypeOf((*Mockdb)(nil).Session), ctx, sessionID)
 }
 
+// SessionData mocks base method.
+func (m *Mockdb) SessionData(ctx context.Context, sessionID ccc.UUID) (map[string]json.RawMessage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SessionData", ctx, sessionID)
+	ret0, _ := ret[0].(map[string]json.RawMessage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SessionData indicates an expected call of SessionData.
+func (mr *MockdbMockRecorder) SessionData(ctx, sessionID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SessionData", reflect.TypeOf((*Mockdb)(nil).SessionData), ctx, sessionID)
+}
+
 // SetSessionTableName mocks base method.
 func (m *Mockdb) SetSessionTableName(name string) {
 	m.ctrl.T.Helper()
@@ -820,6 +1252,48 @@ func (mr *MockdbMockRecorder) UpdateSessionActivity(ctx, sessionID any) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSessionActivity", reflect.TypeOf((*Mockdb)(nil).UpdateSessionActivity), ctx, sessionID)
 }
 
+// UpdateSessionAuthentication mocks base method.
+func (m *Mockdb) UpdateSessionAuthentication(ctx context.Context, sessionID ccc.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateSessionAuthentication", ctx, sessionID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateSessionAuthentication indicates an expected call of UpdateSessionAuthentication.
+func (mr *MockdbMockRecorder) UpdateSessionAuthentication(ctx, sessionID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSessionAuthentication", reflect.TypeOf((*Mockdb)(nil).UpdateSessionAuthentication), ctx, sessionID)
+}
+
+// UpdateSessionData mocks base method.
+func (m *Mockdb) UpdateSessionData(ctx context.Context, sessionID ccc.UUID, update *dbtype.SessionDataUpdate) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateSessionData", ctx, sessionID, update)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateSessionData indicates an expected call of UpdateSessionData.
+func (mr *MockdbMockRecorder) UpdateSessionData(ctx, sessionID, update any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSessionData", reflect.TypeOf((*Mockdb)(nil).UpdateSessionData), ctx, sessionID, update)
+}
+
+// UpdateSessionsActivity mocks base method.
+func (m *Mockdb) UpdateSessionsActivity(ctx context.Context, activity []*dbtype.SessionActivity) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateSessionsActivity", ctx, activity)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateSessionsActivity indicates an expected call of UpdateSessionsActivity.
+func (mr *MockdbMockRecorder) UpdateSessionsActivity(ctx, activity any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSessionsActivity", reflect.TypeOf((*Mockdb)(nil).UpdateSessionsActivity), ctx, activity)
+}
+
 // User mocks base method.
 func (m *Mockdb) User(ctx context.Context, id ccc.UUID) (*dbtype.SessionUser, error) {
 	m.ctrl.T.Helper()
@@ -849,3 +1323,33 @@ func (mr *MockdbMockRecorder) UserByUserName(ctx, username any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UserByUserName", reflect.TypeOf((*Mockdb)(nil).UserByUserName), ctx, username)
 }
+
+// UserSessions mocks base method.
+func (m *Mockdb) UserSessions(ctx context.Context, query *dbtype.UserSessions) ([]*dbtype.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UserSessions", ctx, query)
+	ret0, _ := ret[0].([]*dbtype.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UserSessions indicates an expected call of UserSessions.
+func (mr *MockdbMockRecorder) UserSessions(ctx, query any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UserSessions", reflect.TypeOf((*Mockdb)(nil).UserSessions), ctx, query)
+}
+
+// UsernameOIDC mocks base method.
+func (m *Mockdb) UsernameOIDC(ctx context.Context, oidcSID string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UsernameOIDC", ctx, oidcSID)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UsernameOIDC indicates an expected call of UsernameOIDC.
+func (mr *MockdbMockRecorder) UsernameOIDC(ctx, oidcSID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UsernameOIDC", reflect.TypeOf((*Mockdb)(nil).UsernameOIDC), ctx, oidcSID)
+}
@@ -0,0 +1,31 @@
+// Package mysql provides the MySQL / MariaDB session storage. It is kept apart from the sessionstorage package
+// so that only services that store sessions in MySQL link, and register, the github.com/go-sql-driver/mysql driver.
+// The session tables are created with sessionstorage.MigrateMySQL.
+package mysql
+
+import (
+	"github.com/cccteam/session/sessionstorage"
+	"github.com/cccteam/session/sessionstorage/internal/mysql"
+)
+
+// Queryer is an interface that wraps the basic BeginTx, QueryContext, QueryRowContext, and ExecContext methods.
+// It is satisfied by *sql.DB.
+type Queryer = mysql.Queryer
+
+// NewPreauth is the function that you use to create the session manager that handles the session creation and updates.
+// The connection must be opened with the github.com/go-sql-driver/mysql driver and a DSN that sets parseTime=true.
+func NewPreauth(db Queryer) *sessionstorage.Preauth {
+	return sessionstorage.NewPreauthWithDriver(mysql.NewSessionStorageDriver(db))
+}
+
+// NewPasswordAuth creates a new MySQL PasswordAuth instance.
+// The connection must be opened with the github.com/go-sql-driver/mysql driver and a DSN that sets parseTime=true.
+func NewPasswordAuth(db Queryer) *sessionstorage.PasswordAuth {
+	return sessionstorage.NewPasswordAuthWithDriver(mysql.NewSessionStorageDriver(db))
+}
+
+// NewOIDC creates a new MySQL OIDC instance.
+// The connection must be opened with the github.com/go-sql-driver/mysql driver and a DSN that sets parseTime=true.
+func NewOIDC(db Queryer) *sessionstorage.OIDC {
+	return sessionstorage.NewOIDCWithDriver(mysql.NewSessionStorageDriver(db))
+}
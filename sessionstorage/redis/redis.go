@@ -0,0 +1,24 @@
+// Package redis provides the session storage for Redis and servers that speak the Redis protocol. It is kept
+// apart from the sessionstorage package so that only services that store sessions in Redis link its client.
+// Username/Password sessions are not supported, as their users are kept in a database.
+package redis
+
+import (
+	"time"
+
+	"github.com/cccteam/session/sessionstorage"
+	"github.com/cccteam/session/sessionstorage/internal/redis"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// NewPreauth is the function that you use to create the session manager that handles the session creation and updates.
+// Sessions are removed by key expiry sessionTimeout after their last activity, so it should match the handler's session timeout.
+func NewPreauth(client goredis.UniversalClient, sessionTimeout time.Duration) *sessionstorage.Preauth {
+	return sessionstorage.NewPreauthWithDriver(redis.NewSessionStorageDriver(client, sessionTimeout))
+}
+
+// NewOIDC creates a new Redis OIDC instance.
+// Sessions are removed by key expiry sessionTimeout after their last activity, so it should match the handler's session timeout.
+func NewOIDC(client goredis.UniversalClient, sessionTimeout time.Duration) *sessionstorage.OIDC {
+	return sessionstorage.NewOIDCWithDriver(redis.NewSessionStorageDriver(client, sessionTimeout))
+}
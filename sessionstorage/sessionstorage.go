@@ -13,7 +13,8 @@ import (
 
 // sessionStorage is what you use to create / update sessions inside of the handlers or as a standalone if you don't want the handlers
 type sessionStorage struct {
-	db db
+	db    db
+	limit dbtype.SessionLimit
 }
 
 // SetSessionTableName sets the name of the session table.
@@ -31,13 +32,26 @@ func (s *sessionStorage) NewSession(ctx context.Context, username string) (ccc.U
 	ctx, span := tracer.Start(ctx)
 	defer span.End()
 
-	session := &dbtype.InsertSession{
-		Username:  username,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+	session := s.newInsertSession(ctx, username)
+
+	id, err := s.db.InsertSession(ctx, &session)
+	if err != nil {
+		return ccc.NilUUID, errors.Wrap(err, "db.InsertSession()")
 	}
 
-	id, err := s.db.InsertSession(ctx, session)
+	return id, nil
+}
+
+// NewPersistentSession inserts a session that stays valid for idleTimeout without activity,
+// instead of the configured session timeout
+func (s *sessionStorage) NewPersistentSession(ctx context.Context, username string, idleTimeout time.Duration) (ccc.UUID, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	session := s.newInsertSession(ctx, username)
+	session.IdleTimeoutSeconds = int64(idleTimeout / time.Second)
+
+	id, err := s.db.InsertSession(ctx, &session)
 	if err != nil {
 		return ccc.NilUUID, errors.Wrap(err, "db.InsertSession()")
 	}
@@ -45,6 +59,33 @@ func (s *sessionStorage) NewSession(ctx context.Context, username string) (ccc.U
 	return id, nil
 }
 
+// newInsertSession returns a new session for username, recording the client and impersonator stored
+// in ctx. It is subject to the session limit unless it is impersonated, so that impersonating a user
+// does not evict or reject their sessions. An impersonated session has no authentication time, as
+// the user did not authenticate in it.
+func (s *sessionStorage) newInsertSession(ctx context.Context, username string) dbtype.InsertSession {
+	client := sessioninfo.ClientFromCtx(ctx)
+	now := time.Now()
+
+	session := dbtype.InsertSession{
+		Username:   username,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		ClientIP:   client.IP,
+		UserAgent:  client.UserAgent,
+		AuthMethod: string(client.AuthMethod),
+	}
+	if impersonator, ok := sessioninfo.ImpersonatorFromCtx(ctx); ok {
+		session.ImpersonatorUsername = impersonator.Username
+		session.ImpersonatorSessionID = impersonator.SessionID.String()
+	} else {
+		session.LastAuthenticatedAt = now
+		session.Limit = s.limit.InsertLimit(now)
+	}
+
+	return session
+}
+
 // Session returns the session information from the database for given sessionID
 func (s *sessionStorage) Session(ctx context.Context, sessionID ccc.UUID) (*sessioninfo.SessionInfo, error) {
 	ctx, span := tracer.Start(ctx)
@@ -55,7 +96,7 @@ func (s *sessionStorage) Session(ctx context.Context, sessionID ccc.UUID) (*sess
 		return nil, errors.Wrap(err, "db.Session()")
 	}
 
-	return (*sessioninfo.SessionInfo)(si), nil
+	return si.SessionInfo(), nil
 }
 
 // UpdateSessionActivity updates the database with the current time for the session activity
@@ -70,6 +111,18 @@ func (s *sessionStorage) UpdateSessionActivity(ctx context.Context, sessionID cc
 	return nil
 }
 
+// UpdateSessionAuthentication records the current time as the last activity and last authentication of the session
+func (s *sessionStorage) UpdateSessionAuthentication(ctx context.Context, sessionID ccc.UUID) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	if err := s.db.UpdateSessionAuthentication(ctx, sessionID); err != nil {
+		return errors.Wrap(err, "db.UpdateSessionAuthentication()")
+	}
+
+	return nil
+}
+
 // DestroySession marks the session as expired
 func (s *sessionStorage) DestroySession(ctx context.Context, sessionID ccc.UUID) error {
 	ctx, span := tracer.Start(ctx)
@@ -82,7 +135,21 @@ func (s *sessionStorage) DestroySession(ctx context.Context, sessionID ccc.UUID)
 	return nil
 }
 
-// DestroyAllUserSessions destroys all sessions for a given user
+// RotateSession moves an active session to a new ID, keeping its data, and returns the new ID
+func (s *sessionStorage) RotateSession(ctx context.Context, sessionID ccc.UUID) (ccc.UUID, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	id, err := s.db.RotateSession(ctx, sessionID)
+	if err != nil {
+		return ccc.NilUUID, errors.Wrap(err, "db.RotateSession()")
+	}
+
+	return id, nil
+}
+
+// DestroyAllUserSessions destroys all sessions for a given user, including the sessions in which the user
+// impersonates another user
 func (s *sessionStorage) DestroyAllUserSessions(ctx context.Context, username string) error {
 	ctx, span := tracer.Start(ctx)
 	defer span.End()
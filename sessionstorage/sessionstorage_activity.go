@@ -0,0 +1,239 @@
+package sessionstorage
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cccteam/ccc"
+	"github.com/cccteam/ccc/tracer"
+	"github.com/cccteam/httpio"
+	"github.com/cccteam/logger"
+	"github.com/cccteam/session/internal/dbtype"
+	"github.com/go-playground/errors/v5"
+)
+
+const (
+	// DefaultActivityFlushInterval is how often an ActivityWriter writes buffered activity when
+	// ActivityWriterOptions.FlushInterval is not set.
+	DefaultActivityFlushInterval = 5 * time.Second
+	// DefaultActivityBatchSize is the number of sessions written per call when ActivityWriterOptions.BatchSize is not set.
+	DefaultActivityBatchSize = 500
+)
+
+var (
+	_ ActivityStore = (*Preauth)(nil)
+	_ ActivityStore = (*PasswordAuth)(nil)
+	_ ActivityStore = (*OIDC)(nil)
+	_ ActivityStore = (*CachedPreauth)(nil)
+	_ ActivityStore = (*CachedPasswordAuth)(nil)
+	_ ActivityStore = (*CachedOIDC)(nil)
+)
+
+// SessionActivity is the time of the last request made with a session.
+type SessionActivity = dbtype.SessionActivity
+
+// ActivityStore defines an interface for recording the activity of many sessions in one write.
+type ActivityStore interface {
+	// UpdateSessionsActivity sets the last activity time of each session, skipping sessions that no longer exist.
+	UpdateSessionsActivity(ctx context.Context, activity []*SessionActivity) error
+}
+
+// UpdateSessionsActivity sets the last activity time of each session in one write, skipping sessions that no longer exist.
+func (s *sessionStorage) UpdateSessionsActivity(ctx context.Context, activity []*SessionActivity) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	if err := s.db.UpdateSessionsActivity(ctx, activity); err != nil {
+		return errors.Wrap(err, "db.UpdateSessionsActivity()")
+	}
+
+	return nil
+}
+
+// updateSessionsActivity writes activity with a single call when store is an ActivityStore.
+// Other stores are updated one session at a time with the current time.
+func updateSessionsActivity(ctx context.Context, store BaseStore, activity []*SessionActivity) error {
+	if s, ok := store.(ActivityStore); ok {
+		if err := s.UpdateSessionsActivity(ctx, activity); err != nil {
+			return errors.Wrap(err, "sessionstorage.ActivityStore.UpdateSessionsActivity()")
+		}
+
+		return nil
+	}
+
+	for _, a := range activity {
+		if err := store.UpdateSessionActivity(ctx, a.ID); err != nil && !httpio.HasNotFound(err) {
+			return errors.Wrap(err, "sessionstorage.BaseStore.UpdateSessionActivity()")
+		}
+	}
+
+	return nil
+}
+
+// ActivityWriterOptions configures an ActivityWriter.
+type ActivityWriterOptions struct {
+	// FlushInterval is how often buffered activity is written to the store. Defaults to DefaultActivityFlushInterval.
+	FlushInterval time.Duration
+	// BatchSize is the maximum number of sessions written per call. Defaults to DefaultActivityBatchSize.
+	BatchSize int
+}
+
+// ActivityWriter buffers session activity in memory and writes it to a store in batches,
+// which keeps the write out of the request path and coalesces repeated activity of a session
+// into a single update.
+//
+// Activity that has not been written yet is lost if the process exits without calling Close,
+// so FlushInterval should be well below the session timeout.
+type ActivityWriter struct {
+	store     BaseStore
+	batchSize int
+
+	mu       sync.Mutex
+	pending  map[ccc.UUID]time.Time
+	inflight map[ccc.UUID]time.Time
+	closed   bool
+
+	flushMu   sync.Mutex
+	closeOnce sync.Once
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// NewActivityWriter creates an ActivityWriter for store and starts writing buffered activity
+// every FlushInterval. Stores that implement ActivityStore are written in batches, other stores
+// are updated one session at a time. Close must be called to stop the writer.
+func NewActivityWriter(store BaseStore, opts ActivityWriterOptions) *ActivityWriter {
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = DefaultActivityFlushInterval
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultActivityBatchSize
+	}
+
+	w := &ActivityWriter{
+		store:     store,
+		batchSize: opts.BatchSize,
+		pending:   make(map[ccc.UUID]time.Time),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go w.run(opts.FlushInterval)
+
+	return w
+}
+
+func (w *ActivityWriter) run(interval time.Duration) {
+	defer close(w.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			ctx := context.Background()
+			if err := w.Flush(ctx); err != nil {
+				logger.FromCtx(ctx).Errorf("failed to write session activity, retrying on the next interval: %v", err)
+			}
+		}
+	}
+}
+
+// Record buffers the current time as the last activity of the session. Once the writer
+// is closed, the activity is written to the store before Record returns.
+func (w *ActivityWriter) Record(ctx context.Context, sessionID ccc.UUID) error {
+	w.mu.Lock()
+	if !w.closed {
+		w.pending[sessionID] = time.Now()
+		w.mu.Unlock()
+
+		return nil
+	}
+	w.mu.Unlock()
+
+	if err := w.store.UpdateSessionActivity(ctx, sessionID); err != nil {
+		return errors.Wrap(err, "sessionstorage.BaseStore.UpdateSessionActivity()")
+	}
+
+	return nil
+}
+
+// LastActivity returns the activity recorded for the session that has not been written to the store yet.
+func (w *ActivityWriter) LastActivity(sessionID ccc.UUID) (time.Time, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if t, ok := w.pending[sessionID]; ok {
+		return t, true
+	}
+	t, ok := w.inflight[sessionID]
+
+	return t, ok
+}
+
+// Flush writes the buffered activity to the store. Activity that could not be written
+// stays buffered and is retried by the next flush.
+func (w *ActivityWriter) Flush(ctx context.Context) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	w.flushMu.Lock()
+	defer w.flushMu.Unlock()
+
+	w.mu.Lock()
+	pending := w.pending
+	w.pending = make(map[ccc.UUID]time.Time)
+	w.inflight = pending
+	w.mu.Unlock()
+
+	defer func() {
+		w.mu.Lock()
+		w.inflight = nil
+		w.mu.Unlock()
+	}()
+
+	activity := make([]*SessionActivity, 0, len(pending))
+	for id, t := range pending {
+		activity = append(activity, &SessionActivity{ID: id, UpdatedAt: t})
+	}
+
+	for start := 0; start < len(activity); start += w.batchSize {
+		if err := updateSessionsActivity(ctx, w.store, activity[start:min(start+w.batchSize, len(activity))]); err != nil {
+			w.requeue(activity[start:])
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+// requeue buffers activity again unless newer activity was recorded in the meantime.
+func (w *ActivityWriter) requeue(activity []*SessionActivity) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, a := range activity {
+		if t, ok := w.pending[a.ID]; !ok || t.Before(a.UpdatedAt) {
+			w.pending[a.ID] = a.UpdatedAt
+		}
+	}
+}
+
+// Close stops the periodic writes and flushes the buffered activity. Activity recorded
+// after Close is written to the store directly. Close is safe to call more than once.
+func (w *ActivityWriter) Close(ctx context.Context) error {
+	w.closeOnce.Do(func() {
+		w.mu.Lock()
+		w.closed = true
+		w.mu.Unlock()
+
+		close(w.stop)
+		<-w.done
+	})
+
+	return w.Flush(ctx)
+}
@@ -0,0 +1,232 @@
+package sessionstorage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cccteam/ccc"
+	"github.com/cccteam/httpio"
+	"github.com/cccteam/session/internal/dbtype"
+	"github.com/cccteam/session/sessionstorage/mock/mock_sessionstorage"
+	"github.com/go-playground/errors/v5"
+	gomock "go.uber.org/mock/gomock"
+)
+
+func Test_sessionStorage_UpdateSessionsActivity(t *testing.T) {
+	t.Parallel()
+
+	activity := []*SessionActivity{{ID: ccc.Must(ccc.NewUUID()), UpdatedAt: time.Now()}}
+
+	tests := []struct {
+		name    string
+		dbErr   error
+		wantErr bool
+	}{
+		{
+			name: "success",
+		},
+		{
+			name:    "db error",
+			dbErr:   errors.New("update failed"),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			mockDB := mock_sessionstorage.NewMockdb(ctrl)
+			mockDB.EXPECT().UpdateSessionsActivity(gomock.Any(), activity).Return(tt.dbErr).Times(1)
+
+			storage := &sessionStorage{db: mockDB}
+			if err := storage.UpdateSessionsActivity(context.Background(), activity); (err != nil) != tt.wantErr {
+				t.Errorf("UpdateSessionsActivity() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// newMemorySessions creates a memory store with n sessions and returns their ids.
+func newMemorySessions(t *testing.T, n int) (*Preauth, []ccc.UUID) {
+	t.Helper()
+
+	store := NewMemoryPreauth()
+	ids := make([]ccc.UUID, 0, n)
+	for range n {
+		id, err := store.NewSession(context.Background(), "alice")
+		if err != nil {
+			t.Fatalf("NewSession() error = %v", err)
+		}
+		ids = append(ids, id)
+	}
+
+	return store, ids
+}
+
+func TestActivityWriter_Flush(t *testing.T) {
+	t.Parallel()
+
+	store, ids := newMemorySessions(t, 3)
+	w := NewActivityWriter(store, ActivityWriterOptions{FlushInterval: time.Hour, BatchSize: 2})
+	t.Cleanup(func() { _ = w.Close(context.Background()) })
+
+	time.Sleep(time.Millisecond)
+	want := make(map[ccc.UUID]time.Time)
+	for _, id := range ids {
+		if err := w.Record(context.Background(), id); err != nil {
+			t.Fatalf("ActivityWriter.Record() error = %v", err)
+		}
+		recorded, ok := w.LastActivity(id)
+		if !ok {
+			t.Fatalf("ActivityWriter.LastActivity() ok = false, want true")
+		}
+		want[id] = recorded
+
+		if si, _ := store.Session(context.Background(), id); si.UpdatedAt.Equal(recorded) {
+			t.Errorf("ActivityWriter.Record() wrote to the store before Flush()")
+		}
+	}
+
+	if err := w.Flush(context.Background()); err != nil {
+		t.Fatalf("ActivityWriter.Flush() error = %v", err)
+	}
+
+	for id, recorded := range want {
+		si, err := store.Session(context.Background(), id)
+		if err != nil {
+			t.Fatalf("Session() error = %v", err)
+		}
+		if !si.UpdatedAt.Equal(recorded) {
+			t.Errorf("Session().UpdatedAt = %v, want %v", si.UpdatedAt, recorded)
+		}
+		if _, ok := w.LastActivity(id); ok {
+			t.Errorf("ActivityWriter.LastActivity() ok = true after Flush(), want false")
+		}
+	}
+}
+
+func TestActivityWriter_Flush_retriesFailedWrites(t *testing.T) {
+	t.Parallel()
+
+	id := ccc.Must(ccc.NewUUID())
+	ctrl := gomock.NewController(t)
+	store := mock_sessionstorage.NewMockPreauthStore(ctrl)
+
+	// The mock does not implement ActivityStore, so sessions are updated one at a time.
+	gomock.InOrder(
+		store.EXPECT().UpdateSessionActivity(gomock.Any(), id).Return(errors.New("update failed")),
+		store.EXPECT().UpdateSessionActivity(gomock.Any(), id).Return(httpio.NewNotFoundMessage("session not found")),
+	)
+
+	w := NewActivityWriter(store, ActivityWriterOptions{FlushInterval: time.Hour})
+	t.Cleanup(func() { _ = w.Close(context.Background()) })
+
+	if err := w.Record(context.Background(), id); err != nil {
+		t.Fatalf("ActivityWriter.Record() error = %v", err)
+	}
+
+	if err := w.Flush(context.Background()); err == nil {
+		t.Fatal("ActivityWriter.Flush() error = nil, want error")
+	}
+	if _, ok := w.LastActivity(id); !ok {
+		t.Error("ActivityWriter.LastActivity() ok = false after a failed Flush(), want true")
+	}
+
+	if err := w.Flush(context.Background()); err != nil {
+		t.Errorf("ActivityWriter.Flush() error = %v, want sessions that no longer exist to be skipped", err)
+	}
+	if _, ok := w.LastActivity(id); ok {
+		t.Error("ActivityWriter.LastActivity() ok = true after Flush(), want false")
+	}
+}
+
+func TestActivityWriter_Close(t *testing.T) {
+	t.Parallel()
+
+	store, ids := newMemorySessions(t, 2)
+	w := NewActivityWriter(store, ActivityWriterOptions{FlushInterval: time.Hour})
+
+	time.Sleep(time.Millisecond)
+	if err := w.Record(context.Background(), ids[0]); err != nil {
+		t.Fatalf("ActivityWriter.Record() error = %v", err)
+	}
+	recorded, _ := w.LastActivity(ids[0])
+
+	if err := w.Close(context.Background()); err != nil {
+		t.Fatalf("ActivityWriter.Close() error = %v", err)
+	}
+	if si, _ := store.Session(context.Background(), ids[0]); !si.UpdatedAt.Equal(recorded) {
+		t.Errorf("Session().UpdatedAt = %v after Close(), want %v", si.UpdatedAt, recorded)
+	}
+
+	// Activity recorded after Close is written directly.
+	before, _ := store.Session(context.Background(), ids[1])
+	time.Sleep(time.Millisecond)
+	if err := w.Record(context.Background(), ids[1]); err != nil {
+		t.Fatalf("ActivityWriter.Record() error = %v", err)
+	}
+	if _, ok := w.LastActivity(ids[1]); ok {
+		t.Error("ActivityWriter.LastActivity() ok = true after Close(), want false")
+	}
+	if si, _ := store.Session(context.Background(), ids[1]); !si.UpdatedAt.After(before.UpdatedAt) {
+		t.Errorf("Session().UpdatedAt = %v, want after %v", si.UpdatedAt, before.UpdatedAt)
+	}
+
+	if err := w.Close(context.Background()); err != nil {
+		t.Errorf("ActivityWriter.Close() second call error = %v", err)
+	}
+}
+
+func TestActivityWriter_flushesOnInterval(t *testing.T) {
+	t.Parallel()
+
+	store, ids := newMemorySessions(t, 1)
+	w := NewActivityWriter(store, ActivityWriterOptions{FlushInterval: 10 * time.Millisecond})
+	t.Cleanup(func() { _ = w.Close(context.Background()) })
+
+	if err := w.Record(context.Background(), ids[0]); err != nil {
+		t.Fatalf("ActivityWriter.Record() error = %v", err)
+	}
+	recorded, _ := w.LastActivity(ids[0])
+
+	deadline := time.After(5 * time.Second)
+	for {
+		if si, _ := store.Session(context.Background(), ids[0]); si.UpdatedAt.Equal(recorded) {
+			return
+		}
+
+		select {
+		case <-deadline:
+			t.Fatal("ActivityWriter did not flush within 5s")
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+}
+
+func TestCachedPreauth_UpdateSessionsActivity(t *testing.T) {
+	t.Parallel()
+
+	store, ids := newMemorySessions(t, 1)
+	cached := NewCachedPreauth(store, CacheOptions{})
+
+	if _, err := cached.Session(context.Background(), ids[0]); err != nil {
+		t.Fatalf("Session() error = %v", err)
+	}
+
+	updatedAt := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := cached.UpdateSessionsActivity(context.Background(), []*dbtype.SessionActivity{{ID: ids[0], UpdatedAt: updatedAt}}); err != nil {
+		t.Fatalf("UpdateSessionsActivity() error = %v", err)
+	}
+
+	for name, s := range map[string]BaseStore{"cache": cached, "store": store} {
+		si, err := s.Session(context.Background(), ids[0])
+		if err != nil {
+			t.Fatalf("Session() error = %v", err)
+		}
+		if !si.UpdatedAt.Equal(updatedAt) {
+			t.Errorf("%s Session().UpdatedAt = %v, want %v", name, si.UpdatedAt, updatedAt)
+		}
+	}
+}
@@ -0,0 +1,598 @@
+package sessionstorage
+
+import (
+	"context"
+	"time"
+
+	"github.com/cccteam/ccc"
+	"github.com/cccteam/ccc/securehash"
+	"github.com/cccteam/ccc/tracer"
+	"github.com/cccteam/session/internal/dbtype"
+	"github.com/cccteam/session/internal/lru"
+	"github.com/cccteam/session/sessioninfo"
+	"github.com/go-playground/errors/v5"
+)
+
+const (
+	// DefaultCacheSize is the number of sessions, and of users, held when CacheOptions.Size is not set.
+	DefaultCacheSize = 10000
+	// DefaultCacheTTL is how long an entry is served from the cache when CacheOptions.TTL is not set.
+	DefaultCacheTTL = 30 * time.Second
+)
+
+var (
+	_ PreauthStore      = (*CachedPreauth)(nil)
+	_ PasswordAuthStore = (*CachedPasswordAuth)(nil)
+	_ OIDCStore         = (*CachedOIDC)(nil)
+)
+
+// CacheOptions configures the caching store decorators.
+//
+// Invalidation only reaches the cache of the instance that made the change, so when a service
+// runs more than one instance, TTL is the longest time another instance can keep accepting a
+// destroyed session or a deactivated user.
+type CacheOptions struct {
+	// Size is the maximum number of entries held in each cache. Defaults to DefaultCacheSize.
+	Size int
+	// TTL is the maximum time an entry is served from the cache. Defaults to DefaultCacheTTL.
+	TTL time.Duration
+}
+
+// sessionCache caches the session lookups made by BaseStore.Session.
+type sessionCache struct {
+	store    BaseStore
+	sessions *lru.Cache[ccc.UUID, sessioninfo.SessionInfo]
+}
+
+func (o CacheOptions) withDefaults() CacheOptions {
+	if o.Size <= 0 {
+		o.Size = DefaultCacheSize
+	}
+	if o.TTL <= 0 {
+		o.TTL = DefaultCacheTTL
+	}
+
+	return o
+}
+
+func newSessionCache(store BaseStore, opts CacheOptions) *sessionCache {
+	opts = opts.withDefaults()
+
+	return &sessionCache{
+		store:    store,
+		sessions: lru.New[ccc.UUID, sessioninfo.SessionInfo](opts.Size, opts.TTL),
+	}
+}
+
+func (c *sessionCache) session(ctx context.Context, sessionID ccc.UUID) (*sessioninfo.SessionInfo, error) {
+	if si, ok := c.sessions.Get(sessionID); ok {
+		return &si, nil
+	}
+
+	si, err := c.store.Session(ctx, sessionID)
+	if err != nil {
+		return nil, errors.Wrap(err, "sessionstorage.BaseStore.Session()")
+	}
+	c.sessions.Add(sessionID, *si)
+
+	return si, nil
+}
+
+// updateSessionActivity records the new activity time on the cached session so
+// that idle timeout checks made against the cache see it.
+func (c *sessionCache) updateSessionActivity(ctx context.Context, sessionID ccc.UUID) error {
+	if err := c.store.UpdateSessionActivity(ctx, sessionID); err != nil {
+		c.sessions.Remove(sessionID)
+
+		return errors.Wrap(err, "sessionstorage.BaseStore.UpdateSessionActivity()")
+	}
+
+	now := time.Now()
+	c.sessions.Update(sessionID, func(si sessioninfo.SessionInfo) sessioninfo.SessionInfo {
+		si.UpdatedAt = now
+
+		return si
+	})
+
+	return nil
+}
+
+// updateSessionAuthentication records the new activity and authentication time on the cached session
+func (c *sessionCache) updateSessionAuthentication(ctx context.Context, sessionID ccc.UUID) error {
+	if err := c.store.UpdateSessionAuthentication(ctx, sessionID); err != nil {
+		c.sessions.Remove(sessionID)
+
+		return errors.Wrap(err, "sessionstorage.BaseStore.UpdateSessionAuthentication()")
+	}
+
+	now := time.Now()
+	c.sessions.Update(sessionID, func(si sessioninfo.SessionInfo) sessioninfo.SessionInfo {
+		si.UpdatedAt = now
+		si.LastAuthenticatedAt = now
+
+		return si
+	})
+
+	return nil
+}
+
+// updateSessionsActivity records the activity times on the cached sessions, never moving one backwards.
+func (c *sessionCache) updateSessionsActivity(ctx context.Context, activity []*SessionActivity) error {
+	if err := updateSessionsActivity(ctx, c.store, activity); err != nil {
+		for _, a := range activity {
+			c.sessions.Remove(a.ID)
+		}
+
+		return err
+	}
+
+	for _, a := range activity {
+		c.sessions.Update(a.ID, func(si sessioninfo.SessionInfo) sessioninfo.SessionInfo {
+			if a.UpdatedAt.After(si.UpdatedAt) {
+				si.UpdatedAt = a.UpdatedAt
+			}
+
+			return si
+		})
+	}
+
+	return nil
+}
+
+func (c *sessionCache) destroySession(ctx context.Context, sessionID ccc.UUID) error {
+	defer c.sessions.Remove(sessionID)
+
+	if err := c.store.DestroySession(ctx, sessionID); err != nil {
+		return errors.Wrap(err, "sessionstorage.BaseStore.DestroySession()")
+	}
+
+	return nil
+}
+
+func (c *sessionCache) rotateSession(ctx context.Context, sessionID ccc.UUID) (ccc.UUID, error) {
+	defer c.sessions.Remove(sessionID)
+
+	id, err := c.store.RotateSession(ctx, sessionID)
+	if err != nil {
+		return ccc.NilUUID, errors.Wrap(err, "sessionstorage.BaseStore.RotateSession()")
+	}
+
+	return id, nil
+}
+
+// removeUserSessions removes the sessions of username, and the sessions in which username
+// impersonates another user, from the cache.
+func (c *sessionCache) removeUserSessions(username string) {
+	c.sessions.RemoveFunc(func(_ ccc.UUID, si sessioninfo.SessionInfo) bool {
+		return si.Username == username || (si.Impersonator != nil && si.Impersonator.Username == username)
+	})
+}
+
+// CachedPreauth is a PreauthStore that serves session lookups from a local cache.
+type CachedPreauth struct {
+	PreauthStore
+	cache *sessionCache
+}
+
+// NewCachedPreauth wraps store with a bounded, expiring cache of session lookups.
+func NewCachedPreauth(store PreauthStore, opts CacheOptions) *CachedPreauth {
+	return &CachedPreauth{
+		PreauthStore: store,
+		cache:        newSessionCache(store, opts),
+	}
+}
+
+// Session returns the session information for sessionID, reading the database only on a cache miss.
+func (c *CachedPreauth) Session(ctx context.Context, sessionID ccc.UUID) (*sessioninfo.SessionInfo, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	return c.cache.session(ctx, sessionID)
+}
+
+// UpdateSessionActivity updates the database and the cached session with the current time for the session activity
+func (c *CachedPreauth) UpdateSessionActivity(ctx context.Context, sessionID ccc.UUID) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	return c.cache.updateSessionActivity(ctx, sessionID)
+}
+
+// UpdateSessionAuthentication updates the database and the cached session with the current time for the
+// session activity and last authentication
+func (c *CachedPreauth) UpdateSessionAuthentication(ctx context.Context, sessionID ccc.UUID) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	return c.cache.updateSessionAuthentication(ctx, sessionID)
+}
+
+// UpdateSessionsActivity sets the last activity time of each session in the database and the cache
+func (c *CachedPreauth) UpdateSessionsActivity(ctx context.Context, activity []*SessionActivity) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	return c.cache.updateSessionsActivity(ctx, activity)
+}
+
+// DestroySession marks the session as expired and removes it from the cache
+func (c *CachedPreauth) DestroySession(ctx context.Context, sessionID ccc.UUID) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	return c.cache.destroySession(ctx, sessionID)
+}
+
+// RotateSession moves an active session to a new ID and removes the old ID from the cache
+func (c *CachedPreauth) RotateSession(ctx context.Context, sessionID ccc.UUID) (ccc.UUID, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	return c.cache.rotateSession(ctx, sessionID)
+}
+
+// NewSession creates a new session and removes the user's sessions from the cache, since the
+// session limit may have expired some of them
+func (c *CachedPreauth) NewSession(ctx context.Context, username string) (ccc.UUID, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	defer c.cache.removeUserSessions(username)
+
+	id, err := c.PreauthStore.NewSession(ctx, username)
+	if err != nil {
+		return ccc.NilUUID, errors.Wrap(err, "sessionstorage.PreauthStore.NewSession()")
+	}
+
+	return id, nil
+}
+
+// NewPersistentSession creates a new persistent session and removes the user's sessions from the cache,
+// since the session limit may have expired some of them
+func (c *CachedPreauth) NewPersistentSession(ctx context.Context, username string, idleTimeout time.Duration) (ccc.UUID, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	defer c.cache.removeUserSessions(username)
+
+	id, err := c.PreauthStore.NewPersistentSession(ctx, username, idleTimeout)
+	if err != nil {
+		return ccc.NilUUID, errors.Wrap(err, "sessionstorage.PreauthStore.NewPersistentSession()")
+	}
+
+	return id, nil
+}
+
+// DestroyAllUserSessions destroys all sessions for a given user and removes them from the cache
+func (c *CachedPreauth) DestroyAllUserSessions(ctx context.Context, username string) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	defer c.cache.removeUserSessions(username)
+
+	if err := c.PreauthStore.DestroyAllUserSessions(ctx, username); err != nil {
+		return errors.Wrap(err, "sessionstorage.PreauthStore.DestroyAllUserSessions()")
+	}
+
+	return nil
+}
+
+// CachedPasswordAuth is a PasswordAuthStore that serves session and user lookups from a local cache.
+type CachedPasswordAuth struct {
+	PasswordAuthStore
+	cache *sessionCache
+	users *lru.Cache[string, dbtype.SessionUser]
+}
+
+// NewCachedPasswordAuth wraps store with bounded, expiring caches of session and user lookups.
+func NewCachedPasswordAuth(store PasswordAuthStore, opts CacheOptions) *CachedPasswordAuth {
+	opts = opts.withDefaults()
+
+	return &CachedPasswordAuth{
+		PasswordAuthStore: store,
+		cache:             newSessionCache(store, opts),
+		users:             lru.New[string, dbtype.SessionUser](opts.Size, opts.TTL),
+	}
+}
+
+// Session returns the session information for sessionID, reading the database only on a cache miss.
+func (c *CachedPasswordAuth) Session(ctx context.Context, sessionID ccc.UUID) (*sessioninfo.SessionInfo, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	return c.cache.session(ctx, sessionID)
+}
+
+// UpdateSessionActivity updates the database and the cached session with the current time for the session activity
+func (c *CachedPasswordAuth) UpdateSessionActivity(ctx context.Context, sessionID ccc.UUID) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	return c.cache.updateSessionActivity(ctx, sessionID)
+}
+
+// UpdateSessionAuthentication updates the database and the cached session with the current time for the
+// session activity and last authentication
+func (c *CachedPasswordAuth) UpdateSessionAuthentication(ctx context.Context, sessionID ccc.UUID) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	return c.cache.updateSessionAuthentication(ctx, sessionID)
+}
+
+// UpdateSessionsActivity sets the last activity time of each session in the database and the cache
+func (c *CachedPasswordAuth) UpdateSessionsActivity(ctx context.Context, activity []*SessionActivity) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	return c.cache.updateSessionsActivity(ctx, activity)
+}
+
+// DestroySession marks the session as expired and removes it from the cache
+func (c *CachedPasswordAuth) DestroySession(ctx context.Context, sessionID ccc.UUID) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	return c.cache.destroySession(ctx, sessionID)
+}
+
+// RotateSession moves an active session to a new ID and removes the old ID from the cache
+func (c *CachedPasswordAuth) RotateSession(ctx context.Context, sessionID ccc.UUID) (ccc.UUID, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	return c.cache.rotateSession(ctx, sessionID)
+}
+
+// NewSession creates a new session and removes the user's sessions from the cache, since the
+// session limit may have expired some of them
+func (c *CachedPasswordAuth) NewSession(ctx context.Context, username string) (ccc.UUID, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	defer c.cache.removeUserSessions(username)
+
+	id, err := c.PasswordAuthStore.NewSession(ctx, username)
+	if err != nil {
+		return ccc.NilUUID, errors.Wrap(err, "sessionstorage.PasswordAuthStore.NewSession()")
+	}
+
+	return id, nil
+}
+
+// NewPersistentSession creates a new persistent session and removes the user's sessions from the cache,
+// since the session limit may have expired some of them
+func (c *CachedPasswordAuth) NewPersistentSession(ctx context.Context, username string, idleTimeout time.Duration) (ccc.UUID, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	defer c.cache.removeUserSessions(username)
+
+	id, err := c.PasswordAuthStore.NewPersistentSession(ctx, username, idleTimeout)
+	if err != nil {
+		return ccc.NilUUID, errors.Wrap(err, "sessionstorage.PasswordAuthStore.NewPersistentSession()")
+	}
+
+	return id, nil
+}
+
+// DestroyAllUserSessions destroys all sessions for a given user and removes them from the cache
+func (c *CachedPasswordAuth) DestroyAllUserSessions(ctx context.Context, username string) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	defer c.cache.removeUserSessions(username)
+
+	if err := c.PasswordAuthStore.DestroyAllUserSessions(ctx, username); err != nil {
+		return errors.Wrap(err, "sessionstorage.PasswordAuthStore.DestroyAllUserSessions()")
+	}
+
+	return nil
+}
+
+// UserByUserName returns the user record associated with the username, reading the database only on a cache miss.
+func (c *CachedPasswordAuth) UserByUserName(ctx context.Context, username string) (*dbtype.SessionUser, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	if u, ok := c.users.Get(username); ok {
+		return &u, nil
+	}
+
+	u, err := c.PasswordAuthStore.UserByUserName(ctx, username)
+	if err != nil {
+		return nil, errors.Wrap(err, "sessionstorage.PasswordAuthStore.UserByUserName()")
+	}
+	c.users.Add(username, *u)
+
+	return u, nil
+}
+
+// SetUserUsername updates the user username and removes the user and their sessions from the cache,
+// since the cached sessions still carry the old username.
+func (c *CachedPasswordAuth) SetUserUsername(ctx context.Context, id ccc.UUID, newUsername string) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	defer func() {
+		usernames := c.removeUser(id)
+		if len(usernames) == 0 {
+			// The old username is unknown, so any cached session could belong to the user.
+			c.cache.sessions.Purge()
+		}
+		for _, username := range usernames {
+			c.cache.removeUserSessions(username)
+		}
+	}()
+
+	if err := c.PasswordAuthStore.SetUserUsername(ctx, id, newUsername); err != nil {
+		return errors.Wrap(err, "sessionstorage.PasswordAuthStore.SetUserUsername()")
+	}
+
+	return nil
+}
+
+// SetUserPasswordHash updates the user password hash and removes the user from the cache
+func (c *CachedPasswordAuth) SetUserPasswordHash(ctx context.Context, id ccc.UUID, hash *securehash.Hash) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	defer c.removeUser(id)
+
+	if err := c.PasswordAuthStore.SetUserPasswordHash(ctx, id, hash); err != nil {
+		return errors.Wrap(err, "sessionstorage.PasswordAuthStore.SetUserPasswordHash()")
+	}
+
+	return nil
+}
+
+// ActivateUser activates a user and removes the user from the cache
+func (c *CachedPasswordAuth) ActivateUser(ctx context.Context, id ccc.UUID) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	defer c.removeUser(id)
+
+	if err := c.PasswordAuthStore.ActivateUser(ctx, id); err != nil {
+		return errors.Wrap(err, "sessionstorage.PasswordAuthStore.ActivateUser()")
+	}
+
+	return nil
+}
+
+// DeactivateUser deactivates a user and removes the user from the cache
+func (c *CachedPasswordAuth) DeactivateUser(ctx context.Context, id ccc.UUID) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	defer c.removeUser(id)
+
+	if err := c.PasswordAuthStore.DeactivateUser(ctx, id); err != nil {
+		return errors.Wrap(err, "sessionstorage.PasswordAuthStore.DeactivateUser()")
+	}
+
+	return nil
+}
+
+// DeleteUser deletes a user and removes the user from the cache
+func (c *CachedPasswordAuth) DeleteUser(ctx context.Context, id ccc.UUID) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	defer c.removeUser(id)
+
+	if err := c.PasswordAuthStore.DeleteUser(ctx, id); err != nil {
+		return errors.Wrap(err, "sessionstorage.PasswordAuthStore.DeleteUser()")
+	}
+
+	return nil
+}
+
+// removeUser removes the cached lookups of the user and returns the usernames they were cached under.
+func (c *CachedPasswordAuth) removeUser(id ccc.UUID) []string {
+	var usernames []string
+	c.users.RemoveFunc(func(username string, u dbtype.SessionUser) bool {
+		if u.ID != id {
+			return false
+		}
+		usernames = append(usernames, username, u.Username)
+
+		return true
+	})
+
+	return usernames
+}
+
+// CachedOIDC is an OIDCStore that serves session lookups from a local cache.
+type CachedOIDC struct {
+	OIDCStore
+	cache *sessionCache
+}
+
+// NewCachedOIDC wraps store with a bounded, expiring cache of session lookups.
+func NewCachedOIDC(store OIDCStore, opts CacheOptions) *CachedOIDC {
+	return &CachedOIDC{
+		OIDCStore: store,
+		cache:     newSessionCache(store, opts),
+	}
+}
+
+// Session returns the session information for sessionID, reading the database only on a cache miss.
+func (c *CachedOIDC) Session(ctx context.Context, sessionID ccc.UUID) (*sessioninfo.SessionInfo, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	return c.cache.session(ctx, sessionID)
+}
+
+// UpdateSessionActivity updates the database and the cached session with the current time for the session activity
+func (c *CachedOIDC) UpdateSessionActivity(ctx context.Context, sessionID ccc.UUID) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	return c.cache.updateSessionActivity(ctx, sessionID)
+}
+
+// UpdateSessionAuthentication updates the database and the cached session with the current time for the
+// session activity and last authentication
+func (c *CachedOIDC) UpdateSessionAuthentication(ctx context.Context, sessionID ccc.UUID) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	return c.cache.updateSessionAuthentication(ctx, sessionID)
+}
+
+// UpdateSessionsActivity sets the last activity time of each session in the database and the cache
+func (c *CachedOIDC) UpdateSessionsActivity(ctx context.Context, activity []*SessionActivity) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	return c.cache.updateSessionsActivity(ctx, activity)
+}
+
+// DestroySession marks the session as expired and removes it from the cache
+func (c *CachedOIDC) DestroySession(ctx context.Context, sessionID ccc.UUID) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	return c.cache.destroySession(ctx, sessionID)
+}
+
+// RotateSession moves an active session to a new ID and removes the old ID from the cache
+func (c *CachedOIDC) RotateSession(ctx context.Context, sessionID ccc.UUID) (ccc.UUID, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	return c.cache.rotateSession(ctx, sessionID)
+}
+
+// NewSession creates a new session and removes the user's sessions from the cache, since the
+// session limit may have expired some of them
+func (c *CachedOIDC) NewSession(ctx context.Context, username, oidcSID string) (ccc.UUID, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	defer c.cache.removeUserSessions(username)
+
+	id, err := c.OIDCStore.NewSession(ctx, username, oidcSID)
+	if err != nil {
+		return ccc.NilUUID, errors.Wrap(err, "sessionstorage.OIDCStore.NewSession()")
+	}
+
+	return id, nil
+}
+
+// DestroySessionOIDC marks the session as expired and clears the session cache,
+// because cached sessions do not record their OIDC session ID.
+func (c *CachedOIDC) DestroySessionOIDC(ctx context.Context, oidcSID string) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	defer c.cache.sessions.Purge()
+
+	if err := c.OIDCStore.DestroySessionOIDC(ctx, oidcSID); err != nil {
+		return errors.Wrap(err, "sessionstorage.OIDCStore.DestroySessionOIDC()")
+	}
+
+	return nil
+}
@@ -0,0 +1,190 @@
+package sessionstorage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cccteam/ccc"
+	"github.com/cccteam/session/internal/dbtype"
+	"github.com/cccteam/session/sessioninfo"
+	"github.com/cccteam/session/sessionstorage/mock/mock_sessionstorage"
+	gomock "go.uber.org/mock/gomock"
+)
+
+func TestCachedPreauth_Session(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	sessionID := ccc.Must(ccc.NewUUID())
+	stored := &sessioninfo.SessionInfo{ID: sessionID, Username: "alice", UpdatedAt: time.Now().Add(-time.Minute)}
+
+	ctrl := gomock.NewController(t)
+	store := mock_sessionstorage.NewMockPreauthStore(ctrl)
+	cache := NewCachedPreauth(store, CacheOptions{})
+
+	gomock.InOrder(
+		store.EXPECT().Session(gomock.Any(), sessionID).Return(stored, nil).Times(1),
+		store.EXPECT().UpdateSessionActivity(gomock.Any(), sessionID).Return(nil).Times(1),
+		store.EXPECT().DestroyAllUserSessions(gomock.Any(), "alice").Return(nil).Times(1),
+		store.EXPECT().Session(gomock.Any(), sessionID).Return(&sessioninfo.SessionInfo{ID: sessionID, Username: "alice", Expired: true}, nil).Times(1),
+	)
+
+	first, err := cache.Session(ctx, sessionID)
+	if err != nil {
+		t.Fatalf("CachedPreauth.Session() error = %v", err)
+	}
+	if err := cache.UpdateSessionActivity(ctx, sessionID); err != nil {
+		t.Fatalf("CachedPreauth.UpdateSessionActivity() error = %v", err)
+	}
+
+	second, err := cache.Session(ctx, sessionID)
+	if err != nil {
+		t.Fatalf("CachedPreauth.Session() error = %v", err)
+	}
+	if !second.UpdatedAt.After(first.UpdatedAt) {
+		t.Errorf("CachedPreauth.Session() UpdatedAt = %v, want after %v", second.UpdatedAt, first.UpdatedAt)
+	}
+
+	if err := cache.DestroyAllUserSessions(ctx, "alice"); err != nil {
+		t.Fatalf("CachedPreauth.DestroyAllUserSessions() error = %v", err)
+	}
+	third, err := cache.Session(ctx, sessionID)
+	if err != nil {
+		t.Fatalf("CachedPreauth.Session() error = %v", err)
+	}
+	if !third.Expired {
+		t.Errorf("CachedPreauth.Session() Expired = false, want session reloaded after DestroyAllUserSessions()")
+	}
+}
+
+func TestCachedPreauth_SessionTTL(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	sessionID := ccc.Must(ccc.NewUUID())
+
+	ctrl := gomock.NewController(t)
+	store := mock_sessionstorage.NewMockPreauthStore(ctrl)
+	store.EXPECT().Session(gomock.Any(), sessionID).Return(&sessioninfo.SessionInfo{ID: sessionID}, nil).Times(2)
+
+	cache := NewCachedPreauth(store, CacheOptions{TTL: time.Millisecond})
+	for range 2 {
+		if _, err := cache.Session(ctx, sessionID); err != nil {
+			t.Fatalf("CachedPreauth.Session() error = %v", err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestCachedPasswordAuth_Invalidation(t *testing.T) {
+	t.Parallel()
+
+	userID := ccc.Must(ccc.NewUUID())
+	sessionID := ccc.Must(ccc.NewUUID())
+
+	tests := []struct {
+		name       string
+		invalidate func(context.Context, *CachedPasswordAuth) error
+		prepare    func(*mock_sessionstorage.MockPasswordAuthStore)
+		reloadUser bool
+		reloadSess bool
+	}{
+		{
+			name: "DeactivateUser",
+			invalidate: func(ctx context.Context, c *CachedPasswordAuth) error {
+				return c.DeactivateUser(ctx, userID)
+			},
+			prepare: func(store *mock_sessionstorage.MockPasswordAuthStore) {
+				store.EXPECT().DeactivateUser(gomock.Any(), userID).Return(nil).Times(1)
+			},
+			reloadUser: true,
+		},
+		{
+			name: "SetUserUsername",
+			invalidate: func(ctx context.Context, c *CachedPasswordAuth) error {
+				return c.SetUserUsername(ctx, userID, "bob")
+			},
+			prepare: func(store *mock_sessionstorage.MockPasswordAuthStore) {
+				store.EXPECT().SetUserUsername(gomock.Any(), userID, "bob").Return(nil).Times(1)
+			},
+			reloadUser: true,
+			reloadSess: true,
+		},
+		{
+			name: "DestroySession",
+			invalidate: func(ctx context.Context, c *CachedPasswordAuth) error {
+				return c.DestroySession(ctx, sessionID)
+			},
+			prepare: func(store *mock_sessionstorage.MockPasswordAuthStore) {
+				store.EXPECT().DestroySession(gomock.Any(), sessionID).Return(nil).Times(1)
+			},
+			reloadSess: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			ctrl := gomock.NewController(t)
+			store := mock_sessionstorage.NewMockPasswordAuthStore(ctrl)
+			cache := NewCachedPasswordAuth(store, CacheOptions{})
+
+			userLoads, sessLoads := 1, 1
+			if tt.reloadUser {
+				userLoads++
+			}
+			if tt.reloadSess {
+				sessLoads++
+			}
+			store.EXPECT().UserByUserName(gomock.Any(), "alice").Return(&dbtype.SessionUser{ID: userID, Username: "alice"}, nil).Times(userLoads)
+			store.EXPECT().Session(gomock.Any(), sessionID).Return(&sessioninfo.SessionInfo{ID: sessionID, Username: "alice"}, nil).Times(sessLoads)
+			tt.prepare(store)
+
+			for range 2 {
+				if _, err := cache.UserByUserName(ctx, "alice"); err != nil {
+					t.Fatalf("CachedPasswordAuth.UserByUserName() error = %v", err)
+				}
+				if _, err := cache.Session(ctx, sessionID); err != nil {
+					t.Fatalf("CachedPasswordAuth.Session() error = %v", err)
+				}
+			}
+
+			if err := tt.invalidate(ctx, cache); err != nil {
+				t.Fatalf("invalidate() error = %v", err)
+			}
+
+			if _, err := cache.UserByUserName(ctx, "alice"); err != nil {
+				t.Fatalf("CachedPasswordAuth.UserByUserName() error = %v", err)
+			}
+			if _, err := cache.Session(ctx, sessionID); err != nil {
+				t.Fatalf("CachedPasswordAuth.Session() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestCachedOIDC_DestroySessionOIDC(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	sessionID := ccc.Must(ccc.NewUUID())
+
+	ctrl := gomock.NewController(t)
+	store := mock_sessionstorage.NewMockOIDCStore(ctrl)
+	store.EXPECT().Session(gomock.Any(), sessionID).Return(&sessioninfo.SessionInfo{ID: sessionID}, nil).Times(2)
+	store.EXPECT().DestroySessionOIDC(gomock.Any(), "sid-1").Return(nil).Times(1)
+
+	cache := NewCachedOIDC(store, CacheOptions{})
+	if _, err := cache.Session(ctx, sessionID); err != nil {
+		t.Fatalf("CachedOIDC.Session() error = %v", err)
+	}
+	if err := cache.DestroySessionOIDC(ctx, "sid-1"); err != nil {
+		t.Fatalf("CachedOIDC.DestroySessionOIDC() error = %v", err)
+	}
+	if _, err := cache.Session(ctx, sessionID); err != nil {
+		t.Fatalf("CachedOIDC.Session() error = %v", err)
+	}
+}
@@ -0,0 +1,47 @@
+package sessionstorage
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/cccteam/ccc"
+	"github.com/cccteam/ccc/tracer"
+	"github.com/cccteam/session/internal/dbtype"
+	"github.com/go-playground/errors/v5"
+)
+
+// SessionData returns the data stored with the session by key. It is empty when the session has no data.
+func (s *sessionStorage) SessionData(ctx context.Context, sessionID ccc.UUID) (map[string]json.RawMessage, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	data, err := s.db.SessionData(ctx, sessionID)
+	if err != nil {
+		return nil, errors.Wrap(err, "db.SessionData()")
+	}
+
+	return data, nil
+}
+
+// UpdateSessionData writes the keys in set and removes the keys in deleted from the data stored with the session.
+// It returns a NotFound error when the session does not exist.
+func (s *sessionStorage) UpdateSessionData(ctx context.Context, sessionID ccc.UUID, set map[string]json.RawMessage, deleted []string) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	if len(set) == 0 && len(deleted) == 0 {
+		return nil
+	}
+
+	update := &dbtype.SessionDataUpdate{
+		Set:       set,
+		Deleted:   deleted,
+		UpdatedAt: time.Now(),
+	}
+	if err := s.db.UpdateSessionData(ctx, sessionID, update); err != nil {
+		return errors.Wrap(err, "db.UpdateSessionData()")
+	}
+
+	return nil
+}
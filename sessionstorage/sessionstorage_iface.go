@@ -1,17 +1,24 @@
 // Package sessionstorage implements database storage for session data.
-// There are implementations for both Spanner and Postgres for each
-// session type (i.e. OIDC, Username/Password, etc)
+// There are implementations for Spanner, Postgres and process memory for each session type
+// (i.e. OIDC, Username/Password, etc), and a stateless Preauth implementation that keeps sessions
+// in the Auth Cookie. The MySQL, SQLite and Redis implementations are in the sessionstorage/mysql,
+// sessionstorage/sqlite and sessionstorage/redis packages, so that only the services that use them
+// link their database clients.
 package sessionstorage
 
 import (
 	"context"
+	"encoding/json"
+	"time"
 
 	"github.com/cccteam/ccc"
 	"github.com/cccteam/ccc/securehash"
 	"github.com/cccteam/session/internal/dbtype"
 	"github.com/cccteam/session/sessioninfo"
+	"github.com/cccteam/session/sessionstorage/internal/memory"
 	"github.com/cccteam/session/sessionstorage/internal/postgres"
 	"github.com/cccteam/session/sessionstorage/internal/spanner"
+	"github.com/cccteam/session/sessionstorage/internal/stateless"
 )
 
 // BaseStore defines an interface for managing session storage.
@@ -20,21 +27,41 @@ type BaseStore interface {
 	Session(ctx context.Context, sessionID ccc.UUID) (*sessioninfo.SessionInfo, error)
 	// UpdateSessionActivity updates the database with the current time for the session activity
 	UpdateSessionActivity(ctx context.Context, sessionID ccc.UUID) error
+	// UpdateSessionAuthentication records the current time as the last activity and last authentication of the
+	// session, after its user authenticated again. It returns a NotFound error when the session does not exist.
+	UpdateSessionAuthentication(ctx context.Context, sessionID ccc.UUID) error
 	// DestroySession marks the session as expired
 	DestroySession(ctx context.Context, sessionID ccc.UUID) error
+	// RotateSession moves an active session to a new ID, keeping its data, and returns the new ID.
+	// The old ID is no longer valid afterwards.
+	RotateSession(ctx context.Context, sessionID ccc.UUID) (ccc.UUID, error)
+	// UserSessions returns a page of the sessions for username that match filter, newest first
+	UserSessions(ctx context.Context, username string, filter dbtype.SessionFilter, page dbtype.Page) (*dbtype.SessionPage, error)
+	// SessionData returns the data stored with the session by key. It is empty when the session has no data.
+	SessionData(ctx context.Context, sessionID ccc.UUID) (map[string]json.RawMessage, error)
+	// UpdateSessionData writes the keys in set and removes the keys in deleted from the data stored with the session.
+	// It returns a NotFound error when the session does not exist.
+	UpdateSessionData(ctx context.Context, sessionID ccc.UUID, set map[string]json.RawMessage, deleted []string) error
 	// SetSessionTableName sets the name of the session table.
 	SetSessionTableName(name string)
 	// SetUserTableName sets the name of the user table.
 	SetUserTableName(name string)
+	// SetSessionLimit sets the limit on active sessions per user applied to new sessions.
+	SetSessionLimit(limit dbtype.SessionLimit)
 }
 
 var _ PreauthStore = (*Preauth)(nil)
 
 // PreauthStore defines an interface for managing pre-authenticated session storage.
 type PreauthStore interface {
-	// NewSession creates a new session in the database, returning its id
+	// NewSession creates a new session in the database, returning its id. The session records
+	// the client from sessioninfo.ClientFromCtx(ctx).
 	NewSession(ctx context.Context, username string) (ccc.UUID, error)
-	// DestroyAllUserSessions destroys all sessions for a given user
+	// NewPersistentSession creates a new session like NewSession that stays valid for idleTimeout
+	// without activity, instead of the configured session timeout, returning its id
+	NewPersistentSession(ctx context.Context, username string, idleTimeout time.Duration) (ccc.UUID, error)
+	// DestroyAllUserSessions destroys all sessions for a given user, including the sessions in which the user
+	// impersonates another user
 	DestroyAllUserSessions(ctx context.Context, username string) error
 
 	// shared storage methods
@@ -73,6 +100,9 @@ var _ OIDCStore = (*OIDC)(nil)
 type OIDCStore interface {
 	DestroySessionOIDC(ctx context.Context, oidcSID string) error
 	NewSession(ctx context.Context, username, oidcSID string) (ccc.UUID, error)
+	// UsernameOIDC returns the username of the session with the oidcSID. It returns a NotFound error
+	// when no session has the oidcSID.
+	UsernameOIDC(ctx context.Context, oidcSID string) (string, error)
 
 	// shared storage methods
 	BaseStore
@@ -81,6 +111,8 @@ type OIDCStore interface {
 var (
 	_ db = (*spanner.SessionStorageDriver)(nil)
 	_ db = (*postgres.SessionStorageDriver)(nil)
+	_ db = (*memory.SessionStorageDriver)(nil)
+	_ db = (*stateless.SessionStorageDriver)(nil)
 )
 
 // db defines an interface for database operations related to session management.
@@ -91,8 +123,25 @@ type db interface {
 	InsertSession(ctx context.Context, session *dbtype.InsertSession) (ccc.UUID, error)
 	// UpdateSessionActivity updates the session activity column with the current time.
 	UpdateSessionActivity(ctx context.Context, sessionID ccc.UUID) error
+	// UpdateSessionAuthentication sets the session activity and last authentication columns to the current time.
+	UpdateSessionAuthentication(ctx context.Context, sessionID ccc.UUID) error
+	// UpdateSessionsActivity sets the session activity column of each session in one write.
+	// Sessions that no longer exist are skipped.
+	UpdateSessionsActivity(ctx context.Context, activity []*dbtype.SessionActivity) error
 	// DestroySession marks the session as expired.
 	DestroySession(ctx context.Context, sessionID ccc.UUID) error
+	// RotateSession moves an active session to a new ID, keeping the rest of the row and its data, and returns the new ID.
+	RotateSession(ctx context.Context, sessionID ccc.UUID) (ccc.UUID, error)
+	// UserSessions returns up to query.Limit sessions for query.Username that match the query, newest first.
+	UserSessions(ctx context.Context, query *dbtype.UserSessions) ([]*dbtype.Session, error)
+	// PurgeSessions deletes up to purge.Limit session rows matching the criteria and returns the number deleted.
+	// The data of deleted sessions is deleted with them.
+	PurgeSessions(ctx context.Context, purge *dbtype.PurgeSessions) (int64, error)
+	// SessionData returns the data stored with the session by key. It is empty when the session has no data.
+	SessionData(ctx context.Context, sessionID ccc.UUID) (map[string]json.RawMessage, error)
+	// UpdateSessionData applies update to the data stored with the session. It returns a NotFound error
+	// when the session does not exist.
+	UpdateSessionData(ctx context.Context, sessionID ccc.UUID, update *dbtype.SessionDataUpdate) error
 	// SetSessionTableName sets the name of the session table.
 	SetSessionTableName(name string)
 	// SetUserTableName sets the name of the user table.
@@ -119,7 +168,7 @@ type db interface {
 	DeactivateUser(ctx context.Context, id ccc.UUID) error
 	// DeleteUser deletes a user
 	DeleteUser(ctx context.Context, id ccc.UUID) error
-	// DestroyAllUserSessions destroys all sessions for a given user
+	// DestroyAllUserSessions destroys all sessions for a given user, including its impersonation sessions
 	DestroyAllUserSessions(ctx context.Context, username string) error
 
 	//
@@ -130,4 +179,6 @@ type db interface {
 	InsertSessionOIDC(ctx context.Context, session *dbtype.InsertOIDCSession) (ccc.UUID, error)
 	// DestroySessionOIDC marks the OIDC session as expired by oidcSID.
 	DestroySessionOIDC(ctx context.Context, oidcSID string) error
+	// UsernameOIDC returns the username of the session with the oidcSID.
+	UsernameOIDC(ctx context.Context, oidcSID string) (string, error)
 }
@@ -0,0 +1,25 @@
+package sessionstorage
+
+import (
+	"github.com/cccteam/session/internal/dbtype"
+)
+
+// SessionLimitPolicy decides what happens when a user who already has the maximum number of
+// active sessions logs in.
+type SessionLimitPolicy = dbtype.SessionLimitPolicy
+
+const (
+	// EvictOldestSession expires the oldest active sessions of the user to make room for the new session.
+	EvictOldestSession = dbtype.EvictOldestSession
+	// RejectNewSession fails the new session with a Conflict error and keeps the existing sessions.
+	RejectNewSession = dbtype.RejectNewSession
+)
+
+// SessionLimit limits the number of active sessions per user. It is enforced atomically
+// with the insert of each new session.
+type SessionLimit = dbtype.SessionLimit
+
+// SetSessionLimit sets the limit on active sessions per user applied to new sessions.
+func (s *sessionStorage) SetSessionLimit(limit SessionLimit) {
+	s.limit = limit
+}
@@ -0,0 +1,115 @@
+package sessionstorage
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"github.com/cccteam/ccc"
+	"github.com/cccteam/ccc/tracer"
+	"github.com/cccteam/httpio"
+	"github.com/cccteam/session/internal/dbtype"
+	"github.com/cccteam/session/sessioninfo"
+	"github.com/go-playground/errors/v5"
+)
+
+const (
+	// DefaultPageSize is the number of sessions returned by UserSessions when Page.Size is not set.
+	DefaultPageSize = 50
+	// MaxPageSize is the largest number of sessions returned by a single UserSessions call.
+	MaxPageSize = 500
+)
+
+// SessionState selects sessions by state in UserSessions.
+type SessionState = dbtype.SessionState
+
+const (
+	// SessionStateAll selects active and expired sessions.
+	SessionStateAll = dbtype.SessionStateAll
+	// SessionStateActive selects sessions that can still be used.
+	SessionStateActive = dbtype.SessionStateActive
+	// SessionStateExpired selects sessions that were destroyed or timed out.
+	SessionStateExpired = dbtype.SessionStateExpired
+)
+
+type (
+	// SessionFilter selects the sessions returned by UserSessions.
+	SessionFilter = dbtype.SessionFilter
+	// Page requests one page of a listing.
+	Page = dbtype.Page
+	// SessionPage is one page of sessions, newest first.
+	SessionPage = dbtype.SessionPage
+)
+
+// UserSessions returns a page of the sessions for username that match filter, newest first.
+func (s *sessionStorage) UserSessions(ctx context.Context, username string, filter SessionFilter, page Page) (*SessionPage, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	size := page.Size
+	if size <= 0 {
+		size = DefaultPageSize
+	}
+	size = min(size, MaxPageSize)
+
+	query := &dbtype.UserSessions{
+		Username: username,
+		Active:   filter.State == SessionStateAll || filter.State == SessionStateActive,
+		Expired:  filter.State == SessionStateAll || filter.State == SessionStateExpired,
+		Limit:    size + 1,
+	}
+	if filter.IdleTimeout > 0 {
+		query.Now = time.Now()
+		query.IdleBefore = query.Now.Add(-filter.IdleTimeout)
+	}
+	if page.Cursor != "" {
+		cursor, err := decodeCursor(page.Cursor)
+		if err != nil {
+			return nil, httpio.NewBadRequestMessageWithError(err, "invalid cursor")
+		}
+		query.After = cursor
+	}
+
+	sessions, err := s.db.UserSessions(ctx, query)
+	if err != nil {
+		return nil, errors.Wrap(err, "db.UserSessions()")
+	}
+
+	result := &SessionPage{Sessions: make([]*sessioninfo.SessionInfo, 0, min(len(sessions), size))}
+	for _, si := range sessions[:min(len(sessions), size)] {
+		result.Sessions = append(result.Sessions, si.SessionInfo())
+	}
+	if len(sessions) > size {
+		last := sessions[size-1]
+		result.NextCursor = encodeCursor(&dbtype.SessionCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+
+	return result, nil
+}
+
+func encodeCursor(c *dbtype.SessionCursor) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(c.CreatedAt.UTC().Format(time.RFC3339Nano) + " " + c.ID.String()))
+}
+
+func decodeCursor(cursor string) (*dbtype.SessionCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errors.Wrap(err, "base64.Encoding.DecodeString()")
+	}
+
+	createdAt, id, ok := strings.Cut(string(b), " ")
+	if !ok {
+		return nil, errors.New("malformed cursor")
+	}
+
+	c := &dbtype.SessionCursor{}
+	if c.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt); err != nil {
+		return nil, errors.Wrap(err, "time.Parse()")
+	}
+	if c.ID, err = ccc.UUIDFromString(id); err != nil {
+		return nil, errors.Wrap(err, "ccc.UUIDFromString()")
+	}
+
+	return c, nil
+}
@@ -0,0 +1,162 @@
+package sessionstorage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cccteam/ccc"
+	"github.com/cccteam/httpio"
+	"github.com/cccteam/session/internal/dbtype"
+	"github.com/cccteam/session/sessionstorage/mock/mock_sessionstorage"
+	"github.com/go-playground/errors/v5"
+	"github.com/google/go-cmp/cmp"
+	gomock "go.uber.org/mock/gomock"
+)
+
+func newListedSessions(n int) []*dbtype.Session {
+	sessions := make([]*dbtype.Session, 0, n)
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range n {
+		sessions = append(sessions, &dbtype.Session{
+			ID:        ccc.Must(ccc.NewUUID()),
+			Username:  "alice",
+			CreatedAt: createdAt.Add(-time.Duration(i) * time.Minute),
+		})
+	}
+
+	return sessions
+}
+
+func Test_sessionStorage_UserSessions(t *testing.T) {
+	t.Parallel()
+
+	listed := newListedSessions(3)
+	cursor := encodeCursor(&dbtype.SessionCursor{CreatedAt: listed[1].CreatedAt, ID: listed[1].ID})
+
+	tests := []struct {
+		name           string
+		filter         SessionFilter
+		page           Page
+		prepare        func(*mock_sessionstorage.Mockdb)
+		wantCount      int
+		wantNextCursor string
+		wantBadRequest bool
+		wantErr        bool
+	}{
+		{
+			name: "last page has no cursor",
+			prepare: func(mockDB *mock_sessionstorage.Mockdb) {
+				mockDB.EXPECT().
+					UserSessions(gomock.Any(), gomock.Cond(func(q *dbtype.UserSessions) bool {
+						return q.Username == "alice" && q.Active && q.Expired && q.IdleBefore.IsZero() && q.After == nil && q.Limit == DefaultPageSize+1
+					})).
+					Return(listed, nil).
+					Times(1)
+			},
+			wantCount: 3,
+		},
+		{
+			name: "full page returns a cursor for the last session",
+			page: Page{Size: 2},
+			prepare: func(mockDB *mock_sessionstorage.Mockdb) {
+				mockDB.EXPECT().UserSessions(gomock.Any(), gomock.Any()).Return(listed, nil).Times(1)
+			},
+			wantCount:      2,
+			wantNextCursor: cursor,
+		},
+		{
+			name:   "active filter with idle timeout and cursor",
+			filter: SessionFilter{State: SessionStateActive, IdleTimeout: time.Hour},
+			page:   Page{Cursor: cursor, Size: MaxPageSize + 1},
+			prepare: func(mockDB *mock_sessionstorage.Mockdb) {
+				mockDB.EXPECT().
+					UserSessions(gomock.Any(), gomock.Cond(func(q *dbtype.UserSessions) bool {
+						idle := time.Since(q.IdleBefore)
+
+						return q.Active && !q.Expired && idle >= time.Hour && idle < time.Hour+time.Minute &&
+							q.After != nil && q.After.ID == listed[1].ID && q.After.CreatedAt.Equal(listed[1].CreatedAt) &&
+							q.Limit == MaxPageSize+1
+					})).
+					Return(listed[2:], nil).
+					Times(1)
+			},
+			wantCount: 1,
+		},
+		{
+			name:   "expired filter",
+			filter: SessionFilter{State: SessionStateExpired},
+			prepare: func(mockDB *mock_sessionstorage.Mockdb) {
+				mockDB.EXPECT().
+					UserSessions(gomock.Any(), gomock.Cond(func(q *dbtype.UserSessions) bool {
+						return !q.Active && q.Expired
+					})).
+					Return(nil, nil).
+					Times(1)
+			},
+		},
+		{
+			name:           "invalid cursor",
+			page:           Page{Cursor: "not a cursor"},
+			wantBadRequest: true,
+			wantErr:        true,
+		},
+		{
+			name: "database error",
+			prepare: func(mockDB *mock_sessionstorage.Mockdb) {
+				mockDB.EXPECT().UserSessions(gomock.Any(), gomock.Any()).Return(nil, errors.New("query failed")).Times(1)
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			mockDB := mock_sessionstorage.NewMockdb(ctrl)
+			storage := &sessionStorage{
+				db: mockDB,
+			}
+
+			if tt.prepare != nil {
+				tt.prepare(mockDB)
+			}
+
+			got, err := storage.UserSessions(context.Background(), "alice", tt.filter, tt.page)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UserSessions() error = %v, wantErr = %v", err, tt.wantErr)
+			}
+			if tt.wantBadRequest && !httpio.HasBadRequest(err) {
+				t.Errorf("UserSessions() error = %v, want BadRequest", err)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got.Sessions) != tt.wantCount {
+				t.Errorf("UserSessions() returned %d sessions, want %d", len(got.Sessions), tt.wantCount)
+			}
+			if got.NextCursor != tt.wantNextCursor {
+				t.Errorf("UserSessions() NextCursor = %q, want %q", got.NextCursor, tt.wantNextCursor)
+			}
+		})
+	}
+}
+
+func Test_decodeCursor(t *testing.T) {
+	t.Parallel()
+
+	want := &dbtype.SessionCursor{
+		CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 123456789, time.UTC),
+		ID:        ccc.Must(ccc.UUIDFromString("38bd570b-1280-421b-888e-a63f0ca35be7")),
+	}
+
+	got, err := decodeCursor(encodeCursor(want))
+	if err != nil {
+		t.Fatalf("decodeCursor() error = %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("decodeCursor() mismatch (-want +got):\n%s", diff)
+	}
+}
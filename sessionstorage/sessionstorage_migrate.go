@@ -0,0 +1,211 @@
+package sessionstorage
+
+import (
+	"context"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/cccteam/session/internal/dbtype"
+	"github.com/cccteam/session/schema"
+	"github.com/go-playground/errors/v5"
+)
+
+// Variant selects the schema that Migrate applies.
+type Variant int
+
+const (
+	// VariantPassword is the schema used by Preauth and PasswordAuth (schema/<database>/migrations).
+	VariantPassword Variant = iota
+	// VariantOIDC is the schema used by OIDC (schema/<database>/oidc/migrations).
+	VariantOIDC
+)
+
+// String implements the fmt.Stringer interface.
+func (v Variant) String() string {
+	switch v {
+	case VariantPassword:
+		return "password"
+	case VariantOIDC:
+		return "oidc"
+	default:
+		return "unknown"
+	}
+}
+
+const (
+	defaultSessionTableName = "Sessions"
+	defaultUserTableName    = "SessionUsers"
+)
+
+// MigrateOption configures the Migrate functions.
+type MigrateOption func(*migrateConfig)
+
+type migrateConfig struct {
+	sessionTableName string
+	userTableName    string
+}
+
+// WithSessionTableName creates the session table under name. It should match the
+// name given to session.WithSessionTableName. (default: Sessions)
+func WithSessionTableName(name string) MigrateOption {
+	return func(c *migrateConfig) {
+		c.sessionTableName = name
+	}
+}
+
+// WithUserTableName creates the user table under name. It should match the
+// name given to session.WithUserTableName. (default: SessionUsers)
+func WithUserTableName(name string) MigrateOption {
+	return func(c *migrateConfig) {
+		c.userTableName = name
+	}
+}
+
+// migrationStep is a schema change that is applied when its target table,
+// or column when set, does not exist yet. Deciding from the live schema rather
+// than a version table lets Migrate adopt databases that were created by copying
+// the SQL files into another migration tool.
+type migrationStep struct {
+	file      string
+	variants  []Variant
+	userTable bool
+	dataTable bool
+	column    string
+}
+
+// migrationSteps are applied in order. Paths are relative to the database directory in schema.FS.
+var migrationSteps = []migrationStep{
+	{file: "migrations/000001_Sessions.up.sql", variants: []Variant{VariantPassword}},
+	{file: "oidc/migrations/000001_Sessions.up.sql", variants: []Variant{VariantOIDC}},
+	{file: "oidc/upgrade/000001_SessionsOidcSid.up.sql", variants: []Variant{VariantOIDC}, column: "OidcSid"},
+	{file: "migrations/000002_SessionUsers.up.sql", variants: []Variant{VariantPassword}, userTable: true},
+	{file: "migrations/000003_SessionsIdleTimeout.up.sql", variants: []Variant{VariantPassword}, column: "IdleTimeoutSeconds"},
+	{file: "oidc/migrations/000002_SessionsIdleTimeout.up.sql", variants: []Variant{VariantOIDC}, column: "IdleTimeoutSeconds"},
+	{file: "migrations/000004_SessionsClient.up.sql", variants: []Variant{VariantPassword}, column: "ClientIp"},
+	{file: "oidc/migrations/000003_SessionsClient.up.sql", variants: []Variant{VariantOIDC}, column: "ClientIp"},
+	{file: "migrations/000005_SessionsData.up.sql", variants: []Variant{VariantPassword}, dataTable: true},
+	{file: "oidc/migrations/000004_SessionsData.up.sql", variants: []Variant{VariantOIDC}, dataTable: true},
+	{file: "migrations/000006_SessionsImpersonator.up.sql", variants: []Variant{VariantPassword}, column: "ImpersonatorUsername"},
+	{file: "oidc/migrations/000005_SessionsImpersonator.up.sql", variants: []Variant{VariantOIDC}, column: "ImpersonatorUsername"},
+	{file: "migrations/000007_SessionsLastAuthenticatedAt.up.sql", variants: []Variant{VariantPassword}, column: "LastAuthenticatedAt"},
+	{file: "oidc/migrations/000006_SessionsLastAuthenticatedAt.up.sql", variants: []Variant{VariantOIDC}, column: "LastAuthenticatedAt"},
+}
+
+// migrator applies migration steps to a specific database.
+type migrator interface {
+	tableExists(ctx context.Context, table string) (bool, error)
+	columnExists(ctx context.Context, table, column string) (bool, error)
+	exec(ctx context.Context, script string) error
+}
+
+// migrate applies the steps for variant using the migration files found in dir.
+func migrate(ctx context.Context, m migrator, dir string, variant Variant, opts []MigrateOption) error {
+	cfg := &migrateConfig{
+		sessionTableName: defaultSessionTableName,
+		userTableName:    defaultUserTableName,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	for _, step := range migrationSteps {
+		if !step.appliesTo(variant) {
+			continue
+		}
+
+		table := cfg.sessionTableName
+		switch {
+		case step.userTable:
+			table = cfg.userTableName
+		case step.dataTable:
+			table = cfg.sessionTableName + dbtype.SessionDataTableSuffix
+		}
+
+		exists, err := m.tableExists(ctx, table)
+		if err != nil {
+			return err
+		}
+		if exists && step.column != "" {
+			exists, err = m.columnExists(ctx, table, step.column)
+			if err != nil {
+				return err
+			}
+		}
+		if exists {
+			continue
+		}
+
+		b, err := schema.FS.ReadFile(path.Join(dir, step.file))
+		if err != nil {
+			return errors.Wrap(err, "schema.FS.ReadFile()")
+		}
+
+		if err := m.exec(ctx, renameTables(string(b), cfg)); err != nil {
+			return errors.Wrapf(err, "failed to apply %s", step.file)
+		}
+	}
+
+	return nil
+}
+
+func (s migrationStep) appliesTo(variant Variant) bool {
+	for _, v := range s.variants {
+		if v == variant {
+			return true
+		}
+	}
+
+	return false
+}
+
+// tableIdentifier matches identifiers derived from the default table names,
+// such as Sessions_pkey, SessionsByOidcSid or CK_SessionUsersId.
+var tableIdentifier = regexp.MustCompile(`\b(CK_)?(SessionUsers|Sessions)(\w*)\b`)
+
+// renameTables replaces the default table names in script with the configured names.
+func renameTables(script string, cfg *migrateConfig) string {
+	if cfg.sessionTableName == defaultSessionTableName && cfg.userTableName == defaultUserTableName {
+		return script
+	}
+
+	return tableIdentifier.ReplaceAllStringFunc(script, func(ident string) string {
+		m := tableIdentifier.FindStringSubmatch(ident)
+		name := cfg.sessionTableName
+		if m[2] == defaultUserTableName {
+			name = cfg.userTableName
+		}
+
+		return m[1] + name + m[3]
+	})
+}
+
+// transactionKeywords matches the BEGIN and COMMIT lines wrapping some migration files.
+var transactionKeywords = regexp.MustCompile(`(?mi)^\s*(BEGIN|COMMIT)\s*;\s*$`)
+
+// stripTransaction removes BEGIN and COMMIT lines so the script can run inside the migrator's transaction.
+func stripTransaction(script string) string {
+	return transactionKeywords.ReplaceAllString(script, "")
+}
+
+// splitStatements splits script into individual statements for databases that
+// cannot execute a multi-statement script. Comment lines are dropped.
+func splitStatements(script string) []string {
+	var b strings.Builder
+	for line := range strings.SplitSeq(stripTransaction(script), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "--") {
+			continue
+		}
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+
+	var statements []string
+	for stmt := range strings.SplitSeq(b.String(), ";") {
+		if stmt = strings.TrimSpace(stmt); stmt != "" {
+			statements = append(statements, stmt)
+		}
+	}
+
+	return statements
+}
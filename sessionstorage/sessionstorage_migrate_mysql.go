@@ -0,0 +1,79 @@
+package sessionstorage
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/cccteam/ccc/tracer"
+	"github.com/go-playground/errors/v5"
+)
+
+// mysqlMigrateLockTimeout is the number of seconds MigrateMySQL waits for another instance to finish migrating.
+const mysqlMigrateLockTimeout = 60
+
+// MigrateMySQL creates or upgrades the session tables for variant in the current database of db.
+// MySQL commits DDL statements implicitly, so a named lock rather than a transaction keeps
+// concurrent callers from applying the same step twice.
+func MigrateMySQL(ctx context.Context, db *sql.DB, variant Variant, opts ...MigrateOption) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return errors.Wrap(err, "sql.DB.Conn()")
+	}
+	defer conn.Close()
+
+	var locked sql.NullInt64
+	if err := conn.QueryRowContext(ctx, `SELECT GET_LOCK(?, ?)`, "cccteam/session", mysqlMigrateLockTimeout).Scan(&locked); err != nil {
+		return errors.Wrap(err, "sql.Row.Scan()")
+	}
+	if locked.Int64 != 1 {
+		return errors.New("timed out waiting for the migration lock")
+	}
+	defer func() {
+		_, _ = conn.ExecContext(context.WithoutCancel(ctx), `SELECT RELEASE_LOCK(?)`, "cccteam/session")
+	}()
+
+	return migrate(ctx, &mysqlMigrator{conn: conn}, "mysql", variant, opts)
+}
+
+type mysqlMigrator struct {
+	conn *sql.Conn
+}
+
+func (m *mysqlMigrator) tableExists(ctx context.Context, table string) (bool, error) {
+	var exists bool
+	if err := m.conn.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM information_schema.tables
+			WHERE table_schema = DATABASE() AND table_name = ?
+		)`, table).Scan(&exists); err != nil {
+		return false, errors.Wrap(err, "sql.Row.Scan()")
+	}
+
+	return exists, nil
+}
+
+func (m *mysqlMigrator) columnExists(ctx context.Context, table, column string) (bool, error) {
+	var exists bool
+	if err := m.conn.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM information_schema.columns
+			WHERE table_schema = DATABASE() AND table_name = ? AND column_name = ?
+		)`, table, column).Scan(&exists); err != nil {
+		return false, errors.Wrap(err, "sql.Row.Scan()")
+	}
+
+	return exists, nil
+}
+
+func (m *mysqlMigrator) exec(ctx context.Context, script string) error {
+	for _, stmt := range splitStatements(script) {
+		if _, err := m.conn.ExecContext(ctx, stmt); err != nil {
+			return errors.Wrap(err, "sql.Conn.ExecContext()")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package sessionstorage
+
+import (
+	"context"
+
+	"github.com/cccteam/ccc/tracer"
+	"github.com/cccteam/session/sessionstorage/internal/postgres"
+	"github.com/go-playground/errors/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+// MigratePostgres creates or upgrades the session tables for variant in the current schema of conn.
+// It holds an advisory lock for the duration of the migration, so it is safe to call from every
+// instance of a service at startup.
+func MigratePostgres(ctx context.Context, conn postgres.Queryer, variant Variant, opts ...MigrateOption) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return errors.Wrap(err, "postgres.Queryer.Begin()")
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, "cccteam/session"); err != nil {
+		return errors.Wrap(err, "pgx.Tx.Exec()")
+	}
+
+	if err := migrate(ctx, &postgresMigrator{tx: tx}, "postgresql", variant, opts); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return errors.Wrap(err, "pgx.Tx.Commit()")
+	}
+
+	return nil
+}
+
+type postgresMigrator struct {
+	tx pgx.Tx
+}
+
+func (m *postgresMigrator) tableExists(ctx context.Context, table string) (bool, error) {
+	var exists bool
+	if err := m.tx.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM information_schema.tables
+			WHERE table_schema = current_schema() AND table_name = $1
+		)`, table).Scan(&exists); err != nil {
+		return false, errors.Wrap(err, "pgx.Row.Scan()")
+	}
+
+	return exists, nil
+}
+
+func (m *postgresMigrator) columnExists(ctx context.Context, table, column string) (bool, error) {
+	var exists bool
+	if err := m.tx.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM information_schema.columns
+			WHERE table_schema = current_schema() AND table_name = $1 AND column_name = $2
+		)`, table, column).Scan(&exists); err != nil {
+		return false, errors.Wrap(err, "pgx.Row.Scan()")
+	}
+
+	return exists, nil
+}
+
+func (m *postgresMigrator) exec(ctx context.Context, script string) error {
+	// Without arguments pgx uses the simple protocol, which accepts multiple statements.
+	if _, err := m.tx.Exec(ctx, stripTransaction(script)); err != nil {
+		return errors.Wrap(err, "pgx.Tx.Exec()")
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package sessionstorage
+
+import (
+	"context"
+
+	cloudspanner "cloud.google.com/go/spanner"
+	database "cloud.google.com/go/spanner/admin/database/apiv1"
+	"cloud.google.com/go/spanner/admin/database/apiv1/databasepb"
+	"github.com/cccteam/ccc/tracer"
+	"github.com/go-playground/errors/v5"
+)
+
+// MigrateSpanner creates or upgrades the session tables for variant in the database of client.
+// Schema changes are submitted through admin and Migrate waits for them to complete.
+func MigrateSpanner(ctx context.Context, admin *database.DatabaseAdminClient, client *cloudspanner.Client, variant Variant, opts ...MigrateOption) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	return migrate(ctx, &spannerMigrator{admin: admin, client: client}, "spanner", variant, opts)
+}
+
+type spannerMigrator struct {
+	admin  *database.DatabaseAdminClient
+	client *cloudspanner.Client
+}
+
+func (m *spannerMigrator) tableExists(ctx context.Context, table string) (bool, error) {
+	stmt := cloudspanner.Statement{
+		SQL: `SELECT COUNT(*) > 0 FROM INFORMATION_SCHEMA.TABLES
+			WHERE TABLE_SCHEMA = '' AND TABLE_NAME = @table`,
+		Params: map[string]any{"table": table},
+	}
+
+	return m.queryExists(ctx, stmt)
+}
+
+func (m *spannerMigrator) columnExists(ctx context.Context, table, column string) (bool, error) {
+	stmt := cloudspanner.Statement{
+		SQL: `SELECT COUNT(*) > 0 FROM INFORMATION_SCHEMA.COLUMNS
+			WHERE TABLE_SCHEMA = '' AND TABLE_NAME = @table AND COLUMN_NAME = @column`,
+		Params: map[string]any{"table": table, "column": column},
+	}
+
+	return m.queryExists(ctx, stmt)
+}
+
+func (m *spannerMigrator) queryExists(ctx context.Context, stmt cloudspanner.Statement) (bool, error) {
+	var exists bool
+	if err := m.client.Single().Query(ctx, stmt).Do(func(r *cloudspanner.Row) error {
+		return r.Column(0, &exists)
+	}); err != nil {
+		return false, errors.Wrap(err, "spanner.RowIterator.Do()")
+	}
+
+	return exists, nil
+}
+
+func (m *spannerMigrator) exec(ctx context.Context, script string) error {
+	op, err := m.admin.UpdateDatabaseDdl(ctx, &databasepb.UpdateDatabaseDdlRequest{
+		Database:   m.client.DatabaseName(),
+		Statements: splitStatements(script),
+	})
+	if err != nil {
+		return errors.Wrap(err, "database.DatabaseAdminClient.UpdateDatabaseDdl()")
+	}
+
+	if err := op.Wait(ctx); err != nil {
+		return errors.Wrap(err, "database.UpdateDatabaseDdlOperation.Wait()")
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package sessionstorage
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/cccteam/ccc/tracer"
+	"github.com/go-playground/errors/v5"
+)
+
+// MigrateSQLite creates or upgrades the session tables for variant in db.
+func MigrateSQLite(ctx context.Context, db *sql.DB, variant Variant, opts ...MigrateOption) error {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "sql.DB.BeginTx()")
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := migrate(ctx, &sqliteMigrator{tx: tx}, "sqlite", variant, opts); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, "sql.Tx.Commit()")
+	}
+
+	return nil
+}
+
+type sqliteMigrator struct {
+	tx *sql.Tx
+}
+
+func (m *sqliteMigrator) tableExists(ctx context.Context, table string) (bool, error) {
+	var exists bool
+	if err := m.tx.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = ?)`, table,
+	).Scan(&exists); err != nil {
+		return false, errors.Wrap(err, "sql.Row.Scan()")
+	}
+
+	return exists, nil
+}
+
+func (m *sqliteMigrator) columnExists(ctx context.Context, table, column string) (bool, error) {
+	var exists bool
+	if err := m.tx.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM pragma_table_info(?) WHERE name = ?)`, table, column,
+	).Scan(&exists); err != nil {
+		return false, errors.Wrap(err, "sql.Row.Scan()")
+	}
+
+	return exists, nil
+}
+
+func (m *sqliteMigrator) exec(ctx context.Context, script string) error {
+	if _, err := m.tx.ExecContext(ctx, stripTransaction(script)); err != nil {
+		return errors.Wrap(err, "sql.Tx.ExecContext()")
+	}
+
+	return nil
+}
@@ -0,0 +1,200 @@
+package sessionstorage
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/cccteam/ccc/securehash"
+	"github.com/cccteam/session/internal/dbtype"
+	"github.com/cccteam/session/sessionstorage/internal/sqlite"
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_renameTables(t *testing.T) {
+	t.Parallel()
+
+	script := `CREATE TABLE "Sessions" (CONSTRAINT "Sessions_pkey" PRIMARY KEY ("Id"));
+CREATE INDEX SessionsByOidcSid ON Sessions(OidcSid DESC);
+CREATE TABLE SessionUsers (CONSTRAINT CK_SessionUsersId CHECK (Id != ''));`
+
+	tests := []struct {
+		name string
+		cfg  *migrateConfig
+		want string
+	}{
+		{
+			name: "default names",
+			cfg:  &migrateConfig{sessionTableName: "Sessions", userTableName: "SessionUsers"},
+			want: script,
+		},
+		{
+			name: "custom names",
+			cfg:  &migrateConfig{sessionTableName: "AppSessions", userTableName: "AppUsers"},
+			want: `CREATE TABLE "AppSessions" (CONSTRAINT "AppSessions_pkey" PRIMARY KEY ("Id"));
+CREATE INDEX AppSessionsByOidcSid ON AppSessions(OidcSid DESC);
+CREATE TABLE AppUsers (CONSTRAINT CK_AppUsersId CHECK (Id != ''));`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if diff := cmp.Diff(tt.want, renameTables(script, tt.cfg)); diff != "" {
+				t.Errorf("renameTables() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_splitStatements(t *testing.T) {
+	t.Parallel()
+
+	script := `BEGIN;
+
+-- Table: Sessions
+-- DROP TABLE Sessions;
+
+CREATE TABLE Sessions (
+    Id STRING(36) NOT NULL
+) PRIMARY KEY (Id);
+
+CREATE INDEX SessionsByUsername ON Sessions(Username);
+
+COMMIT;
+`
+	want := []string{
+		"CREATE TABLE Sessions (\n    Id STRING(36) NOT NULL\n) PRIMARY KEY (Id)",
+		"CREATE INDEX SessionsByUsername ON Sessions(Username)",
+	}
+
+	if diff := cmp.Diff(want, splitStatements(script)); diff != "" {
+		t.Errorf("splitStatements() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func openSQLite(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "session.db")+"?_pragma=busy_timeout(5000)")
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	return db
+}
+
+func TestMigrateSQLite(t *testing.T) {
+	t.Parallel()
+
+	t.Run("password variant is idempotent", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		db := openSQLite(t)
+
+		for range 2 {
+			if err := MigrateSQLite(ctx, db, VariantPassword); err != nil {
+				t.Fatalf("MigrateSQLite() error = %v", err)
+			}
+		}
+
+		store := NewPasswordAuthWithDriver(sqlite.NewSessionStorageDriver(db))
+		user, err := store.CreateUser(ctx, &dbtype.InsertSessionUser{Username: "alice"})
+		if err != nil {
+			t.Fatalf("PasswordAuth.CreateUser() error = %v", err)
+		}
+		if _, err := store.NewSession(ctx, user.Username); err != nil {
+			t.Fatalf("PasswordAuth.NewSession() error = %v", err)
+		}
+	})
+
+	t.Run("oidc variant", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		db := openSQLite(t)
+
+		if err := MigrateSQLite(ctx, db, VariantOIDC); err != nil {
+			t.Fatalf("MigrateSQLite() error = %v", err)
+		}
+
+		store := NewOIDCWithDriver(sqlite.NewSessionStorageDriver(db))
+		if _, err := store.NewSession(ctx, "alice", "sid-1"); err != nil {
+			t.Fatalf("OIDC.NewSession() error = %v", err)
+		}
+		if err := store.DestroySessionOIDC(ctx, "sid-1"); err != nil {
+			t.Fatalf("OIDC.DestroySessionOIDC() error = %v", err)
+		}
+	})
+
+	t.Run("upgrades password schema to oidc", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		db := openSQLite(t)
+
+		if err := MigrateSQLite(ctx, db, VariantPassword); err != nil {
+			t.Fatalf("MigrateSQLite(VariantPassword) error = %v", err)
+		}
+		existing, err := NewPreauthWithDriver(sqlite.NewSessionStorageDriver(db)).NewSession(ctx, "alice")
+		if err != nil {
+			t.Fatalf("Preauth.NewSession() error = %v", err)
+		}
+
+		if err := MigrateSQLite(ctx, db, VariantOIDC); err != nil {
+			t.Fatalf("MigrateSQLite(VariantOIDC) error = %v", err)
+		}
+
+		store := NewOIDCWithDriver(sqlite.NewSessionStorageDriver(db))
+		if _, err := store.Session(ctx, existing); err != nil {
+			t.Fatalf("OIDC.Session() error = %v", err)
+		}
+		if _, err := store.NewSession(ctx, "bob", "sid-1"); err != nil {
+			t.Fatalf("OIDC.NewSession() error = %v", err)
+		}
+	})
+
+	t.Run("custom table names", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		db := openSQLite(t)
+
+		if err := MigrateSQLite(ctx, db, VariantPassword, WithSessionTableName("AppSessions"), WithUserTableName("AppUsers")); err != nil {
+			t.Fatalf("MigrateSQLite() error = %v", err)
+		}
+
+		var count int
+		if err := db.QueryRowContext(ctx,
+			`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name IN ('Sessions', 'SessionUsers')`,
+		).Scan(&count); err != nil {
+			t.Fatalf("sql.Row.Scan() error = %v", err)
+		}
+		if count != 0 {
+			t.Errorf("found %d tables with default names, want 0", count)
+		}
+
+		store := NewPasswordAuthWithDriver(sqlite.NewSessionStorageDriver(db))
+		store.SetSessionTableName("AppSessions")
+		store.SetUserTableName("AppUsers")
+
+		hash, err := securehash.New(securehash.Argon2()).Hash("password")
+		if err != nil {
+			t.Fatalf("securehash.Hash() error = %v", err)
+		}
+		user, err := store.CreateUser(ctx, &dbtype.InsertSessionUser{Username: "alice", PasswordHash: hash})
+		if err != nil {
+			t.Fatalf("PasswordAuth.CreateUser() error = %v", err)
+		}
+		if _, err := store.NewSession(ctx, user.Username); err != nil {
+			t.Fatalf("PasswordAuth.NewSession() error = %v", err)
+		}
+		if _, err := store.CreateUser(ctx, &dbtype.InsertSessionUser{Username: "ALICE"}); err == nil {
+			t.Errorf("PasswordAuth.CreateUser() expected a conflict for a duplicate normalized username")
+		}
+	})
+}
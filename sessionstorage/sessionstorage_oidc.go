@@ -2,12 +2,12 @@ package sessionstorage
 
 import (
 	"context"
-	"time"
 
 	cloudspanner "cloud.google.com/go/spanner"
 	"github.com/cccteam/ccc"
 	"github.com/cccteam/ccc/tracer"
 	"github.com/cccteam/session/internal/dbtype"
+	"github.com/cccteam/session/sessionstorage/internal/memory"
 	"github.com/cccteam/session/sessionstorage/internal/postgres"
 	"github.com/cccteam/session/sessionstorage/internal/spanner"
 	"github.com/go-playground/errors/v5"
@@ -38,18 +38,34 @@ func NewPostgresOIDC(pg postgres.Queryer) *OIDC {
 	}
 }
 
-// NewSession inserts SessionInfo into database
+// NewMemoryOIDC creates an OIDC storage instance that keeps sessions in process memory.
+// It is intended for tests and single-instance services; sessions are lost when the process exits.
+func NewMemoryOIDC() *OIDC {
+	return &OIDC{
+		sessionStorage: sessionStorage{
+			db: memory.NewSessionStorageDriver(),
+		},
+	}
+}
+
+// NewOIDCWithDriver creates an OIDC instance on top of a storage driver. It is used by the
+// driver packages, such as sessionstorage/mysql, that are imported only by the services that use them.
+func NewOIDCWithDriver(driver db) *OIDC {
+	return &OIDC{
+		sessionStorage: sessionStorage{
+			db: driver,
+		},
+	}
+}
+
+// NewSession inserts SessionInfo into database, recording the client from sessioninfo.ClientFromCtx(ctx)
 func (s *OIDC) NewSession(ctx context.Context, username, oidcSID string) (ccc.UUID, error) {
 	ctx, span := tracer.Start(ctx)
 	defer span.End()
 
 	session := &dbtype.InsertOIDCSession{
-		OidcSID: oidcSID,
-		InsertSession: dbtype.InsertSession{
-			Username:  username,
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
-		},
+		OidcSID:       oidcSID,
+		InsertSession: s.newInsertSession(ctx, username),
 	}
 
 	id, err := s.db.InsertSessionOIDC(ctx, session)
@@ -71,3 +87,16 @@ func (s *OIDC) DestroySessionOIDC(ctx context.Context, oidcSID string) error {
 
 	return nil
 }
+
+// UsernameOIDC returns the username of the session with the oidcSID
+func (s *OIDC) UsernameOIDC(ctx context.Context, oidcSID string) (string, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	username, err := s.db.UsernameOIDC(ctx, oidcSID)
+	if err != nil {
+		return "", errors.Wrap(err, "db.UsernameOIDC()")
+	}
+
+	return username, nil
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/cccteam/ccc/securehash"
 	"github.com/cccteam/ccc/tracer"
 	"github.com/cccteam/session/internal/dbtype"
+	"github.com/cccteam/session/sessionstorage/internal/memory"
 	"github.com/cccteam/session/sessionstorage/internal/postgres"
 	"github.com/cccteam/session/sessionstorage/internal/spanner"
 	"github.com/go-playground/errors/v5"
@@ -15,6 +16,13 @@ import (
 
 var _ PasswordAuthStore = (*PasswordAuth)(nil)
 
+type (
+	// SessionUser is a user record returned by a PasswordAuthStore.
+	SessionUser = dbtype.SessionUser
+	// InsertSessionUser is the user record passed to PasswordAuthStore.CreateUser.
+	InsertSessionUser = dbtype.InsertSessionUser
+)
+
 // PasswordAuth is the session storage implementation with PasswordAuth support.
 type PasswordAuth struct {
 	sessionStorage
@@ -38,6 +46,26 @@ func NewPostgresPassword(pg postgres.Queryer) *PasswordAuth {
 	}
 }
 
+// NewMemoryPasswordAuth creates a PasswordAuth storage instance that keeps sessions and users in process memory.
+// It is intended for tests and single-instance services; all data is lost when the process exits.
+func NewMemoryPasswordAuth() *PasswordAuth {
+	return &PasswordAuth{
+		sessionStorage: sessionStorage{
+			db: memory.NewSessionStorageDriver(),
+		},
+	}
+}
+
+// NewPasswordAuthWithDriver creates a PasswordAuth instance on top of a storage driver. It is used by the
+// driver packages, such as sessionstorage/mysql, that are imported only by the services that use them.
+func NewPasswordAuthWithDriver(driver db) *PasswordAuth {
+	return &PasswordAuth{
+		sessionStorage: sessionStorage{
+			db: driver,
+		},
+	}
+}
+
 // User returns the user record associated with the username
 func (p *PasswordAuth) User(ctx context.Context, id ccc.UUID) (*dbtype.SessionUser, error) {
 	ctx, span := tracer.Start(ctx)
@@ -2,6 +2,7 @@ package sessionstorage
 
 import (
 	cloudspanner "cloud.google.com/go/spanner"
+	"github.com/cccteam/session/sessionstorage/internal/memory"
 	"github.com/cccteam/session/sessionstorage/internal/postgres"
 	"github.com/cccteam/session/sessionstorage/internal/spanner"
 )
@@ -30,3 +31,23 @@ func NewPostgresPreauth(db postgres.Queryer) *Preauth {
 		},
 	}
 }
+
+// NewMemoryPreauth creates a Preauth storage instance that keeps sessions in process memory.
+// It is intended for tests and single-instance services; sessions are lost when the process exits.
+func NewMemoryPreauth() *Preauth {
+	return &Preauth{
+		sessionStorage: sessionStorage{
+			db: memory.NewSessionStorageDriver(),
+		},
+	}
+}
+
+// NewPreauthWithDriver creates a Preauth instance on top of a storage driver. It is used by the
+// driver packages, such as sessionstorage/mysql, that are imported only by the services that use them.
+func NewPreauthWithDriver(driver db) *Preauth {
+	return &Preauth{
+		sessionStorage: sessionStorage{
+			db: driver,
+		},
+	}
+}
@@ -0,0 +1,120 @@
+package sessionstorage
+
+import (
+	"context"
+	"time"
+
+	"github.com/cccteam/ccc/tracer"
+	"github.com/cccteam/logger"
+	"github.com/cccteam/session/internal/dbtype"
+	"github.com/go-playground/errors/v5"
+)
+
+// DefaultPurgeBatchSize is the number of rows deleted per statement when PurgeOptions.BatchSize is not set.
+const DefaultPurgeBatchSize = 1000
+
+var (
+	_ Purger = (*Preauth)(nil)
+	_ Purger = (*PasswordAuth)(nil)
+	_ Purger = (*OIDC)(nil)
+)
+
+// Purger defines an interface for deleting session rows that are no longer needed.
+type Purger interface {
+	// PurgeSessions deletes expired, idle and aged out session rows and returns the number of rows deleted.
+	PurgeSessions(ctx context.Context, opts PurgeOptions) (int64, error)
+}
+
+// PurgeOptions selects the session rows deleted by PurgeSessions. Expired sessions are always deleted.
+type PurgeOptions struct {
+	// IdleRetention deletes sessions that have had no activity for longer than this duration. Sessions with
+	// their own idle timeout, such as remember-me sessions, are kept until that timeout has also passed.
+	// Zero disables the check.
+	IdleRetention time.Duration
+	// MaxAge deletes sessions that were created longer ago than this duration. Zero disables the check.
+	MaxAge time.Duration
+	// BatchSize is the maximum number of rows deleted per statement. Defaults to DefaultPurgeBatchSize.
+	BatchSize int
+}
+
+// PurgeSessions deletes session rows in batches until no matching rows remain and returns the
+// number of rows deleted. When an error occurs, the rows deleted by earlier batches are still counted.
+func (s *sessionStorage) PurgeSessions(ctx context.Context, opts PurgeOptions) (int64, error) {
+	ctx, span := tracer.Start(ctx)
+	defer span.End()
+
+	now := time.Now()
+	purge := &dbtype.PurgeSessions{Now: now, Limit: opts.BatchSize}
+	if purge.Limit <= 0 {
+		purge.Limit = DefaultPurgeBatchSize
+	}
+	if opts.IdleRetention > 0 {
+		purge.IdleBefore = now.Add(-opts.IdleRetention)
+	}
+	if opts.MaxAge > 0 {
+		purge.CreatedBefore = now.Add(-opts.MaxAge)
+	}
+
+	var total int64
+	for {
+		n, err := s.db.PurgeSessions(ctx, purge)
+		if err != nil {
+			return total, errors.Wrap(err, "db.PurgeSessions()")
+		}
+		total += n
+
+		if n < int64(purge.Limit) {
+			return total, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return total, errors.Wrap(err, "context.Context.Err()")
+		}
+	}
+}
+
+// Reaper periodically purges session rows from a store in the background.
+type Reaper struct {
+	store    Purger
+	interval time.Duration
+	opts     PurgeOptions
+}
+
+// NewReaper creates a Reaper that calls store.PurgeSessions with opts every interval.
+func NewReaper(store Purger, interval time.Duration, opts PurgeOptions) *Reaper {
+	return &Reaper{
+		store:    store,
+		interval: interval,
+		opts:     opts,
+	}
+}
+
+// Run purges sessions immediately and then once every interval until ctx is canceled.
+// It blocks, so it is normally started in its own goroutine. The number of rows removed
+// by each run is logged, and a failed run is logged and retried on the next interval.
+func (r *Reaper) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		r.purge(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Reaper) purge(ctx context.Context) {
+	n, err := r.store.PurgeSessions(ctx, r.opts)
+	if err != nil {
+		if ctx.Err() == nil {
+			logger.FromCtx(ctx).Errorf("purged %d session rows before failing: %v", n, errors.Wrap(err, "Purger.PurgeSessions()"))
+		}
+
+		return
+	}
+
+	logger.FromCtx(ctx).Infof("purged %d session rows", n)
+}
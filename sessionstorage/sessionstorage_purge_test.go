@@ -0,0 +1,150 @@
+package sessionstorage
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cccteam/session/internal/dbtype"
+	"github.com/cccteam/session/sessionstorage/mock/mock_sessionstorage"
+	"github.com/go-playground/errors/v5"
+	gomock "go.uber.org/mock/gomock"
+)
+
+func Test_sessionStorage_PurgeSessions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		opts        PurgeOptions
+		prepare     func(*mock_sessionstorage.Mockdb)
+		wantDeleted int64
+		wantErr     bool
+	}{
+		{
+			name: "single batch with default batch size",
+			prepare: func(mockDB *mock_sessionstorage.Mockdb) {
+				mockDB.EXPECT().
+					PurgeSessions(gomock.Any(), gomock.Cond(func(p *dbtype.PurgeSessions) bool {
+						return p.Limit == DefaultPurgeBatchSize && p.IdleBefore.IsZero() && p.CreatedBefore.IsZero()
+					})).
+					Return(int64(3), nil).
+					Times(1)
+			},
+			wantDeleted: 3,
+		},
+		{
+			name: "repeats full batches until a partial batch",
+			opts: PurgeOptions{BatchSize: 2},
+			prepare: func(mockDB *mock_sessionstorage.Mockdb) {
+				gomock.InOrder(
+					mockDB.EXPECT().PurgeSessions(gomock.Any(), gomock.Any()).Return(int64(2), nil),
+					mockDB.EXPECT().PurgeSessions(gomock.Any(), gomock.Any()).Return(int64(2), nil),
+					mockDB.EXPECT().PurgeSessions(gomock.Any(), gomock.Any()).Return(int64(0), nil),
+				)
+			},
+			wantDeleted: 4,
+		},
+		{
+			name: "converts retention durations to cutoffs",
+			opts: PurgeOptions{IdleRetention: time.Hour, MaxAge: 24 * time.Hour},
+			prepare: func(mockDB *mock_sessionstorage.Mockdb) {
+				mockDB.EXPECT().
+					PurgeSessions(gomock.Any(), gomock.Cond(func(p *dbtype.PurgeSessions) bool {
+						idle := time.Since(p.IdleBefore)
+						created := time.Since(p.CreatedBefore)
+
+						return idle >= time.Hour && idle < time.Hour+time.Minute &&
+							created >= 24*time.Hour && created < 24*time.Hour+time.Minute
+					})).
+					Return(int64(1), nil).
+					Times(1)
+			},
+			wantDeleted: 1,
+		},
+		{
+			name: "error reports rows deleted by earlier batches",
+			opts: PurgeOptions{BatchSize: 5},
+			prepare: func(mockDB *mock_sessionstorage.Mockdb) {
+				gomock.InOrder(
+					mockDB.EXPECT().PurgeSessions(gomock.Any(), gomock.Any()).Return(int64(5), nil),
+					mockDB.EXPECT().PurgeSessions(gomock.Any(), gomock.Any()).Return(int64(0), errors.New("delete failed")),
+				)
+			},
+			wantDeleted: 5,
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			mockDB := mock_sessionstorage.NewMockdb(ctrl)
+			storage := &sessionStorage{
+				db: mockDB,
+			}
+
+			if tt.prepare != nil {
+				tt.prepare(mockDB)
+			}
+
+			got, err := storage.PurgeSessions(context.Background(), tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("PurgeSessions() error = %v, wantErr = %v", err, tt.wantErr)
+			}
+			if got != tt.wantDeleted {
+				t.Errorf("PurgeSessions() = %v, want %v", got, tt.wantDeleted)
+			}
+		})
+	}
+}
+
+type countingPurger struct {
+	calls atomic.Int64
+	opts  PurgeOptions
+}
+
+func (c *countingPurger) PurgeSessions(_ context.Context, opts PurgeOptions) (int64, error) {
+	c.calls.Add(1)
+	c.opts = opts
+
+	return 1, nil
+}
+
+func TestReaper_Run(t *testing.T) {
+	t.Parallel()
+
+	purger := &countingPurger{}
+	opts := PurgeOptions{IdleRetention: time.Hour, BatchSize: 10}
+	reaper := NewReaper(purger, 10*time.Millisecond, opts)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		reaper.Run(ctx)
+		close(done)
+	}()
+
+	deadline := time.After(5 * time.Second)
+	for purger.calls.Load() < 3 {
+		select {
+		case <-deadline:
+			t.Fatalf("Reaper.Run() purged %d times, want at least 3", purger.calls.Load())
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Reaper.Run() did not return after the context was canceled")
+	}
+
+	if purger.opts != opts {
+		t.Errorf("Reaper.Run() opts = %v, want %v", purger.opts, opts)
+	}
+}
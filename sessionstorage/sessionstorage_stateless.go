@@ -0,0 +1,125 @@
+package sessionstorage
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cccteam/ccc"
+	"github.com/cccteam/session/sessionstorage/internal/stateless"
+)
+
+// StatelessStore is implemented by the stores that can keep sessions in the Auth Cookie instead of a database.
+type StatelessStore interface {
+	// Stateless reports whether sessions are kept in the Auth Cookie, which the session handlers read
+	// into the request context and write back to the response when the session changed.
+	Stateless() bool
+}
+
+// Stateless reports whether sessions are kept in the Auth Cookie instead of a database.
+func (s *sessionStorage) Stateless() bool {
+	_, ok := s.db.(*stateless.SessionStorageDriver)
+
+	return ok
+}
+
+// Denylist records the sessions revoked from a stateless store, which can not delete sessions kept in cookies.
+// Its entries should be kept for at least the maximum session lifetime.
+type Denylist = stateless.Denylist
+
+// NewStatelessPreauth creates a Preauth storage instance that keeps each session, with its data, in the
+// encrypted Auth Cookie instead of a database. It must be used with the StartSession handler, which reads
+// the session from the cookie and writes it back before the response when it changed.
+//
+// Without a database the store only knows the session of the current request: UserSessions only lists it,
+// the session limit is not applied, impersonation is not supported, and other sessions can only be revoked
+// (RevokeSession, RevokeOtherSessions and DestroyAllUserSessions) when denylist is not nil. A revoked session
+// is denied when it is validated. denylist can be nil, in which case Logout still expires the session in its
+// cookie. Session data is limited by the size of a cookie (4 KB).
+func NewStatelessPreauth(denylist Denylist) *Preauth {
+	return &Preauth{
+		sessionStorage: sessionStorage{
+			db: stateless.NewSessionStorageDriver(denylist),
+		},
+	}
+}
+
+var _ Denylist = (*MemoryDenylist)(nil)
+
+// MemoryDenylist is a Denylist that keeps revoked sessions in process memory. Entries are removed
+// once they are older than the retention given to NewMemoryDenylist.
+type MemoryDenylist struct {
+	mu        sync.RWMutex
+	retention time.Duration
+	sessions  map[ccc.UUID]time.Time
+	users     map[string]time.Time
+}
+
+// NewMemoryDenylist creates a MemoryDenylist that keeps each entry for retention, which should be at
+// least the maximum session lifetime (session.WithMaxSessionLifetime). A retention of 0 keeps entries
+// until the process exits.
+func NewMemoryDenylist(retention time.Duration) *MemoryDenylist {
+	return &MemoryDenylist{
+		retention: retention,
+		sessions:  make(map[ccc.UUID]time.Time),
+		users:     make(map[string]time.Time),
+	}
+}
+
+// DenySession revokes the session with sessionID
+func (d *MemoryDenylist) DenySession(_ context.Context, sessionID ccc.UUID) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	d.removeExpired(now)
+	d.sessions[sessionID] = now
+
+	return nil
+}
+
+// DenyUserSessions revokes the sessions of username created before createdBefore
+func (d *MemoryDenylist) DenyUserSessions(_ context.Context, username string, createdBefore time.Time) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.removeExpired(time.Now())
+	if createdBefore.After(d.users[username]) {
+		d.users[username] = createdBefore
+	}
+
+	return nil
+}
+
+// Denied reports whether the session with sessionID, of username and created at createdAt, was revoked
+func (d *MemoryDenylist) Denied(_ context.Context, sessionID ccc.UUID, username string, createdAt time.Time) (bool, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if _, ok := d.sessions[sessionID]; ok {
+		return true, nil
+	}
+	if createdBefore, ok := d.users[username]; ok && createdAt.Before(createdBefore) {
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// removeExpired removes the entries older than the retention. The caller must hold d.mu.
+func (d *MemoryDenylist) removeExpired(now time.Time) {
+	if d.retention <= 0 {
+		return
+	}
+
+	for id, deniedAt := range d.sessions {
+		if now.Sub(deniedAt) > d.retention {
+			delete(d.sessions, id)
+		}
+	}
+	for username, createdBefore := range d.users {
+		if now.Sub(createdBefore) > d.retention {
+			delete(d.users, username)
+		}
+	}
+}
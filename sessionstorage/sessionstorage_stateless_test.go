@@ -0,0 +1,75 @@
+package sessionstorage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cccteam/ccc"
+)
+
+func TestMemoryDenylist_Denied(t *testing.T) {
+	t.Parallel()
+
+	deniedID := ccc.Must(ccc.NewUUID())
+	otherID := ccc.Must(ccc.NewUUID())
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		sessionID ccc.UUID
+		username  string
+		createdAt time.Time
+		want      bool
+	}{
+		{
+			name:      "denied session",
+			sessionID: deniedID,
+			username:  "other_user",
+			createdAt: now,
+			want:      true,
+		},
+		{
+			name:      "session of denied user created before",
+			sessionID: otherID,
+			username:  "test_user",
+			createdAt: now.Add(-time.Hour),
+			want:      true,
+		},
+		{
+			name:      "session of denied user created after",
+			sessionID: otherID,
+			username:  "test_user",
+			createdAt: now.Add(time.Hour),
+		},
+		{
+			name:      "session not denied",
+			sessionID: otherID,
+			username:  "other_user",
+			createdAt: now,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			d := NewMemoryDenylist(0)
+			if err := d.DenySession(ctx, deniedID); err != nil {
+				t.Fatalf("MemoryDenylist.DenySession() error = %v", err)
+			}
+			if err := d.DenyUserSessions(ctx, "test_user", now); err != nil {
+				t.Fatalf("MemoryDenylist.DenyUserSessions() error = %v", err)
+			}
+
+			got, err := d.Denied(ctx, tt.sessionID, tt.username, tt.createdAt)
+			if err != nil {
+				t.Fatalf("MemoryDenylist.Denied() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("MemoryDenylist.Denied() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
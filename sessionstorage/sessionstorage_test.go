@@ -104,6 +104,8 @@ func Test_sessionStorage_Session(t *testing.T) {
 				CreatedAt: ccc.Must(time.Parse(time.RFC3339, "2021-01-01T00:00:00Z")),
 				UpdatedAt: ccc.Must(time.Parse(time.RFC3339, "2021-01-01T00:00:00Z")),
 				Expired:   false,
+				// A session without a last authentication reports its login time
+				LastAuthenticatedAt: ccc.Must(time.Parse(time.RFC3339, "2021-01-01T00:00:00Z")),
 			},
 		},
 		{
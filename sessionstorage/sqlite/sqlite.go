@@ -0,0 +1,31 @@
+// Package sqlite provides the SQLite session storage. It is kept apart from the sessionstorage package
+// so that only services that store sessions in SQLite link, and register, the modernc.org/sqlite driver.
+// The session tables are created with sessionstorage.MigrateSQLite.
+package sqlite
+
+import (
+	"github.com/cccteam/session/sessionstorage"
+	"github.com/cccteam/session/sessionstorage/internal/sqlite"
+)
+
+// Queryer is an interface that wraps the basic BeginTx, QueryContext, QueryRowContext, and ExecContext methods.
+// It is satisfied by *sql.DB.
+type Queryer = sqlite.Queryer
+
+// NewPreauth is the function that you use to create the session manager that handles the session creation and updates.
+// The connection must be opened with the modernc.org/sqlite driver (driver name "sqlite").
+func NewPreauth(db Queryer) *sessionstorage.Preauth {
+	return sessionstorage.NewPreauthWithDriver(sqlite.NewSessionStorageDriver(db))
+}
+
+// NewPasswordAuth creates a new SQLite PasswordAuth instance.
+// The connection must be opened with the modernc.org/sqlite driver (driver name "sqlite").
+func NewPasswordAuth(db Queryer) *sessionstorage.PasswordAuth {
+	return sessionstorage.NewPasswordAuthWithDriver(sqlite.NewSessionStorageDriver(db))
+}
+
+// NewOIDC creates a new SQLite OIDC instance.
+// The connection must be opened with the modernc.org/sqlite driver (driver name "sqlite").
+func NewOIDC(db Queryer) *sessionstorage.OIDC {
+	return sessionstorage.NewOIDCWithDriver(sqlite.NewSessionStorageDriver(db))
+}
@@ -0,0 +1,214 @@
+// Package storagetest provides a conformance suite for session storage implementations.
+//
+// RunConformance checks a store against the rules the built-in drivers follow, so a
+// custom store can be verified before it is handed to the session handlers:
+//
+//	func TestStore(t *testing.T) {
+//		storagetest.RunConformance(t, storagetest.Factory{
+//			PasswordAuth: func(t *testing.T) sessionstorage.PasswordAuthStore {
+//				return newStore(t)
+//			},
+//		})
+//	}
+package storagetest
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/cccteam/ccc"
+	"github.com/cccteam/session/sessioninfo"
+	"github.com/cccteam/session/sessionstorage"
+)
+
+// clockTolerance is the difference allowed between the test clock and the
+// timestamps a store returns, which covers databases with coarser precision.
+const clockTolerance = time.Second
+
+// Factory creates the stores under test. Each function is called once per test and may
+// return stores that share a database, since every test works with its own usernames.
+// Functions left nil are skipped.
+type Factory struct {
+	// Preauth returns a store for the PreauthStore contract.
+	Preauth func(t *testing.T) sessionstorage.PreauthStore
+	// PasswordAuth returns a store for the PasswordAuthStore contract, which includes the PreauthStore contract.
+	PasswordAuth func(t *testing.T) sessionstorage.PasswordAuthStore
+	// OIDC returns a store for the OIDCStore contract.
+	OIDC func(t *testing.T) sessionstorage.OIDCStore
+}
+
+// RunConformance runs the conformance suite against the stores created by factory.
+// The tests run in parallel, so the stores must be safe for concurrent use.
+func RunConformance(t *testing.T, factory Factory) {
+	t.Helper()
+
+	if factory.Preauth != nil {
+		t.Run("Preauth", func(t *testing.T) {
+			t.Parallel()
+
+			runPreauth(t, factory.Preauth)
+		})
+	}
+
+	if factory.PasswordAuth != nil {
+		t.Run("PasswordAuth", func(t *testing.T) {
+			t.Parallel()
+
+			runPreauth(t, func(t *testing.T) sessionstorage.PreauthStore {
+				return factory.PasswordAuth(t)
+			})
+			runPasswordAuth(t, factory.PasswordAuth)
+		})
+	}
+
+	if factory.OIDC != nil {
+		t.Run("OIDC", func(t *testing.T) {
+			t.Parallel()
+
+			runOIDC(t, factory.OIDC)
+		})
+	}
+}
+
+// sessionStore is a BaseStore with the NewSession method of its contract.
+type sessionStore struct {
+	sessionstorage.BaseStore
+	newSession func(ctx context.Context, username string) (ccc.UUID, error)
+}
+
+// test is a single conformance test.
+type test[S any] struct {
+	name string
+	run  func(t *testing.T, store S)
+}
+
+// runTests runs each test in parallel against a store returned by newStore.
+func runTests[S any](t *testing.T, newStore func(t *testing.T) S, tests []test[S]) {
+	t.Helper()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tt.run(t, newStore(t))
+		})
+	}
+}
+
+// uniqueName returns prefix followed by a random suffix, so tests never share usernames.
+func uniqueName(t *testing.T, prefix string) string {
+	t.Helper()
+
+	id, err := ccc.NewUUID()
+	if err != nil {
+		t.Fatalf("ccc.NewUUID() error = %v", err)
+	}
+
+	return prefix + "-" + id.String()
+}
+
+// randomID returns an id that no store has seen.
+func randomID(t *testing.T) ccc.UUID {
+	t.Helper()
+
+	id, err := ccc.NewUUID()
+	if err != nil {
+		t.Fatalf("ccc.NewUUID() error = %v", err)
+	}
+
+	return id
+}
+
+func newSession(t *testing.T, store *sessionStore, username string) ccc.UUID {
+	t.Helper()
+
+	id, err := store.newSession(t.Context(), username)
+	if err != nil {
+		t.Fatalf("NewSession() error = %v", err)
+	}
+
+	return id
+}
+
+func session(t *testing.T, store sessionstorage.BaseStore, id ccc.UUID) *sessioninfo.SessionInfo {
+	t.Helper()
+
+	si, err := store.Session(t.Context(), id)
+	if err != nil {
+		t.Fatalf("Session() error = %v", err)
+	}
+
+	return si
+}
+
+// expired reports whether the session is marked as expired.
+func expired(t *testing.T, store sessionstorage.BaseStore, id ccc.UUID) bool {
+	t.Helper()
+
+	return session(t, store, id).Expired
+}
+
+// userSessions returns every session for username that matches filter, following the page cursors.
+func userSessions(t *testing.T, store sessionstorage.BaseStore, username string, filter sessionstorage.SessionFilter, size int) []*sessioninfo.SessionInfo {
+	t.Helper()
+
+	var sessions []*sessioninfo.SessionInfo
+	page := sessionstorage.Page{Size: size}
+	for {
+		p, err := store.UserSessions(t.Context(), username, filter, page)
+		if err != nil {
+			t.Fatalf("UserSessions() error = %v", err)
+		}
+		if size > 0 && len(p.Sessions) > size {
+			t.Fatalf("UserSessions() returned %d sessions, want at most %d", len(p.Sessions), size)
+		}
+		sessions = append(sessions, p.Sessions...)
+
+		if p.NextCursor == "" {
+			return sessions
+		}
+		if len(p.Sessions) == 0 {
+			t.Fatal("UserSessions() returned a cursor with an empty page")
+		}
+		page.Cursor = p.NextCursor
+	}
+}
+
+// sessionData returns the data of the session id, decoded so that values compare the same
+// whatever JSON formatting the store returns.
+func sessionData(t *testing.T, store sessionstorage.BaseStore, id ccc.UUID) map[string]any {
+	t.Helper()
+
+	data, err := store.SessionData(t.Context(), id)
+	if err != nil {
+		t.Fatalf("SessionData() error = %v", err)
+	}
+
+	decoded := make(map[string]any, len(data))
+	for name, value := range data {
+		var v any
+		if err := json.Unmarshal(value, &v); err != nil {
+			t.Fatalf("json.Unmarshal() of %q error = %v", name, err)
+		}
+		decoded[name] = v
+	}
+
+	return decoded
+}
+
+// sessionIDs returns the ids of sessions in order.
+func sessionIDs(sessions []*sessioninfo.SessionInfo) []ccc.UUID {
+	ids := make([]ccc.UUID, 0, len(sessions))
+	for _, s := range sessions {
+		ids = append(ids, s.ID)
+	}
+
+	return ids
+}
+
+// within reports whether ts falls between start and end, allowing for clockTolerance.
+func within(ts, start, end time.Time) bool {
+	return !ts.Before(start.Add(-clockTolerance)) && !ts.After(end.Add(clockTolerance))
+}
@@ -0,0 +1,119 @@
+package storagetest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cccteam/ccc"
+	"github.com/cccteam/httpio"
+	"github.com/cccteam/session/sessionstorage"
+)
+
+// runOIDC runs the OIDCStore contract, which includes the BaseStore contract.
+func runOIDC(t *testing.T, newStore func(t *testing.T) sessionstorage.OIDCStore) {
+	t.Helper()
+
+	runSessionTests(t, func(t *testing.T) *sessionStore {
+		store := newStore(t)
+
+		return &sessionStore{
+			BaseStore: store,
+			newSession: func(ctx context.Context, username string) (ccc.UUID, error) {
+				return store.NewSession(ctx, username, uniqueName(t, "sid"))
+			},
+		}
+	})
+
+	runTests(t, newStore, []test[sessionstorage.OIDCStore]{
+		{name: "DestroySessionOIDC", run: testDestroySessionOIDC},
+		{name: "DestroySessionOIDC after RotateSession", run: testDestroySessionOIDCRotated},
+		{name: "UsernameOIDC", run: testUsernameOIDC},
+	})
+}
+
+// testDestroySessionOIDC checks that a logout for one sid ends every session of
+// the user it belongs to, as the identity provider has ended the user's login.
+func testDestroySessionOIDC(t *testing.T, store sessionstorage.OIDCStore) {
+	username := uniqueName(t, "user")
+	sid := uniqueName(t, "sid")
+
+	ids := make([]ccc.UUID, 0, 2)
+	for _, s := range []string{sid, uniqueName(t, "sid")} {
+		id, err := store.NewSession(t.Context(), username, s)
+		if err != nil {
+			t.Fatalf("NewSession() error = %v", err)
+		}
+		ids = append(ids, id)
+	}
+	other, err := store.NewSession(t.Context(), uniqueName(t, "other"), uniqueName(t, "sid"))
+	if err != nil {
+		t.Fatalf("NewSession() error = %v", err)
+	}
+
+	if err := store.DestroySessionOIDC(t.Context(), sid); err != nil {
+		t.Fatalf("DestroySessionOIDC() error = %v", err)
+	}
+	for _, id := range ids {
+		if !expired(t, store, id) {
+			t.Errorf("Session(%v).Expired = false after DestroySessionOIDC(), want true", id)
+		}
+	}
+	if expired(t, store, other) {
+		t.Error("DestroySessionOIDC() expired a session of another user")
+	}
+
+	if err := store.DestroySessionOIDC(t.Context(), uniqueName(t, "unknown")); err != nil {
+		t.Errorf("DestroySessionOIDC() for an unknown sid error = %v, want nil", err)
+	}
+}
+
+// testDestroySessionOIDCRotated checks that a rotated session keeps its sid, so that a logout
+// from the identity provider still ends it.
+func testDestroySessionOIDCRotated(t *testing.T, store sessionstorage.OIDCStore) {
+	sid := uniqueName(t, "sid")
+	id, err := store.NewSession(t.Context(), uniqueName(t, "user"), sid)
+	if err != nil {
+		t.Fatalf("NewSession() error = %v", err)
+	}
+
+	rotated, err := store.RotateSession(t.Context(), id)
+	if err != nil {
+		t.Fatalf("RotateSession() error = %v", err)
+	}
+
+	if err := store.DestroySessionOIDC(t.Context(), sid); err != nil {
+		t.Fatalf("DestroySessionOIDC() error = %v", err)
+	}
+	if !expired(t, store, rotated) {
+		t.Error("Session().Expired = false after DestroySessionOIDC() of a rotated session, want true")
+	}
+}
+
+// testUsernameOIDC checks that the user of a logout request can be found by its sid,
+// including after the session was destroyed.
+func testUsernameOIDC(t *testing.T, store sessionstorage.OIDCStore) {
+	username := uniqueName(t, "user")
+	sid := uniqueName(t, "sid")
+	if _, err := store.NewSession(t.Context(), username, sid); err != nil {
+		t.Fatalf("NewSession() error = %v", err)
+	}
+
+	got, err := store.UsernameOIDC(t.Context(), sid)
+	if err != nil {
+		t.Fatalf("UsernameOIDC() error = %v", err)
+	}
+	if got != username {
+		t.Errorf("UsernameOIDC() = %q, want %q", got, username)
+	}
+
+	if err := store.DestroySessionOIDC(t.Context(), sid); err != nil {
+		t.Fatalf("DestroySessionOIDC() error = %v", err)
+	}
+	if got, err := store.UsernameOIDC(t.Context(), sid); err != nil || got != username {
+		t.Errorf("UsernameOIDC() after DestroySessionOIDC() = %q, %v, want %q, nil", got, err, username)
+	}
+
+	if _, err := store.UsernameOIDC(t.Context(), uniqueName(t, "unknown")); !httpio.HasNotFound(err) {
+		t.Errorf("UsernameOIDC() for an unknown sid error = %v, want NotFound", err)
+	}
+}
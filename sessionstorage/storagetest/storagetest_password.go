@@ -0,0 +1,269 @@
+package storagetest
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cccteam/ccc/securehash"
+	"github.com/cccteam/httpio"
+	"github.com/cccteam/session/sessionstorage"
+)
+
+const (
+	password    = "password"
+	newPassword = "new password"
+)
+
+var (
+	hasher = securehash.New(securehash.Argon2())
+
+	// The hashes are shared between tests because hashing is deliberately slow.
+	passwordHash    = sync.OnceValues(func() (*securehash.Hash, error) { return hasher.Hash(password) })
+	newPasswordHash = sync.OnceValues(func() (*securehash.Hash, error) { return hasher.Hash(newPassword) })
+)
+
+// runPasswordAuth runs the user management part of the PasswordAuthStore contract.
+func runPasswordAuth(t *testing.T, newStore func(t *testing.T) sessionstorage.PasswordAuthStore) {
+	t.Helper()
+
+	runTests(t, newStore, []test[sessionstorage.PasswordAuthStore]{
+		{name: "CreateUser", run: testCreateUser},
+		{name: "CreateUser conflict", run: testCreateUserConflict},
+		{name: "user not found", run: testUserNotFound},
+		{name: "SetUserUsername", run: testSetUserUsername},
+		{name: "SetUserUsername conflict", run: testSetUserUsernameConflict},
+		{name: "SetUserPasswordHash", run: testSetUserPasswordHash},
+		{name: "DeactivateUser and ActivateUser", run: testDeactivateUser},
+		{name: "DeleteUser", run: testDeleteUser},
+	})
+}
+
+func hash(t *testing.T, newHash func() (*securehash.Hash, error)) *securehash.Hash {
+	t.Helper()
+
+	h, err := newHash()
+	if err != nil {
+		t.Fatalf("securehash.SecureHasher.Hash() error = %v", err)
+	}
+
+	return h
+}
+
+func createUser(t *testing.T, store sessionstorage.PasswordAuthStore, username string) *sessionstorage.SessionUser {
+	t.Helper()
+
+	user, err := store.CreateUser(t.Context(), &sessionstorage.InsertSessionUser{
+		Username:     username,
+		PasswordHash: hash(t, passwordHash),
+	})
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+
+	return user
+}
+
+func user(t *testing.T, store sessionstorage.PasswordAuthStore, username string) *sessionstorage.SessionUser {
+	t.Helper()
+
+	u, err := store.UserByUserName(t.Context(), username)
+	if err != nil {
+		t.Fatalf("UserByUserName() error = %v", err)
+	}
+
+	return u
+}
+
+func testCreateUser(t *testing.T, store sessionstorage.PasswordAuthStore) {
+	username := uniqueName(t, "User")
+
+	created, err := store.CreateUser(t.Context(), &sessionstorage.InsertSessionUser{
+		Username:     username,
+		PasswordHash: hash(t, passwordHash),
+		Disabled:     true,
+	})
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	if created.Username != username || !created.Disabled {
+		t.Errorf("CreateUser() = %+v, want a disabled user named %q", created, username)
+	}
+
+	byID, err := store.User(t.Context(), created.ID)
+	if err != nil {
+		t.Fatalf("User() error = %v", err)
+	}
+	if byID.ID != created.ID || byID.Username != username || !byID.Disabled {
+		t.Errorf("User() = %+v, want %+v", byID, created)
+	}
+	if _, err := hasher.Compare(byID.PasswordHash, password); err != nil {
+		t.Errorf("User().PasswordHash does not match the password: %v", err)
+	}
+
+	// Usernames are looked up case-insensitively but keep the case they were created with.
+	for _, lookup := range []string{username, strings.ToUpper(username), strings.ToLower(username)} {
+		if got := user(t, store, lookup); got.ID != created.ID || got.Username != username {
+			t.Errorf("UserByUserName(%q) = %+v, want %+v", lookup, got, created)
+		}
+	}
+}
+
+func testCreateUserConflict(t *testing.T, store sessionstorage.PasswordAuthStore) {
+	username := uniqueName(t, "user")
+	createUser(t, store, username)
+
+	for _, duplicate := range []string{username, strings.ToUpper(username)} {
+		_, err := store.CreateUser(t.Context(), &sessionstorage.InsertSessionUser{
+			Username:     duplicate,
+			PasswordHash: hash(t, passwordHash),
+		})
+		if !httpio.HasConflict(err) {
+			t.Errorf("CreateUser(%q) error = %v, want a conflict error", duplicate, err)
+		}
+	}
+}
+
+func testUserNotFound(t *testing.T, store sessionstorage.PasswordAuthStore) {
+	ctx := t.Context()
+	id := randomID(t)
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{name: "User", call: func() error { _, err := store.User(ctx, id); return err }},
+		{name: "UserByUserName", call: func() error { _, err := store.UserByUserName(ctx, uniqueName(t, "unknown")); return err }},
+		{name: "SetUserUsername", call: func() error { return store.SetUserUsername(ctx, id, uniqueName(t, "user")) }},
+		{name: "SetUserPasswordHash", call: func() error { return store.SetUserPasswordHash(ctx, id, hash(t, newPasswordHash)) }},
+		{name: "ActivateUser", call: func() error { return store.ActivateUser(ctx, id) }},
+		{name: "DeactivateUser", call: func() error { return store.DeactivateUser(ctx, id) }},
+		{name: "DeleteUser", call: func() error { return store.DeleteUser(ctx, id) }},
+	}
+	for _, tt := range tests {
+		if err := tt.call(); !httpio.HasNotFound(err) {
+			t.Errorf("%s() error = %v, want a not found error", tt.name, err)
+		}
+	}
+}
+
+func testSetUserUsername(t *testing.T, store sessionstorage.PasswordAuthStore) {
+	oldUsername := uniqueName(t, "user")
+	newUsername := uniqueName(t, "renamed")
+	u := createUser(t, store, oldUsername)
+
+	active, err := store.NewSession(t.Context(), oldUsername)
+	if err != nil {
+		t.Fatalf("NewSession() error = %v", err)
+	}
+	destroyed, err := store.NewSession(t.Context(), oldUsername)
+	if err != nil {
+		t.Fatalf("NewSession() error = %v", err)
+	}
+	if err := store.DestroySession(t.Context(), destroyed); err != nil {
+		t.Fatalf("DestroySession() error = %v", err)
+	}
+
+	if err := store.SetUserUsername(t.Context(), u.ID, newUsername); err != nil {
+		t.Fatalf("SetUserUsername() error = %v", err)
+	}
+
+	if got := user(t, store, newUsername); got.ID != u.ID || got.Username != newUsername {
+		t.Errorf("UserByUserName() = %+v, want user %v named %q", got, u.ID, newUsername)
+	}
+	if _, err := store.UserByUserName(t.Context(), oldUsername); !httpio.HasNotFound(err) {
+		t.Errorf("UserByUserName() with the old username error = %v, want a not found error", err)
+	}
+
+	// Active sessions follow the user, destroyed sessions keep the name they were used with.
+	if got := session(t, store, active); got.Username != newUsername || got.Expired {
+		t.Errorf("Session() = %+v, want an active session for %q", got, newUsername)
+	}
+	if got := session(t, store, destroyed); got.Username != oldUsername || !got.Expired {
+		t.Errorf("Session() = %+v, want an expired session for %q", got, oldUsername)
+	}
+
+	// Changing only the case of the username is not a conflict with itself.
+	upper := strings.ToUpper(newUsername)
+	if err := store.SetUserUsername(t.Context(), u.ID, upper); err != nil {
+		t.Fatalf("SetUserUsername() to a new case error = %v", err)
+	}
+	if got := user(t, store, newUsername); got.Username != upper {
+		t.Errorf("UserByUserName().Username = %q, want %q", got.Username, upper)
+	}
+}
+
+func testSetUserUsernameConflict(t *testing.T, store sessionstorage.PasswordAuthStore) {
+	taken := uniqueName(t, "user")
+	createUser(t, store, taken)
+	username := uniqueName(t, "user")
+	u := createUser(t, store, username)
+
+	if err := store.SetUserUsername(t.Context(), u.ID, strings.ToUpper(taken)); !httpio.HasConflict(err) {
+		t.Errorf("SetUserUsername() error = %v, want a conflict error", err)
+	}
+	if got := user(t, store, username); got.ID != u.ID {
+		t.Errorf("UserByUserName() = %+v after a failed rename, want user %v", got, u.ID)
+	}
+}
+
+func testSetUserPasswordHash(t *testing.T, store sessionstorage.PasswordAuthStore) {
+	u := createUser(t, store, uniqueName(t, "user"))
+
+	if err := store.SetUserPasswordHash(t.Context(), u.ID, hash(t, newPasswordHash)); err != nil {
+		t.Fatalf("SetUserPasswordHash() error = %v", err)
+	}
+
+	got, err := store.User(t.Context(), u.ID)
+	if err != nil {
+		t.Fatalf("User() error = %v", err)
+	}
+	if _, err := hasher.Compare(got.PasswordHash, newPassword); err != nil {
+		t.Errorf("User().PasswordHash does not match the new password: %v", err)
+	}
+	if _, err := hasher.Compare(got.PasswordHash, password); err == nil {
+		t.Error("User().PasswordHash still matches the old password")
+	}
+}
+
+func testDeactivateUser(t *testing.T, store sessionstorage.PasswordAuthStore) {
+	username := uniqueName(t, "user")
+	u := createUser(t, store, username)
+	if u.Disabled {
+		t.Fatal("CreateUser().Disabled = true, want false")
+	}
+
+	if err := store.DeactivateUser(t.Context(), u.ID); err != nil {
+		t.Fatalf("DeactivateUser() error = %v", err)
+	}
+	if !user(t, store, username).Disabled {
+		t.Error("Disabled = false after DeactivateUser(), want true")
+	}
+
+	if err := store.ActivateUser(t.Context(), u.ID); err != nil {
+		t.Fatalf("ActivateUser() error = %v", err)
+	}
+	if user(t, store, username).Disabled {
+		t.Error("Disabled = true after ActivateUser(), want false")
+	}
+}
+
+func testDeleteUser(t *testing.T, store sessionstorage.PasswordAuthStore) {
+	username := uniqueName(t, "user")
+	u := createUser(t, store, username)
+
+	if err := store.DeleteUser(t.Context(), u.ID); err != nil {
+		t.Fatalf("DeleteUser() error = %v", err)
+	}
+	if _, err := store.User(t.Context(), u.ID); !httpio.HasNotFound(err) {
+		t.Errorf("User() error = %v, want a not found error", err)
+	}
+	if _, err := store.UserByUserName(t.Context(), username); !httpio.HasNotFound(err) {
+		t.Errorf("UserByUserName() error = %v, want a not found error", err)
+	}
+
+	// The username is free to use again.
+	if recreated := createUser(t, store, username); recreated.ID == u.ID {
+		t.Errorf("CreateUser() reused the id %v of a deleted user", u.ID)
+	}
+}
@@ -0,0 +1,690 @@
+package storagetest
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cccteam/ccc"
+	"github.com/cccteam/httpio"
+	"github.com/cccteam/session/sessioninfo"
+	"github.com/cccteam/session/sessionstorage"
+	"github.com/google/go-cmp/cmp"
+)
+
+// concurrency is the number of goroutines used by the concurrent update tests.
+const concurrency = 10
+
+// runPreauth runs the PreauthStore contract, which includes the BaseStore contract.
+func runPreauth(t *testing.T, newStore func(t *testing.T) sessionstorage.PreauthStore) {
+	t.Helper()
+
+	runSessionTests(t, func(t *testing.T) *sessionStore {
+		store := newStore(t)
+
+		return &sessionStore{BaseStore: store, newSession: store.NewSession}
+	})
+
+	runTests(t, newStore, []test[sessionstorage.PreauthStore]{
+		{name: "DestroyAllUserSessions", run: testDestroyAllUserSessions},
+		{name: "NewPersistentSession", run: testNewPersistentSession},
+		{name: "UserSessions persistent session idle timeout", run: testUserSessionsPersistentIdleTimeout},
+	})
+}
+
+// runSessionTests runs the BaseStore contract.
+func runSessionTests(t *testing.T, newStore func(t *testing.T) *sessionStore) {
+	t.Helper()
+
+	runTests(t, newStore, []test[*sessionStore]{
+		{name: "NewSession", run: testNewSession},
+		{name: "NewSession client", run: testNewSessionClient},
+		{name: "NewSession impersonator", run: testNewSessionImpersonator},
+		{name: "NewSession limit evicts oldest", run: testNewSessionLimitEvict},
+		{name: "NewSession limit rejects", run: testNewSessionLimitReject},
+		{name: "Session not found", run: testSessionNotFound},
+		{name: "UpdateSessionActivity", run: testUpdateSessionActivity},
+		{name: "UpdateSessionActivity not found", run: testUpdateSessionActivityNotFound},
+		{name: "UpdateSessionAuthentication", run: testUpdateSessionAuthentication},
+		{name: "UpdateSessionAuthentication not found", run: testUpdateSessionAuthenticationNotFound},
+		{name: "UpdateSessionsActivity", run: testUpdateSessionsActivity},
+		{name: "DestroySession", run: testDestroySession},
+		{name: "DestroySession not found", run: testDestroySessionNotFound},
+		{name: "RotateSession", run: testRotateSession},
+		{name: "RotateSession not found", run: testRotateSessionNotFound},
+		{name: "SessionData", run: testSessionData},
+		{name: "SessionData after RotateSession", run: testSessionDataRotated},
+		{name: "UpdateSessionData not found", run: testUpdateSessionDataNotFound},
+		{name: "UserSessions", run: testUserSessions},
+		{name: "UserSessions pagination", run: testUserSessionsPagination},
+		{name: "UserSessions idle timeout", run: testUserSessionsIdleTimeout},
+		{name: "UserSessions invalid cursor", run: testUserSessionsInvalidCursor},
+		{name: "concurrent updates", run: testConcurrentUpdates},
+	})
+}
+
+func testNewSession(t *testing.T, store *sessionStore) {
+	username := uniqueName(t, "user")
+
+	start := time.Now()
+	id := newSession(t, store, username)
+	end := time.Now()
+
+	got := session(t, store, id)
+	if got.ID != id {
+		t.Errorf("Session().ID = %v, want %v", got.ID, id)
+	}
+	if got.Username != username {
+		t.Errorf("Session().Username = %q, want %q", got.Username, username)
+	}
+	if got.Expired {
+		t.Error("Session().Expired = true, want false")
+	}
+	if !within(got.CreatedAt, start, end) {
+		t.Errorf("Session().CreatedAt = %v, want between %v and %v", got.CreatedAt, start, end)
+	}
+	if !within(got.UpdatedAt, start, end) {
+		t.Errorf("Session().UpdatedAt = %v, want between %v and %v", got.UpdatedAt, start, end)
+	}
+	if !within(got.LastAuthenticatedAt, start, end) {
+		t.Errorf("Session().LastAuthenticatedAt = %v, want between %v and %v", got.LastAuthenticatedAt, start, end)
+	}
+
+	if other := newSession(t, store, username); other == id {
+		t.Errorf("NewSession() returned id %v twice", id)
+	}
+}
+
+func testNewSessionClient(t *testing.T, store *sessionStore) {
+	client := &sessioninfo.Client{
+		IP:         "203.0.113.7",
+		UserAgent:  "Mozilla/5.0 (storagetest)",
+		AuthMethod: sessioninfo.AuthMethodPassword,
+	}
+	ctx := context.WithValue(t.Context(), sessioninfo.CtxClient, client)
+
+	id, err := store.newSession(ctx, uniqueName(t, "user"))
+	if err != nil {
+		t.Fatalf("NewSession() error = %v", err)
+	}
+
+	// The client is kept when the session is rotated
+	rotated, err := store.RotateSession(t.Context(), id)
+	if err != nil {
+		t.Fatalf("RotateSession() error = %v", err)
+	}
+
+	got := session(t, store, rotated)
+	if got.ClientIP != client.IP {
+		t.Errorf("Session().ClientIP = %q, want %q", got.ClientIP, client.IP)
+	}
+	if got.UserAgent != client.UserAgent {
+		t.Errorf("Session().UserAgent = %q, want %q", got.UserAgent, client.UserAgent)
+	}
+	if got.AuthMethod != client.AuthMethod {
+		t.Errorf("Session().AuthMethod = %q, want %q", got.AuthMethod, client.AuthMethod)
+	}
+}
+
+func testNewSessionImpersonator(t *testing.T, store *sessionStore) {
+	store.SetSessionLimit(sessionstorage.SessionLimit{MaxSessions: 1, Policy: sessionstorage.RejectNewSession, IdleTimeout: time.Hour})
+
+	username := uniqueName(t, "user")
+	current := newSession(t, store, username)
+	if got := session(t, store, current); got.Impersonator != nil {
+		t.Errorf("Session().Impersonator = %+v, want nil for a session that is not impersonated", got.Impersonator)
+	}
+
+	impersonator := &sessioninfo.Impersonator{Username: uniqueName(t, "admin"), SessionID: randomID(t)}
+	ctx := context.WithValue(t.Context(), sessioninfo.CtxImpersonator, impersonator)
+
+	// An impersonated session is not subject to the session limit of the user
+	id, err := store.newSession(ctx, username)
+	if err != nil {
+		t.Fatalf("NewSession() error = %v", err)
+	}
+	if expired(t, store, current) {
+		t.Error("Session().Expired = true after an impersonated NewSession(), want false")
+	}
+
+	// The impersonator is kept when the session is rotated
+	rotated, err := store.RotateSession(t.Context(), id)
+	if err != nil {
+		t.Fatalf("RotateSession() error = %v", err)
+	}
+
+	got := session(t, store, rotated)
+	if got.Username != username {
+		t.Errorf("Session().Username = %q, want %q", got.Username, username)
+	}
+	if diff := cmp.Diff(impersonator, got.Impersonator); diff != "" {
+		t.Errorf("Session().Impersonator mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// newSessions creates n sessions for username, oldest first.
+func newSessions(t *testing.T, store *sessionStore, username string, n int) []ccc.UUID {
+	t.Helper()
+
+	ids := make([]ccc.UUID, 0, n)
+	for range n {
+		ids = append(ids, newSession(t, store, username))
+		// Keep the creation times distinct, so that the oldest session is well defined
+		time.Sleep(time.Millisecond)
+	}
+
+	return ids
+}
+
+func testNewSessionLimitEvict(t *testing.T, store *sessionStore) {
+	store.SetSessionLimit(sessionstorage.SessionLimit{MaxSessions: 2, Policy: sessionstorage.EvictOldestSession, IdleTimeout: time.Hour})
+
+	username := uniqueName(t, "user")
+	ids := newSessions(t, store, username, 3)
+	other := newSession(t, store, uniqueName(t, "other"))
+
+	for i, want := range []bool{true, false, false} {
+		if got := expired(t, store, ids[i]); got != want {
+			t.Errorf("Session(%d).Expired = %v, want %v", i, got, want)
+		}
+	}
+	if expired(t, store, other) {
+		t.Error("NewSession() expired a session of another user")
+	}
+
+	// A destroyed session no longer counts towards the limit
+	if err := store.DestroySession(t.Context(), ids[1]); err != nil {
+		t.Fatalf("DestroySession() error = %v", err)
+	}
+	id := newSession(t, store, username)
+	if expired(t, store, ids[2]) {
+		t.Error("Session().Expired = true, want false when a destroyed session made room")
+	}
+	if expired(t, store, id) {
+		t.Error("Session().Expired = true for the new session, want false")
+	}
+}
+
+func testNewSessionLimitReject(t *testing.T, store *sessionStore) {
+	store.SetSessionLimit(sessionstorage.SessionLimit{MaxSessions: 2, Policy: sessionstorage.RejectNewSession, IdleTimeout: time.Hour})
+
+	username := uniqueName(t, "user")
+	ids := newSessions(t, store, username, 2)
+
+	if _, err := store.newSession(t.Context(), username); !httpio.HasConflict(err) {
+		t.Fatalf("NewSession() error = %v, want a conflict error", err)
+	}
+	for i, id := range ids {
+		if expired(t, store, id) {
+			t.Errorf("Session(%d).Expired = true after a rejected NewSession(), want false", i)
+		}
+	}
+	if got := userSessions(t, store, username, sessionstorage.SessionFilter{State: sessionstorage.SessionStateAll}, 0); len(got) != len(ids) {
+		t.Errorf("UserSessions() returned %d sessions after a rejected NewSession(), want %d", len(got), len(ids))
+	}
+}
+
+func testSessionNotFound(t *testing.T, store *sessionStore) {
+	_, err := store.Session(t.Context(), randomID(t))
+	if !httpio.HasNotFound(err) {
+		t.Errorf("Session() error = %v, want a not found error", err)
+	}
+}
+
+func testUpdateSessionActivity(t *testing.T, store *sessionStore) {
+	id := newSession(t, store, uniqueName(t, "user"))
+	before := session(t, store, id)
+
+	time.Sleep(10 * time.Millisecond)
+	if err := store.UpdateSessionActivity(t.Context(), id); err != nil {
+		t.Fatalf("UpdateSessionActivity() error = %v", err)
+	}
+
+	after := session(t, store, id)
+	if !after.UpdatedAt.After(before.UpdatedAt) {
+		t.Errorf("Session().UpdatedAt = %v, want after %v", after.UpdatedAt, before.UpdatedAt)
+	}
+	if !after.CreatedAt.Equal(before.CreatedAt) {
+		t.Errorf("Session().CreatedAt = %v, want %v", after.CreatedAt, before.CreatedAt)
+	}
+	if after.Expired {
+		t.Error("Session().Expired = true, want false")
+	}
+}
+
+func testUpdateSessionActivityNotFound(t *testing.T, store *sessionStore) {
+	err := store.UpdateSessionActivity(t.Context(), randomID(t))
+	if !httpio.HasNotFound(err) {
+		t.Errorf("UpdateSessionActivity() error = %v, want a not found error", err)
+	}
+}
+
+func testUpdateSessionAuthentication(t *testing.T, store *sessionStore) {
+	id := newSession(t, store, uniqueName(t, "user"))
+	before := session(t, store, id)
+
+	time.Sleep(10 * time.Millisecond)
+	start := time.Now()
+	if err := store.UpdateSessionAuthentication(t.Context(), id); err != nil {
+		t.Fatalf("UpdateSessionAuthentication() error = %v", err)
+	}
+	end := time.Now()
+
+	// The last authentication is kept when the session is rotated
+	rotated, err := store.RotateSession(t.Context(), id)
+	if err != nil {
+		t.Fatalf("RotateSession() error = %v", err)
+	}
+
+	after := session(t, store, rotated)
+	if !within(after.LastAuthenticatedAt, start, end) {
+		t.Errorf("Session().LastAuthenticatedAt = %v, want between %v and %v", after.LastAuthenticatedAt, start, end)
+	}
+	if !after.UpdatedAt.After(before.UpdatedAt) {
+		t.Errorf("Session().UpdatedAt = %v, want after %v", after.UpdatedAt, before.UpdatedAt)
+	}
+	if !after.CreatedAt.Equal(before.CreatedAt) {
+		t.Errorf("Session().CreatedAt = %v, want %v", after.CreatedAt, before.CreatedAt)
+	}
+}
+
+func testUpdateSessionAuthenticationNotFound(t *testing.T, store *sessionStore) {
+	err := store.UpdateSessionAuthentication(t.Context(), randomID(t))
+	if !httpio.HasNotFound(err) {
+		t.Errorf("UpdateSessionAuthentication() error = %v, want a not found error", err)
+	}
+}
+
+// testUpdateSessionsActivity runs for stores that implement ActivityStore.
+func testUpdateSessionsActivity(t *testing.T, store *sessionStore) {
+	activityStore, ok := store.BaseStore.(sessionstorage.ActivityStore)
+	if !ok {
+		t.Skip("store does not implement sessionstorage.ActivityStore")
+	}
+
+	username := uniqueName(t, "user")
+	ids := []ccc.UUID{newSession(t, store, username), newSession(t, store, username)}
+
+	// Whole seconds survive every database's timestamp precision.
+	updatedAt := time.Now().Add(time.Minute).Truncate(time.Second)
+	activity := []*sessionstorage.SessionActivity{
+		{ID: ids[0], UpdatedAt: updatedAt},
+		{ID: ids[1], UpdatedAt: updatedAt.Add(time.Second)},
+		{ID: randomID(t), UpdatedAt: updatedAt},
+	}
+	if err := activityStore.UpdateSessionsActivity(t.Context(), activity); err != nil {
+		t.Fatalf("UpdateSessionsActivity() error = %v", err)
+	}
+
+	for _, a := range activity[:2] {
+		if got := session(t, store, a.ID); !got.UpdatedAt.Equal(a.UpdatedAt) {
+			t.Errorf("Session(%v).UpdatedAt = %v, want %v", a.ID, got.UpdatedAt, a.UpdatedAt)
+		}
+	}
+	if _, err := store.Session(t.Context(), activity[2].ID); !httpio.HasNotFound(err) {
+		t.Errorf("Session() error = %v after UpdateSessionsActivity() for a missing session, want a not found error", err)
+	}
+
+	// Activity flushed late must not move UpdatedAt backwards past a newer write.
+	stale := []*sessionstorage.SessionActivity{{ID: ids[0], UpdatedAt: updatedAt.Add(-time.Hour)}}
+	if err := activityStore.UpdateSessionsActivity(t.Context(), stale); err != nil {
+		t.Fatalf("UpdateSessionsActivity() error = %v", err)
+	}
+	if got := session(t, store, ids[0]); !got.UpdatedAt.Equal(updatedAt) {
+		t.Errorf("Session(%v).UpdatedAt = %v after stale UpdateSessionsActivity(), want %v", ids[0], got.UpdatedAt, updatedAt)
+	}
+
+	if err := activityStore.UpdateSessionsActivity(t.Context(), nil); err != nil {
+		t.Errorf("UpdateSessionsActivity() with no activity error = %v, want nil", err)
+	}
+}
+
+func testDestroySession(t *testing.T, store *sessionStore) {
+	username := uniqueName(t, "user")
+	id := newSession(t, store, username)
+	other := newSession(t, store, username)
+
+	if err := store.DestroySession(t.Context(), id); err != nil {
+		t.Fatalf("DestroySession() error = %v", err)
+	}
+	if !expired(t, store, id) {
+		t.Error("Session().Expired = false after DestroySession(), want true")
+	}
+	if expired(t, store, other) {
+		t.Error("DestroySession() expired another session of the same user")
+	}
+
+	// Activity on a destroyed session must not bring it back.
+	if err := store.UpdateSessionActivity(t.Context(), id); err != nil {
+		t.Fatalf("UpdateSessionActivity() error = %v", err)
+	}
+	if !expired(t, store, id) {
+		t.Error("Session().Expired = false after UpdateSessionActivity() on a destroyed session, want true")
+	}
+
+	if err := store.DestroySession(t.Context(), id); err != nil {
+		t.Errorf("DestroySession() on a destroyed session error = %v, want nil", err)
+	}
+}
+
+func testDestroySessionNotFound(t *testing.T, store *sessionStore) {
+	if err := store.DestroySession(t.Context(), randomID(t)); err != nil {
+		t.Errorf("DestroySession() error = %v, want nil", err)
+	}
+}
+
+// testRotateSession checks that a rotated session keeps its data under the new ID and
+// that the old ID can no longer be used.
+func testRotateSession(t *testing.T, store *sessionStore) {
+	username := uniqueName(t, "user")
+	id := newSession(t, store, username)
+	want := session(t, store, id)
+
+	rotated, err := store.RotateSession(t.Context(), id)
+	if err != nil {
+		t.Fatalf("RotateSession() error = %v", err)
+	}
+	if rotated == id {
+		t.Fatalf("RotateSession() = %v, want a new id", rotated)
+	}
+
+	if _, err := store.Session(t.Context(), id); !httpio.HasNotFound(err) {
+		t.Errorf("Session() of the old id error = %v, want a not found error", err)
+	}
+	got := session(t, store, rotated)
+	if got.ID != rotated {
+		t.Errorf("Session().ID = %v, want %v", got.ID, rotated)
+	}
+	if got.Username != want.Username || got.Expired != want.Expired {
+		t.Errorf("Session() = %+v, want the data of %+v", got, want)
+	}
+	if !got.CreatedAt.Equal(want.CreatedAt) || !got.UpdatedAt.Equal(want.UpdatedAt) {
+		t.Errorf("Session() times = %v, %v, want %v, %v", got.CreatedAt, got.UpdatedAt, want.CreatedAt, want.UpdatedAt)
+	}
+
+	if ids := sessionIDs(userSessions(t, store, username, sessionstorage.SessionFilter{}, 10)); !slices.Equal(ids, []ccc.UUID{rotated}) {
+		t.Errorf("UserSessions() = %v, want %v", ids, []ccc.UUID{rotated})
+	}
+}
+
+func testRotateSessionNotFound(t *testing.T, store *sessionStore) {
+	destroyed := newSession(t, store, uniqueName(t, "user"))
+	if err := store.DestroySession(t.Context(), destroyed); err != nil {
+		t.Fatalf("DestroySession() error = %v", err)
+	}
+
+	for name, id := range map[string]ccc.UUID{"unknown": randomID(t), "destroyed": destroyed} {
+		if _, err := store.RotateSession(t.Context(), id); !httpio.HasNotFound(err) {
+			t.Errorf("RotateSession() of a %s session error = %v, want a not found error", name, err)
+		}
+	}
+}
+
+func testSessionData(t *testing.T, store *sessionStore) {
+	id := newSession(t, store, uniqueName(t, "user"))
+	other := newSession(t, store, uniqueName(t, "user"))
+
+	if got := sessionData(t, store, id); len(got) != 0 {
+		t.Errorf("SessionData() of a new session = %v, want empty", got)
+	}
+
+	set := map[string]json.RawMessage{
+		"cart":  json.RawMessage(`{"items":[1,2]}`),
+		"theme": json.RawMessage(`"dark"`),
+		"step":  json.RawMessage(`3`),
+	}
+	if err := store.UpdateSessionData(t.Context(), id, set, nil); err != nil {
+		t.Fatalf("UpdateSessionData() error = %v", err)
+	}
+	want := map[string]any{"cart": map[string]any{"items": []any{1.0, 2.0}}, "theme": "dark", "step": 3.0}
+	if diff := cmp.Diff(want, sessionData(t, store, id)); diff != "" {
+		t.Errorf("SessionData() mismatch (-want +got):\n%s", diff)
+	}
+
+	update := map[string]json.RawMessage{"step": json.RawMessage(`4`)}
+	if err := store.UpdateSessionData(t.Context(), id, update, []string{"theme", "unknown"}); err != nil {
+		t.Fatalf("UpdateSessionData() error = %v", err)
+	}
+	want = map[string]any{"cart": map[string]any{"items": []any{1.0, 2.0}}, "step": 4.0}
+	if diff := cmp.Diff(want, sessionData(t, store, id)); diff != "" {
+		t.Errorf("SessionData() after update mismatch (-want +got):\n%s", diff)
+	}
+
+	if got := sessionData(t, store, other); len(got) != 0 {
+		t.Errorf("SessionData() of another session = %v, want empty", got)
+	}
+}
+
+// testSessionDataRotated checks that the data of a session moves with it to its new ID.
+func testSessionDataRotated(t *testing.T, store *sessionStore) {
+	id := newSession(t, store, uniqueName(t, "user"))
+	if err := store.UpdateSessionData(t.Context(), id, map[string]json.RawMessage{"theme": json.RawMessage(`"dark"`)}, nil); err != nil {
+		t.Fatalf("UpdateSessionData() error = %v", err)
+	}
+
+	rotated, err := store.RotateSession(t.Context(), id)
+	if err != nil {
+		t.Fatalf("RotateSession() error = %v", err)
+	}
+
+	if diff := cmp.Diff(map[string]any{"theme": "dark"}, sessionData(t, store, rotated)); diff != "" {
+		t.Errorf("SessionData() of the new id mismatch (-want +got):\n%s", diff)
+	}
+	if got := sessionData(t, store, id); len(got) != 0 {
+		t.Errorf("SessionData() of the old id = %v, want empty", got)
+	}
+}
+
+func testUpdateSessionDataNotFound(t *testing.T, store *sessionStore) {
+	set := map[string]json.RawMessage{"theme": json.RawMessage(`"dark"`)}
+	if err := store.UpdateSessionData(t.Context(), randomID(t), set, nil); !httpio.HasNotFound(err) {
+		t.Errorf("UpdateSessionData() error = %v, want a not found error", err)
+	}
+}
+
+func testUserSessions(t *testing.T, store *sessionStore) {
+	username := uniqueName(t, "user")
+
+	// Sessions are created apart so their order is not left to the id tiebreak.
+	oldest := newSession(t, store, username)
+	time.Sleep(5 * time.Millisecond)
+	destroyed := newSession(t, store, username)
+	time.Sleep(5 * time.Millisecond)
+	newest := newSession(t, store, username)
+	newSession(t, store, uniqueName(t, "other"))
+
+	if err := store.DestroySession(t.Context(), destroyed); err != nil {
+		t.Fatalf("DestroySession() error = %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		state sessionstorage.SessionState
+		want  []ccc.UUID
+	}{
+		{name: "all", state: sessionstorage.SessionStateAll, want: []ccc.UUID{newest, destroyed, oldest}},
+		{name: "active", state: sessionstorage.SessionStateActive, want: []ccc.UUID{newest, oldest}},
+		{name: "expired", state: sessionstorage.SessionStateExpired, want: []ccc.UUID{destroyed}},
+	}
+	for _, tt := range tests {
+		got := userSessions(t, store, username, sessionstorage.SessionFilter{State: tt.state}, 0)
+		if ids := sessionIDs(got); !slices.Equal(ids, tt.want) {
+			t.Errorf("UserSessions(%s) = %v, want %v", tt.name, ids, tt.want)
+		}
+		for _, s := range got {
+			if s.Username != username {
+				t.Errorf("UserSessions(%s) returned a session for %q, want %q", tt.name, s.Username, username)
+			}
+		}
+	}
+
+	if got := userSessions(t, store, uniqueName(t, "unknown"), sessionstorage.SessionFilter{}, 0); len(got) != 0 {
+		t.Errorf("UserSessions() for an unknown user = %v, want none", sessionIDs(got))
+	}
+}
+
+func testUserSessionsPagination(t *testing.T, store *sessionStore) {
+	username := uniqueName(t, "user")
+	for range 5 {
+		newSession(t, store, username)
+	}
+
+	want := sessionIDs(userSessions(t, store, username, sessionstorage.SessionFilter{}, 0))
+	if len(want) != 5 {
+		t.Fatalf("UserSessions() returned %d sessions, want 5", len(want))
+	}
+
+	for _, size := range []int{1, 2, 5} {
+		got := sessionIDs(userSessions(t, store, username, sessionstorage.SessionFilter{}, size))
+		if !slices.Equal(got, want) {
+			t.Errorf("UserSessions() with page size %d = %v, want %v", size, got, want)
+		}
+	}
+}
+
+func testUserSessionsIdleTimeout(t *testing.T, store *sessionStore) {
+	username := uniqueName(t, "user")
+	idle := newSession(t, store, username)
+	time.Sleep(50 * time.Millisecond)
+	active := newSession(t, store, username)
+
+	filter := sessionstorage.SessionFilter{State: sessionstorage.SessionStateActive, IdleTimeout: 25 * time.Millisecond}
+	if got := sessionIDs(userSessions(t, store, username, filter, 0)); !slices.Equal(got, []ccc.UUID{active}) {
+		t.Errorf("UserSessions(active) = %v, want %v", got, []ccc.UUID{active})
+	}
+
+	filter.State = sessionstorage.SessionStateExpired
+	if got := sessionIDs(userSessions(t, store, username, filter, 0)); !slices.Equal(got, []ccc.UUID{idle}) {
+		t.Errorf("UserSessions(expired) = %v, want %v", got, []ccc.UUID{idle})
+	}
+}
+
+func testUserSessionsInvalidCursor(t *testing.T, store *sessionStore) {
+	_, err := store.UserSessions(t.Context(), uniqueName(t, "user"), sessionstorage.SessionFilter{}, sessionstorage.Page{Cursor: "not a cursor"})
+	if !httpio.HasBadRequest(err) {
+		t.Errorf("UserSessions() error = %v, want a bad request error", err)
+	}
+}
+
+func testConcurrentUpdates(t *testing.T, store *sessionStore) {
+	username := uniqueName(t, "user")
+	id := newSession(t, store, username)
+
+	var wg sync.WaitGroup
+	for range concurrency {
+		wg.Go(func() {
+			if err := store.UpdateSessionActivity(t.Context(), id); err != nil {
+				t.Errorf("UpdateSessionActivity() error = %v", err)
+			}
+			if _, err := store.newSession(t.Context(), username); err != nil {
+				t.Errorf("NewSession() error = %v", err)
+			}
+			if _, err := store.Session(t.Context(), id); err != nil {
+				t.Errorf("Session() error = %v", err)
+			}
+		})
+	}
+	wg.Wait()
+
+	got := session(t, store, id)
+	if got.Expired || got.Username != username {
+		t.Errorf("Session() = %+v, want an active session for %q", got, username)
+	}
+
+	if sessions := userSessions(t, store, username, sessionstorage.SessionFilter{}, 0); len(sessions) != concurrency+1 {
+		t.Errorf("UserSessions() returned %d sessions, want %d", len(sessions), concurrency+1)
+	}
+}
+
+func testDestroyAllUserSessions(t *testing.T, store sessionstorage.PreauthStore) {
+	username := uniqueName(t, "user")
+	otherUsername := uniqueName(t, "other")
+
+	var ids []ccc.UUID
+	for range 3 {
+		id, err := store.NewSession(t.Context(), username)
+		if err != nil {
+			t.Fatalf("NewSession() error = %v", err)
+		}
+		ids = append(ids, id)
+	}
+	other, err := store.NewSession(t.Context(), otherUsername)
+	if err != nil {
+		t.Fatalf("NewSession() error = %v", err)
+	}
+
+	// A session in which the user impersonates another user is destroyed with the sessions of the user
+	impersonator := &sessioninfo.Impersonator{Username: username, SessionID: ids[0]}
+	impersonation, err := store.NewSession(context.WithValue(t.Context(), sessioninfo.CtxImpersonator, impersonator), otherUsername)
+	if err != nil {
+		t.Fatalf("NewSession() error = %v", err)
+	}
+	ids = append(ids, impersonation)
+
+	if err := store.DestroyAllUserSessions(t.Context(), username); err != nil {
+		t.Fatalf("DestroyAllUserSessions() error = %v", err)
+	}
+	for _, id := range ids {
+		if !expired(t, store, id) {
+			t.Errorf("Session(%v).Expired = false after DestroyAllUserSessions(), want true", id)
+		}
+	}
+	if expired(t, store, other) {
+		t.Error("DestroyAllUserSessions() expired a session of another user")
+	}
+
+	if err := store.DestroyAllUserSessions(t.Context(), uniqueName(t, "unknown")); err != nil {
+		t.Errorf("DestroyAllUserSessions() for an unknown user error = %v, want nil", err)
+	}
+}
+
+func testNewPersistentSession(t *testing.T, store sessionstorage.PreauthStore) {
+	username := uniqueName(t, "user")
+
+	id, err := store.NewPersistentSession(t.Context(), username, time.Hour)
+	if err != nil {
+		t.Fatalf("NewPersistentSession() error = %v", err)
+	}
+
+	got := session(t, store, id)
+	if got.Username != username {
+		t.Errorf("Session().Username = %q, want %q", got.Username, username)
+	}
+	if got.IdleTimeout != time.Hour {
+		t.Errorf("Session().IdleTimeout = %v, want %v", got.IdleTimeout, time.Hour)
+	}
+
+	other, err := store.NewSession(t.Context(), username)
+	if err != nil {
+		t.Fatalf("NewSession() error = %v", err)
+	}
+	if got := session(t, store, other); got.IdleTimeout != 0 {
+		t.Errorf("Session().IdleTimeout = %v for a NewSession() session, want 0", got.IdleTimeout)
+	}
+}
+
+func testUserSessionsPersistentIdleTimeout(t *testing.T, store sessionstorage.PreauthStore) {
+	username := uniqueName(t, "user")
+	idle, err := store.NewSession(t.Context(), username)
+	if err != nil {
+		t.Fatalf("NewSession() error = %v", err)
+	}
+	persistent, err := store.NewPersistentSession(t.Context(), username, time.Hour)
+	if err != nil {
+		t.Fatalf("NewPersistentSession() error = %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	filter := sessionstorage.SessionFilter{State: sessionstorage.SessionStateActive, IdleTimeout: 25 * time.Millisecond}
+	if got := sessionIDs(userSessions(t, store, username, filter, 0)); !slices.Equal(got, []ccc.UUID{persistent}) {
+		t.Errorf("UserSessions(active) = %v, want %v", got, []ccc.UUID{persistent})
+	}
+
+	filter.State = sessionstorage.SessionStateExpired
+	if got := sessionIDs(userSessions(t, store, username, filter, 0)); !slices.Equal(got, []ccc.UUID{idle}) {
+		t.Errorf("UserSessions(expired) = %v, want %v", got, []ccc.UUID{idle})
+	}
+}
@@ -0,0 +1,105 @@
+package storagetest_test
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/cccteam/session/sessionstorage"
+	"github.com/cccteam/session/sessionstorage/redis"
+	"github.com/cccteam/session/sessionstorage/sqlite"
+	"github.com/cccteam/session/sessionstorage/storagetest"
+	goredis "github.com/redis/go-redis/v9"
+	_ "modernc.org/sqlite"
+)
+
+func TestMemory(t *testing.T) {
+	t.Parallel()
+
+	storagetest.RunConformance(t, storagetest.Factory{
+		Preauth: func(*testing.T) sessionstorage.PreauthStore {
+			return sessionstorage.NewMemoryPreauth()
+		},
+		PasswordAuth: func(*testing.T) sessionstorage.PasswordAuthStore {
+			return sessionstorage.NewMemoryPasswordAuth()
+		},
+		OIDC: func(*testing.T) sessionstorage.OIDCStore {
+			return sessionstorage.NewMemoryOIDC()
+		},
+	})
+}
+
+func TestSQLite(t *testing.T) {
+	t.Parallel()
+
+	storagetest.RunConformance(t, storagetest.Factory{
+		Preauth: func(t *testing.T) sessionstorage.PreauthStore {
+			return sqlite.NewPreauth(openSQLite(t, sessionstorage.VariantPassword))
+		},
+		PasswordAuth: func(t *testing.T) sessionstorage.PasswordAuthStore {
+			return sqlite.NewPasswordAuth(openSQLite(t, sessionstorage.VariantPassword))
+		},
+		OIDC: func(t *testing.T) sessionstorage.OIDCStore {
+			return sqlite.NewOIDC(openSQLite(t, sessionstorage.VariantOIDC))
+		},
+	})
+}
+
+func TestRedis(t *testing.T) {
+	t.Parallel()
+
+	storagetest.RunConformance(t, storagetest.Factory{
+		Preauth: func(t *testing.T) sessionstorage.PreauthStore {
+			return redis.NewPreauth(openRedis(t), time.Hour)
+		},
+		OIDC: func(t *testing.T) sessionstorage.OIDCStore {
+			return redis.NewOIDC(openRedis(t), time.Hour)
+		},
+	})
+}
+
+func TestCached(t *testing.T) {
+	t.Parallel()
+
+	storagetest.RunConformance(t, storagetest.Factory{
+		Preauth: func(*testing.T) sessionstorage.PreauthStore {
+			return sessionstorage.NewCachedPreauth(sessionstorage.NewMemoryPreauth(), sessionstorage.CacheOptions{})
+		},
+		PasswordAuth: func(*testing.T) sessionstorage.PasswordAuthStore {
+			return sessionstorage.NewCachedPasswordAuth(sessionstorage.NewMemoryPasswordAuth(), sessionstorage.CacheOptions{})
+		},
+		OIDC: func(*testing.T) sessionstorage.OIDCStore {
+			return sessionstorage.NewCachedOIDC(sessionstorage.NewMemoryOIDC(), sessionstorage.CacheOptions{})
+		},
+	})
+}
+
+// openSQLite creates a database file with the schema for variant.
+func openSQLite(t *testing.T, variant sessionstorage.Variant) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "session.db")+"?_pragma=busy_timeout(5000)")
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	if err := sessionstorage.MigrateSQLite(t.Context(), db, variant); err != nil {
+		t.Fatalf("sessionstorage.MigrateSQLite() error = %v", err)
+	}
+
+	return db
+}
+
+// openRedis starts an in-process Redis server and returns a client connected to it.
+func openRedis(t *testing.T) goredis.UniversalClient {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	return client
+}